@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultAgentInterval 探针默认上报间隔
+	defaultAgentInterval = 30 * time.Second
+	// defaultAgentTimeout 超过该时间未收到上报时认为探针失联
+	defaultAgentTimeout = 2 * time.Minute
+)
+
+// AgentConfig 探针模式配置，Server非空时在本机执行检查并上报到中心节点
+type AgentConfig struct {
+	// Server 中心节点地址，如 https://status.example.com
+	Server string `yaml:"server"`
+	// Name 探针名称，需与中心节点配置一致
+	Name string `yaml:"name"`
+	// Token 上报认证令牌，用于签名请求，不在请求中传输
+	Token string `yaml:"token"`
+	// TokenFile 轮换后令牌的保存路径，文件存在时优先于token
+	TokenFile string `yaml:"token_file,omitempty"`
+	// RotateInterval 自动轮换令牌的间隔，为0时不轮换，需配置token_file
+	RotateInterval time.Duration `yaml:"rotate_interval,omitempty"`
+	// Interval 检查与上报间隔
+	Interval time.Duration `yaml:"interval"`
+}
+
+// validate 校验探针模式配置
+func (ac *AgentConfig) validate() error {
+	if ac.RotateInterval < 0 {
+		return fmt.Errorf("agent.rotate_interval不能为负数")
+	}
+	if ac.RotateInterval > 0 && ac.TokenFile == "" {
+		return fmt.Errorf("agent.rotate_interval需要配置token_file保存轮换后的令牌")
+	}
+	return nil
+}
+
+// RemoteAgentConfig 中心节点允许上报的探针
+type RemoteAgentConfig struct {
+	// Name 探针名称
+	Name string `yaml:"name"`
+	// Token 上报认证令牌，探针轮换后以轮换结果为准，修改该值可重置为新的令牌
+	Token string `yaml:"token"`
+	// AllowBearer 是否接受仅携带Bearer令牌、未签名的上报，用于尚未升级的探针；这类请求无法防止重放
+	AllowBearer bool `yaml:"allow_bearer,omitempty"`
+}
+
+// AgentReport 探针上报的数据
+type AgentReport struct {
+	// Agent 探针名称
+	Agent string `json:"agent"`
+	// Services 探针检查的服务状态
+	Services []*Service `json:"services"`
+	// SentAt 上报时间
+	SentAt time.Time `json:"sent_at"`
+}
+
+// agentState 中心节点保存的探针状态
+type agentState struct {
+	report   AgentReport
+	received time.Time
+}
+
+// AgentRegistry 中心节点的探针注册表，保存各探针的令牌与最近一次上报
+type AgentRegistry struct {
+	lock    sync.RWMutex
+	creds   map[string]*agentCredential
+	timeout time.Duration
+	agents  map[string]*agentState
+	// nonces 近期签名请求使用过的随机数，用于拒绝重放
+	nonces map[string]time.Time
+	// states 轮换后的令牌
+	states map[string]agentCredentialState
+	// path 轮换后令牌的保存路径，为空时仅保存在内存中
+	path string
+}
+
+// NewAgentRegistry 创建探针注册表
+func NewAgentRegistry(agents []RemoteAgentConfig, timeout time.Duration) *AgentRegistry {
+	if timeout <= 0 {
+		timeout = defaultAgentTimeout
+	}
+	creds := make(map[string]*agentCredential, len(agents))
+	for _, agent := range agents {
+		creds[agent.Name] = &agentCredential{configured: agent.Token, current: agent.Token, allowBearer: agent.AllowBearer}
+	}
+	return &AgentRegistry{
+		creds:   creds,
+		timeout: timeout,
+		agents:  make(map[string]*agentState),
+		nonces:  make(map[string]time.Time),
+		states:  make(map[string]agentCredentialState),
+	}
+}
+
+// Report 保存探针上报
+func (ar *AgentRegistry) Report(report AgentReport) {
+	ar.lock.Lock()
+	defer ar.lock.Unlock()
+	ar.agents[report.Agent] = &agentState{report: report, received: time.Now()}
+}
+
+// Services 返回所有探针上报的服务，失联探针的服务标记为离线
+func (ar *AgentRegistry) Services() []*Service {
+	ar.lock.RLock()
+	defer ar.lock.RUnlock()
+
+	now := time.Now()
+	locale := defaultLocale.Load().(string)
+	services := make([]*Service, 0)
+	for name, state := range ar.agents {
+		stale := now.Sub(state.received) > ar.timeout
+		for _, reported := range state.report.Services {
+			service := *reported
+			service.Host = name
+			// 探针只上报已启用的服务
+			service.Enabled = true
+			if stale {
+				service.Status = StatusOffline
+				service.LastError = translate(locale, "agent.stale", name, formatTime(locale, state.received, "datetime"))
+			}
+			services = append(services, &service)
+		}
+	}
+	return services
+}
+
+// apiAgentReportHandler 接收探针上报
+func apiAgentReportHandler(c *gin.Context) {
+	name := c.Param("name")
+	body, token, ok := readAgentRequest(c)
+	if !ok {
+		return
+	}
+
+	var report AgentReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		apiError(c, http.StatusBadRequest, "error.invalid_report")
+		return
+	}
+	report.Agent = name
+	agentRegistry.Report(report)
+	signedAgentResponse(c, token, http.StatusNoContent, nil)
+}
+
+// Agent 探针，定期在本机执行检查并上报到中心节点
+type Agent struct {
+	cfg     AgentConfig
+	manager *ServiceManager
+	client  *http.Client
+	// token 当前令牌，轮换后更新
+	token string
+	// rotated 最近一次轮换令牌的时间
+	rotated time.Time
+}
+
+// NewAgent 创建探针，token_file存在时使用其中保存的轮换后令牌
+func NewAgent(cfg AgentConfig, manager *ServiceManager) *Agent {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultAgentInterval
+	}
+	a := &Agent{
+		cfg:     cfg,
+		manager: manager,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		token:   cfg.Token,
+		rotated: time.Now(),
+	}
+	if cfg.TokenFile != "" {
+		if info, err := os.Stat(cfg.TokenFile); err == nil {
+			a.rotated = info.ModTime()
+			if data, err := os.ReadFile(cfg.TokenFile); err == nil && strings.TrimSpace(string(data)) != "" {
+				a.token = strings.TrimSpace(string(data))
+			}
+		}
+	}
+	return a
+}
+
+// Run 循环检查并上报，直到ctx取消
+func (a *Agent) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		a.manager.UpdateAllStatus()
+		if err := a.push(ctx); err != nil {
+			slog.Warn("探针上报失败", "server", a.cfg.Server, "error", err)
+		}
+		if a.cfg.RotateInterval > 0 && time.Since(a.rotated) >= a.cfg.RotateInterval {
+			if err := a.rotate(ctx); err != nil {
+				slog.Warn("探针轮换令牌失败", "server", a.cfg.Server, "error", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// push 上报一次当前服务状态
+func (a *Agent) push(ctx context.Context) error {
+	services := make([]*Service, 0)
+	for _, service := range a.manager.GetServices() {
+		if service.Enabled {
+			services = append(services, service)
+		}
+	}
+	report := AgentReport{
+		Agent:    a.cfg.Name,
+		Services: services,
+		SentAt:   time.Now(),
+	}
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	_, err = a.send(ctx, "report", body)
+	return err
+}
+
+// rotate 向中心节点申请新的令牌，先写入token_file再切换，写入失败时继续使用当前令牌
+func (a *Agent) rotate(ctx context.Context) error {
+	data, err := a.send(ctx, "rotate", nil)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil || result.Token == "" {
+		return fmt.Errorf("无效的轮换响应")
+	}
+	tmp := a.cfg.TokenFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(result.Token+"\n"), 0600); err != nil {
+		return fmt.Errorf("保存令牌失败: %v", err)
+	}
+	if err := os.Rename(tmp, a.cfg.TokenFile); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("保存令牌失败: %v", err)
+	}
+	a.token, a.rotated = result.Token, time.Now()
+	slog.Info("探针令牌已轮换", "server", a.cfg.Server)
+	return nil
+}
+
+// send 以当前令牌签名并发送请求到中心节点的探针接口，校验响应的中心节点签名后返回响应体
+func (a *Agent) send(ctx context.Context, action string, body []byte) ([]byte, error) {
+	base, err := url.Parse(a.cfg.Server)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := base.JoinPath("api/v1/agents", a.cfg.Name, action)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := newAgentNonce()
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(agentTimestampHeader, timestamp)
+	req.Header.Set(agentNonceHeader, nonce)
+	// 签名的路径不含中心节点地址中的前缀，兼容反向代理
+	path := "/api/v1/agents/" + a.cfg.Name + "/" + action
+	req.Header.Set(agentSignatureHeader, signAgentRequest(a.token, http.MethodPost, path, timestamp, nonce, body))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAgentRequestSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("中心节点返回状态码 %d", resp.StatusCode)
+	}
+	expected := signServerResponse(a.token, nonce, resp.StatusCode, data)
+	if !hmac.Equal([]byte(expected), []byte(resp.Header.Get(serverSignatureHeader))) {
+		return nil, fmt.Errorf("中心节点的响应签名无效，可能不是配置的中心节点")
+	}
+	return data, nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 探针签名请求与中心节点签名响应使用的请求头
+const (
+	agentTimestampHeader  = "X-Agent-Timestamp"
+	agentNonceHeader      = "X-Agent-Nonce"
+	agentSignatureHeader  = "X-Agent-Signature"
+	serverSignatureHeader = "X-Server-Signature"
+)
+
+const (
+	// agentMaxClockSkew 签名请求的时间戳与中心节点时间允许的最大偏差，也是随机数的保留时间
+	agentMaxClockSkew = 5 * time.Minute
+	// maxAgentRequestSize 探针请求体的最大字节数
+	maxAgentRequestSize = 8 << 20
+)
+
+// agentCredential 中心节点保存的探针令牌，轮换后上一个令牌在探针使用新令牌前仍然有效，
+// 避免轮换响应丢失时探针无法再上报
+type agentCredential struct {
+	// configured 配置文件中的令牌
+	configured string
+	// current 当前令牌，未轮换时为配置文件中的令牌
+	current string
+	// previous 轮换前的令牌，探针使用新令牌后清空
+	previous string
+	// allowBearer 是否接受不签名、仅携带Bearer令牌的上报
+	allowBearer bool
+}
+
+// agentCredentialState 持久化的轮换后令牌
+type agentCredentialState struct {
+	// Base 轮换所基于的配置令牌的SHA-256，配置文件中的令牌修改后轮换结果作废
+	Base string `json:"base"`
+	// Token 当前令牌
+	Token string `json:"token"`
+	// Previous 轮换前的令牌
+	Previous string `json:"previous,omitempty"`
+	// RotatedAt 最近一次轮换时间
+	RotatedAt time.Time `json:"rotated_at"`
+}
+
+// sha256Hex 返回数据SHA-256的十六进制形式
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacHex 以令牌为密钥计算各部分以换行连接后的HMAC-SHA256
+func hmacHex(token string, parts ...string) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signAgentRequest 计算探针请求的签名，覆盖方法、路径、时间戳、随机数与请求体
+func signAgentRequest(token, method, path, timestamp, nonce string, body []byte) string {
+	return hmacHex(token, method, path, timestamp, nonce, sha256Hex(body))
+}
+
+// signServerResponse 计算中心节点响应的签名，覆盖请求的随机数、状态码与响应体，
+// 探针据此确认响应来自持有同一令牌的中心节点
+func signServerResponse(token, nonce string, status int, body []byte) string {
+	return hmacHex(token, "response", nonce, strconv.Itoa(status), sha256Hex(body))
+}
+
+// newAgentNonce 生成请求随机数
+func newAgentNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newAgentToken 生成轮换后的探针令牌
+func newAgentToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Open 加载检查记录存储目录下保存的轮换后令牌，配置文件中的令牌已修改的探针以配置为准；
+// 存储路径为空时轮换结果仅保存在内存中，重启后恢复为配置文件中的令牌
+func (ar *AgentRegistry) Open(storage StorageConfig) error {
+	if storage.Path == "" {
+		return nil
+	}
+	ar.lock.Lock()
+	defer ar.lock.Unlock()
+	ar.path = strings.TrimSuffix(storage.Path, filepath.Ext(storage.Path)) + ".agents.json"
+
+	data, err := os.ReadFile(ar.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取探针令牌失败: %v", err)
+	}
+	states := make(map[string]agentCredentialState)
+	if err := json.Unmarshal(data, &states); err != nil {
+		return fmt.Errorf("解析探针令牌失败: %v", err)
+	}
+	for name, state := range states {
+		cred, ok := ar.creds[name]
+		if !ok || state.Base != sha256Hex([]byte(cred.configured)) || state.Token == "" {
+			continue
+		}
+		cred.current, cred.previous = state.Token, state.Previous
+		ar.states[name] = state
+	}
+	return nil
+}
+
+// save 保存轮换后的令牌，需持有锁调用
+func (ar *AgentRegistry) save() error {
+	if ar.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(ar.states)
+	if err != nil {
+		return err
+	}
+	tmp := ar.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("保存探针令牌失败: %v", err)
+	}
+	if err := os.Rename(tmp, ar.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("保存探针令牌失败: %v", err)
+	}
+	return nil
+}
+
+// Verify 校验探针请求：签名请求需时间戳在允许偏差内、随机数未使用过且签名与当前或轮换前的令牌匹配；
+// 配置了allow_bearer的探针也可仅携带Bearer令牌。返回匹配的令牌，用于签名响应
+func (ar *AgentRegistry) Verify(c *gin.Context, name string, body []byte) (string, error) {
+	ar.lock.Lock()
+	defer ar.lock.Unlock()
+
+	cred, ok := ar.creds[name]
+	if !ok || cred.current == "" {
+		return "", fmt.Errorf("未知的探针")
+	}
+	signature := c.GetHeader(agentSignatureHeader)
+	if signature == "" {
+		token := bearerToken(c)
+		if !cred.allowBearer {
+			return "", fmt.Errorf("请求未签名")
+		}
+		for _, candidate := range []string{cred.current, cred.previous} {
+			if candidate != "" && subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+				return candidate, nil
+			}
+		}
+		return "", fmt.Errorf("令牌无效")
+	}
+
+	timestamp, nonce := c.GetHeader(agentTimestampHeader), c.GetHeader(agentNonceHeader)
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("无效的时间戳")
+	}
+	now := time.Now()
+	if skew := now.Sub(time.Unix(sec, 0)); skew > agentMaxClockSkew || skew < -agentMaxClockSkew {
+		return "", fmt.Errorf("时间戳与中心节点相差 %s", skew.Round(time.Second))
+	}
+	if len(nonce) < 16 || len(nonce) > 128 {
+		return "", fmt.Errorf("无效的随机数")
+	}
+	var matched string
+	for _, candidate := range []string{cred.current, cred.previous} {
+		if candidate == "" {
+			continue
+		}
+		expected := signAgentRequest(candidate, c.Request.Method, c.Request.URL.Path, timestamp, nonce, body)
+		if hmac.Equal([]byte(expected), []byte(signature)) {
+			matched = candidate
+			break
+		}
+	}
+	if matched == "" {
+		return "", fmt.Errorf("签名无效")
+	}
+
+	// 随机数在时间戳允许的偏差内只能使用一次，更早的请求已因时间戳被拒绝
+	for key, seen := range ar.nonces {
+		if now.Sub(seen) > 2*agentMaxClockSkew {
+			delete(ar.nonces, key)
+		}
+	}
+	key := name + "\x00" + nonce
+	if _, replayed := ar.nonces[key]; replayed {
+		return "", fmt.Errorf("重放的请求")
+	}
+	ar.nonces[key] = now
+
+	// 探针已开始使用轮换后的令牌，上一个令牌作废
+	if matched == cred.current && cred.previous != "" {
+		cred.previous = ""
+		state := ar.states[name]
+		state.Previous = ""
+		ar.states[name] = state
+		if err := ar.save(); err != nil {
+			return "", err
+		}
+	}
+	return matched, nil
+}
+
+// Rotate 为探针生成新的令牌，used为本次请求使用的令牌，在探针使用新令牌前仍然有效
+func (ar *AgentRegistry) Rotate(name, used string) (string, error) {
+	token, err := newAgentToken()
+	if err != nil {
+		return "", err
+	}
+	ar.lock.Lock()
+	defer ar.lock.Unlock()
+	cred, ok := ar.creds[name]
+	if !ok {
+		return "", fmt.Errorf("未知的探针")
+	}
+	prev, rotated := ar.states[name]
+	ar.states[name] = agentCredentialState{
+		Base:      sha256Hex([]byte(cred.configured)),
+		Token:     token,
+		Previous:  used,
+		RotatedAt: time.Now(),
+	}
+	if err := ar.save(); err != nil {
+		// 未能保存时不切换，避免重启后探针持有的令牌失效
+		if rotated {
+			ar.states[name] = prev
+		} else {
+			delete(ar.states, name)
+		}
+		return "", err
+	}
+	cred.current, cred.previous = token, used
+	return token, nil
+}
+
+// readAgentRequest 读取并校验探针请求，认证失败时返回401
+func readAgentRequest(c *gin.Context) (body []byte, token string, ok bool) {
+	name := c.Param("name")
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAgentRequestSize+1))
+	if err != nil || len(body) > maxAgentRequestSize {
+		apiError(c, http.StatusBadRequest, "error.read_body")
+		return nil, "", false
+	}
+	token, err = agentRegistry.Verify(c, name, body)
+	if err != nil {
+		slog.Warn("探针认证失败", "agent", name, "ip", requestIP(c), "reason", err)
+		apiError(c, http.StatusUnauthorized, "error.unauthorized")
+		return nil, "", false
+	}
+	return body, token, true
+}
+
+// signedAgentResponse 返回探针请求的响应，签名请求的响应附带中心节点签名
+func signedAgentResponse(c *gin.Context, token string, status int, body []byte) {
+	if nonce := c.GetHeader(agentNonceHeader); nonce != "" && c.GetHeader(agentSignatureHeader) != "" {
+		c.Header(serverSignatureHeader, signServerResponse(token, nonce, status, body))
+	}
+	if len(body) == 0 {
+		c.Status(status)
+		return
+	}
+	c.Data(status, "application/json; charset=utf-8", body)
+}
+
+// apiAgentRotateHandler 探针轮换令牌：以当前令牌签名请求，响应中的新令牌以当前令牌签名
+func apiAgentRotateHandler(c *gin.Context) {
+	name := c.Param("name")
+	_, used, ok := readAgentRequest(c)
+	if !ok {
+		return
+	}
+	token, err := agentRegistry.Rotate(name, used)
+	if err != nil {
+		slog.Error("轮换探针令牌失败", "agent", name, "error", err)
+		apiError(c, http.StatusInternalServerError, "error.rotate_failed")
+		return
+	}
+	auditLog.Record(AuditEntry{Actor: name, ClientIP: requestIP(c), Action: auditAgentRotate, Target: name})
+	body, _ := json.Marshal(gin.H{"token": token})
+	signedAgentResponse(c, used, http.StatusOK, body)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testAgentPath = "/api/v1/agents/edge/report"
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// newTestAgentRegistry 创建只有一个探针edge的注册表，令牌为initial
+func newTestAgentRegistry(t *testing.T) *AgentRegistry {
+	t.Helper()
+	ar := NewAgentRegistry([]RemoteAgentConfig{{Name: "edge", Token: "initial"}}, 0)
+	if err := ar.Open(StorageConfig{Path: filepath.Join(t.TempDir(), "status.jsonl")}); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return ar
+}
+
+// signedAgentContext 构造以token签名的探针请求
+func signedAgentContext(token string, at time.Time, nonce string, body []byte) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, testAgentPath, bytes.NewReader(body))
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	c.Request.Header.Set(agentTimestampHeader, timestamp)
+	c.Request.Header.Set(agentNonceHeader, nonce)
+	c.Request.Header.Set(agentSignatureHeader, signAgentRequest(token, http.MethodPost, testAgentPath, timestamp, nonce, body))
+	return c
+}
+
+func TestAgentVerifyRejectsClockSkew(t *testing.T) {
+	ar := newTestAgentRegistry(t)
+	body := []byte(`{}`)
+	for _, offset := range []time.Duration{-agentMaxClockSkew - time.Minute, agentMaxClockSkew + time.Minute} {
+		c := signedAgentContext("initial", time.Now().Add(offset), "0123456789abcdef"+offset.String(), body)
+		if _, err := ar.Verify(c, "edge", body); err == nil {
+			t.Errorf("偏差 %s 的请求应被拒绝", offset)
+		}
+	}
+	c := signedAgentContext("initial", time.Now().Add(-time.Minute), "0123456789abcdef", body)
+	if _, err := ar.Verify(c, "edge", body); err != nil {
+		t.Errorf("偏差在允许范围内的请求应通过: %v", err)
+	}
+}
+
+func TestAgentVerifyRejectsReplay(t *testing.T) {
+	ar := newTestAgentRegistry(t)
+	body := []byte(`{"agent":"edge"}`)
+	now := time.Now()
+	if _, err := ar.Verify(signedAgentContext("initial", now, "replayed-nonce-0001", body), "edge", body); err != nil {
+		t.Fatalf("首次请求应通过: %v", err)
+	}
+	if _, err := ar.Verify(signedAgentContext("initial", now, "replayed-nonce-0001", body), "edge", body); err == nil {
+		t.Fatal("重复使用随机数的请求应被拒绝")
+	}
+	if _, err := ar.Verify(signedAgentContext("initial", now, "replayed-nonce-0002", body), "edge", body); err != nil {
+		t.Fatalf("新的随机数应通过: %v", err)
+	}
+}
+
+func TestAgentVerifyPreviousTokenUntilNewTokenUsed(t *testing.T) {
+	ar := newTestAgentRegistry(t)
+	token, err := ar.Rotate("edge", "initial")
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	body := []byte(`{}`)
+	now := time.Now()
+
+	// 轮换响应丢失时探针仍使用原令牌
+	matched, err := ar.Verify(signedAgentContext("initial", now, "previous-nonce-0001", body), "edge", body)
+	if err != nil || matched != "initial" {
+		t.Fatalf("轮换前的令牌应仍然有效: matched=%q err=%v", matched, err)
+	}
+	if ar.states["edge"].Previous != "initial" {
+		t.Fatal("使用原令牌不应清除轮换前的令牌")
+	}
+
+	matched, err = ar.Verify(signedAgentContext(token, now, "current-nonce-00001", body), "edge", body)
+	if err != nil || matched != token {
+		t.Fatalf("新令牌应有效: matched=%q err=%v", matched, err)
+	}
+	if ar.creds["edge"].previous != "" || ar.states["edge"].Previous != "" {
+		t.Fatal("使用新令牌后应清除轮换前的令牌")
+	}
+	if _, err := ar.Verify(signedAgentContext("initial", now, "previous-nonce-0002", body), "edge", body); err == nil {
+		t.Fatal("使用新令牌后原令牌应失效")
+	}
+
+	// 清除结果已保存，重新加载后原令牌仍然无效
+	reopened := NewAgentRegistry([]RemoteAgentConfig{{Name: "edge", Token: "initial"}}, 0)
+	if err := reopened.Open(StorageConfig{Path: filepath.Join(filepath.Dir(ar.path), "status.jsonl")}); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if cred := reopened.creds["edge"]; cred.current != token || cred.previous != "" {
+		t.Fatalf("重新加载的令牌不正确: current=%q previous=%q", cred.current, cred.previous)
+	}
+}
+
+func TestAgentRotateRollsBackWhenSaveFails(t *testing.T) {
+	ar := newTestAgentRegistry(t)
+	first, err := ar.Rotate("edge", "initial")
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	saved := ar.states["edge"]
+
+	// 保存目录不存在，写入失败
+	ar.path = filepath.Join(t.TempDir(), "missing", "status.agents.json")
+	if _, err := ar.Rotate("edge", first); err == nil {
+		t.Fatal("保存失败时应返回错误")
+	}
+	if ar.states["edge"] != saved {
+		t.Fatalf("保存失败时应恢复原有的轮换结果: %+v", ar.states["edge"])
+	}
+	if cred := ar.creds["edge"]; cred.current != first || cred.previous != "initial" {
+		t.Fatalf("保存失败时不应切换令牌: current=%q previous=%q", cred.current, cred.previous)
+	}
+
+	// 从未轮换过的探针保存失败后不留下轮换结果
+	fresh := NewAgentRegistry([]RemoteAgentConfig{{Name: "edge", Token: "initial"}}, 0)
+	fresh.path = ar.path
+	if _, err := fresh.Rotate("edge", "initial"); err == nil {
+		t.Fatal("保存失败时应返回错误")
+	}
+	if _, ok := fresh.states["edge"]; ok {
+		t.Fatal("保存失败时不应留下轮换结果")
+	}
+	if cred := fresh.creds["edge"]; cred.current != "initial" || cred.previous != "" {
+		t.Fatalf("保存失败时不应切换令牌: current=%q previous=%q", cred.current, cred.previous)
+	}
+}
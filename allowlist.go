@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAccessConfig 管理接口与探针上报接口的来源IP白名单，即使令牌泄露也只接受来自管理网络的请求
+type AdminAccessConfig struct {
+	// AllowedCIDRs 允许访问的网段，如10.0.0.0/8，也可填写单个IP；为空时不限制来源
+	AllowedCIDRs []string `yaml:"allowed_cidrs,omitempty"`
+	// TrustedProxies 可信的反向代理网段，仅来自这些地址的请求才按X-Forwarded-For判断来源IP
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty"`
+}
+
+// ipAllowlist 解析后的来源IP白名单
+type ipAllowlist struct {
+	allowed []*net.IPNet
+	proxies []*net.IPNet
+}
+
+// parseCIDRs 解析网段列表，单个IP视为只包含该地址的网段
+func parseCIDRs(values []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的IP地址 '%s'", v)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("无效的网段 '%s': %v", v, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// build 校验并解析白名单配置
+func (ac *AdminAccessConfig) build() (*ipAllowlist, error) {
+	allowed, err := parseCIDRs(ac.AllowedCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("admin_access.allowed_cidrs: %v", err)
+	}
+	proxies, err := parseCIDRs(ac.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("admin_access.trusted_proxies: %v", err)
+	}
+	return &ipAllowlist{allowed: allowed, proxies: proxies}, nil
+}
+
+// containsIP 判断IP是否属于任一网段
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP 返回请求的来源IP：直连地址为可信代理时，从右向左跳过X-Forwarded-For中的可信代理，
+// 取第一个不可信的地址；不使用gin的ClientIP，避免未配置可信代理时伪造请求头绕过白名单。
+// unix socket上的对端没有IP地址，只能是本机的反向代理，同样视为可信代理，未携带X-Forwarded-For时返回nil
+func (al *ipAllowlist) clientIP(c *gin.Context) net.IP {
+	ip := net.ParseIP(c.RemoteIP())
+	if ip != nil && !containsIP(al.proxies, ip) {
+		return ip
+	}
+	hops := strings.Split(c.GetHeader("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !containsIP(al.proxies, hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// allow 判断请求是否来自白名单内的地址，未配置白名单时始终允许
+func (al *ipAllowlist) allow(c *gin.Context) (net.IP, bool) {
+	if len(al.allowed) == 0 {
+		return nil, true
+	}
+	ip := al.clientIP(c)
+	return ip, ip != nil && containsIP(al.allowed, ip)
+}
+
+// adminAllowlist 当前生效的管理接口来源IP白名单
+var adminAllowlist atomic.Pointer[ipAllowlist]
+
+// requestIP 按admin_access.trusted_proxies解析请求的来源IP，用于限流与日志；
+// 来自unix socket且未携带X-Forwarded-For的请求返回空字符串
+func requestIP(c *gin.Context) string {
+	al := adminAllowlist.Load()
+	if al == nil {
+		al = &ipAllowlist{}
+	}
+	if ip := al.clientIP(c); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// prepareAdminAllowlist 解析来源IP白名单，返回更新白名单的函数
+func prepareAdminAllowlist(cfg *Config) (func(), error) {
+	al, err := cfg.AdminAccess.build()
+	if err != nil {
+		return nil, err
+	}
+	return func() { adminAllowlist.Store(al) }, nil
+}
+
+// setAdminAllowlist 根据配置更新来源IP白名单，立即对后续请求生效
+func setAdminAllowlist(cfg *Config) error {
+	apply, err := prepareAdminAllowlist(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// requireAdminNetwork 返回来源IP白名单中间件，来源不在白名单中时返回403
+func requireAdminNetwork() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		al := adminAllowlist.Load()
+		if al == nil {
+			c.Next()
+			return
+		}
+		if ip, ok := al.allow(c); !ok {
+			slog.Warn("拒绝来自白名单外的管理请求", "ip", ip, "remote", c.RemoteIP(), "method", c.Request.Method, "path", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": tr(c, "error.ip_not_allowed")})
+			return
+		}
+		c.Next()
+	}
+}
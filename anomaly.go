@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// defaultAnomalyAlpha 基线的默认平滑系数
+	defaultAnomalyAlpha = 0.1
+	// defaultAnomalySigma 默认的偏离阈值（标准差倍数）
+	defaultAnomalySigma = 3
+	// defaultAnomalyWarmup 开始检测前默认需要的样本数
+	defaultAnomalyWarmup = 20
+	// defaultAnomalyConsecutive 默认需要连续异常的检查次数
+	defaultAnomalyConsecutive = 3
+	// defaultAnomalyMinDelta 默认的最小偏离值
+	defaultAnomalyMinDelta = 50 * time.Millisecond
+)
+
+// AnomalyConfig 延迟异常检测配置：以EWMA维护成功检查延迟的基线与波动，
+// 延迟连续多次高于基线Sigma倍标准差时标记为性能异常，延迟回落后恢复，不改变服务状态
+type AnomalyConfig struct {
+	// Alpha 基线的平滑系数（0~1），越大越快适应新的延迟水平，默认0.1
+	Alpha float64 `yaml:"alpha,omitempty"`
+	// Sigma 偏离阈值，延迟超过基线多少倍标准差视为异常，默认3
+	Sigma float64 `yaml:"sigma,omitempty"`
+	// Warmup 开始检测前需要的样本数，默认20
+	Warmup int `yaml:"warmup,omitempty"`
+	// Consecutive 连续异常多少次后标记，默认3
+	Consecutive int `yaml:"consecutive,omitempty"`
+	// MinDelta 与基线的最小偏离，避免低延迟服务的微小波动被标记，默认50ms
+	MinDelta time.Duration `yaml:"min_delta,omitempty"`
+	// Notify 出现与恢复异常时是否发送通知，默认只记录事件
+	Notify bool `yaml:"notify,omitempty"`
+}
+
+// validate 校验延迟异常检测配置
+func (ac *AnomalyConfig) validate() error {
+	if ac.Alpha < 0 || ac.Alpha >= 1 {
+		return fmt.Errorf("延迟异常检测的alpha需在0~1之间")
+	}
+	if ac.Sigma < 0 || ac.Warmup < 0 || ac.Consecutive < 0 || ac.MinDelta < 0 {
+		return fmt.Errorf("延迟异常检测的sigma、warmup、consecutive与min_delta不能为负数")
+	}
+	return nil
+}
+
+// withDefaults 返回补充默认值后的配置
+func (ac AnomalyConfig) withDefaults() AnomalyConfig {
+	if ac.Alpha == 0 {
+		ac.Alpha = defaultAnomalyAlpha
+	}
+	if ac.Sigma == 0 {
+		ac.Sigma = defaultAnomalySigma
+	}
+	if ac.Warmup == 0 {
+		ac.Warmup = defaultAnomalyWarmup
+	}
+	if ac.Consecutive == 0 {
+		ac.Consecutive = defaultAnomalyConsecutive
+	}
+	if ac.MinDelta == 0 {
+		ac.MinDelta = defaultAnomalyMinDelta
+	}
+	return ac
+}
+
+// notify 是否为性能异常发送通知，未配置时返回false
+func (ac *AnomalyConfig) notify() bool {
+	return ac != nil && ac.Notify
+}
+
+// LatencyAnomaly 进行中的性能异常
+type LatencyAnomaly struct {
+	// Since 开始时间
+	Since time.Time `json:"since"`
+	// LatencyMS 最近一次检查的延迟（毫秒）
+	LatencyMS float64 `json:"latency_ms"`
+	// BaselineMS 基线延迟（毫秒）
+	BaselineMS float64 `json:"baseline_ms"`
+	// StddevMS 基线的标准差（毫秒）
+	StddevMS float64 `json:"stddev_ms"`
+}
+
+// latencyBaseline 服务延迟的EWMA基线
+type latencyBaseline struct {
+	// samples 已纳入基线的样本数
+	samples int
+	// mean 延迟的指数加权平均值（毫秒）
+	mean float64
+	// variance 延迟的指数加权方差
+	variance float64
+	// streak 连续异常的次数
+	streak int
+}
+
+// add 将样本纳入基线
+func (b *latencyBaseline) add(ms, alpha float64) {
+	if b.samples == 0 {
+		b.mean, b.variance = ms, 0
+	} else {
+		diff := ms - b.mean
+		incr := alpha * diff
+		b.mean += incr
+		b.variance = (1 - alpha) * (b.variance + diff*incr)
+	}
+	b.samples++
+}
+
+// applyAnomaly 用本次成功检查的延迟更新基线并判断是否出现性能异常，异常出现或恢复时返回true；
+// 检查失败时直接清除进行中的异常，由状态变化说明故障，需持有锁调用
+func applyAnomaly(service *Service, status ServiceStatus, err error, duration time.Duration, now time.Time) bool {
+	if service.AnomalyDetection == nil {
+		service.baseline = latencyBaseline{}
+		service.Anomaly = nil
+		return false
+	}
+	if err != nil || !isUp(status) {
+		service.baseline.streak = 0
+		service.Anomaly = nil
+		return false
+	}
+
+	cfg := service.AnomalyDetection.withDefaults()
+	b := &service.baseline
+	ms := float64(duration) / float64(time.Millisecond)
+	if b.samples >= cfg.Warmup {
+		stddev := math.Sqrt(b.variance)
+		deviation := ms - b.mean
+		if deviation > cfg.Sigma*stddev && deviation >= float64(cfg.MinDelta)/float64(time.Millisecond) {
+			b.streak++
+		} else {
+			b.streak = 0
+		}
+	}
+	active := service.Anomaly != nil
+	switch {
+	case b.streak >= cfg.Consecutive:
+		since := now
+		if active {
+			since = service.Anomaly.Since
+		}
+		service.Anomaly = &LatencyAnomaly{Since: since, LatencyMS: ms, BaselineMS: b.mean, StddevMS: math.Sqrt(b.variance)}
+	case b.streak == 0:
+		service.Anomaly = nil
+	}
+	// 偏离的样本不纳入基线，避免波动被异常值放大而掩盖持续的变慢
+	if b.streak == 0 {
+		b.add(ms, cfg.Alpha)
+	}
+	return active != (service.Anomaly != nil)
+}
+
+// anomalyReason 生成性能异常的原因说明
+func anomalyReason(service *Service) string {
+	if service.Anomaly == nil {
+		return fmt.Sprintf("延迟已回到基线 %.1fms 附近", service.baseline.mean)
+	}
+	a := service.Anomaly
+	return fmt.Sprintf("延迟 %.1fms 持续高于基线 %.1fms（标准差 %.1fms）", a.LatencyMS, a.BaselineMS, a.StddevMS)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultChecksLimit 检查记录接口默认返回条数
+	defaultChecksLimit = 50
+	// maxChecksLimit 检查记录接口单次最多返回条数
+	maxChecksLimit = 500
+)
+
+// apiServiceChecksHandler 返回单个服务最近的检查记录
+func apiServiceChecksHandler(c *gin.Context) {
+	name := c.Param("name")
+	if publicService(c, name) == nil {
+		apiError(c, http.StatusNotFound, "error.service_not_found")
+		return
+	}
+
+	limit := defaultChecksLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			apiError(c, http.StatusBadRequest, "error.invalid_param", "limit")
+			return
+		}
+		limit = min(n, maxChecksLimit)
+	}
+
+	var since time.Time
+	if v := c.Query("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "error.invalid_since")
+			return
+		}
+		since = t
+	}
+
+	checks, err := serviceManager.History().Query(name, since, limit)
+	if err != nil {
+		slog.Error("查询检查记录失败", "service", name, "error", err)
+		apiError(c, http.StatusInternalServerError, "error.internal")
+		return
+	}
+	if detailsHidden(canViewPrivate(c)) {
+		for i := range checks {
+			if checks[i].Error != "" {
+				checks[i].Error = translate(requestLocale(c), "error.check_failed")
+			}
+			checks[i].Attempts = nil
+		}
+	}
+	c.Header("Vary", "Authorization")
+	c.JSON(http.StatusOK, gin.H{
+		"service": name,
+		"checks":  checks,
+	})
+}
+
+// apiServiceCheckHandler 立即重新检查单个服务并返回最新结果
+func apiServiceCheckHandler(c *gin.Context) {
+	service, result, err := serviceManager.CheckService(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(c, auditServiceCheck, service.Name, "")
+	c.JSON(http.StatusOK, gin.H{
+		"service": service,
+		"result":  result,
+	})
+}
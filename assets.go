@@ -0,0 +1,162 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"sort"
+	"time"
+)
+
+// embeddedAssets 编译进二进制的模板与静态资源
+//
+//go:embed templates static
+var embeddedAssets embed.FS
+
+// overlayFS 叠加文件系统，优先读取覆盖目录中的文件，不存在时回退到内嵌资源
+type overlayFS struct {
+	// upper 覆盖目录
+	upper fs.FS
+	// lower 内嵌资源
+	lower fs.FS
+}
+
+// newAssetsFS 创建资源文件系统，dir为空时仅使用内嵌资源
+func newAssetsFS(dir string) fs.FS {
+	if dir == "" {
+		return embeddedAssets
+	}
+	return &overlayFS{upper: os.DirFS(dir), lower: embeddedAssets}
+}
+
+// Open 实现fs.FS接口
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	if info, err := fs.Stat(o.upper, name); err == nil && !info.IsDir() {
+		return o.upper.Open(name)
+	}
+	return o.lower.Open(name)
+}
+
+// ReadDir 实现fs.ReadDirFS接口，合并两层目录的内容
+func (o *overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	merged := make(map[string]fs.DirEntry)
+	lowerEntries, lowerErr := fs.ReadDir(o.lower, name)
+	for _, entry := range lowerEntries {
+		merged[entry.Name()] = entry
+	}
+	upperEntries, upperErr := fs.ReadDir(o.upper, name)
+	for _, entry := range upperEntries {
+		merged[entry.Name()] = entry
+	}
+	if lowerErr != nil && upperErr != nil {
+		return nil, errors.Join(lowerErr, upperErr)
+	}
+
+	entries := make([]fs.DirEntry, 0, len(merged))
+	for _, entry := range merged {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// statusLabel 状态在页面上的标签文字
+func statusLabel(locale string, s ServiceStatus) string {
+	return translate(locale, "status."+s.String()+".label")
+}
+
+// statusTitle 状态在页面上的提示文字
+func statusTitle(locale string, s ServiceStatus) string {
+	return translate(locale, "status."+s.String()+".title")
+}
+
+// templateFuncs 模板中可用的辅助函数，语言相关的函数第一个参数为语言
+var templateFuncs = template.FuncMap{
+	// t 翻译消息
+	"t": translate,
+	// statusLabel 状态标签文字
+	"statusLabel": statusLabel,
+	// statusTitle 状态提示文字
+	"statusTitle": statusTitle,
+	// formatTime 按语言格式化时间
+	"formatTime": formatTime,
+	// formatBytes 格式化字节数
+	"formatBytes": formatBytes,
+	// formatUptime 格式化运行时长（秒）
+	"formatUptime": formatUptime,
+	// formatSpeed 格式化传输速度（字节/秒）
+	"formatSpeed": formatSpeed,
+	// formatPercent 格式化百分比，nil时显示为-
+	"formatPercent": formatPercent,
+	// formatLatency 格式化延迟（毫秒），nil时显示为-
+	"formatLatency": formatLatency,
+}
+
+// formatBytes 将字节数格式化为易读的形式
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatSpeed 将每秒字节数格式化为易读的传输速度
+func formatSpeed(bps float64) string {
+	return formatBytes(uint64(bps)) + "/s"
+}
+
+// formatPercent 将百分比格式化为保留三位小数的形式
+func formatPercent(p *float64) string {
+	if p == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.3f%%", *p)
+}
+
+// formatLatency 将毫秒延迟格式化为易读的形式
+func formatLatency(ms *float64) string {
+	if ms == nil {
+		return "-"
+	}
+	if *ms >= 1000 {
+		return fmt.Sprintf("%.2fs", *ms/1000)
+	}
+	return fmt.Sprintf("%.0fms", *ms)
+}
+
+// formatUptime 将秒数格式化为易读的运行时长
+func formatUptime(locale string, seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	switch {
+	case days > 0:
+		return translate(locale, "uptime.days", days, hours)
+	case hours > 0:
+		return translate(locale, "uptime.hours", hours, minutes)
+	default:
+		return translate(locale, "uptime.minutes", minutes)
+	}
+}
+
+// loadTemplates 从资源文件系统中解析HTML模板
+func loadTemplates(assets fs.FS) (*template.Template, error) {
+	return template.New("").Funcs(templateFuncs).ParseFS(assets, "templates/*")
+}
+
+// staticFS 返回静态资源子目录
+func staticFS(assets fs.FS) (fs.FS, error) {
+	return fs.Sub(assets, "static")
+}
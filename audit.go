@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// maxAuditEntries 内存中保留的审计记录数，更早的记录仅保存在审计文件中
+const maxAuditEntries = 10000
+
+// 审计记录的操作类型
+const (
+	auditServiceCheck   = "service_check"
+	auditOverrideSet    = "override_set"
+	auditOverrideClear  = "override_clear"
+	auditAck            = "ack"
+	auditConfigImport   = "config_import"
+	auditConfigReload   = "config_reload"
+	auditConfigRollback = "config_rollback"
+	auditServiceAdd     = "service_added"
+	auditServiceUpdate  = "service_updated"
+	auditServiceRemove  = "service_removed"
+	auditLogin          = "login"
+	auditAgentRotate    = "agent_token_rotated"
+)
+
+// auditActorConfigFile 修改配置文件或发送SIGHUP触发重载时的操作者，管理接口的操作者为令牌名称
+const auditActorConfigFile = "config_file"
+
+// AuditEntry 管理操作或配置变更的审计记录
+type AuditEntry struct {
+	// ID 记录序号，单调递增
+	ID int64 `json:"id"`
+	// Time 发生时间
+	Time time.Time `json:"time"`
+	// Actor 操作者，为访问令牌的名称或config_file
+	Actor string `json:"actor"`
+	// ClientIP 调用管理接口的客户端地址，配置文件重载时为空
+	ClientIP string `json:"client_ip,omitempty"`
+	// Action 操作类型
+	Action string `json:"action"`
+	// Target 操作对象，如服务名称，全局配置为空
+	Target string `json:"target,omitempty"`
+	// Diff 变更内容，以-与+标记删除与新增的YAML行，敏感字段已隐藏
+	Diff string `json:"diff,omitempty"`
+}
+
+// AuditLog 只追加的审计日志，保存在检查记录存储目录下，不随检查记录清理
+type AuditLog struct {
+	lock sync.RWMutex
+	// entries 最近的记录，按时间正序
+	entries []AuditEntry
+	// nextID 下一条记录的序号
+	nextID int64
+	// file 追加写入的审计文件，为nil时仅保存在内存中
+	file *os.File
+}
+
+// auditLog 全局审计日志
+var auditLog = &AuditLog{nextID: 1}
+
+// Open 加载已有的审计记录并持续追加写入，存储路径为空时仅保存在内存中
+func (l *AuditLog) Open(storage StorageConfig) error {
+	if storage.Path == "" {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	path := strings.TrimSuffix(storage.Path, filepath.Ext(storage.Path)) + ".audit.jsonl"
+	err := readLines(path, func(line []byte) error {
+		var entry AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		l.add(entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	l.file, err = openAppend(path)
+	return err
+}
+
+// add 将记录加入内存，超出容量时丢弃最旧的记录，需持有锁调用
+func (l *AuditLog) add(entry AuditEntry) {
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > maxAuditEntries {
+		l.entries = append(l.entries[:0:0], l.entries[len(l.entries)-maxAuditEntries:]...)
+	}
+	if entry.ID >= l.nextID {
+		l.nextID = entry.ID + 1
+	}
+}
+
+// Record 分配序号并追加一条审计记录，未设置时间时使用当前时间
+func (l *AuditLog) Record(entry AuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	entry.ID = l.nextID
+	l.add(entry)
+	if err := writeLine(l.file, entry); err != nil {
+		slog.Error("保存审计记录失败", "action", entry.Action, "target", entry.Target, "error", err)
+	}
+	slog.Info("管理操作", "actor", entry.Actor, "action", entry.Action, "target", entry.Target)
+}
+
+// Query 按时间倒序返回match接受的审计记录，以及分页前的记录总数
+func (l *AuditLog) Query(q listParams, match func(AuditEntry) bool) ([]AuditEntry, int) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	start := 0
+	if !q.Since.IsZero() {
+		start = sort.Search(len(l.entries), func(i int) bool { return !l.entries[i].Time.Before(q.Since) })
+	}
+	end := len(l.entries)
+	if !q.Until.IsZero() {
+		end = sort.Search(len(l.entries), func(i int) bool { return !l.entries[i].Time.Before(q.Until) })
+	}
+
+	out := make([]AuditEntry, 0)
+	total := 0
+	for i := end - 1; i >= start; i-- {
+		entry := l.entries[i]
+		if !match(entry) {
+			continue
+		}
+		total++
+		if total > q.Offset && len(out) < q.Limit {
+			out = append(out, entry)
+		}
+	}
+	return out, total
+}
+
+// recordAudit 记录一次管理接口调用
+func recordAudit(c *gin.Context, action, target, diff string) {
+	auditLog.Record(AuditEntry{
+		Actor:    requestActor(c),
+		ClientIP: requestIP(c),
+		Action:   action,
+		Target:   target,
+		Diff:     diff,
+	})
+}
+
+// recordConfigChange 记录一次配置变更：全局配置的差异记为一条action记录，
+// 服务的新增、修改与删除各记为一条记录，内容没有变化的部分不记录
+func recordConfigChange(old, cfg *Config, action, actor, clientIP string) {
+	now := time.Now()
+	record := func(action, target, diff string) {
+		auditLog.Record(AuditEntry{Time: now, Actor: actor, ClientIP: clientIP, Action: action, Target: target, Diff: diff})
+	}
+
+	oldGlobal, newGlobal := *old, *cfg
+	oldGlobal.Services, newGlobal.Services = nil, nil
+	if diff := yamlDiff(&oldGlobal, &newGlobal); diff != "" {
+		record(action, "", diff)
+	}
+
+	before := make(map[string]*ServiceConfig, len(old.Services))
+	for i := range old.Services {
+		before[old.Services[i].Name] = &old.Services[i]
+	}
+	for i := range cfg.Services {
+		sc := &cfg.Services[i]
+		prev, ok := before[sc.Name]
+		delete(before, sc.Name)
+		switch {
+		case !ok:
+			record(auditServiceAdd, sc.Name, yamlDiff(nil, sc))
+		default:
+			if diff := yamlDiff(prev, sc); diff != "" {
+				record(auditServiceUpdate, sc.Name, diff)
+			}
+		}
+	}
+	removed := make([]string, 0, len(before))
+	for name := range before {
+		removed = append(removed, name)
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		record(auditServiceRemove, name, yamlDiff(before[name], nil))
+	}
+}
+
+// secretLine 匹配YAML中令牌、密码等敏感字段所在的行
+var secretLine = regexp.MustCompile(`(?i)^(\s*(?:-\s+)?[a-z0-9_]*(?:token|password|secret|api_key|private_key|authorization)[a-z0-9_]*:\s+)\S.*$`)
+
+// yamlLines 将值序列化为YAML行，值为nil时返回空
+func yamlLines(v interface{}) []string {
+	if v == nil {
+		return nil
+	}
+	data, err := yaml.Marshal(v)
+	if err != nil || string(data) == "null\n" {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+}
+
+// yamlDiff 比较两个值的YAML形式，返回以-与+标记的差异行，没有差异时返回空；
+// 敏感字段先比较再隐藏，修改令牌等字段时仍会记录该字段发生了变化
+func yamlDiff(before, after interface{}) string {
+	return diffLines(yamlLines(before), yamlLines(after))
+}
+
+// diffLines 基于最长公共子序列比较两组行，只输出删除（-）与新增（+）的行，
+// 变更行前以空格开头输出其所在的上级字段，便于定位嵌套的配置
+func diffLines(a, b []string) string {
+	// lcs[i][j]为a[i:]与b[j:]的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	var shown []string
+	write := func(prefix string, lines []string, k int) {
+		chain := yamlAncestors(lines, k)
+		for p, line := range chain {
+			if p >= len(shown) || shown[p] != line {
+				sb.WriteString(" " + maskSecret(line) + "\n")
+			}
+		}
+		shown = chain
+		sb.WriteString(prefix + maskSecret(lines[k]) + "\n")
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			write("-", a, i)
+			i++
+		default:
+			write("+", b, j)
+			j++
+		}
+	}
+	return sb.String()
+}
+
+// maskSecret 隐藏敏感字段的值
+func maskSecret(line string) string {
+	return secretLine.ReplaceAllString(line, "${1}******")
+}
+
+// yamlAncestors 返回第k行所在的各级上级字段，按从外到内的顺序
+func yamlAncestors(lines []string, k int) []string {
+	indent := yamlIndent(lines[k])
+	chain := make([]string, 0)
+	for i := k - 1; i >= 0 && indent > 0; i-- {
+		if n := yamlIndent(lines[i]); n < indent {
+			chain = append(chain, lines[i])
+			indent = n
+		}
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// yamlIndent 返回行首的缩进宽度
+func yamlIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// apiAuditHandler 分页返回审计记录，支持按操作类型、操作对象、操作者与时间范围过滤
+func apiAuditHandler(c *gin.Context) {
+	params, ok := parseListParams(c)
+	if !ok {
+		return
+	}
+	action, target, actor := c.Query("action"), c.Query("target"), c.Query("actor")
+	entries, total := auditLog.Query(params, func(e AuditEntry) bool {
+		return (action == "" || e.Action == action) &&
+			(target == "" || e.Target == target) &&
+			(actor == "" || e.Actor == actor)
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   total,
+		"offset":  params.Offset,
+		"limit":   params.Limit,
+	})
+}
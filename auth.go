@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Role 访问令牌的角色，高级角色拥有低级角色的全部权限
+type Role int
+
+const (
+	// RoleNone 未认证
+	RoleNone Role = iota
+	// RoleViewer 可查看隐藏与停用的服务及其记录、诊断报告
+	RoleViewer
+	// RoleOperator 可确认故障、设置状态覆盖与立即检查
+	RoleOperator
+	// RoleAdmin 可导出与导入配置、查看审计日志
+	RoleAdmin
+)
+
+// String 返回角色名称
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RoleOperator:
+		return "operator"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// ParseRole 解析角色名称
+func ParseRole(s string) (Role, error) {
+	switch strings.ToLower(s) {
+	case "viewer":
+		return RoleViewer, nil
+	case "operator":
+		return RoleOperator, nil
+	case "admin":
+		return RoleAdmin, nil
+	default:
+		return RoleNone, fmt.Errorf("未知的角色: %s", s)
+	}
+}
+
+// MarshalText 实现encoding.TextMarshaler
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText 实现encoding.TextUnmarshaler，用于YAML等文本格式
+func (r *Role) UnmarshalText(text []byte) error {
+	role, err := ParseRole(string(text))
+	if err != nil {
+		return err
+	}
+	*r = role
+	return nil
+}
+
+// APIToken 带角色的访问令牌
+type APIToken struct {
+	// Name 令牌名称，作为审计日志中的操作者
+	Name string `yaml:"name"`
+	// Token Bearer令牌
+	Token string `yaml:"token"`
+	// Role 角色：viewer、operator或admin
+	Role Role `yaml:"role"`
+}
+
+// adminTokenName admin_token在审计日志中的操作者名称
+const adminTokenName = "admin"
+
+// validateTokens 校验访问令牌，名称与令牌均不能重复
+func (cfg *Config) validateTokens() error {
+	names := map[string]bool{}
+	tokens := map[string]bool{}
+	if cfg.AdminToken != "" {
+		names[adminTokenName] = true
+		tokens[cfg.AdminToken] = true
+	}
+	for _, t := range cfg.Tokens {
+		if t.Name == "" || t.Token == "" {
+			return fmt.Errorf("访问令牌需要配置name与token")
+		}
+		if t.Role == RoleNone {
+			return fmt.Errorf("访问令牌 '%s' 缺少role", t.Name)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("访问令牌名称 '%s' 重复", t.Name)
+		}
+		if tokens[t.Token] {
+			return fmt.Errorf("访问令牌 '%s' 的token与其他令牌重复", t.Name)
+		}
+		names[t.Name], tokens[t.Token] = true, true
+	}
+	return nil
+}
+
+// accessTokens 当前生效的访问令牌，admin_token视为名为admin的管理员令牌
+var accessTokens atomic.Pointer[[]APIToken]
+
+// prepareAccessTokens 校验访问令牌，返回更新访问令牌的函数
+func prepareAccessTokens(cfg *Config) (func(), error) {
+	if err := cfg.validateTokens(); err != nil {
+		return nil, err
+	}
+	tokens := make([]APIToken, 0, len(cfg.Tokens)+1)
+	if cfg.AdminToken != "" {
+		tokens = append(tokens, APIToken{Name: adminTokenName, Token: cfg.AdminToken, Role: RoleAdmin})
+	}
+	tokens = append(tokens, cfg.Tokens...)
+	return func() { accessTokens.Store(&tokens) }, nil
+}
+
+// setAccessTokens 根据配置更新访问令牌，立即对后续请求生效
+func setAccessTokens(cfg *Config) error {
+	apply, err := prepareAccessTokens(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// bearerToken 从Authorization头中提取Bearer令牌
+func bearerToken(c *gin.Context) string {
+	auth := c.GetHeader("Authorization")
+	if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// validToken 判断请求是否携带了指定的Bearer令牌，令牌为空时始终返回false
+func validToken(c *gin.Context, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(bearerToken(c))) == 1
+}
+
+// requestToken 返回请求携带的访问令牌，没有有效的Bearer令牌时使用OIDC登录会话，均无效时返回false
+func requestToken(c *gin.Context) (APIToken, bool) {
+	if tokens := accessTokens.Load(); tokens != nil {
+		for _, t := range *tokens {
+			if validToken(c, t.Token) {
+				return t, true
+			}
+		}
+	}
+	return oidcAuth.Session(c)
+}
+
+// requestRole 返回请求的角色，未认证时为RoleNone
+func requestRole(c *gin.Context) Role {
+	t, _ := requestToken(c)
+	return t.Role
+}
+
+// requestActor 返回请求使用的令牌名称或登录用户，用于审计日志
+func requestActor(c *gin.Context) string {
+	t, _ := requestToken(c)
+	return t.Name
+}
+
+// canViewPrivate 判断请求是否可以查看隐藏与停用的服务，需要viewer及以上角色
+func canViewPrivate(c *gin.Context) bool {
+	return requestRole(c) >= RoleViewer
+}
+
+// requireRole 返回接口认证中间件：未携带有效令牌时返回401，角色不足时返回403
+func requireRole(role Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, ok := requestToken(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": tr(c, "error.unauthorized")})
+			return
+		}
+		if t.Role < role {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": tr(c, "error.forbidden", role)})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BootReportConfig 启动检查配置：启动时按依赖关系分层检查全部服务，记录启动时已经异常的服务，
+// 弥补启动后首次检查不发送状态变化通知的空缺，适用于主机重启后确认各服务是否恢复
+type BootReportConfig struct {
+	// Enabled 是否启用
+	Enabled bool `yaml:"enabled"`
+	// Notify 是否发送启动报告通知，默认发送到default_route
+	Notify bool `yaml:"notify,omitempty"`
+	// Channels 启动报告使用的通知渠道，为空时使用default_route
+	Channels []string `yaml:"channels,omitempty"`
+}
+
+// validate 校验启动检查配置引用的通知渠道
+func (bc *BootReportConfig) validate(nc NotificationConfig) error {
+	for _, name := range bc.Channels {
+		if !slices.ContainsFunc(nc.Channels, func(cc ChannelConfig) bool { return cc.Name == name }) {
+			return fmt.Errorf("boot_report 引用的通知渠道 '%s' 不存在", name)
+		}
+	}
+	return nil
+}
+
+// BootReportEntry 启动时异常的服务
+type BootReportEntry struct {
+	// Name 服务名称
+	Name string `json:"name"`
+	// Status 服务状态
+	Status ServiceStatus `json:"status"`
+	// Error 检查错误
+	Error string `json:"error,omitempty"`
+	// ImpactedBy 导致该服务受影响的依赖
+	ImpactedBy []string `json:"impacted_by,omitempty"`
+}
+
+// BootReport 启动检查的结果
+type BootReport struct {
+	// Time 启动检查开始时间
+	Time time.Time `json:"time"`
+	// DurationMS 启动检查耗时（毫秒）
+	DurationMS float64 `json:"duration_ms"`
+	// Levels 按依赖关系划分的检查层数
+	Levels int `json:"levels"`
+	// Total 检查的服务数
+	Total int `json:"total"`
+	// Counts 各状态的服务数
+	Counts map[string]int `json:"counts"`
+	// Outages 启动时异常（离线、降级或受影响）的服务
+	Outages []BootReportEntry `json:"outages"`
+}
+
+// bootReport 最近一次启动检查的结果，未启用时为nil
+var bootReport atomic.Pointer[BootReport]
+
+// dependencyLevels 按依赖关系将服务分层：不依赖其他服务的位于第0层，其余服务位于其依赖所在最深层的下一层，
+// 依赖关系已在加载配置时校验过不存在循环
+func dependencyLevels(services []*Service) [][]*Service {
+	byName := make(map[string]*Service, len(services))
+	for _, service := range services {
+		byName[service.Name] = service
+	}
+	depth := make(map[string]int, len(services))
+	var resolve func(service *Service) int
+	resolve = func(service *Service) int {
+		if d, ok := depth[service.Name]; ok {
+			return d
+		}
+		// 先写入0，防止异常情况下的循环依赖导致无限递归
+		depth[service.Name] = 0
+		d := 0
+		for _, name := range service.DependsOn {
+			if dep, ok := byName[name]; ok {
+				d = max(d, resolve(dep)+1)
+			}
+		}
+		depth[service.Name] = d
+		return d
+	}
+
+	var levels [][]*Service
+	for _, service := range services {
+		d := resolve(service)
+		for len(levels) <= d {
+			levels = append(levels, nil)
+		}
+		levels[d] = append(levels[d], service)
+	}
+	return levels
+}
+
+// CheckOrdered 按依赖关系逐层检查全部服务，被依赖的服务先于依赖方完成检查，返回检查的层数
+func (sm *ServiceManager) CheckOrdered() int {
+	sm.lock.RLock()
+	services := append([]*Service(nil), sm.services...)
+	sm.lock.RUnlock()
+
+	levels := dependencyLevels(services)
+	for _, level := range levels {
+		ctx, cancel := context.WithTimeout(sm.ctx, sm.refreshTimeout)
+		sm.checkBatch(ctx, level)
+		cancel()
+	}
+	return len(levels)
+}
+
+// runBootCheck 执行启动检查，记录启动报告并按配置发送通知
+func runBootCheck(cfg BootReportConfig) {
+	start := time.Now()
+	levels := serviceManager.CheckOrdered()
+
+	report := &BootReport{
+		Time:       start,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		Levels:     levels,
+		Counts:     make(map[string]int),
+		Outages:    make([]BootReportEntry, 0),
+	}
+	for _, service := range serviceManager.GetServices() {
+		if !service.Enabled {
+			continue
+		}
+		report.Total++
+		report.Counts[service.Status.String()]++
+		if statusImpact[service.Status] > 0 {
+			report.Outages = append(report.Outages, BootReportEntry{
+				Name:       service.Name,
+				Status:     service.Status,
+				Error:      service.LastError,
+				ImpactedBy: service.ImpactedBy,
+			})
+		}
+	}
+	bootReport.Store(report)
+
+	slog.Info("启动检查完成", "services", report.Total, "outages", len(report.Outages), "levels", levels, "duration", time.Since(start).Round(time.Millisecond))
+	for _, entry := range report.Outages {
+		slog.Warn("启动时服务异常", "service", entry.Name, "status", entry.Status, "error", entry.Error, "impacted_by", entry.ImpactedBy)
+	}
+	if !cfg.Notify {
+		return
+	}
+	n := Notification{Kind: "boot_report", Time: start, Message: bootReportMessage(report)}
+	if len(cfg.Channels) > 0 {
+		notifications.SendTo(cfg.Channels, n)
+	} else {
+		notifications.Send(n)
+	}
+}
+
+// bootReportMessage 按默认语言生成启动报告通知正文
+func bootReportMessage(report *BootReport) string {
+	locale := defaultLocale.Load().(string)
+	var b strings.Builder
+	if len(report.Outages) == 0 {
+		b.WriteString(translate(locale, "boot.all_ok", report.Total))
+	} else {
+		b.WriteString(translate(locale, "boot.outages", report.Total, len(report.Outages)))
+	}
+	for _, entry := range report.Outages {
+		fmt.Fprintf(&b, "\n- %s: %s", entry.Name, statusTitle(locale, entry.Status))
+		switch {
+		case len(entry.ImpactedBy) > 0:
+			b.WriteString(translate(locale, "boot.impacted_by", strings.Join(entry.ImpactedBy, translate(locale, "page.list_sep"))))
+		case entry.Error != "":
+			b.WriteString(translate(locale, "boot.error", entry.Error))
+		}
+	}
+	return b.String() + "\n" + translate(locale, "boot.time", formatTime(locale, report.Time, "datetime"))
+}
+
+// apiBootReportHandler 返回启动检查的结果
+func apiBootReportHandler(c *gin.Context) {
+	report := bootReport.Load()
+	if report == nil {
+		apiError(c, http.StatusNotFound, "error.no_boot_report")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultBrowserTimeout 浏览器检查的默认超时时间，包含启动Chrome的时间
+const defaultBrowserTimeout = 30 * time.Second
+
+// BrowserChecker 在无头Chrome中打开页面，等待元素渲染后校验页面内容，用于检查单页应用等
+// HTTP状态码无法反映可用性的前端。需使用 -tags chrome 编译，并在运行环境中安装Chrome或Chromium
+type BrowserChecker struct {
+	// URL 要打开的页面
+	URL string `yaml:"url"`
+	// WaitFor 等待可见的元素（CSS选择器），为空时只等待页面加载完成
+	WaitFor string `yaml:"wait_for,omitempty"`
+	// Selector 读取文本的元素（CSS选择器），默认body
+	Selector string `yaml:"selector,omitempty"`
+	// Contains 元素文本需包含的内容，为空时不校验
+	Contains string `yaml:"contains,omitempty"`
+	// Eval 页面中执行的JavaScript表达式，结果需为真值，如 document.querySelectorAll('.item').length > 0
+	Eval string `yaml:"eval,omitempty"`
+	// Timeout 超时时间，默认30s
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// ExecPath Chrome可执行文件路径，为空时自动查找
+	ExecPath string `yaml:"exec_path,omitempty"`
+}
+
+// validate 校验浏览器检查配置
+func (b *BrowserChecker) validate() error {
+	if !browserSupported {
+		return fmt.Errorf("当前版本不支持浏览器检查，需使用 -tags chrome 编译")
+	}
+	if b.URL == "" {
+		return fmt.Errorf("浏览器检查缺少url")
+	}
+	return nil
+}
+
+// timeout 返回检查的超时时间
+func (b *BrowserChecker) timeout() time.Duration {
+	if b.Timeout > 0 {
+		return b.Timeout
+	}
+	return defaultBrowserTimeout
+}
+
+// selector 返回读取文本的元素
+func (b *BrowserChecker) selector() string {
+	if b.Selector != "" {
+		return b.Selector
+	}
+	return "body"
+}
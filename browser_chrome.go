@@ -0,0 +1,117 @@
+//go:build chrome
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// pdfTimeout 生成PDF的超时时间，包含启动Chrome的时间
+const pdfTimeout = time.Minute
+
+// browserSupported 使用chrome标签编译时支持浏览器检查与生成PDF
+const browserSupported = true
+
+// CheckStatus 实现StatusChecker接口，每次检查启动独立的无头Chrome，检查结束后退出
+func (b *BrowserChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout())
+	defer cancel()
+
+	browserCtx, cancelBrowser := newBrowser(ctx, b.ExecPath)
+	defer cancelBrowser()
+
+	actions := []chromedp.Action{chromedp.Navigate(b.URL)}
+	if b.WaitFor != "" {
+		actions = append(actions, chromedp.WaitVisible(b.WaitFor, chromedp.ByQuery))
+	}
+	var text string
+	if b.Contains != "" {
+		actions = append(actions, chromedp.Text(b.selector(), &text, chromedp.ByQuery))
+	}
+	var result interface{}
+	if b.Eval != "" {
+		actions = append(actions, chromedp.Evaluate(b.Eval, &result))
+	}
+	if err := chromedp.Run(browserCtx, actions...); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && b.WaitFor != "" {
+			return StatusOffline, fmt.Errorf("等待元素 %s 超时", b.WaitFor)
+		}
+		return StatusOffline, fmt.Errorf("浏览器检查失败: %v", err)
+	}
+
+	if b.Contains != "" && !strings.Contains(text, b.Contains) {
+		return StatusOffline, fmt.Errorf("元素 %s 的内容未包含 '%s'", b.selector(), b.Contains)
+	}
+	if b.Eval != "" {
+		if !jsTruthy(result) {
+			return StatusOffline, fmt.Errorf("表达式 '%s' 的结果为 %s", b.Eval, jsonString(result))
+		}
+	}
+	return StatusOnline, nil
+}
+
+// newBrowser 启动无头Chrome，execPath为空时自动查找，取消返回的context后退出
+func newBrowser(ctx context.Context, execPath string) (context.Context, context.CancelFunc) {
+	opts := chromedp.DefaultExecAllocatorOptions[:]
+	if execPath != "" {
+		opts = append(opts[:len(opts):len(opts)], chromedp.ExecPath(execPath))
+	}
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, opts...)
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+	return browserCtx, func() {
+		cancelBrowser()
+		cancelAlloc()
+	}
+}
+
+// renderPDF 在无头Chrome中打开HTML内容并打印为PDF
+func renderPDF(ctx context.Context, execPath string, html []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, pdfTimeout)
+	defer cancel()
+	browserCtx, cancelBrowser := newBrowser(ctx, execPath)
+	defer cancelBrowser()
+
+	var pdf []byte
+	err := chromedp.Run(browserCtx,
+		chromedp.Navigate("about:blank"),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			tree, err := page.GetFrameTree().Do(ctx)
+			if err != nil {
+				return err
+			}
+			return page.SetDocumentContent(tree.Frame.ID, string(html)).Do(ctx)
+		}),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			pdf, _, err = page.PrintToPDF().WithPrintBackground(true).Do(ctx)
+			return err
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("生成PDF失败: %v", err)
+	}
+	return pdf, nil
+}
+
+// jsTruthy 按JavaScript的规则判断表达式结果是否为真值
+func jsTruthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		return x != ""
+	default:
+		return true
+	}
+}
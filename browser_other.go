@@ -0,0 +1,21 @@
+//go:build !chrome
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// browserSupported 未使用chrome标签编译时不支持浏览器检查与生成PDF
+const browserSupported = false
+
+// CheckStatus 实现StatusChecker接口，未包含浏览器支持时始终失败
+func (b *BrowserChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	return StatusOffline, fmt.Errorf("当前版本不支持浏览器检查")
+}
+
+// renderPDF 未包含浏览器支持时无法生成PDF
+func renderPDF(ctx context.Context, execPath string, html []byte) ([]byte, error) {
+	return nil, fmt.Errorf("当前版本不支持生成PDF，需使用 -tags chrome 编译")
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// 命令行退出码
+const (
+	// exitOK 执行成功，check命令表示所有服务可用
+	exitOK = 0
+	// exitFailure check命令存在不可用的服务，或执行失败
+	exitFailure = 1
+	// exitUsage 参数或配置错误
+	exitUsage = 2
+)
+
+// usageText 命令行帮助
+const usageText = `用法: jjapps-status [命令] [参数]
+
+命令:
+  serve      启动状态页服务（默认）；--mock以模拟检查器代替真实检查，用于开发页面与通知
+  check      立即检查服务并输出结果，可指定服务名称；--format可选table、json、prometheus，
+             配合--output写入文件后可由cron或node_exporter的textfile收集器使用
+  validate   校验配置文件
+  import     将Uptime Kuma备份JSON或Gatus配置转换为本程序的服务配置并输出YAML
+  help       显示帮助
+
+退出码: 0 成功，1 存在不可用的服务或执行失败，2 参数或配置错误
+使用 "jjapps-status <命令> -h" 查看命令的参数
+`
+
+// run 解析命令行并执行对应的命令，返回退出码
+func run(args []string) int {
+	cmd := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "serve":
+		return serveCommand(args)
+	case "check":
+		return checkCommand(args)
+	case "validate":
+		return validateCommand(args)
+	case "import":
+		return importCommand(args)
+	case "help":
+		fmt.Fprint(os.Stdout, usageText)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "未知的命令 '%s'\n\n%s", cmd, usageText)
+		return exitUsage
+	}
+}
+
+// newFlagSet 创建命令的参数集，包含公共的--config参数
+func newFlagSet(name, summary string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: jjapps-status %s\n\n参数:\n", summary)
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", envOr("CONFIG", "services.yaml"), "配置文件路径，默认读取CONFIG环境变量")
+	return fs, configPath
+}
+
+// parseFlags 解析参数，返回非负数时表示应立即以该退出码结束
+func parseFlags(fs *flag.FlagSet, args []string) int {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	return -1
+}
+
+// envOr 读取环境变量，为空时返回默认值
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// serveCommand 启动Web服务，未指定的参数兼容原有的环境变量
+func serveCommand(args []string) int {
+	fs, configPath := newFlagSet("serve", "serve [--config 文件] [--listen 地址] [--mock]")
+	listen := fs.String("listen", "", "监听地址，如:8080或127.0.0.1:8080，默认使用PORTS环境变量监听127.0.0.1")
+	socket := fs.String("socket", os.Getenv("SOCKET"), "监听的unix socket路径，优先于--listen")
+	socketMode := fs.String("socket-mode", os.Getenv("SOCKET_MODE"), "unix socket的八进制权限，默认0660")
+	assetsDir := fs.String("assets", os.Getenv("ASSETS_DIR"), "覆盖内嵌模板与静态资源的目录")
+	mock := fs.Bool("mock", false, "模拟模式：所有检查器替换为服务mock字段配置的模拟检查器，不访问真实服务，用于开发页面与通知")
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "多余的参数: %s\n", strings.Join(fs.Args(), " "))
+		return exitUsage
+	}
+	if *listen == "" {
+		if port := os.Getenv("PORTS"); port != "" {
+			*listen = "127.0.0.1:" + port
+		}
+	}
+	serve(serveOptions{
+		configPath: *configPath,
+		listen:     *listen,
+		socket:     *socket,
+		socketMode: *socketMode,
+		assetsDir:  *assetsDir,
+		mock:       *mock,
+	})
+	return exitOK
+}
+
+// loadConfigFile 读取并完整校验配置文件，与服务启动不同，文件不存在时返回错误
+func loadConfigFile(path string) (*Config, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validateCommand 校验配置文件
+func validateCommand(args []string) int {
+	fs, configPath := newFlagSet("validate", "validate [--config 文件] [--mock]")
+	mock := fs.Bool("mock", false, "按模拟模式校验，允许服务配置mock字段")
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	mockMode.Store(*mock)
+	cfg, err := loadConfigFile(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+		return exitUsage
+	}
+	fmt.Fprintf(os.Stdout, "%s: 配置有效，共 %d 个服务\n", *configPath, len(cfg.Services))
+	return exitOK
+}
+
+// checkCommand 立即检查服务并以表格输出结果，存在不可用的服务时返回exitFailure
+func checkCommand(args []string) int {
+	fs, configPath := newFlagSet("check", "check [--config 文件] [--format 格式] [--output 文件] [服务名称...]")
+	format := fs.String("format", "table", "输出格式：table、json或prometheus")
+	output := fs.String("output", "", "写入的文件路径，默认输出到标准输出；文件先写入临时文件再替换，可直接用于textfile收集器")
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	writers := map[string]func(io.Writer, []CheckResult) error{
+		"table":      writeResultsTable,
+		"json":       writeResultsJSON,
+		"prometheus": writeResultsPrometheus,
+	}
+	write, ok := writers[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "无效的输出格式 '%s'，可选table、json或prometheus\n", *format)
+		return exitUsage
+	}
+	// 错误日志输出到标准错误，避免干扰结果
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})))
+
+	cfg, err := loadConfigFile(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+		return exitUsage
+	}
+	results, err := checkOnce(cfg, fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if err := writeOutput(*output, func(w io.Writer) error { return write(w, results) }); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	return checkExitCode(results)
+}
+
+// writeOutput 将内容写入标准输出，或先写入同目录的临时文件再替换path，避免读取到写了一半的文件
+func writeOutput(path string, write func(io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("写入输出文件失败: %v", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("写入输出文件失败: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入输出文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入输出文件失败: %v", err)
+	}
+	return nil
+}
+
+// checkOnce 按配置检查一次指定的服务（为空时检查全部服务），不保存检查记录
+func checkOnce(cfg *Config, names []string) ([]CheckResult, error) {
+	services, err := cfg.BuildServices()
+	if err != nil {
+		return nil, err
+	}
+	if err := setDisplayTimezone(cfg.Timezone); err != nil {
+		return nil, err
+	}
+	setTransportConfig(cfg.HTTPTransport)
+	setMaintenance(cfg.Maintenance)
+
+	if len(names) > 0 {
+		byName := make(map[string]*Service, len(services))
+		for _, service := range services {
+			byName[service.Name] = service
+		}
+		selected := make([]*Service, 0, len(names))
+		for _, name := range names {
+			service, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("服务 '%s' 不存在", name)
+			}
+			selected = append(selected, service)
+		}
+		services = selected
+	}
+
+	history, err := NewFileStore(StorageConfig{})
+	if err != nil {
+		return nil, err
+	}
+	manager := NewServiceManager(history)
+	for _, service := range services {
+		manager.AddService(service)
+	}
+	timeout := cfg.RefreshTimeout
+	if timeout <= 0 {
+		timeout = defaultRefreshTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return manager.checkBatch(ctx, services), nil
+}
+
+// checkExitCode 存在离线或受影响的服务时返回exitFailure
+func checkExitCode(results []CheckResult) int {
+	for _, result := range results {
+		if !isUp(result.Status) && result.Status != StatusMaintenance {
+			return exitFailure
+		}
+	}
+	return exitOK
+}
+
+// writeResultsTable 以表格输出检查结果
+func writeResultsTable(w io.Writer, results []CheckResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tSTATUS\tLATENCY\tERROR")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%.1fms\t%s\n", result.Service, result.Status, result.LatencyMS, result.Error)
+	}
+	return tw.Flush()
+}
+
+// writeResultsJSON 以JSON输出检查结果
+func writeResultsJSON(w io.Writer, results []CheckResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{
+		"time":    time.Now().Format(time.RFC3339),
+		"results": results,
+	})
+}
+
+// promLabel 转义Prometheus标签值
+func promLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// writeResultsPrometheus 以Prometheus文本格式输出检查结果
+func writeResultsPrometheus(w io.Writer, results []CheckResult) error {
+	var b strings.Builder
+	metric := func(name, help string, value func(CheckResult) float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, result := range results {
+			fmt.Fprintf(&b, "%s{service=\"%s\"} %s\n", name, promLabel(result.Service), strconv.FormatFloat(value(result), 'f', -1, 64))
+		}
+	}
+	metric("jjapps_status_up", "服务是否可用（在线或降级为1）", func(r CheckResult) float64 {
+		if isUp(r.Status) {
+			return 1
+		}
+		return 0
+	})
+	fmt.Fprintf(&b, "# HELP jjapps_status_state 服务当前状态，当前状态为1\n# TYPE jjapps_status_state gauge\n")
+	states := []ServiceStatus{StatusOnline, StatusDegraded, StatusMaintenance, StatusImpacted, StatusOffline}
+	for _, result := range results {
+		for _, state := range states {
+			value := 0
+			if result.Status == state {
+				value = 1
+			}
+			fmt.Fprintf(&b, "jjapps_status_state{service=\"%s\",status=\"%s\"} %d\n", promLabel(result.Service), state, value)
+		}
+	}
+	metric("jjapps_status_check_duration_seconds", "检查耗时（秒）", func(r CheckResult) float64 {
+		return r.LatencyMS / 1000
+	})
+	metric("jjapps_status_last_check_timestamp_seconds", "检查时间（Unix时间戳）", func(r CheckResult) float64 {
+		return float64(r.Time.Unix())
+	})
+	_, err := io.WriteString(w, b.String())
+	return err
+}
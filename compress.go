@@ -0,0 +1,236 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultCompressMinSize 默认的最小压缩字节数，更小的响应压缩收益不大
+const defaultCompressMinSize = 1024
+
+// CompressionConfig 响应压缩配置
+type CompressionConfig struct {
+	// Enabled 是否启用，默认启用
+	Enabled *bool `yaml:"enabled,omitempty"`
+	// Level gzip压缩级别（1-9），默认6
+	Level int `yaml:"level,omitempty"`
+	// Brotli 客户端支持时优先使用brotli压缩
+	Brotli bool `yaml:"brotli,omitempty"`
+	// MinSize 最小压缩字节数，默认1024
+	MinSize int `yaml:"min_size,omitempty"`
+}
+
+// validate 校验压缩配置
+func (cc *CompressionConfig) validate() error {
+	if cc.Level != 0 && (cc.Level < gzip.BestSpeed || cc.Level > gzip.BestCompression) {
+		return fmt.Errorf("无效的压缩级别 %d，需为1-9", cc.Level)
+	}
+	return nil
+}
+
+// compressible 判断内容类型是否值得压缩，图片、压缩包等已压缩的内容跳过
+func compressible(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch {
+	case strings.HasPrefix(contentType, "text/"):
+		return true
+	case contentType == "application/json", contentType == "application/javascript",
+		contentType == "application/xml", contentType == "image/svg+xml",
+		strings.HasSuffix(contentType, "+json"), strings.HasSuffix(contentType, "+xml"):
+		return true
+	}
+	return false
+}
+
+// acceptEncoding 根据Accept-Encoding选择压缩方式，不支持压缩时返回空字符串
+func acceptEncoding(header string, allowBrotli bool) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+		weight := 1.0
+		for _, param := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					weight = f
+				}
+			}
+		}
+		q[name] = weight
+	}
+	weight := func(name string) float64 {
+		if w, ok := q[name]; ok {
+			return w
+		}
+		return q["*"]
+	}
+
+	best, bestWeight := "", 0.0
+	if allowBrotli && weight("br") > 0 {
+		best, bestWeight = "br", weight("br")
+	}
+	if w := weight("gzip"); w > 0 && w > bestWeight {
+		best = "gzip"
+	}
+	return best
+}
+
+// compressWriter 缓冲响应开头的内容，达到最小压缩字节数后再决定是否压缩
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minSize  int
+	newEnc   func(io.Writer) io.WriteCloser
+	buf      []byte
+	enc      io.WriteCloser
+	decided  bool
+}
+
+// start 确定是否压缩并写出已缓冲的内容
+func (w *compressWriter) start(compress bool) {
+	w.decided = true
+	header := w.Header()
+	status := w.Status()
+	if compress && status != http.StatusNoContent && status != http.StatusNotModified &&
+		status != http.StatusPartialContent && header.Get("Content-Encoding") == "" && header.Get("Content-Range") == "" {
+		contentType := header.Get("Content-Type")
+		if contentType == "" {
+			contentType = http.DetectContentType(w.buf)
+		}
+		if compressible(contentType) {
+			header.Set("Content-Encoding", w.encoding)
+			header.Add("Vary", "Accept-Encoding")
+			header.Del("Content-Length")
+			w.enc = w.newEnc(w.ResponseWriter)
+		}
+	}
+	if len(w.buf) > 0 {
+		w.write(w.buf)
+		w.buf = nil
+	}
+}
+
+// write 写出内容，需先调用start
+func (w *compressWriter) write(data []byte) (int, error) {
+	if w.enc != nil {
+		return w.enc.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Write 实现io.Writer接口
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		return w.write(data)
+	}
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.minSize {
+		w.start(true)
+	}
+	return len(data), nil
+}
+
+// WriteString 实现io.StringWriter接口
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written 缓冲中的内容同样视为已写出
+func (w *compressWriter) Written() bool {
+	return w.decided || len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// Flush 立即发送已写入的内容
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.start(true)
+	}
+	if flusher, ok := w.enc.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// finish 结束响应，未达到最小压缩字节数的内容直接发送
+func (w *compressWriter) finish() {
+	if !w.decided {
+		w.start(len(w.buf) >= w.minSize)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+	}
+}
+
+// CompressionMiddleware 返回响应压缩中间件，按Accept-Encoding使用gzip或brotli压缩
+func CompressionMiddleware(cfg CompressionConfig) gin.HandlerFunc {
+	if cfg.Enabled != nil && !*cfg.Enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+	level := cfg.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	minSize := cfg.MinSize
+	if minSize <= 0 {
+		minSize = defaultCompressMinSize
+	}
+
+	gzipPool := sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, level)
+		return w
+	}}
+	encoders := map[string]func(io.Writer) io.WriteCloser{
+		"gzip": func(dst io.Writer) io.WriteCloser {
+			w := gzipPool.Get().(*gzip.Writer)
+			w.Reset(dst)
+			return &pooledGzip{Writer: w, pool: &gzipPool}
+		},
+		"br": func(dst io.Writer) io.WriteCloser {
+			return brotli.NewWriter(dst)
+		},
+	}
+
+	return func(c *gin.Context) {
+		encoding := acceptEncoding(c.GetHeader("Accept-Encoding"), cfg.Brotli)
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			minSize:        minSize,
+			newEnc:         encoders[encoding],
+		}
+		c.Writer = w
+		defer func() {
+			w.finish()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// pooledGzip 关闭时归还到池中的gzip写入器
+type pooledGzip struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+// Close 结束压缩流并归还写入器
+func (p *pooledGzip) Close() error {
+	err := p.Writer.Close()
+	p.pool.Put(p.Writer)
+	return err
+}
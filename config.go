@@ -0,0 +1,428 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigData 内嵌的默认服务配置，配置文件不存在时使用
+//
+//go:embed services.yaml
+var defaultConfigData []byte
+
+// Config 配置文件结构
+type Config struct {
+	// Log 日志配置，修改后需重启生效
+	Log LogConfig `yaml:"log,omitempty"`
+	// Locale 默认语言（zh或en），页面与接口会优先使用请求的Accept-Language
+	Locale string `yaml:"locale,omitempty"`
+	// Timezone 页面与通知中显示时间使用的时区，如Asia/Shanghai，为空时使用本地时区
+	Timezone string `yaml:"timezone,omitempty"`
+	// RateLimit 公开接口的限流配置，修改后需重启生效
+	RateLimit RateLimitConfig `yaml:"rate_limit,omitempty"`
+	// CORS JSON接口的跨域配置，修改后需重启生效
+	CORS CORSConfig `yaml:"cors,omitempty"`
+	// Compression 响应压缩配置，修改后需重启生效
+	Compression CompressionConfig `yaml:"compression,omitempty"`
+	// AdminToken 管理员角色的Bearer令牌，等同于tokens中名为admin的admin角色令牌
+	AdminToken string `yaml:"admin_token,omitempty"`
+	// Tokens 带角色的访问令牌，未配置任何令牌时禁用管理接口
+	Tokens []APIToken `yaml:"tokens,omitempty"`
+	// AdminAccess 管理接口与探针上报接口的来源IP白名单
+	AdminAccess AdminAccessConfig `yaml:"admin_access,omitempty"`
+	// OIDC 通过OpenID Connect登录浏览器会话，登录后按配置授予角色
+	OIDC OIDCConfig `yaml:"oidc,omitempty"`
+	// Storage 检查记录存储配置，修改后需重启生效
+	Storage StorageConfig `yaml:"storage,omitempty"`
+	// HTTPTransport HTTP类检查共用的连接配置，检查器可通过transport字段单独覆盖
+	HTTPTransport TransportConfig `yaml:"http_transport,omitempty"`
+	// RefreshTimeout 一轮全量检查的总超时时间，默认30s
+	RefreshTimeout time.Duration `yaml:"refresh_timeout,omitempty"`
+	// RefreshInterval 未启用定时检查时，访问状态接口触发后台刷新的最小间隔，默认5s
+	RefreshInterval time.Duration `yaml:"refresh_interval,omitempty"`
+	// HideErrorDetails 对未认证的调用方隐藏检查错误信息与服务地址，仅显示检查失败；
+	// viewer及以上角色与日志中仍保留完整信息
+	HideErrorDetails bool `yaml:"hide_error_details,omitempty"`
+	// Agent 探针模式配置，修改后需重启生效
+	Agent AgentConfig `yaml:"agent,omitempty"`
+	// Agents 中心节点允许上报的远程探针，修改后需重启生效
+	Agents []RemoteAgentConfig `yaml:"agents,omitempty"`
+	// AgentTimeout 远程探针失联判定时间，默认2m
+	AgentTimeout time.Duration `yaml:"agent_timeout,omitempty"`
+	// Region 本机检查位置的名称，显示在服务的多地检查结果中，默认local
+	Region string `yaml:"region,omitempty"`
+	// Notifications 通知渠道与默认路由
+	Notifications NotificationConfig `yaml:"notifications,omitempty"`
+	// Subscriptions 访客邮件订阅配置
+	Subscriptions SubscriptionConfig `yaml:"subscriptions,omitempty"`
+	// Reports 月度可用性报告配置
+	Reports ReportConfig `yaml:"reports,omitempty"`
+	// Push 定期推送状态快照的外部地址
+	Push []PushTarget `yaml:"push,omitempty"`
+	// Schedule 后台定时检查配置，修改后需重启生效
+	Schedule ScheduleConfig `yaml:"schedule,omitempty"`
+	// Discovery 服务自动发现来源，修改后需重启生效
+	Discovery []DiscoveryConfig `yaml:"discovery,omitempty"`
+	// Diagnostics 服务进入离线状态时执行的诊断命令，服务可单独配置
+	Diagnostics DiagnosticsConfig `yaml:"diagnostics,omitempty"`
+	// Maintenance 计划维护时段，时段内相关服务显示为维护状态
+	Maintenance []MaintenanceWindow `yaml:"maintenance,omitempty"`
+	// Plugins 外部检查器插件，注册后可在服务中以插件名称作为检查器类型
+	Plugins []PluginConfig `yaml:"plugins,omitempty"`
+	// Pages 独立状态页，每个页面展示一部分服务
+	Pages []PageConfig `yaml:"pages,omitempty"`
+	// Theme 状态页的标题、Logo、配色、页脚与自动刷新间隔
+	Theme ThemeConfig `yaml:"theme,omitempty"`
+	// Summary 整体状态的计算配置
+	Summary SummaryConfig `yaml:"summary,omitempty"`
+	// Snapshots 配置快照设置，定期及每次配置变更时保存生效的配置，可通过管理接口回滚
+	Snapshots SnapshotConfig `yaml:"snapshots,omitempty"`
+	// BootReport 启动检查配置，修改后需重启生效
+	BootReport BootReportConfig `yaml:"boot_report,omitempty"`
+	// Services 服务定义列表
+	Services []ServiceConfig `yaml:"services"`
+}
+
+// ServiceConfig 单个服务的配置
+type ServiceConfig struct {
+	// Name 服务名称，作为服务的唯一标识
+	Name string `yaml:"name"`
+	// Description 服务描述
+	Description string `yaml:"description,omitempty"`
+	// URL 服务URL
+	URL string `yaml:"url,omitempty"`
+	// Order 显示顺序，数值小的在前，相同时按配置顺序
+	Order int `yaml:"order,omitempty"`
+	// Hidden 在公开页面与接口中隐藏，仍会检查，携带viewer及以上角色令牌的请求可见
+	Hidden bool `yaml:"hidden,omitempty"`
+	// Enabled 是否启用，默认启用；停用的服务保留在配置中但不检查
+	Enabled *bool `yaml:"enabled,omitempty"`
+	// Checker 状态检查器配置
+	Checker CheckerConfig `yaml:"checker"`
+	// Override 状态覆盖，如迁移期间强制显示为维护状态
+	Override *StatusOverride `yaml:"override,omitempty"`
+	// DependsOn 依赖的服务名称，依赖不可用时本服务的检查失败会显示为受影响
+	DependsOn []string `yaml:"depends_on,omitempty"`
+	// Notify 状态变化时使用的通知渠道，为空时使用默认路由
+	Notify []string `yaml:"notify,omitempty"`
+	// Interval 定时检查间隔，为0时使用schedule.interval
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Timeout 单次检查的总时限，超过后取消检查并视为失败，为0时只受refresh_timeout限制
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Retry 检查失败后的重试策略，如服务重启期间的连接被拒绝
+	Retry *RetryPolicy `yaml:"retry,omitempty"`
+	// Invert 反向检查，检查失败时视为正常、检查成功时视为离线，用于确认数据库、管理后台等端口没有对外暴露
+	Invert bool `yaml:"invert,omitempty"`
+	// Latency 延迟阈值，检查成功但延迟持续过高时服务降级
+	Latency *LatencyThreshold `yaml:"latency,omitempty"`
+	// Anomaly 延迟异常检测，延迟持续明显高于历史基线时标记为性能异常
+	Anomaly *AnomalyConfig `yaml:"anomaly,omitempty"`
+	// SLO 服务等级目标，用于计算错误预算
+	SLO *SLOConfig `yaml:"slo,omitempty"`
+	// Weight 计入整体状态的权重，默认1，核心服务可设置更大的权重
+	Weight float64 `yaml:"weight,omitempty"`
+	// Critical 关键服务，通知不受渠道免打扰时段限制
+	Critical bool `yaml:"critical,omitempty"`
+	// Escalation 服务单独的告警升级规则，为空时使用全局规则
+	Escalation *EscalationConfig `yaml:"escalation,omitempty"`
+	// Diagnostics 服务单独的诊断配置，为空时使用全局配置
+	Diagnostics *DiagnosticsConfig `yaml:"diagnostics,omitempty"`
+	// Heal 连续检查失败后执行的自动修复动作
+	Heal *HealConfig `yaml:"heal,omitempty"`
+	// Regions 同时检查该服务的远程探针名称，探针需配置同名服务；
+	// 所有位置均离线时服务为离线，部分位置离线时为降级
+	Regions []string `yaml:"regions,omitempty"`
+	// Mock 以serve --mock运行时替代checker的模拟检查器配置，未配置时始终在线
+	Mock *MockChecker `yaml:"mock,omitempty"`
+}
+
+// CheckerConfig 检查器配置，Type决定检查器种类，其余字段由对应检查器解析
+type CheckerConfig struct {
+	// Type 检查器类型
+	Type string `yaml:"type"`
+	// node 原始配置节点
+	node *yaml.Node
+}
+
+// checkerFactories 检查器类型注册表
+var checkerFactories = map[string]func() StatusChecker{
+	"http":          func() StatusChecker { return &HTTPChecker{} },
+	"ping":          func() StatusChecker { return &PingChecker{} },
+	"cmd":           func() StatusChecker { return &CmdChecker{} },
+	"systemd":       func() StatusChecker { return &SystemdChecker{} },
+	"prometheus":    func() StatusChecker { return &PromQLChecker{} },
+	"json":          func() StatusChecker { return &JSONChecker{} },
+	"domain":        func() StatusChecker { return &DomainChecker{} },
+	"heartbeat":     func() StatusChecker { return &HeartbeatChecker{} },
+	"amqp":          func() StatusChecker { return &AMQPChecker{} },
+	"kafka":         func() StatusChecker { return &KafkaChecker{} },
+	"elasticsearch": func() StatusChecker { return &ElasticsearchChecker{} },
+	"ntp":           func() StatusChecker { return &NTPChecker{} },
+	"dns":           func() StatusChecker { return &DNSChecker{} },
+	"sftp":          func() StatusChecker { return &SFTPChecker{} },
+	"s3":            func() StatusChecker { return &S3Checker{} },
+	"transaction":   func() StatusChecker { return &TransactionChecker{} },
+	"browser":       func() StatusChecker { return &BrowserChecker{} },
+	"mock":          func() StatusChecker { return &MockChecker{} },
+}
+
+// UnmarshalYAML 实现yaml.Unmarshaler接口，保留原始节点供检查器解析
+func (c *CheckerConfig) UnmarshalYAML(node *yaml.Node) error {
+	var head struct {
+		Type string `yaml:"type"`
+	}
+	if err := node.Decode(&head); err != nil {
+		return err
+	}
+	c.Type = head.Type
+	c.node = node
+	return nil
+}
+
+// MarshalYAML 实现yaml.Marshaler接口，原样输出检查器配置
+func (c CheckerConfig) MarshalYAML() (interface{}, error) {
+	if c.node != nil {
+		return c.node, nil
+	}
+	return map[string]string{"type": c.Type}, nil
+}
+
+// Build 根据配置创建检查器，非内置的类型在plugins中查找同名插件
+func (c *CheckerConfig) Build(service string, plugins map[string]*PluginConfig) (StatusChecker, error) {
+	if c.Type == "" {
+		return nil, nil
+	}
+	factory, ok := checkerFactories[c.Type]
+	if !ok {
+		if plugin, ok := plugins[c.Type]; ok {
+			return newPluginChecker(plugin, service, c.node)
+		}
+		return nil, fmt.Errorf("未知的检查器类型 '%s'", c.Type)
+	}
+	checker := factory()
+	if c.node != nil {
+		if err := c.node.Decode(checker); err != nil {
+			return nil, fmt.Errorf("解析检查器配置失败: %v", err)
+		}
+	}
+	if v, ok := checker.(interface{ validate() error }); ok {
+		if err := v.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return checker, nil
+}
+
+// Build 根据配置创建服务
+func (sc *ServiceConfig) Build(plugins map[string]*PluginConfig) (*Service, error) {
+	checker, err := sc.Checker.Build(sc.Name, plugins)
+	if err != nil {
+		return nil, fmt.Errorf("服务 '%s': %v", sc.Name, err)
+	}
+	// 模拟模式下仍校验真实的检查器配置，未配置检查器的服务保持不检查
+	if mockMode.Load() && checker != nil {
+		if checker, err = sc.mockChecker(); err != nil {
+			return nil, fmt.Errorf("服务 '%s': %v", sc.Name, err)
+		}
+	} else if sc.Mock != nil {
+		// 非模拟模式下不接受mock，避免其随配置导出与快照保存后被误认为已生效
+		return nil, fmt.Errorf("服务 '%s': mock仅在以serve --mock运行时可用", sc.Name)
+	}
+	if sc.Latency != nil {
+		if err := sc.Latency.validate(); err != nil {
+			return nil, fmt.Errorf("服务 '%s': %v", sc.Name, err)
+		}
+	}
+	if sc.Anomaly != nil {
+		if err := sc.Anomaly.validate(); err != nil {
+			return nil, fmt.Errorf("服务 '%s': %v", sc.Name, err)
+		}
+	}
+	if sc.SLO != nil {
+		if err := sc.SLO.validate(); err != nil {
+			return nil, fmt.Errorf("服务 '%s': %v", sc.Name, err)
+		}
+	}
+	if sc.Heal != nil {
+		if err := sc.Heal.validate(); err != nil {
+			return nil, fmt.Errorf("服务 '%s': %v", sc.Name, err)
+		}
+	}
+	if sc.Retry != nil {
+		if err := sc.Retry.validate(); err != nil {
+			return nil, fmt.Errorf("服务 '%s': %v", sc.Name, err)
+		}
+	}
+	if err := sc.validateInvert(); err != nil {
+		return nil, fmt.Errorf("服务 '%s': %v", sc.Name, err)
+	}
+	if sc.Weight < 0 {
+		return nil, fmt.Errorf("服务 '%s': weight不能为负数", sc.Name)
+	}
+	if sc.Override != nil {
+		if err := sc.Override.validate(); err != nil {
+			return nil, fmt.Errorf("服务 '%s': %v", sc.Name, err)
+		}
+	}
+	return &Service{
+		Name:             sc.Name,
+		Description:      sc.Description,
+		URL:              sc.URL,
+		Order:            sc.Order,
+		Hidden:           sc.Hidden,
+		Enabled:          sc.Enabled == nil || *sc.Enabled,
+		Status:           StatusOnline,
+		Checker:          checker,
+		CheckerType:      sc.Checker.Type,
+		Override:         sc.Override,
+		configOverride:   sc.Override,
+		DependsOn:        sc.DependsOn,
+		Interval:         sc.Interval,
+		Timeout:          sc.Timeout,
+		Retry:            sc.Retry,
+		Invert:           sc.Invert,
+		Weight:           sc.Weight,
+		Latency:          sc.Latency,
+		SLO:              sc.SLO,
+		Regions:          sc.Regions,
+		AnomalyDetection: sc.Anomaly,
+	}, nil
+}
+
+// BuildServices 根据配置创建全部服务
+func (cfg *Config) BuildServices() ([]*Service, error) {
+	if err := cfg.HTTPTransport.validate(); err != nil {
+		return nil, err
+	}
+	plugins, err := cfg.validatePlugins()
+	if err != nil {
+		return nil, err
+	}
+	services := make([]*Service, 0, len(cfg.Services))
+	seen := make(map[string]bool)
+	for i := range cfg.Services {
+		sc := &cfg.Services[i]
+		if sc.Name == "" {
+			return nil, fmt.Errorf("第 %d 个服务缺少名称", i+1)
+		}
+		if seen[sc.Name] {
+			return nil, fmt.Errorf("服务名称 '%s' 重复", sc.Name)
+		}
+		seen[sc.Name] = true
+
+		service, err := sc.Build(plugins)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, service)
+	}
+	if err := validateDependencies(services); err != nil {
+		return nil, err
+	}
+	if err := cfg.validatePages(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Theme.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Summary.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateMaintenance(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateRegions(); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// Validate 完整校验配置，包括服务、通知渠道、语言、时区以及需重启生效的压缩与服务发现配置
+func (cfg *Config) Validate() error {
+	if _, err := cfg.BuildServices(); err != nil {
+		return err
+	}
+	if _, err := cfg.Notifications.buildChannels(cfg.Services); err != nil {
+		return err
+	}
+	if _, err := cfg.Subscriptions.mailer(cfg.Notifications); err != nil {
+		return err
+	}
+	if _, err := cfg.Reports.validate(cfg.Notifications); err != nil {
+		return err
+	}
+	if err := cfg.validatePush(); err != nil {
+		return err
+	}
+	if err := cfg.validateTokens(); err != nil {
+		return err
+	}
+	if _, err := cfg.AdminAccess.build(); err != nil {
+		return err
+	}
+	if err := cfg.OIDC.validate(); err != nil {
+		return err
+	}
+	if err := validateLocale(cfg.Locale); err != nil {
+		return err
+	}
+	if err := validateTimezone(cfg.Timezone); err != nil {
+		return err
+	}
+	if err := cfg.Compression.validate(); err != nil {
+		return err
+	}
+	if err := validateLatencyBuckets(cfg.Storage.LatencyBuckets); err != nil {
+		return err
+	}
+	if err := cfg.Snapshots.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Agent.validate(); err != nil {
+		return err
+	}
+	if err := cfg.BootReport.validate(cfg.Notifications); err != nil {
+		return err
+	}
+	for i := range cfg.Discovery {
+		if _, err := cfg.Discovery[i].Build(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseConfig 解析配置内容
+func parseConfig(data []byte) (*Config, error) {
+	cfg := new(Config)
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("解析配置失败: %v", err)
+	}
+	return cfg, nil
+}
+
+// readConfigFile 读取配置文件内容，文件不存在时返回内嵌的默认配置
+func readConfigFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return defaultConfigData, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	return data, nil
+}
+
+// LoadConfig 加载配置文件，文件不存在时使用内嵌的默认配置
+func LoadConfig(path string) (*Config, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(data)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// ConsulSource 从Consul读取已注册服务的健康状态
+type ConsulSource struct {
+	// Address Consul HTTP地址，默认http://127.0.0.1:8500
+	Address string `yaml:"address"`
+	// Token ACL令牌
+	Token string `yaml:"token"`
+	// Datacenter 数据中心，为空时使用Agent所在的数据中心
+	Datacenter string `yaml:"datacenter"`
+	// Services 服务名称过滤，支持通配符，为空时包含全部服务
+	Services []string `yaml:"services"`
+}
+
+// consulCheck Consul健康检查结果
+type consulCheck struct {
+	CheckID     string `json:"CheckID"`
+	Status      string `json:"Status"`
+	Output      string `json:"Output"`
+	ServiceID   string `json:"ServiceID"`
+	ServiceName string `json:"ServiceName"`
+}
+
+// consulMaintenancePrefix Consul维护模式检查的ID前缀
+const consulMaintenancePrefix = "_service_maintenance:"
+
+// match 判断服务名称是否满足过滤条件
+func (cs *ConsulSource) match(name string) bool {
+	if len(cs.Services) == 0 {
+		return true
+	}
+	for _, pattern := range cs.Services {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Discover 实现DiscoverySource接口：服务的全部实例均不健康时为离线，部分不健康时为降级
+func (cs *ConsulSource) Discover(ctx context.Context) ([]DiscoveredService, error) {
+	address := cs.Address
+	if address == "" {
+		address = "http://127.0.0.1:8500"
+	}
+	url := strings.TrimRight(address, "/") + "/v1/health/state/any"
+	if cs.Datacenter != "" {
+		url += "?dc=" + cs.Datacenter
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cs.Token != "" {
+		req.Header.Set("X-Consul-Token", cs.Token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求Consul失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Consul返回状态码 %d", resp.StatusCode)
+	}
+	var checks []consulCheck
+	if err := json.NewDecoder(resp.Body).Decode(&checks); err != nil {
+		return nil, fmt.Errorf("解析Consul响应失败: %v", err)
+	}
+
+	// 按服务实例汇总，实例的状态取其全部检查中最差的
+	type instance struct {
+		status ServiceStatus
+		output string
+	}
+	services := make(map[string]map[string]*instance)
+	order := make([]string, 0)
+	for _, check := range checks {
+		if check.ServiceName == "" || !cs.match(check.ServiceName) {
+			continue
+		}
+		instances, ok := services[check.ServiceName]
+		if !ok {
+			instances = make(map[string]*instance)
+			services[check.ServiceName] = instances
+			order = append(order, check.ServiceName)
+		}
+		inst, ok := instances[check.ServiceID]
+		if !ok {
+			inst = &instance{status: StatusOnline}
+			instances[check.ServiceID] = inst
+		}
+		status := StatusOnline
+		switch {
+		case strings.HasPrefix(check.CheckID, consulMaintenancePrefix):
+			status = StatusMaintenance
+		case check.Status == "critical":
+			status = StatusOffline
+		case check.Status == "warning":
+			status = StatusDegraded
+		}
+		if statusSeverity[status] > statusSeverity[inst.status] {
+			inst.status, inst.output = status, strings.TrimSpace(check.Output)
+		}
+	}
+
+	found := make([]DiscoveredService, 0, len(order))
+	for _, name := range order {
+		ds := DiscoveredService{Name: name, Description: "Consul", Status: StatusOnline}
+		down, maintenance := 0, 0
+		for _, inst := range services[name] {
+			switch inst.status {
+			case StatusOffline:
+				down++
+			case StatusMaintenance:
+				maintenance++
+			}
+			if inst.status != StatusOnline && ds.Error == "" {
+				ds.Error = inst.output
+			}
+			if inst.status == StatusDegraded {
+				ds.Status = StatusDegraded
+			}
+		}
+		total := len(services[name])
+		switch {
+		case maintenance == total:
+			ds.Status = StatusMaintenance
+		case down+maintenance == total:
+			ds.Status = StatusOffline
+		case down > 0:
+			ds.Status = StatusDegraded
+		}
+		if ds.Status != StatusOnline && ds.Error == "" {
+			ds.Error = fmt.Sprintf("%d/%d 个实例不健康", down, total)
+		}
+		found = append(found, ds)
+	}
+	return found, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig 跨域配置
+type CORSConfig struct {
+	// AllowedOrigins 允许的来源，"*"表示允许所有来源，为空时不启用跨域
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	// AllowedMethods 允许的请求方法，默认GET、OPTIONS
+	AllowedMethods []string `yaml:"allowed_methods"`
+	// AllowedHeaders 允许的请求头
+	AllowedHeaders []string `yaml:"allowed_headers"`
+	// MaxAge 预检请求缓存时间（秒）
+	MaxAge int `yaml:"max_age"`
+}
+
+// allowOrigin 判断来源是否允许，返回应写入响应头的值
+func (cc *CORSConfig) allowOrigin(origin string) (string, bool) {
+	for _, allowed := range cc.AllowedOrigins {
+		if allowed == "*" {
+			return "*", true
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// CORSMiddleware 返回跨域中间件，预检请求直接返回204
+func CORSMiddleware(cfg CORSConfig) gin.HandlerFunc {
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodOptions}
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || len(cfg.AllowedOrigins) == 0 {
+			c.Next()
+			return
+		}
+
+		value, ok := cfg.allowOrigin(origin)
+		if !ok {
+			c.Next()
+			return
+		}
+		c.Header("Access-Control-Allow-Origin", value)
+		if value != "*" {
+			c.Header("Vary", "Origin")
+		}
+
+		// 预检请求
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", allowMethods)
+			if allowHeaders != "" {
+				c.Header("Access-Control-Allow-Headers", allowHeaders)
+			}
+			if cfg.MaxAge > 0 {
+				c.Header("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge))
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
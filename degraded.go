@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultLatencyWindow 延迟阈值默认的滑动窗口大小（检查次数）
+const defaultLatencyWindow = 5
+
+// 延迟告警级别
+const (
+	latencyLevelWarn     = "warn"
+	latencyLevelCritical = "critical"
+)
+
+// LatencyThreshold 延迟阈值配置，检查成功但最近Window次的平均延迟超过阈值时服务降级
+type LatencyThreshold struct {
+	// Warn 警告阈值
+	Warn time.Duration `yaml:"warn"`
+	// Critical 严重阈值，为0时不区分严重级别
+	Critical time.Duration `yaml:"critical,omitempty"`
+	// Window 滑动窗口大小，默认为5
+	Window int `yaml:"window,omitempty"`
+}
+
+// validate 校验延迟阈值配置
+func (lt *LatencyThreshold) validate() error {
+	if lt.Warn <= 0 && lt.Critical <= 0 {
+		return fmt.Errorf("延迟阈值至少需要配置warn或critical")
+	}
+	if lt.Warn > 0 && lt.Critical > 0 && lt.Critical < lt.Warn {
+		return fmt.Errorf("延迟阈值critical不能小于warn")
+	}
+	if lt.Window < 0 {
+		return fmt.Errorf("延迟阈值window不能为负数")
+	}
+	return nil
+}
+
+// window 返回滑动窗口大小
+func (lt *LatencyThreshold) window() int {
+	if lt.Window > 0 {
+		return lt.Window
+	}
+	return defaultLatencyWindow
+}
+
+// level 根据平均延迟返回告警级别，未超过阈值时为空
+func (lt *LatencyThreshold) level(avg time.Duration) string {
+	switch {
+	case lt.Critical > 0 && avg >= lt.Critical:
+		return latencyLevelCritical
+	case lt.Warn > 0 && avg >= lt.Warn:
+		return latencyLevelWarn
+	}
+	return ""
+}
+
+// threshold 返回告警级别对应的阈值
+func (lt *LatencyThreshold) threshold(level string) time.Duration {
+	if level == latencyLevelCritical {
+		return lt.Critical
+	}
+	return lt.Warn
+}
+
+// applyLatency 记录成功检查的延迟并根据滑动窗口平均值更新告警级别，
+// 超过阈值时将在线状态调整为降级，需持有锁调用
+func applyLatency(service *Service, status ServiceStatus, duration time.Duration) ServiceStatus {
+	lt := service.Latency
+	if lt == nil || status != StatusOnline {
+		service.latencies = nil
+		service.LatencyLevel = ""
+		return status
+	}
+
+	service.latencies = append(service.latencies, duration)
+	if n := lt.window(); len(service.latencies) > n {
+		service.latencies = service.latencies[len(service.latencies)-n:]
+	}
+	if len(service.latencies) < lt.window() {
+		return status
+	}
+	var sum time.Duration
+	for _, d := range service.latencies {
+		sum += d
+	}
+	service.latencyAvg = sum / time.Duration(len(service.latencies))
+	service.LatencyLevel = lt.level(service.latencyAvg)
+	if service.LatencyLevel != "" {
+		return StatusDegraded
+	}
+	return status
+}
+
+// latencyReason 生成延迟告警的原因说明
+func latencyReason(service *Service) string {
+	avg := service.latencyAvg.Round(time.Millisecond)
+	if service.LatencyLevel == "" {
+		return fmt.Sprintf("最近 %d 次检查的平均延迟 %s 已恢复正常", service.Latency.window(), avg)
+	}
+	return fmt.Sprintf("最近 %d 次检查的平均延迟 %s 超过阈值 %s", service.Latency.window(), avg, service.Latency.threshold(service.LatencyLevel))
+}
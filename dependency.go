@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateDependencies 校验依赖关系：依赖的服务必须存在且不能存在循环依赖
+func validateDependencies(services []*Service) error {
+	byName := make(map[string]*Service, len(services))
+	for _, service := range services {
+		byName[service.Name] = service
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(services))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("存在循环依赖: %v", append(path, name))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range byName[name].DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return fmt.Errorf("服务 '%s' 依赖的服务 '%s' 不存在", name, dep)
+			}
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+
+	for _, service := range services {
+		if err := visit(service.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isDown 判断服务作为依赖时是否不可用
+func isDown(status ServiceStatus) bool {
+	return status != StatusOnline && status != StatusDegraded
+}
+
+// applyDependencies 根据依赖关系级联计算服务状态：服务自身检查失败且有依赖不可用时，
+// 标记为受影响而不是离线，需持有锁调用
+func (sm *ServiceManager) applyDependencies() {
+	byName := make(map[string]*Service, len(sm.services))
+	for _, service := range sm.services {
+		byName[service.Name] = service
+	}
+
+	// effective 记录每个服务考虑依赖后的状态
+	effective := make(map[string]ServiceStatus, len(sm.services))
+	var resolve func(service *Service) ServiceStatus
+	resolve = func(service *Service) ServiceStatus {
+		if status, ok := effective[service.Name]; ok {
+			return status
+		}
+		// 先写入自身状态，防止异常情况下的循环依赖导致无限递归
+		status := service.checkStatus
+		effective[service.Name] = status
+
+		var impactedBy []string
+		for _, name := range service.DependsOn {
+			dep, ok := byName[name]
+			if !ok {
+				continue
+			}
+			if isDown(resolve(dep)) {
+				impactedBy = append(impactedBy, name)
+			}
+		}
+		service.ImpactedBy = nil
+		if status == StatusOffline && len(impactedBy) > 0 {
+			status = StatusImpacted
+			service.ImpactedBy = impactedBy
+		}
+		effective[service.Name] = status
+		return status
+	}
+
+	for _, service := range sm.services {
+		status := resolve(service)
+		if status != service.Status {
+			service.Status = status
+			sm.touch()
+		}
+	}
+}
+
+// DependencyGraph 服务依赖关系图
+type DependencyGraph struct {
+	// Nodes 服务节点
+	Nodes []DependencyNode `json:"nodes"`
+	// Edges 依赖关系，From依赖To
+	Edges []DependencyEdge `json:"edges"`
+}
+
+// DependencyNode 依赖关系图中的服务节点
+type DependencyNode struct {
+	// Name 服务名称
+	Name string `json:"name"`
+	// Status 服务状态
+	Status ServiceStatus `json:"status"`
+	// ImpactedBy 导致该服务受影响的依赖
+	ImpactedBy []string `json:"impacted_by,omitempty"`
+}
+
+// DependencyEdge 依赖关系图中的边
+type DependencyEdge struct {
+	// From 依赖方
+	From string `json:"from"`
+	// To 被依赖方
+	To string `json:"to"`
+}
+
+// DependencyGraph 返回当前的服务依赖关系图，authorized为false时不包含隐藏与停用的服务
+func (sm *ServiceManager) DependencyGraph(authorized bool) DependencyGraph {
+	sm.lock.RLock()
+	defer sm.lock.RUnlock()
+
+	graph := DependencyGraph{
+		Nodes: make([]DependencyNode, 0, len(sm.services)),
+		Edges: make([]DependencyEdge, 0),
+	}
+	shown := make(map[string]bool, len(sm.services))
+	for _, service := range sm.services {
+		shown[service.Name] = visible(service, authorized)
+	}
+	for _, service := range sm.services {
+		if !shown[service.Name] {
+			continue
+		}
+		graph.Nodes = append(graph.Nodes, DependencyNode{
+			Name:       service.Name,
+			Status:     service.Status,
+			ImpactedBy: service.ImpactedBy,
+		})
+		for _, dep := range service.DependsOn {
+			if !shown[dep] {
+				continue
+			}
+			graph.Edges = append(graph.Edges, DependencyEdge{From: service.Name, To: dep})
+		}
+	}
+	return graph
+}
+
+// apiDependenciesHandler 返回服务依赖关系图
+func apiDependenciesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, serviceManager.DependencyGraph(canViewPrivate(c)))
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// detailEvents 服务详情中的最近事件数
+	detailEvents = 20
+	// latencyChartWidth 延迟曲线的宽度
+	latencyChartWidth = 600
+	// latencyChartHeight 延迟曲线的高度
+	latencyChartHeight = 120
+)
+
+// ServiceDetail 单个服务的完整信息
+type ServiceDetail struct {
+	// Service 当前状态
+	Service *Service `json:"service"`
+	// Checker 检查器配置摘要
+	Checker CheckerSummary `json:"checker"`
+	// Events 最近的状态变化事件，按时间倒序
+	Events []Event `json:"events"`
+	// UptimePercent 最近90天的可用率，没有数据时为nil
+	UptimePercent *float64 `json:"uptime_percent"`
+	// Uptime 最近90天按天汇总的可用率
+	Uptime []UptimeBucket `json:"uptime"`
+	// Latency 最近24小时按5分钟汇总的延迟
+	Latency []LatencyBucket `json:"latency"`
+	// SLO 当前周期的SLO与错误预算，未配置SLO时为nil
+	SLO *SLOReport `json:"slo,omitempty"`
+}
+
+// CheckerSummary 检查器配置摘要
+type CheckerSummary struct {
+	// Type 检查器类型
+	Type string `json:"type"`
+	// Interval 服务单独配置的检查间隔，为空时使用全局间隔
+	Interval string `json:"interval,omitempty"`
+	// Timeout 单次检查的总时限，为空时只受refresh_timeout限制
+	Timeout string `json:"timeout,omitempty"`
+	// Config 检查器配置（YAML），敏感字段已隐藏，仅对viewer及以上角色返回
+	Config string `json:"config,omitempty"`
+}
+
+// buildServiceDetail 汇总服务的当前状态、检查配置、最近事件、可用率历史与延迟曲线，
+// authorized为false时不包含检查器配置
+func buildServiceDetail(service *Service, authorized bool, now time.Time) (*ServiceDetail, error) {
+	service.setAge(now)
+	detail := &ServiceDetail{
+		Service: service,
+		Checker: CheckerSummary{Type: service.CheckerType},
+	}
+	if service.Interval > 0 {
+		detail.Checker.Interval = service.Interval.String()
+	}
+	if service.Timeout > 0 {
+		detail.Checker.Timeout = service.Timeout.String()
+	}
+	if authorized && service.Checker != nil {
+		lines := yamlLines(service.Checker)
+		for i, line := range lines {
+			lines[i] = maskSecret(line)
+		}
+		if len(lines) > 0 {
+			detail.Checker.Config = strings.Join(lines, "\n") + "\n"
+		}
+	}
+
+	detail.Events, _ = eventLog.Query(EventQuery{
+		Limit: detailEvents,
+		Match: func(e Event) bool { return e.Service == service.Name },
+	})
+
+	history := serviceManager.History()
+	start, step, count, _ := uptimeRange("day", defaultUptimeDays, now)
+	rollups, err := history.QueryRollups(service.Name, start)
+	if err != nil {
+		return nil, err
+	}
+	detail.Uptime = aggregateUptime(rollups, start, step, count)
+	detail.UptimePercent = overallUptime(detail.Uptime)
+
+	count = int(defaultLatencyRange / defaultLatencyStep)
+	end := now.Truncate(defaultLatencyStep).Add(defaultLatencyStep)
+	start = end.Add(-time.Duration(count) * defaultLatencyStep)
+	results, err := history.Query(service.Name, start, 0)
+	if err != nil {
+		return nil, err
+	}
+	detail.Latency = aggregateLatency(results, start, defaultLatencyStep, count)
+
+	if service.SLO != nil {
+		if detail.SLO, err = buildSLOReport(service.Name, service.SLO, now); err != nil {
+			return nil, err
+		}
+	}
+	return detail, nil
+}
+
+// latencyChart 将延迟数据绘制为SVG折线的点坐标，返回坐标与纵轴的最大值，没有数据时坐标为空
+func latencyChart(buckets []LatencyBucket) (string, float64) {
+	maxMS := 0.0
+	for _, b := range buckets {
+		if b.Avg != nil && *b.Avg > maxMS {
+			maxMS = *b.Avg
+		}
+	}
+	if maxMS == 0 || len(buckets) < 2 {
+		return "", 0
+	}
+	var sb strings.Builder
+	for i, b := range buckets {
+		if b.Avg == nil {
+			continue
+		}
+		x := float64(i) * latencyChartWidth / float64(len(buckets)-1)
+		// 顶部留出10%的空白
+		y := latencyChartHeight - *b.Avg/maxMS*latencyChartHeight*0.9
+		fmt.Fprintf(&sb, "%.1f,%.1f ", x, y)
+	}
+	return strings.TrimSpace(sb.String()), maxMS
+}
+
+// servicePageData 服务详情页的模板数据
+type servicePageData struct {
+	// Title 页面标题
+	Title string
+	// Theme 页面主题，已补充默认值
+	Theme ThemeConfig
+	// Locale 页面语言
+	Locale string
+	// Detail 服务详情
+	Detail *ServiceDetail
+	// ChartPoints 延迟曲线的点坐标，没有数据时为空
+	ChartPoints string
+	// ChartMax 延迟曲线纵轴的最大值（毫秒）
+	ChartMax float64
+	// ChartWidth 延迟曲线的宽度
+	ChartWidth int
+	// ChartHeight 延迟曲线的高度
+	ChartHeight int
+}
+
+// serviceDetail 获取请求可见的服务详情，失败时写入错误响应并返回nil
+func serviceDetail(c *gin.Context) *ServiceDetail {
+	service := publicService(c, c.Param("name"))
+	if service == nil {
+		apiError(c, http.StatusNotFound, "error.service_not_found")
+		return nil
+	}
+	authorized := canViewPrivate(c)
+	detail, err := buildServiceDetail(service, authorized, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil
+	}
+	detail.Events = redactEvents(detail.Events, authorized, requestLocale(c))
+	return detail
+}
+
+// servicePageHandler 服务详情页
+func servicePageHandler(c *gin.Context) {
+	detail := serviceDetail(c)
+	if detail == nil {
+		return
+	}
+	theme := currentTheme()
+	points, maxMS := latencyChart(detail.Latency)
+	c.Header("Vary", "Authorization")
+	c.HTML(http.StatusOK, "service.html", servicePageData{
+		Title:       detail.Service.Name + " - " + theme.Title,
+		Theme:       theme,
+		Locale:      requestLocale(c),
+		Detail:      detail,
+		ChartPoints: points,
+		ChartMax:    maxMS,
+		ChartWidth:  latencyChartWidth,
+		ChartHeight: latencyChartHeight,
+	})
+}
+
+// apiServiceHandler 返回单个服务的详情
+func apiServiceHandler(c *gin.Context) {
+	detail := serviceDetail(c)
+	if detail == nil {
+		return
+	}
+	c.Header("Vary", "Authorization")
+	c.JSON(http.StatusOK, detail)
+}
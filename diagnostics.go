@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultDiagnosticsTimeout 单个诊断命令的默认超时时间
+	defaultDiagnosticsTimeout = 30 * time.Second
+	// defaultDiagnosticsOutput 单个诊断命令保留的默认输出字节数
+	defaultDiagnosticsOutput = 64 << 10
+	// defaultDiagnosticsKeep 每个服务默认保留的诊断报告数
+	defaultDiagnosticsKeep = 10
+)
+
+// defaultDiagnosticsCommands 默认的诊断命令，{host}、{url}、{service}会替换为服务信息，
+// 缺少所需信息的命令会被跳过
+var defaultDiagnosticsCommands = []string{
+	"dig +time=2 +tries=1 {host}",
+	"traceroute -w 2 -q 1 -m 20 {host}",
+	"curl -sS -v -o /dev/null --max-time 10 {url}",
+}
+
+// DiagnosticsConfig 服务进入离线状态时自动执行的诊断命令配置
+type DiagnosticsConfig struct {
+	// Enabled 是否启用
+	Enabled bool `yaml:"enabled"`
+	// Commands 诊断命令，按空白分割参数后直接执行（不经过shell），为空时使用默认命令
+	Commands []string `yaml:"commands,omitempty"`
+	// Timeout 单个命令的超时时间，默认30s
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// MaxOutput 单个命令保留的最大输出字节数，默认64KB
+	MaxOutput int `yaml:"max_output,omitempty"`
+}
+
+// DiagnosticStep 单个诊断命令的执行结果
+type DiagnosticStep struct {
+	// Command 执行的命令
+	Command string `json:"command"`
+	// Output 标准输出与标准错误
+	Output string `json:"output"`
+	// Error 执行错误，命令退出码非0时也会记录
+	Error string `json:"error,omitempty"`
+	// DurationMS 执行耗时（毫秒）
+	DurationMS float64 `json:"duration_ms"`
+	// Truncated 输出是否被截断
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// DiagnosticReport 一次离线时采集的诊断报告
+type DiagnosticReport struct {
+	// Service 服务名称
+	Service string `json:"service"`
+	// Time 服务进入离线状态的时间
+	Time time.Time `json:"time"`
+	// Reason 离线原因
+	Reason string `json:"reason,omitempty"`
+	// Steps 各命令的执行结果
+	Steps []DiagnosticStep `json:"steps"`
+}
+
+// Diagnostician 服务离线时执行诊断命令并保存报告
+type Diagnostician struct {
+	lock sync.Mutex
+	// global 全局诊断配置
+	global DiagnosticsConfig
+	// perService 各服务单独的诊断配置
+	perService map[string]DiagnosticsConfig
+	// running 正在执行诊断的服务
+	running map[string]bool
+	// reports 按服务名称分组的诊断报告，按时间正序
+	reports map[string][]DiagnosticReport
+	// path 报告文件路径，为空时仅保存在内存中
+	path string
+	// file 追加写入的报告文件
+	file *os.File
+}
+
+// diagnostics 全局诊断器
+var diagnostics = &Diagnostician{
+	perService: make(map[string]DiagnosticsConfig),
+	running:    make(map[string]bool),
+	reports:    make(map[string][]DiagnosticReport),
+}
+
+// Open 加载检查记录存储目录下已保存的诊断报告，并持续追加写入；存储路径为空时仅保存在内存中
+func (d *Diagnostician) Open(storage StorageConfig) error {
+	if storage.Path == "" {
+		return nil
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.path = strings.TrimSuffix(storage.Path, filepath.Ext(storage.Path)) + ".diagnostics.jsonl"
+
+	total := 0
+	err := readLines(d.path, func(line []byte) error {
+		var report DiagnosticReport
+		if err := json.Unmarshal(line, &report); err != nil {
+			return err
+		}
+		d.add(report)
+		total++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 只保留每个服务最近的报告，超出时重写文件
+	kept := make([]interface{}, 0)
+	for _, list := range d.reports {
+		for _, report := range list {
+			kept = append(kept, report)
+		}
+	}
+	if len(kept) < total {
+		d.file, err = rewriteFile(nil, d.path, kept)
+		return err
+	}
+	d.file, err = openAppend(d.path)
+	return err
+}
+
+// add 将报告加入内存，超出数量时丢弃最旧的报告，需持有锁调用
+func (d *Diagnostician) add(report DiagnosticReport) {
+	list := append(d.reports[report.Service], report)
+	if len(list) > defaultDiagnosticsKeep {
+		list = append(list[:0:0], list[len(list)-defaultDiagnosticsKeep:]...)
+	}
+	d.reports[report.Service] = list
+}
+
+// Update 根据配置更新诊断规则
+func (d *Diagnostician) Update(cfg *Config) {
+	perService := make(map[string]DiagnosticsConfig)
+	for _, sc := range cfg.Services {
+		if sc.Diagnostics != nil {
+			perService[sc.Name] = *sc.Diagnostics
+		}
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.global = cfg.Diagnostics
+	d.perService = perService
+}
+
+// OnTransition 状态变化监听器：服务进入离线状态时在后台执行诊断
+func (d *Diagnostician) OnTransition(t Transition) {
+	if t.Kind != "status_change" || t.To != StatusOffline {
+		return
+	}
+	d.lock.Lock()
+	cfg, ok := d.perService[t.Service]
+	if !ok {
+		cfg = d.global
+	}
+	if !cfg.Enabled || d.running[t.Service] {
+		d.lock.Unlock()
+		return
+	}
+	d.running[t.Service] = true
+	d.lock.Unlock()
+
+	var target string
+	if service := serviceManager.GetService(t.Service); service != nil {
+		target = service.URL
+	}
+	go func() {
+		report := runDiagnostics(cfg, t, target)
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		delete(d.running, t.Service)
+		d.add(report)
+		if err := writeLine(d.file, report); err != nil {
+			slog.Error("保存诊断报告失败", "service", t.Service, "error", err)
+		}
+		slog.Info("诊断报告已生成", "service", t.Service, "steps", len(report.Steps))
+	}()
+}
+
+// Reports 返回服务的诊断报告，按时间倒序
+func (d *Diagnostician) Reports(service string) []DiagnosticReport {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	list := d.reports[service]
+	out := make([]DiagnosticReport, 0, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		out = append(out, list[i])
+	}
+	return out
+}
+
+// runDiagnostics 依次执行诊断命令，target为服务URL或主机名
+func runDiagnostics(cfg DiagnosticsConfig, t Transition, target string) DiagnosticReport {
+	commands := cfg.Commands
+	if len(commands) == 0 {
+		commands = defaultDiagnosticsCommands
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultDiagnosticsTimeout
+	}
+	maxOutput := cfg.MaxOutput
+	if maxOutput <= 0 {
+		maxOutput = defaultDiagnosticsOutput
+	}
+
+	host, rawURL := diagnosticTarget(target)
+	vars := strings.NewReplacer("{host}", host, "{url}", rawURL, "{service}", t.Service)
+	report := DiagnosticReport{Service: t.Service, Time: t.Time, Reason: t.Reason, Steps: make([]DiagnosticStep, 0, len(commands))}
+	for _, command := range commands {
+		if (strings.Contains(command, "{host}") && host == "") || (strings.Contains(command, "{url}") && rawURL == "") {
+			continue
+		}
+		args := strings.Fields(command)
+		if len(args) == 0 {
+			continue
+		}
+		injected := false
+		for i := range args {
+			replaced := vars.Replace(args[i])
+			// 服务信息来自配置与服务发现的标签，替换后以-开头会被命令解析为选项
+			if replaced != args[i] && strings.HasPrefix(replaced, "-") && !strings.HasPrefix(args[i], "-") {
+				injected = true
+			}
+			args[i] = replaced
+		}
+		if injected {
+			slog.Warn("诊断命令的参数以-开头，已跳过", "service", t.Service, "command", command)
+			continue
+		}
+		report.Steps = append(report.Steps, runDiagnosticCommand(args, timeout, maxOutput))
+	}
+	return report
+}
+
+// diagnosticTarget 从服务URL中解析主机名与URL，target不含协议时视为主机名；
+// 主机名无效（如以-开头）时返回空，使用它的命令会被跳过
+func diagnosticTarget(target string) (host, rawURL string) {
+	if target == "" {
+		return "", ""
+	}
+	if u, err := url.Parse(target); err == nil && u.Scheme != "" && u.Host != "" {
+		if !validDiagnosticHost(u.Hostname()) {
+			return "", ""
+		}
+		return u.Hostname(), target
+	}
+	if !validDiagnosticHost(target) {
+		return "", ""
+	}
+	return target, ""
+}
+
+// validDiagnosticHost 判断是否为IP地址或由字母、数字、-与_组成且各段不以-开头的主机名
+func validDiagnosticHost(host string) bool {
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	if host == "" || len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// runDiagnosticCommand 执行单个诊断命令，合并标准输出与标准错误
+func runDiagnosticCommand(args []string, timeout time.Duration, maxOutput int) DiagnosticStep {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	start := time.Now()
+	err := cmd.Run()
+	step := DiagnosticStep{
+		Command:    strings.Join(args, " "),
+		DurationMS: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+	if ctx.Err() != nil {
+		step.Error = fmt.Sprintf("执行超时（%s）", timeout)
+	} else if err != nil {
+		step.Error = err.Error()
+	}
+	data := out.Bytes()
+	if len(data) > maxOutput {
+		data = data[:maxOutput]
+		step.Truncated = true
+	}
+	step.Output = string(data)
+	return step
+}
+
+// apiServiceDiagnosticsHandler 返回服务最近的诊断报告
+func apiServiceDiagnosticsHandler(c *gin.Context) {
+	name := c.Param("name")
+	if serviceManager.GetService(name) == nil {
+		apiError(c, http.StatusNotFound, "error.service_not_found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"service": name,
+		"reports": diagnostics.Reports(name),
+	})
+}
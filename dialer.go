@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// contextDialFunc 将拨号函数适配为proxy.Dialer与proxy.ContextDialer
+type contextDialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Dial 实现proxy.Dialer接口
+func (f contextDialFunc) Dial(network, addr string) (net.Conn, error) {
+	return f(context.Background(), network, addr)
+}
+
+// DialContext 实现proxy.ContextDialer接口
+func (f contextDialFunc) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, network, addr)
+}
+
+// streamDialer 返回sftp、amqp、kafka、ntp与dns等非HTTP检查使用的拨号函数。
+// 代理只使用配置中明确设置的地址，不读取HTTP_PROXY等环境变量：socks5代理直接转发，
+// http与https代理通过CONNECT建立隧道；UDP无法经过这些代理，始终直接连接。
+// 使用代理时resolver与ip_family作用于目标地址：socks5或设置了二者之一时在本地解析，否则由代理解析
+func (tc TransportConfig) streamDialer(timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	direct := tc.dialContext(timeout)
+	if tc.Proxy == "" || tc.Proxy == "direct" {
+		return direct
+	}
+	proxyURL, err := url.Parse(tc.Proxy)
+	if err != nil {
+		return direct
+	}
+	// 连接代理本身不限制地址族
+	toProxy := TransportConfig{Resolver: tc.Resolver}.dialContext(timeout)
+	localResolve := proxyURL.Scheme == "socks5" || tc.Resolver != "" || tc.IPFamily != ""
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if !strings.HasPrefix(network, "tcp") {
+			return direct(ctx, network, addr)
+		}
+		if localResolve {
+			resolved, err := tc.resolveAddr(ctx, addr, timeout)
+			if err != nil {
+				return nil, err
+			}
+			addr = resolved
+		}
+		switch proxyURL.Scheme {
+		case "socks5", "socks5h":
+			var auth *proxy.Auth
+			if proxyURL.User != nil {
+				password, _ := proxyURL.User.Password()
+				auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+			}
+			d, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, contextDialFunc(toProxy))
+			if err != nil {
+				return nil, err
+			}
+			conn, err := d.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return nil, fmt.Errorf("通过代理 %s 连接失败: %v", proxyURL.Host, err)
+			}
+			return conn, nil
+		}
+		return dialConnect(ctx, toProxy, proxyURL, addr)
+	}
+}
+
+// resolveAddr 按配置的DNS服务器与地址族将host:port中的域名解析为IP地址
+func (tc TransportConfig) resolveAddr(ctx context.Context, addr string, timeout time.Duration) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return addr, err
+	}
+	resolver := tc.resolver(timeout)
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	network := map[string]string{"ipv4": "ip4", "ipv6": "ip6"}[tc.IPFamily]
+	if network == "" {
+		network = "ip"
+	}
+	ips, err := resolver.LookupIP(ctx, network, host)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(ips[0].String(), port), nil
+}
+
+// dialConnect 通过http或https代理的CONNECT方法建立到addr的TCP隧道
+func dialConnect(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+	conn, err := dial(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("连接代理 %s 失败: %v", proxyURL.Host, err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("通过代理 %s 连接失败: %v", proxyURL.Host, err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("通过代理 %s 连接失败: %v", proxyURL.Host, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("代理 %s 拒绝连接: %s", proxyURL.Host, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	// SSH等服务端先发送数据，已读入缓冲区的部分需交给调用方
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 先读取缓冲区中剩余数据的连接
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// Read 实现io.Reader接口
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultDiscoveryInterval 服务发现默认的刷新间隔
+	defaultDiscoveryInterval = 30 * time.Second
+	// defaultDiscoveryRemoveAfter 服务从来源中消失后默认保留的时间
+	defaultDiscoveryRemoveAfter = 10 * time.Minute
+)
+
+// DiscoveredService 服务发现来源返回的服务及其状态
+type DiscoveredService struct {
+	// Name 服务名称
+	Name string
+	// Description 服务描述
+	Description string
+	// URL 服务URL
+	URL string
+	// Status 来源报告的状态
+	Status ServiceStatus
+	// Error 状态说明，状态异常时不为空
+	Error string
+	// Checker 服务的检查器，为空时使用来源报告的状态
+	Checker StatusChecker
+}
+
+// DiscoverySource 服务发现来源接口
+type DiscoverySource interface {
+	// Discover 返回来源中当前的全部服务
+	Discover(ctx context.Context) ([]DiscoveredService, error)
+}
+
+// DiscoveryConfig 服务发现配置，Type决定来源种类，其余字段由对应来源解析
+type DiscoveryConfig struct {
+	// Name 来源名称，用于区分不同来源的服务
+	Name string `yaml:"name"`
+	// Type 来源类型
+	Type string `yaml:"type"`
+	// Interval 刷新间隔，默认30s
+	Interval time.Duration `yaml:"interval"`
+	// RemoveAfter 服务从来源中消失后标记为离线并保留的时间，默认10m
+	RemoveAfter time.Duration `yaml:"remove_after"`
+	// Order 发现的服务的显示顺序
+	Order int `yaml:"order"`
+	// NamePrefix 服务名称前缀
+	NamePrefix string `yaml:"name_prefix"`
+	// node 原始配置节点
+	node *yaml.Node
+}
+
+// discoveryFactories 服务发现来源类型注册表
+var discoveryFactories = map[string]func() DiscoverySource{
+	"consul":  func() DiscoverySource { return &ConsulSource{} },
+	"etcd":    func() DiscoverySource { return &EtcdSource{} },
+	"docker":  func() DiscoverySource { return &DockerSource{} },
+	"systemd": func() DiscoverySource { return &SystemdSource{} },
+}
+
+// UnmarshalYAML 实现yaml.Unmarshaler接口，保留原始节点供来源解析
+func (dc *DiscoveryConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain DiscoveryConfig
+	var head plain
+	if err := node.Decode(&head); err != nil {
+		return err
+	}
+	*dc = DiscoveryConfig(head)
+	dc.node = node
+	return nil
+}
+
+// MarshalYAML 实现yaml.Marshaler接口，原样输出来源配置
+func (dc DiscoveryConfig) MarshalYAML() (interface{}, error) {
+	if dc.node != nil {
+		return dc.node, nil
+	}
+	type plain DiscoveryConfig
+	return plain(dc), nil
+}
+
+// Build 根据配置创建服务发现来源
+func (dc *DiscoveryConfig) Build() (DiscoverySource, error) {
+	factory, ok := discoveryFactories[dc.Type]
+	if !ok {
+		return nil, fmt.Errorf("服务发现 '%s': 未知的来源类型 '%s'", dc.Name, dc.Type)
+	}
+	source := factory()
+	if dc.node != nil {
+		if err := dc.node.Decode(source); err != nil {
+			return nil, fmt.Errorf("服务发现 '%s': 解析配置失败: %v", dc.Name, err)
+		}
+	}
+	return source, nil
+}
+
+// discoveredChecker 返回来源最近一次报告的状态
+type discoveredChecker struct {
+	status ServiceStatus
+	err    error
+}
+
+// CheckStatus 实现StatusChecker接口
+func (d *discoveredChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	return d.status, d.err
+}
+
+// discoveryRunner 定期刷新一个服务发现来源
+type discoveryRunner struct {
+	cfg     DiscoveryConfig
+	source  DiscoverySource
+	manager *ServiceManager
+	// known 已发现的服务及最后一次出现的时间
+	known    map[string]DiscoveredService
+	lastSeen map[string]time.Time
+}
+
+// StartDiscovery 根据配置启动全部服务发现来源，修改后需重启生效
+func StartDiscovery(configs []DiscoveryConfig, manager *ServiceManager) error {
+	seen := make(map[string]bool, len(configs))
+	runners := make([]*discoveryRunner, 0, len(configs))
+	for i := range configs {
+		dc := configs[i]
+		if dc.Name == "" {
+			return fmt.Errorf("第 %d 个服务发现来源缺少名称", i+1)
+		}
+		if seen[dc.Name] {
+			return fmt.Errorf("服务发现来源名称 '%s' 重复", dc.Name)
+		}
+		seen[dc.Name] = true
+		source, err := dc.Build()
+		if err != nil {
+			return err
+		}
+		if dc.Interval <= 0 {
+			dc.Interval = defaultDiscoveryInterval
+		}
+		if dc.RemoveAfter <= 0 {
+			dc.RemoveAfter = defaultDiscoveryRemoveAfter
+		}
+		runners = append(runners, &discoveryRunner{
+			cfg:      dc,
+			source:   source,
+			manager:  manager,
+			known:    make(map[string]DiscoveredService),
+			lastSeen: make(map[string]time.Time),
+		})
+	}
+	for _, runner := range runners {
+		go runner.run()
+	}
+	return nil
+}
+
+// run 定期刷新来源
+func (dr *discoveryRunner) run() {
+	dr.refresh()
+	ticker := time.NewTicker(dr.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-dr.manager.Context().Done():
+			return
+		case <-ticker.C:
+			dr.refresh()
+		}
+	}
+}
+
+// refresh 读取来源并同步服务列表与状态
+func (dr *discoveryRunner) refresh() {
+	ctx, cancel := context.WithTimeout(dr.manager.Context(), dr.cfg.Interval)
+	defer cancel()
+
+	now := time.Now()
+	found, err := dr.source.Discover(ctx)
+	if err != nil {
+		// 来源不可用时保留已发现的服务并标记为离线
+		slog.Error("服务发现失败", "source", dr.cfg.Name, "error", err)
+		for name, ds := range dr.known {
+			ds.Status, ds.Error, ds.Checker = StatusOffline, fmt.Sprintf("服务发现失败: %v", err), nil
+			dr.known[name] = ds
+		}
+	} else {
+		current := make(map[string]bool, len(found))
+		for _, ds := range found {
+			ds.Name = dr.cfg.NamePrefix + ds.Name
+			current[ds.Name] = true
+			if _, ok := dr.known[ds.Name]; !ok {
+				slog.Info("发现新服务", "source", dr.cfg.Name, "service", ds.Name)
+			}
+			dr.known[ds.Name] = ds
+			dr.lastSeen[ds.Name] = now
+		}
+		for name, ds := range dr.known {
+			if current[name] {
+				continue
+			}
+			if now.Sub(dr.lastSeen[name]) > dr.cfg.RemoveAfter {
+				slog.Info("服务已移除", "source", dr.cfg.Name, "service", name)
+				delete(dr.known, name)
+				delete(dr.lastSeen, name)
+				continue
+			}
+			ds.Status, ds.Error, ds.Checker = StatusOffline, "服务已从来源中消失", nil
+			dr.known[name] = ds
+		}
+	}
+
+	services := make([]*Service, 0, len(dr.known))
+	for _, ds := range dr.known {
+		checker := ds.Checker
+		if checker == nil {
+			var checkErr error
+			if ds.Error != "" {
+				checkErr = errors.New(ds.Error)
+			}
+			checker = &discoveredChecker{status: ds.Status, err: checkErr}
+		}
+		services = append(services, &Service{
+			Name:        ds.Name,
+			Description: ds.Description,
+			URL:         ds.URL,
+			Order:       dr.cfg.Order,
+			Enabled:     true,
+			Status:      StatusOnline,
+			Checker:     checker,
+		})
+	}
+	sortServicesByName(services)
+	synced := dr.manager.SyncSource(dr.cfg.Name, services)
+	dr.manager.checkBatch(ctx, synced)
+}
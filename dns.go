@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// defaultDNSTimeout DNS查询的默认超时时间
+	defaultDNSTimeout = 5 * time.Second
+	// maxDNSMessage DNS应答的最大长度
+	maxDNSMessage = 65535
+)
+
+// DNS查询使用的协议
+const (
+	dnsProtocolUDP = "udp"
+	dnsProtocolTCP = "tcp"
+	// dnsProtocolDoT DNS over TLS（RFC 7858）
+	dnsProtocolDoT = "dot"
+	// dnsProtocolDoH DNS over HTTPS（RFC 8484）
+	dnsProtocolDoH = "doh"
+)
+
+// dnsRecordTypes 支持查询的记录类型
+var dnsRecordTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NS":    dnsmessage.TypeNS,
+	"TXT":   dnsmessage.TypeTXT,
+}
+
+// dnsRCodeNames 常见应答码的名称
+var dnsRCodeNames = map[dnsmessage.RCode]string{
+	dnsmessage.RCodeFormatError:    "FORMERR",
+	dnsmessage.RCodeServerFailure:  "SERVFAIL",
+	dnsmessage.RCodeNameError:      "NXDOMAIN",
+	dnsmessage.RCodeNotImplemented: "NOTIMP",
+	dnsmessage.RCodeRefused:        "REFUSED",
+}
+
+// DNSChecker DNS解析检查器：向指定的DNS服务器查询记录，应答码不为NOERROR、没有对应类型的记录
+// 或缺少expect中的值时为离线。支持UDP、TCP以及加密的DoT与DoH，加密连接会校验服务器证书
+type DNSChecker struct {
+	// Server DNS服务器：udp、tcp与dot为地址，如1.1.1.1或dns.google:853，未指定端口时分别使用53与853；
+	// doh为查询地址，如https://cloudflare-dns.com/dns-query
+	Server string `yaml:"server"`
+	// Protocol 查询协议：udp（默认）、tcp、dot或doh
+	Protocol string `yaml:"protocol"`
+	// Name 查询的域名
+	Name string `yaml:"name"`
+	// RecordType 记录类型：A（默认）、AAAA、CNAME、MX、NS或TXT
+	RecordType string `yaml:"record_type"`
+	// Expect 应答中必须包含的值，如IP地址或CNAME目标，为空时只要求存在对应类型的记录
+	Expect []string `yaml:"expect"`
+	// TLSServerName dot校验证书使用的服务器名称，默认为server中的主机名；以IP地址连接时需填写证书中的域名
+	TLSServerName string `yaml:"tls_server_name"`
+	// Timeout 超时时间，默认5s
+	Timeout time.Duration `yaml:"timeout"`
+
+	checkTransport `yaml:",inline"`
+}
+
+// validate 校验DNS检查配置
+func (d *DNSChecker) validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("DNS检查需要配置name")
+	}
+	if _, ok := dnsRecordTypes[strings.ToUpper(d.recordType())]; !ok {
+		return fmt.Errorf("不支持的记录类型 '%s'，可选A、AAAA、CNAME、MX、NS或TXT", d.RecordType)
+	}
+	switch d.protocol() {
+	case dnsProtocolUDP:
+		if d.Server == "" {
+			return fmt.Errorf("DNS检查需要配置server")
+		}
+		return d.checkTransport.validateUDP("DNS检查的udp协议")
+	case dnsProtocolTCP, dnsProtocolDoT:
+		if d.Server == "" {
+			return fmt.Errorf("DNS检查需要配置server")
+		}
+	case dnsProtocolDoH:
+		u, err := url.Parse(d.Server)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("DoH的server需为https地址，如https://cloudflare-dns.com/dns-query")
+		}
+	default:
+		return fmt.Errorf("不支持的DNS协议 '%s'，可选udp、tcp、dot或doh", d.Protocol)
+	}
+	return d.checkTransport.validate()
+}
+
+// protocol 返回查询协议
+func (d *DNSChecker) protocol() string {
+	if d.Protocol == "" {
+		return dnsProtocolUDP
+	}
+	return strings.ToLower(d.Protocol)
+}
+
+// recordType 返回记录类型
+func (d *DNSChecker) recordType() string {
+	if d.RecordType == "" {
+		return "A"
+	}
+	return d.RecordType
+}
+
+// CheckStatus 实现StatusChecker接口
+func (d *DNSChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = defaultDNSTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	qtype := dnsRecordTypes[strings.ToUpper(d.recordType())]
+	fqdn := d.Name
+	if !strings.HasSuffix(fqdn, ".") {
+		fqdn += "."
+	}
+	name, err := dnsmessage.NewName(fqdn)
+	if err != nil {
+		return StatusOffline, fmt.Errorf("无效的域名 '%s': %v", d.Name, err)
+	}
+	resp, err := d.exchange(ctx, name, qtype, timeout)
+	if err != nil {
+		return StatusOffline, fmt.Errorf("查询DNS服务器 %s 失败: %v", d.Server, err)
+	}
+	if resp.RCode != dnsmessage.RCodeSuccess {
+		code, ok := dnsRCodeNames[resp.RCode]
+		if !ok {
+			code = resp.RCode.String()
+		}
+		return StatusOffline, fmt.Errorf("DNS应答错误: %s", code)
+	}
+
+	values := dnsAnswerValues(resp.Answers, qtype)
+	if len(values) == 0 {
+		return StatusOffline, fmt.Errorf("%s 没有%s记录", d.Name, strings.ToUpper(d.recordType()))
+	}
+	for _, want := range d.Expect {
+		if !containsDNSValue(values, want) {
+			return StatusOffline, fmt.Errorf("应答中缺少 %s，实际为 %s", want, strings.Join(values, ", "))
+		}
+	}
+	return StatusOnline, nil
+}
+
+// exchange 按配置的协议发送查询并解析应答；UDP应答被截断时改用TCP重新查询
+func (d *DNSChecker) exchange(ctx context.Context, name dnsmessage.Name, qtype dnsmessage.Type, timeout time.Duration) (*dnsmessage.Message, error) {
+	// DoH建议使用0作为查询ID以便缓存
+	var id uint16
+	if d.protocol() != dnsProtocolDoH {
+		id = uint16(rand.Uint32())
+	}
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []byte
+	dial := d.dialer(timeout)
+	switch d.protocol() {
+	case dnsProtocolUDP:
+		raw, err = d.exchangeUDP(ctx, dial, packed)
+	case dnsProtocolTCP:
+		raw, err = d.exchangeStream(ctx, dial, packed, false)
+	case dnsProtocolDoT:
+		raw, err = d.exchangeStream(ctx, dial, packed, true)
+	case dnsProtocolDoH:
+		raw, err = d.exchangeHTTPS(ctx, packed, timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var resp dnsmessage.Message
+	if err := resp.Unpack(raw); err != nil {
+		return nil, fmt.Errorf("解析应答失败: %v", err)
+	}
+	if resp.ID != id {
+		return nil, fmt.Errorf("应答ID不匹配")
+	}
+	if resp.Truncated && d.protocol() == dnsProtocolUDP {
+		raw, err = d.exchangeStream(ctx, dial, packed, false)
+		if err != nil {
+			return nil, err
+		}
+		if err := resp.Unpack(raw); err != nil {
+			return nil, fmt.Errorf("解析应答失败: %v", err)
+		}
+	}
+	return &resp, nil
+}
+
+// serverAddr 返回补充默认端口后的服务器地址
+func (d *DNSChecker) serverAddr(port string) string {
+	if _, _, err := net.SplitHostPort(d.Server); err == nil {
+		return d.Server
+	}
+	return net.JoinHostPort(strings.Trim(d.Server, "[]"), port)
+}
+
+// exchangeUDP 通过UDP发送查询
+func (d *DNSChecker) exchangeUDP(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), query []byte) ([]byte, error) {
+	conn, err := dial(ctx, "udp", d.serverAddr("53"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxDNSMessage)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// exchangeStream 通过TCP或TLS发送查询，消息前带两字节长度；TLS连接按系统根证书校验服务器证书
+func (d *DNSChecker) exchangeStream(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), query []byte, useTLS bool) ([]byte, error) {
+	addr := d.serverAddr("53")
+	if useTLS {
+		addr = d.serverAddr("853")
+	}
+	conn, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if useTLS {
+		serverName := d.TLSServerName
+		if serverName == "" {
+			serverName, _, _ = net.SplitHostPort(addr)
+		}
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// exchangeHTTPS 通过DoH的POST请求发送查询
+func (d *DNSChecker) exchangeHTTPS(ctx context.Context, query []byte, timeout time.Duration) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Server, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := d.client(timeout).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPStatusError{Code: resp.StatusCode}
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/dns-message") {
+		return nil, fmt.Errorf("响应类型不是application/dns-message: %s", ct)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxDNSMessage))
+}
+
+// dnsAnswerValues 返回应答中指定类型记录的值
+func dnsAnswerValues(answers []dnsmessage.Resource, qtype dnsmessage.Type) []string {
+	var values []string
+	for _, rr := range answers {
+		if rr.Header.Type != qtype {
+			continue
+		}
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			values = append(values, net.IP(body.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			values = append(values, net.IP(body.AAAA[:]).String())
+		case *dnsmessage.CNAMEResource:
+			values = append(values, body.CNAME.String())
+		case *dnsmessage.MXResource:
+			values = append(values, body.MX.String())
+		case *dnsmessage.NSResource:
+			values = append(values, body.NS.String())
+		case *dnsmessage.TXTResource:
+			values = append(values, strings.Join(body.TXT, ""))
+		}
+	}
+	return values
+}
+
+// containsDNSValue 判断应答中是否包含期望的值，域名忽略大小写与末尾的点，IP地址按解析后比较
+func containsDNSValue(values []string, want string) bool {
+	wantIP := net.ParseIP(want)
+	for _, v := range values {
+		if wantIP != nil {
+			if ip := net.ParseIP(v); ip != nil && ip.Equal(wantIP) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(strings.TrimSuffix(v, "."), strings.TrimSuffix(want, ".")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dockerIdleConnTimeout 访问Docker的空闲连接保留时间
+const dockerIdleConnTimeout = 90 * time.Second
+
+// DockerSource 根据容器标签自动发现服务，通过Docker Engine API访问。
+// 带有 <label_prefix>enable=true 标签的容器被注册为服务，可选标签：
+// <label_prefix>name、<label_prefix>url、<label_prefix>description
+type DockerSource struct {
+	// Host Docker地址，支持unix://和tcp://，默认unix:///var/run/docker.sock
+	Host string `yaml:"host"`
+	// LabelPrefix 标签前缀，默认status.
+	LabelPrefix string `yaml:"label_prefix"`
+
+	// once 保证HTTP客户端只创建一次，每次刷新复用同一连接池
+	once       sync.Once
+	httpClient *http.Client
+	base       string
+	err        error
+}
+
+// dockerContainer 容器列表接口返回的容器信息
+type dockerContainer struct {
+	Names  []string          `json:"Names"`
+	State  string            `json:"State"`
+	Status string            `json:"Status"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// client 返回访问Docker的HTTP客户端与基础地址，首次调用时创建
+func (ds *DockerSource) client() (*http.Client, string, error) {
+	ds.once.Do(func() {
+		ds.httpClient, ds.base, ds.err = ds.newClient()
+	})
+	return ds.httpClient, ds.base, ds.err
+}
+
+// newClient 根据Host创建访问Docker的HTTP客户端与基础地址
+func (ds *DockerSource) newClient() (*http.Client, string, error) {
+	host := ds.Host
+	if host == "" {
+		host = "unix:///var/run/docker.sock"
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, "", fmt.Errorf("无效的Docker地址 '%s': %v", host, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+			// 配置重载后旧的来源不再使用，空闲连接超时后关闭
+			IdleConnTimeout: dockerIdleConnTimeout,
+		}
+		return &http.Client{Transport: transport}, "http://docker", nil
+	case "tcp", "http":
+		return http.DefaultClient, "http://" + u.Host, nil
+	}
+	return nil, "", fmt.Errorf("不支持的Docker地址 '%s'", host)
+}
+
+// containerStatus 根据容器状态计算服务状态
+func containerStatus(c dockerContainer) (ServiceStatus, string) {
+	switch c.State {
+	case "running":
+		if strings.Contains(c.Status, "(unhealthy)") {
+			return StatusOffline, "容器健康检查失败: " + c.Status
+		}
+		return StatusOnline, ""
+	case "paused":
+		return StatusMaintenance, "容器已暂停"
+	case "restarting":
+		return StatusDegraded, "容器正在重启: " + c.Status
+	}
+	return StatusOffline, fmt.Sprintf("容器未运行: %s", c.Status)
+}
+
+// Discover 实现DiscoverySource接口，包含已停止的容器以便显示为离线
+func (ds *DockerSource) Discover(ctx context.Context) ([]DiscoveredService, error) {
+	prefix := ds.LabelPrefix
+	if prefix == "" {
+		prefix = "status."
+	}
+	client, base, err := ds.client()
+	if err != nil {
+		return nil, err
+	}
+
+	filters, _ := json.Marshal(map[string][]string{"label": {prefix + "enable=true"}})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/containers/json?all=1&filters="+url.QueryEscape(string(filters)), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求Docker失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Docker返回状态码 %d", resp.StatusCode)
+	}
+	var containers []dockerContainer
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("解析Docker响应失败: %v", err)
+	}
+
+	found := make([]DiscoveredService, 0, len(containers))
+	for _, c := range containers {
+		name := c.Labels[prefix+"name"]
+		if name == "" && len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		if name == "" {
+			continue
+		}
+		status, reason := containerStatus(c)
+		found = append(found, DiscoveredService{
+			Name:        name,
+			Description: c.Labels[prefix+"description"],
+			URL:         c.Labels[prefix+"url"],
+			Status:      status,
+			Error:       reason,
+		})
+	}
+	return found, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rdapBootstrapURL IANA维护的各顶级域名RDAP服务地址
+const rdapBootstrapURL = "https://data.iana.org/rdap/dns.json"
+
+// rdapBootstrap 缓存的RDAP引导数据，顶级域名到服务地址
+var rdapBootstrap struct {
+	sync.Mutex
+	servers map[string]string
+	fetched time.Time
+}
+
+// DomainChecker 通过RDAP查询域名到期时间，剩余天数少于warn_days时标记为降级，
+// 已过期时标记为离线。到期时间变化很少，查询结果会缓存cache时长
+type DomainChecker struct {
+	// Domain 要检查的域名
+	Domain string `yaml:"domain"`
+	// WarnDays 剩余天数少于该值时告警，默认30
+	WarnDays int `yaml:"warn_days"`
+	// RDAP RDAP服务地址，如https://rdap.verisign.com/com/v1/，为空时从IANA引导数据中查找
+	RDAP string `yaml:"rdap"`
+	// Cache 查询结果缓存时长，默认12h
+	Cache time.Duration `yaml:"cache"`
+	// Timeout 超时时间
+	Timeout time.Duration `yaml:"timeout"`
+
+	checkTransport `yaml:",inline"`
+
+	mu      sync.Mutex
+	expiry  time.Time
+	fetched time.Time
+}
+
+// CheckStatus 实现StatusChecker接口
+func (d *DomainChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d.Domain)), ".")
+	if domain == "" {
+		return StatusOffline, fmt.Errorf("域名不能为空")
+	}
+	expiry, err := d.lookup(ctx, domain)
+	if err != nil {
+		return StatusOffline, err
+	}
+
+	warnDays := d.WarnDays
+	if warnDays == 0 {
+		warnDays = 30
+	}
+	left := time.Until(expiry)
+	days := int(left.Hours() / 24)
+	date := formatTime(localeZH, expiry, "datetime")
+	switch {
+	case left <= 0:
+		return StatusOffline, fmt.Errorf("域名 '%s' 已于 %s 过期", domain, date)
+	case days < warnDays:
+		return StatusDegraded, fmt.Errorf("域名 '%s' 将于 %s 过期，剩余 %d 天", domain, date, days)
+	}
+	return StatusOnline, nil
+}
+
+// lookup 返回域名到期时间，缓存有效时直接使用缓存；查询失败但有旧结果时继续使用旧结果
+func (d *DomainChecker) lookup(ctx context.Context, domain string) (time.Time, error) {
+	cache := d.Cache
+	if cache == 0 {
+		cache = 12 * time.Hour
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.fetched.IsZero() && time.Since(d.fetched) < cache {
+		return d.expiry, nil
+	}
+
+	expiry, err := d.query(ctx, domain)
+	if err != nil {
+		if !d.expiry.IsZero() {
+			return d.expiry, nil
+		}
+		return time.Time{}, err
+	}
+	d.expiry = expiry
+	d.fetched = time.Now()
+	return expiry, nil
+}
+
+// query 向RDAP服务查询域名的到期时间
+func (d *DomainChecker) query(ctx context.Context, domain string) (time.Time, error) {
+	client := d.client(d.Timeout)
+	server := d.RDAP
+	if server == "" {
+		var err error
+		if server, err = rdapServer(ctx, client, domain); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	var result struct {
+		Events []struct {
+			Action string    `json:"eventAction"`
+			Date   time.Time `json:"eventDate"`
+		} `json:"events"`
+	}
+	url := strings.TrimSuffix(server, "/") + "/domain/" + domain
+	if err := getJSON(ctx, client, url, "application/rdap+json", &result); err != nil {
+		return time.Time{}, fmt.Errorf("查询域名 '%s' 失败: %v", domain, err)
+	}
+	for _, event := range result.Events {
+		if event.Action == "expiration" {
+			return event.Date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("RDAP未返回域名 '%s' 的到期时间", domain)
+}
+
+// rdapServer 从IANA引导数据中查找域名所属顶级域名的RDAP服务地址，引导数据缓存一天
+func rdapServer(ctx context.Context, client *http.Client, domain string) (string, error) {
+	rdapBootstrap.Lock()
+	defer rdapBootstrap.Unlock()
+	if rdapBootstrap.servers == nil || time.Since(rdapBootstrap.fetched) > 24*time.Hour {
+		var data struct {
+			Services [][][]string `json:"services"`
+		}
+		if err := getJSON(ctx, client, rdapBootstrapURL, "application/json", &data); err != nil {
+			if rdapBootstrap.servers == nil {
+				return "", fmt.Errorf("获取RDAP引导数据失败: %v", err)
+			}
+		} else {
+			servers := make(map[string]string)
+			for _, entry := range data.Services {
+				if len(entry) < 2 || len(entry[1]) == 0 {
+					continue
+				}
+				for _, tld := range entry[0] {
+					servers[strings.ToLower(tld)] = entry[1][0]
+				}
+			}
+			rdapBootstrap.servers = servers
+			rdapBootstrap.fetched = time.Now()
+		}
+	}
+
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+	server, ok := rdapBootstrap.servers[tld]
+	if !ok {
+		return "", fmt.Errorf("顶级域名 '%s' 没有RDAP服务，请配置rdap", tld)
+	}
+	return server, nil
+}
+
+// getJSON 请求URL并解析JSON响应
+func getJSON(ctx context.Context, client *http.Client, url, accept string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &HTTPStatusError{Code: resp.StatusCode}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultDownloadMaxBytes 下载测速默认最多下载的字节数
+const defaultDownloadMaxBytes = 64 << 20
+
+// DownloadCheck 下载测速模式：完整下载响应内容，校验内容长度、SHA-256与最低下载速度，
+// 用于监控CDN等静态资源的分发性能
+type DownloadCheck struct {
+	// Size 期望的内容长度（字节），为0时不校验
+	Size int64 `yaml:"size,omitempty"`
+	// SHA256 期望内容的SHA-256（十六进制），为空时不校验
+	SHA256 string `yaml:"sha256,omitempty"`
+	// MinSpeedKBps 最低下载速度（KB/s），低于时标记为降级，为0时不校验
+	MinSpeedKBps float64 `yaml:"min_speed_kbps,omitempty"`
+	// MaxBytes 最多下载的字节数，超过时视为离线，默认64MB
+	MaxBytes int64 `yaml:"max_bytes,omitempty"`
+}
+
+// validate 校验下载测速配置
+func (d *DownloadCheck) validate() error {
+	if d.Size < 0 || d.MinSpeedKBps < 0 || d.MaxBytes < 0 {
+		return fmt.Errorf("下载测速的size、min_speed_kbps与max_bytes不能为负数")
+	}
+	if d.SHA256 != "" {
+		if b, err := hex.DecodeString(d.SHA256); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("下载测速的sha256需为64位十六进制字符串")
+		}
+	}
+	if d.Size > 0 && d.MaxBytes > 0 && d.Size > d.MaxBytes {
+		return fmt.Errorf("下载测速的size不能大于max_bytes")
+	}
+	return nil
+}
+
+// withDefaults 返回补充默认值后的配置
+func (d DownloadCheck) withDefaults() DownloadCheck {
+	if d.MaxBytes == 0 {
+		d.MaxBytes = max(defaultDownloadMaxBytes, d.Size)
+	}
+	return d
+}
+
+// DownloadMetrics 最近一次下载测速的结果
+type DownloadMetrics struct {
+	// Bytes 下载的字节数
+	Bytes uint64 `json:"bytes"`
+	// DurationMS 从收到响应头到下载完成的耗时（毫秒）
+	DurationMS float64 `json:"duration_ms"`
+	// SpeedBps 下载速度（字节/秒）
+	SpeedBps float64 `json:"speed_bps"`
+	// SHA256 内容的SHA-256
+	SHA256 string `json:"sha256"`
+}
+
+// DownloadMetricsReporter 可提供下载测速结果的检查器
+type DownloadMetricsReporter interface {
+	// DownloadMetrics 返回最近一次检查的下载测速结果，未下载时返回nil
+	DownloadMetrics() *DownloadMetrics
+}
+
+// DownloadMetrics 实现DownloadMetricsReporter接口
+func (h *HTTPChecker) DownloadMetrics() *DownloadMetrics {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.download
+}
+
+// setDownload 记录最近一次下载测速的结果
+func (h *HTTPChecker) setDownload(metrics *DownloadMetrics) {
+	h.mu.Lock()
+	h.download = metrics
+	h.mu.Unlock()
+}
+
+// headWriter 保留写入内容的前limit个字节
+type headWriter struct {
+	limit int
+	data  []byte
+}
+
+// Write 实现io.Writer接口
+func (w *headWriter) Write(p []byte) (int, error) {
+	if room := w.limit - len(w.data); room > 0 {
+		w.data = append(w.data, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// checkDownload 下载测速模式的检查：状态码或检查表达式通过后校验内容与下载速度，
+// 内容不一致时为离线，下载速度不足时为降级
+func (h *HTTPChecker) checkDownload(resp *http.Response, start time.Time) (ServiceStatus, error) {
+	h.setDownload(nil)
+	if h.program == nil && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return StatusOffline, &HTTPStatusError{Code: resp.StatusCode}
+	}
+
+	d := h.Download.withDefaults()
+	hash := sha256.New()
+	head := &headWriter{limit: maxExprBody}
+	transferStart := time.Now()
+	n, err := io.Copy(io.MultiWriter(hash, head), io.LimitReader(resp.Body, d.MaxBytes+1))
+	elapsed := time.Since(transferStart)
+	if err != nil {
+		return StatusOffline, fmt.Errorf("下载失败: %v", err)
+	}
+	if n > d.MaxBytes {
+		return StatusOffline, fmt.Errorf("下载内容超过 %s", formatBytes(uint64(d.MaxBytes)))
+	}
+	metrics := &DownloadMetrics{
+		Bytes:      uint64(n),
+		DurationMS: float64(elapsed) / float64(time.Millisecond),
+		SHA256:     hex.EncodeToString(hash.Sum(nil)),
+	}
+	if elapsed > 0 {
+		metrics.SpeedBps = float64(n) / elapsed.Seconds()
+	}
+	h.setDownload(metrics)
+
+	status := StatusOnline
+	if h.program != nil {
+		if status, err = evalCheckExpression(h.program, h.Expr, newProbeResult(resp, head.data, time.Since(start))); status == StatusOffline {
+			return status, err
+		}
+	}
+	if d.Size > 0 && n != d.Size {
+		return StatusOffline, fmt.Errorf("内容长度 %d 与期望的 %d 不一致", n, d.Size)
+	}
+	if d.SHA256 != "" && !strings.EqualFold(metrics.SHA256, d.SHA256) {
+		return StatusOffline, fmt.Errorf("内容校验和 %s 与期望的不一致", metrics.SHA256)
+	}
+	if d.MinSpeedKBps > 0 && metrics.SpeedBps < d.MinSpeedKBps*1024 {
+		return StatusDegraded, fmt.Errorf("下载速度 %.1fKB/s 低于 %.1fKB/s", metrics.SpeedBps/1024, d.MinSpeedKBps)
+	}
+	return status, err
+}
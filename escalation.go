@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// escalationTick 升级规则的检查间隔
+const escalationTick = 30 * time.Second
+
+// EscalationStep 升级规则：服务持续离线超过After后通知额外的渠道
+type EscalationStep struct {
+	// After 持续离线时间
+	After time.Duration `yaml:"after"`
+	// Channels 需要额外通知的渠道
+	Channels []string `yaml:"channels"`
+}
+
+// EscalationConfig 告警升级配置
+type EscalationConfig struct {
+	// Steps 升级规则
+	Steps []EscalationStep `yaml:"steps"`
+	// RepeatInterval 重复提醒间隔，为0时不重复提醒
+	RepeatInterval time.Duration `yaml:"repeat_interval"`
+}
+
+// outage 进行中的故障状态
+type outage struct {
+	// since 开始离线的时间
+	since time.Time
+	// lastSent 上次发送通知（含提醒）的时间
+	lastSent time.Time
+	// fired 已触发的升级规则序号
+	fired map[int]bool
+	// acked 是否已确认
+	acked bool
+	// reason 离线原因
+	reason string
+}
+
+// Acknowledgement 对进行中故障的确认，确认后不再发送该服务的重复通知，服务恢复后自动清除；
+// 设置SilenceUntil时在此之前即使服务恢复后再次故障也保持静默
+type Acknowledgement struct {
+	// By 确认人
+	By string `json:"by" yaml:"by"`
+	// Note 备注，如正在处理的进展
+	Note string `json:"note,omitempty" yaml:"note,omitempty"`
+	// Time 确认时间
+	Time time.Time `json:"time" yaml:"time"`
+	// SilenceUntil 静默截止时间
+	SilenceUntil *time.Time `json:"silence_until,omitempty" yaml:"silence_until,omitempty"`
+}
+
+// Silencing 判断now时刻是否仍在静默期内
+func (a *Acknowledgement) Silencing(now time.Time) bool {
+	return a != nil && a.SilenceUntil != nil && now.Before(*a.SilenceUntil)
+}
+
+// Escalator 告警升级与重复提醒
+type Escalator struct {
+	lock sync.Mutex
+	// global 全局升级配置
+	global EscalationConfig
+	// perService 各服务单独配置的升级规则
+	perService map[string]EscalationConfig
+	// outages 按服务名称记录的进行中的故障
+	outages map[string]*outage
+}
+
+// escalator 全局告警升级器
+var escalator = &Escalator{
+	perService: make(map[string]EscalationConfig),
+	outages:    make(map[string]*outage),
+}
+
+// Update 根据配置更新升级规则，进行中的故障保留
+func (e *Escalator) Update(cfg *Config) {
+	perService := make(map[string]EscalationConfig)
+	for _, sc := range cfg.Services {
+		if sc.Escalation != nil {
+			perService[sc.Name] = *sc.Escalation
+		}
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.global = cfg.Notifications.Escalation
+	e.perService = perService
+}
+
+// rules 返回服务适用的升级规则，需持有锁调用
+func (e *Escalator) rules(service string) EscalationConfig {
+	if rules, ok := e.perService[service]; ok {
+		return rules
+	}
+	return e.global
+}
+
+// OnTransition 状态变化监听器：进入离线时开始计时，恢复时结束故障
+func (e *Escalator) OnTransition(t Transition) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if t.To == StatusOffline {
+		if _, ok := e.outages[t.Service]; !ok {
+			e.outages[t.Service] = &outage{
+				since:    t.Time,
+				lastSent: t.Time,
+				fired:    make(map[int]bool),
+				// 静默期内再次发生的故障视为已确认
+				acked:  serviceManager.Acknowledgement(t.Service).Silencing(t.Time),
+				reason: t.Reason,
+			}
+		}
+		return
+	}
+	delete(e.outages, t.Service)
+}
+
+// Ack 确认服务的故障，停止后续的升级与重复提醒；没有进行中的故障时返回false
+func (e *Escalator) Ack(service string) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	o, ok := e.outages[service]
+	if !ok {
+		return false
+	}
+	o.acked = true
+	return true
+}
+
+// Acked 判断服务的故障是否已确认
+func (e *Escalator) Acked(service string) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	o, ok := e.outages[service]
+	return ok && o.acked
+}
+
+// evaluate 检查所有进行中的故障，触发到期的升级规则与重复提醒
+func (e *Escalator) evaluate(now time.Time) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for service, o := range e.outages {
+		if o.acked {
+			continue
+		}
+		rules := e.rules(service)
+		elapsed := now.Sub(o.since)
+		for i, step := range rules.Steps {
+			if o.fired[i] || elapsed < step.After {
+				continue
+			}
+			o.fired[i] = true
+			o.lastSent = now
+			notifications.SendTo(step.Channels, Notification{
+				Kind:    "escalation",
+				Service: service,
+				From:    StatusOnline,
+				To:      StatusOffline,
+				Time:    now,
+				Message: fmt.Sprintf("[JJApps Status] 告警升级: 服务 %s 已持续离线 %s\n原因: %s", service, elapsed.Round(time.Second), o.reason),
+			})
+		}
+
+		if rules.RepeatInterval > 0 && now.Sub(o.lastSent) >= rules.RepeatInterval {
+			o.lastSent = now
+			notifications.Send(Notification{
+				Kind:    "reminder",
+				Service: service,
+				From:    StatusOnline,
+				To:      StatusOffline,
+				Time:    now,
+				Message: fmt.Sprintf("[JJApps Status] 提醒: 服务 %s 仍处于离线状态，已持续 %s\n原因: %s", service, elapsed.Round(time.Second), o.reason),
+			})
+		}
+	}
+}
+
+// Start 在后台定期检查升级规则
+func (e *Escalator) Start() {
+	go func() {
+		ticker := time.NewTicker(escalationTick)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			e.evaluate(now)
+		}
+	}()
+}
+
+// ackRequest 确认故障的请求，请求体可为空
+type ackRequest struct {
+	// By 确认人，默认为访问令牌的名称
+	By string `json:"by"`
+	// Note 备注
+	Note string `json:"note"`
+	// SilenceUntil 静默截止时间（RFC3339）
+	SilenceUntil *time.Time `json:"silence_until"`
+	// Silence 静默时长（如"2h"），与SilenceUntil二选一
+	Silence string `json:"silence"`
+}
+
+// SetAck 设置服务的故障确认，页面与接口中会显示确认信息
+func (sm *ServiceManager) SetAck(name string, ack *Acknowledgement) (*Service, error) {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+	service := sm.lookup(name)
+	if service == nil {
+		return nil, fmt.Errorf("服务 '%s' 不存在", name)
+	}
+	service.Ack = ack
+	sm.touch()
+	return service.snapshot(), nil
+}
+
+// Acknowledgement 返回服务当前的故障确认，没有确认时返回nil
+func (sm *ServiceManager) Acknowledgement(name string) *Acknowledgement {
+	sm.lock.RLock()
+	defer sm.lock.RUnlock()
+	if service := sm.lookup(name); service != nil {
+		return service.Ack
+	}
+	return nil
+}
+
+// expireAck 服务恢复且不在静默期内时清除故障确认，需持有锁调用
+func expireAck(service *Service, now time.Time) bool {
+	if service.Ack == nil || service.Status == StatusOffline || service.Ack.Silencing(now) {
+		return false
+	}
+	service.Ack = nil
+	return true
+}
+
+// apiAckHandler 确认服务的故障，可附带确认人、备注与静默时间
+func apiAckHandler(c *gin.Context) {
+	name := c.Param("name")
+	var req ackRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apiError(c, http.StatusBadRequest, "error.invalid_request", err.Error())
+			return
+		}
+	}
+	now := time.Now()
+	ack := &Acknowledgement{By: req.By, Note: req.Note, Time: now, SilenceUntil: req.SilenceUntil}
+	if ack.By == "" {
+		ack.By = requestActor(c)
+	}
+	if req.Silence != "" {
+		d, err := time.ParseDuration(req.Silence)
+		if err != nil || d <= 0 {
+			apiError(c, http.StatusBadRequest, "error.invalid_param", "silence")
+			return
+		}
+		until := now.Add(d)
+		ack.SilenceUntil = &until
+	}
+
+	if !escalator.Ack(name) {
+		apiError(c, http.StatusNotFound, "error.no_outage", name)
+		return
+	}
+	if _, err := serviceManager.SetAck(name, ack); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(c, auditAck, name, yamlDiff(nil, ack))
+	c.JSON(http.StatusOK, gin.H{"service": name, "acked": true, "ack": ack})
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// EtcdSource 从etcd读取服务注册信息，通过v3 HTTP网关访问。
+// 服务实例注册在 <prefix><服务名>/<实例ID> 下，值可为JSON：{"status": "...", "url": "...", "description": "..."}，
+// status支持服务状态或passing/warning/critical，值不是JSON时视为在线
+type EtcdSource struct {
+	// Endpoint etcd地址，默认http://127.0.0.1:2379
+	Endpoint string `yaml:"endpoint"`
+	// Prefix 服务注册的键前缀，默认/services/
+	Prefix string `yaml:"prefix"`
+	// Username 用户名，开启认证时使用
+	Username string `yaml:"username"`
+	// Password 密码
+	Password string `yaml:"password"`
+}
+
+// etcdInstance 实例注册的值
+type etcdInstance struct {
+	Status      string `json:"status"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
+
+// endpoint 返回etcd地址
+func (es *EtcdSource) endpoint() string {
+	if es.Endpoint == "" {
+		return "http://127.0.0.1:2379"
+	}
+	return strings.TrimRight(es.Endpoint, "/")
+}
+
+// post 调用etcd网关接口
+func (es *EtcdSource) post(ctx context.Context, api, token string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, es.endpoint()+api, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求etcd失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("etcd返回状态码 %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// prefixEnd 返回前缀范围查询的结束键
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0}
+}
+
+// parseInstanceStatus 解析实例注册的状态
+func parseInstanceStatus(s string) ServiceStatus {
+	switch strings.ToLower(s) {
+	case "", "passing":
+		return StatusOnline
+	case "warning":
+		return StatusDegraded
+	case "critical":
+		return StatusOffline
+	}
+	status, err := ParseServiceStatus(s)
+	if err != nil {
+		return StatusOffline
+	}
+	return status
+}
+
+// Discover 实现DiscoverySource接口：服务的全部实例均不可用时为离线，部分不可用时为降级
+func (es *EtcdSource) Discover(ctx context.Context) ([]DiscoveredService, error) {
+	prefix := es.Prefix
+	if prefix == "" {
+		prefix = "/services/"
+	}
+
+	var token string
+	if es.Username != "" {
+		var auth struct {
+			Token string `json:"token"`
+		}
+		if err := es.post(ctx, "/v3/auth/authenticate", "", map[string]string{"name": es.Username, "password": es.Password}, &auth); err != nil {
+			return nil, fmt.Errorf("etcd认证失败: %v", err)
+		}
+		token = auth.Token
+	}
+
+	var result struct {
+		Kvs []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	query := map[string]string{
+		"key":       base64.StdEncoding.EncodeToString([]byte(prefix)),
+		"range_end": base64.StdEncoding.EncodeToString(prefixEnd(prefix)),
+	}
+	if err := es.post(ctx, "/v3/kv/range", token, query, &result); err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]*DiscoveredService)
+	counts := make(map[string][2]int)
+	order := make([]string, 0)
+	for _, kv := range result.Kvs {
+		key, _ := base64.StdEncoding.DecodeString(kv.Key)
+		value, _ := base64.StdEncoding.DecodeString(kv.Value)
+		name, _, _ := strings.Cut(strings.TrimPrefix(string(key), prefix), "/")
+		if name == "" {
+			continue
+		}
+		ds, ok := services[name]
+		if !ok {
+			ds = &DiscoveredService{Name: name, Description: "etcd", Status: StatusOnline}
+			services[name] = ds
+			order = append(order, name)
+		}
+		var inst etcdInstance
+		_ = json.Unmarshal(value, &inst)
+		if inst.URL != "" {
+			ds.URL = inst.URL
+		}
+		if inst.Description != "" {
+			ds.Description = inst.Description
+		}
+		c := counts[name]
+		c[0]++
+		if !isUp(parseInstanceStatus(inst.Status)) {
+			c[1]++
+		}
+		counts[name] = c
+	}
+
+	found := make([]DiscoveredService, 0, len(order))
+	for _, name := range order {
+		ds := services[name]
+		total, down := counts[name][0], counts[name][1]
+		switch {
+		case down == total:
+			ds.Status = StatusOffline
+		case down > 0:
+			ds.Status = StatusDegraded
+		}
+		if down > 0 {
+			ds.Error = fmt.Sprintf("%d/%d 个实例不可用", down, total)
+		}
+		found = append(found, *ds)
+	}
+	return found, nil
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxEvents 内存中保留的事件数，更早的事件仅保存在事件文件中
+	maxEvents = 10000
+	// defaultEventsLimit 事件接口默认返回条数
+	defaultEventsLimit = 50
+	// maxEventsLimit 事件接口单次最多返回条数
+	maxEventsLimit = 500
+)
+
+// Event 事件日志中的一条状态变化记录
+type Event struct {
+	// ID 事件序号，单调递增
+	ID int64 `json:"id"`
+	// Service 服务名称
+	Service string `json:"service"`
+	// From 变化前的状态
+	From ServiceStatus `json:"from"`
+	// To 变化后的状态
+	To ServiceStatus `json:"to"`
+	// Kind 变化类型，同Transition.Kind，自动修复的结果为heal_succeeded或heal_failed
+	Kind string `json:"kind"`
+	// Time 发生时间
+	Time time.Time `json:"time"`
+	// Reason 变化原因
+	Reason string `json:"reason,omitempty"`
+	// CheckError Reason是否为检查器返回的错误信息，启用hide_error_details时对未认证的调用方隐藏
+	CheckError bool `json:"check_error,omitempty"`
+}
+
+// EventLog 只追加的状态变化事件日志，保存在检查记录存储目录下，不随检查记录清理
+type EventLog struct {
+	lock sync.RWMutex
+	// events 最近的事件，按时间正序
+	events []Event
+	// nextID 下一个事件的序号
+	nextID int64
+	// file 追加写入的事件文件，为nil时仅保存在内存中
+	file *os.File
+}
+
+// eventLog 全局事件日志
+var eventLog = &EventLog{nextID: 1}
+
+// Open 加载已有的事件并持续追加写入，存储路径为空时仅保存在内存中
+func (l *EventLog) Open(storage StorageConfig) error {
+	if storage.Path == "" {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	path := strings.TrimSuffix(storage.Path, filepath.Ext(storage.Path)) + ".events.jsonl"
+	err := readLines(path, func(line []byte) error {
+		var event Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			return err
+		}
+		l.add(event)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	l.file, err = openAppend(path)
+	return err
+}
+
+// add 将事件加入内存，超出容量时丢弃最旧的事件，需持有锁调用
+func (l *EventLog) add(event Event) {
+	l.events = append(l.events, event)
+	if len(l.events) > maxEvents {
+		l.events = append(l.events[:0:0], l.events[len(l.events)-maxEvents:]...)
+	}
+	if event.ID >= l.nextID {
+		l.nextID = event.ID + 1
+	}
+}
+
+// OnTransition 状态变化监听器：记录启动后首次检查以外的全部状态变化
+func (l *EventLog) OnTransition(t Transition) {
+	if t.Initial {
+		return
+	}
+	l.Record(Event{
+		Service:    t.Service,
+		From:       t.From,
+		To:         t.To,
+		Kind:       t.Kind,
+		Time:       t.Time,
+		Reason:     t.Reason,
+		CheckError: t.CheckError,
+	})
+}
+
+// Record 分配序号并追加一条事件，如状态变化或自动修复结果
+func (l *EventLog) Record(event Event) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	event.ID = l.nextID
+	l.add(event)
+	if err := writeLine(l.file, event); err != nil {
+		slog.Error("保存事件失败", "service", event.Service, "error", err)
+	}
+}
+
+// EventQuery 事件查询条件
+type EventQuery struct {
+	// Since 仅返回该时间及之后的事件
+	Since time.Time
+	// Until 仅返回该时间之前的事件
+	Until time.Time
+	// Offset 跳过的事件数
+	Offset int
+	// Limit 返回的最大事件数
+	Limit int
+	// Match 事件过滤条件，为nil时不过滤
+	Match func(Event) bool
+}
+
+// Query 按时间倒序返回符合条件的事件，以及分页前的事件总数
+func (l *EventLog) Query(q EventQuery) ([]Event, int) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	// 事件按时间正序保存，可以二分查找时间范围
+	start := 0
+	if !q.Since.IsZero() {
+		start = sort.Search(len(l.events), func(i int) bool { return !l.events[i].Time.Before(q.Since) })
+	}
+	end := len(l.events)
+	if !q.Until.IsZero() {
+		end = sort.Search(len(l.events), func(i int) bool { return !l.events[i].Time.Before(q.Until) })
+	}
+
+	out := make([]Event, 0)
+	total := 0
+	for i := end - 1; i >= start; i-- {
+		event := l.events[i]
+		if q.Match != nil && !q.Match(event) {
+			continue
+		}
+		total++
+		if total > q.Offset && len(out) < q.Limit {
+			out = append(out, event)
+		}
+	}
+	return out, total
+}
+
+// eventVisibility 返回判断服务的事件是否可见的函数，未认证的请求只能看到当前公开服务的事件
+func eventVisibility(authorized bool) func(service string) bool {
+	if authorized {
+		return func(string) bool { return true }
+	}
+	public := make(map[string]bool)
+	for _, service := range serviceManager.GetServices() {
+		public[service.Name] = visible(service, false)
+	}
+	return func(service string) bool { return public[service] }
+}
+
+// Incident 由离线与恢复事件组成的一次故障
+type Incident struct {
+	// Service 服务名称
+	Service string `json:"service"`
+	// Start 开始离线的时间
+	Start time.Time `json:"start"`
+	// End 恢复的时间，故障未结束时为空
+	End *time.Time `json:"end,omitempty"`
+	// DurationSeconds 持续时间（秒），未结束的故障计算到当前时间
+	DurationSeconds float64 `json:"duration_seconds"`
+	// Reason 开始离线时的原因
+	Reason string `json:"reason,omitempty"`
+	// CheckError Reason是否为检查器返回的错误信息
+	CheckError bool `json:"-"`
+}
+
+// Incidents 根据内存中的事件还原match接受的服务的故障，按开始时间倒序返回
+func (l *EventLog) Incidents(match func(service string) bool, now time.Time) []Incident {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	incidents := make([]Incident, 0)
+	open := make(map[string]int)
+	for _, event := range l.events {
+		if event.Kind != "status_change" || !match(event.Service) {
+			continue
+		}
+		idx, ongoing := open[event.Service]
+		switch {
+		case event.To == StatusOffline && !ongoing:
+			open[event.Service] = len(incidents)
+			incidents = append(incidents, Incident{Service: event.Service, Start: event.Time, Reason: event.Reason, CheckError: event.CheckError})
+		case event.To != StatusOffline && ongoing:
+			end := event.Time
+			incidents[idx].End = &end
+			incidents[idx].DurationSeconds = end.Sub(incidents[idx].Start).Seconds()
+			delete(open, event.Service)
+		}
+	}
+	for _, idx := range open {
+		incidents[idx].DurationSeconds = now.Sub(incidents[idx].Start).Seconds()
+	}
+	slices.Reverse(incidents)
+	return incidents
+}
+
+// listParams 列表接口的分页与时间范围参数
+type listParams struct {
+	// Since 仅返回该时间及之后的记录
+	Since time.Time
+	// Until 仅返回该时间之前的记录
+	Until time.Time
+	// Offset 跳过的记录数
+	Offset int
+	// Limit 返回的最大记录数
+	Limit int
+}
+
+// parseListParams 解析limit、offset、since与until查询参数，limit超过maxEventsLimit时按上限处理；
+// 参数无效时返回错误响应并返回false
+func parseListParams(c *gin.Context) (listParams, bool) {
+	q := listParams{Limit: defaultEventsLimit}
+	for _, p := range []struct {
+		name string
+		dst  *int
+		min  int
+	}{{"limit", &q.Limit, 1}, {"offset", &q.Offset, 0}} {
+		if v := c.Query(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < p.min {
+				apiError(c, http.StatusBadRequest, "error.invalid_param", p.name)
+				return q, false
+			}
+			*p.dst = n
+		}
+	}
+	if q.Limit > maxEventsLimit {
+		q.Limit = maxEventsLimit
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		if v := c.Query(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				apiError(c, http.StatusBadRequest, "error.invalid_param", p.name)
+				return q, false
+			}
+			*p.dst = t
+		}
+	}
+	return q, true
+}
+
+// apiEventsHandler 分页返回最近的状态变化事件，支持按服务与时间范围过滤
+func apiEventsHandler(c *gin.Context) {
+	params, ok := parseListParams(c)
+	if !ok {
+		return
+	}
+	q := EventQuery{Since: params.Since, Until: params.Until, Offset: params.Offset, Limit: params.Limit}
+
+	name := c.Query("service")
+	allowed := eventVisibility(canViewPrivate(c))
+	q.Match = func(e Event) bool {
+		if name != "" && e.Service != name {
+			return false
+		}
+		return allowed(e.Service)
+	}
+
+	events, total := eventLog.Query(q)
+	c.Header("Vary", "Authorization")
+	c.JSON(http.StatusOK, gin.H{
+		"events": redactEvents(events, canViewPrivate(c), requestLocale(c)),
+		"total":  total,
+		"offset": q.Offset,
+		"limit":  q.Limit,
+	})
+}
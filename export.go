@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// maxImportSize 导入配置的最大字节数
+const maxImportSize = 1 << 20
+
+// csvHeader 检查记录CSV表头
+var csvHeader = []string{"service", "time", "status", "latency_ms", "error"}
+
+// exportResults 查询待导出的检查记录，service为空时导出所有服务
+func exportResults(service string, since time.Time) ([]CheckResult, error) {
+	names := []string{service}
+	if service == "" {
+		names = names[:0]
+		for _, s := range serviceManager.GetServices() {
+			names = append(names, s.Name)
+		}
+	}
+
+	results := make([]CheckResult, 0)
+	for _, name := range names {
+		list, err := serviceManager.History().Query(name, since, 0)
+		if err != nil {
+			return nil, err
+		}
+		// 查询结果为倒序，导出时按时间正序
+		for i := len(list) - 1; i >= 0; i-- {
+			results = append(results, list[i])
+		}
+	}
+	return results, nil
+}
+
+// apiExportHistoryHandler 导出检查记录，format可选csv或json
+func apiExportHistoryHandler(c *gin.Context) {
+	var since time.Time
+	if v := c.Query("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "error.invalid_since")
+			return
+		}
+		since = t
+	}
+	service := c.Query("service")
+	if service != "" && serviceManager.GetService(service) == nil {
+		apiError(c, http.StatusNotFound, "error.service_not_found")
+		return
+	}
+
+	results, err := exportResults(service, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := "history-" + time.Now().Format("20060102-150405")
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		c.JSON(http.StatusOK, results)
+	case "csv":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, filename))
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		// 响应头已发送，写入失败（如客户端断开）时只能记录日志
+		if err := writeResultsCSV(c.Writer, results); err != nil {
+			slog.Warn("导出检查记录失败", "ip", requestIP(c), "error", err)
+		}
+	default:
+		apiError(c, http.StatusBadRequest, "error.invalid_format")
+	}
+}
+
+// writeResultsCSV 以CSV格式写出检查记录
+func writeResultsCSV(w io.Writer, results []CheckResult) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range results {
+		record := []string{
+			r.Service,
+			r.Time.Format(time.RFC3339),
+			r.Status.String(),
+			strconv.FormatFloat(r.LatencyMS, 'f', 3, 64),
+			r.Error,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// apiExportConfigHandler 以YAML格式导出当前生效的配置
+func apiExportConfigHandler(c *gin.Context) {
+	data, err := yaml.Marshal(configReloader.Current())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="services.yaml"`)
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", data)
+}
+
+// apiImportConfigHandler 导入YAML配置，校验通过后写入配置文件并立即生效
+func apiImportConfigHandler(c *gin.Context) {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxImportSize+1))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "error.read_body")
+		return
+	}
+	if len(data) > maxImportSize {
+		apiError(c, http.StatusRequestEntityTooLarge, "error.config_too_large")
+		return
+	}
+	if err := configReloader.Import(data, requestActor(c), requestIP(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"services": len(configReloader.Current().Services)})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// maxExprBody 检查表达式可读取的响应体最大字节数
+const maxExprBody = 1 << 20
+
+// probeResult 检查表达式可使用的探测结果
+type probeResult struct {
+	// Code HTTP状态码
+	Code int `expr:"code"`
+	// Latency 请求耗时，可直接与800ms等时长比较
+	Latency time.Duration `expr:"latency"`
+	// Body 响应体，最多读取1MB
+	Body string `expr:"body"`
+	// Headers 响应头，键为小写的头名称，多个值以逗号连接
+	Headers map[string]string `expr:"headers"`
+	// CertDays 证书剩余有效天数，非HTTPS请求时为-1
+	CertDays float64 `expr:"cert_days"`
+	// FinalURL 跟随重定向后的最终地址
+	FinalURL string `expr:"final_url"`
+	// Redirects 经过的重定向次数
+	Redirects int `expr:"redirects"`
+}
+
+// newProbeResult 根据HTTP响应创建探测结果
+func newProbeResult(resp *http.Response, body []byte, latency time.Duration) probeResult {
+	headers := make(map[string]string, len(resp.Header))
+	for k, v := range resp.Header {
+		headers[strings.ToLower(k)] = strings.Join(v, ", ")
+	}
+	certDays := -1.0
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		certDays = time.Until(resp.TLS.PeerCertificates[0].NotAfter).Hours() / 24
+	}
+	return probeResult{
+		Code:      resp.StatusCode,
+		Latency:   latency,
+		Body:      string(body),
+		Headers:   headers,
+		CertDays:  certDays,
+		FinalURL:  resp.Request.URL.String(),
+		Redirects: redirectCount(resp),
+	}
+}
+
+// durationLiteral 匹配表达式中的时长字面量，如800ms、1.5s
+var durationLiteral = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h)\b`)
+
+// expandDurations 将字符串以外的时长字面量改写为duration("...")调用，使latency < 800ms可直接书写
+func expandDurations(source string) string {
+	var b strings.Builder
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			// 原样保留字符串字面量
+			j := i + 1
+			for j < len(source) && source[j] != c {
+				if source[j] == '\\' && c != '`' {
+					j++
+				}
+				j++
+			}
+			if j < len(source) {
+				j++
+			}
+			b.WriteString(source[i:j])
+			i = j
+		case c >= '0' && c <= '9' && (i == 0 || !isIdentChar(source[i-1])):
+			if m := durationLiteral.FindString(source[i:]); m != "" {
+				fmt.Fprintf(&b, "duration(%q)", m)
+				i += len(m)
+				continue
+			}
+			b.WriteByte(c)
+			i++
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// isIdentChar 判断字符是否可作为标识符的一部分
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// compileCheckExpression 编译检查表达式，表达式只能访问探测结果且必须返回布尔值
+func compileCheckExpression(source string) (*vm.Program, error) {
+	program, err := expr.Compile(expandDurations(source), expr.Env(probeResult{}), expr.AsBool())
+	if err != nil {
+		return nil, fmt.Errorf("无效的检查表达式 '%s': %v", source, err)
+	}
+	return program, nil
+}
+
+// evalCheckExpression 对探测结果执行检查表达式，表达式不成立时返回错误
+func evalCheckExpression(program *vm.Program, source string, result probeResult) (ServiceStatus, error) {
+	out, err := expr.Run(program, result)
+	if err != nil {
+		return StatusOffline, fmt.Errorf("执行检查表达式失败: %v", err)
+	}
+	if ok, _ := out.(bool); !ok {
+		return StatusOffline, fmt.Errorf("检查表达式不成立: %s（状态码 %d，耗时 %s）", source, result.Code, result.Latency.Round(time.Millisecond))
+	}
+	return StatusOnline, nil
+}
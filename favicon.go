@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// faviconSVGs 各整体状态的图标：正常为绿色对勾，部分异常为黄色感叹号，严重故障为红色叉号
+var faviconSVGs = map[string]string{
+	overallOperational: `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 32 32">` +
+		`<circle cx="16" cy="16" r="15" fill="#28a745"/>` +
+		`<path d="M9 16.5l4.5 4.5L23 11.5" fill="none" stroke="#fff" stroke-width="3.5" stroke-linecap="round" stroke-linejoin="round"/></svg>`,
+	overallPartialOutage: `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 32 32">` +
+		`<circle cx="16" cy="16" r="15" fill="#f0ad4e"/>` +
+		`<path d="M16 8v10" stroke="#fff" stroke-width="4" stroke-linecap="round"/><circle cx="16" cy="23.5" r="2.2" fill="#fff"/></svg>`,
+	overallMajorOutage: `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 32 32">` +
+		`<circle cx="16" cy="16" r="15" fill="#dc3545"/>` +
+		`<path d="M10.5 10.5l11 11M21.5 10.5l-11 11" stroke="#fff" stroke-width="3.5" stroke-linecap="round"/></svg>`,
+}
+
+// faviconURL 返回指定整体状态的图标地址，页面脚本在状态变化时替换
+func faviconURL(state string) string {
+	return "/favicon.svg?state=" + state
+}
+
+// documentTitle 返回页面标题，存在异常服务时在前面加上异常数，如"(1 outage) JJApps Status"
+func documentTitle(locale, title string, outages int) string {
+	switch {
+	case outages == 0:
+		return title
+	case outages == 1:
+		return translate(locale, "page.title_outage", outages, title)
+	default:
+		return translate(locale, "page.title_outages", outages, title)
+	}
+}
+
+// faviconHandler 返回反映整体状态的SVG图标；指定state参数时返回该状态的图标，
+// 否则按请求可见的服务计算，浏览器固定的标签页无需执行脚本即可看到故障
+func faviconHandler(c *gin.Context) {
+	state := c.Query("state")
+	if state != "" {
+		if _, ok := faviconSVGs[state]; !ok {
+			apiError(c, http.StatusBadRequest, "error.invalid_param", "state")
+			return
+		}
+		c.Header("Cache-Control", "public, max-age=86400")
+	} else {
+		refreshOnDemand()
+		c.Header("Vary", "Authorization")
+		if notModified(c, serviceManager.LastChange(), "") {
+			return
+		}
+		state = summarize(visibleServices(c), configReloader.Current().Summary).State
+	}
+	c.Data(http.StatusOK, "image/svg+xml", []byte(faviconSVGs[state]))
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	// maxGraphQLSize GraphQL请求体的最大字节数
+	maxGraphQLSize = 64 << 10
+	// defaultGraphQLLimit 列表字段默认返回的条数
+	defaultGraphQLLimit = 50
+)
+
+// graphqlAuthKey context中保存请求是否可以查看隐藏服务的键
+type graphqlAuthKey struct{}
+
+// graphqlLocaleKey context中保存请求语言的键
+type graphqlLocaleKey struct{}
+
+// graphqlAuthorized 判断GraphQL请求是否可以查看隐藏的服务
+func graphqlAuthorized(p graphql.ResolveParams) bool {
+	authorized, _ := p.Context.Value(graphqlAuthKey{}).(bool)
+	return authorized
+}
+
+// graphqlError 按请求语言生成查询错误
+func graphqlError(p graphql.ResolveParams, key string, args ...interface{}) error {
+	return errors.New(translate(graphqlLocale(p), key, args...))
+}
+
+// graphqlLocale 返回GraphQL请求的语言
+func graphqlLocale(p graphql.ResolveParams) string {
+	locale, _ := p.Context.Value(graphqlLocaleKey{}).(string)
+	return locale
+}
+
+// graphqlLimit 读取limit参数，限制在1~maxEventsLimit之间
+func graphqlLimit(p graphql.ResolveParams) int {
+	limit, _ := p.Args["limit"].(int)
+	return min(max(limit, 1), maxEventsLimit)
+}
+
+// graphqlTime 读取RFC3339格式的时间参数，未提供时返回零值
+func graphqlTime(p graphql.ResolveParams, name string) time.Time {
+	t, _ := p.Args[name].(time.Time)
+	return t
+}
+
+// graphqlEvents 按参数查询事件，service非空时只返回该服务的事件
+func graphqlEvents(p graphql.ResolveParams, service string) []Event {
+	kind, _ := p.Args["kind"].(string)
+	offset, _ := p.Args["offset"].(int)
+	allowed := eventVisibility(graphqlAuthorized(p))
+	events, _ := eventLog.Query(EventQuery{
+		Since:  graphqlTime(p, "since"),
+		Until:  graphqlTime(p, "until"),
+		Offset: max(offset, 0),
+		Limit:  graphqlLimit(p),
+		Match: func(e Event) bool {
+			return allowed(e.Service) && (service == "" || e.Service == service) && (kind == "" || e.Kind == kind)
+		},
+	})
+	return redactEvents(events, graphqlAuthorized(p), graphqlLocale(p))
+}
+
+// graphqlIncidents 按参数查询故障，service非空时只返回该服务的故障
+func graphqlIncidents(p graphql.ResolveParams, service string) []Incident {
+	allowed := eventVisibility(graphqlAuthorized(p))
+	incidents := eventLog.Incidents(func(name string) bool {
+		return allowed(name) && (service == "" || name == service)
+	}, time.Now())
+	since := graphqlTime(p, "since")
+	ongoing, onlyOngoing := p.Args["ongoing"].(bool)
+	out := make([]Incident, 0)
+	for _, incident := range incidents {
+		if len(out) >= graphqlLimit(p) {
+			break
+		}
+		if incident.End != nil && incident.End.Before(since) {
+			continue
+		}
+		if onlyOngoing && ongoing != (incident.End == nil) {
+			continue
+		}
+		if incident.CheckError && detailsHidden(graphqlAuthorized(p)) {
+			incident.Reason = translate(graphqlLocale(p), "error.check_failed")
+		}
+		out = append(out, incident)
+	}
+	return out
+}
+
+// graphqlUptime 服务的可用率历史
+type graphqlUptime struct {
+	// UptimePercent 整体可用率
+	UptimePercent *float64 `json:"uptime_percent"`
+	// Buckets 各时间段的统计
+	Buckets []UptimeBucket `json:"buckets"`
+}
+
+var (
+	// eventsArgs 事件列表的过滤参数
+	eventsArgs = graphql.FieldConfigArgument{
+		"kind":   {Type: graphql.String, Description: "变化类型，如status_change"},
+		"since":  {Type: graphql.DateTime, Description: "仅返回该时间及之后的事件"},
+		"until":  {Type: graphql.DateTime, Description: "仅返回该时间之前的事件"},
+		"offset": {Type: graphql.Int, DefaultValue: 0},
+		"limit":  {Type: graphql.Int, DefaultValue: defaultGraphQLLimit},
+	}
+	// incidentsArgs 故障列表的过滤参数
+	incidentsArgs = graphql.FieldConfigArgument{
+		"since":   {Type: graphql.DateTime, Description: "仅返回该时间之后仍在进行或结束的故障"},
+		"ongoing": {Type: graphql.Boolean, Description: "true只返回进行中的故障，false只返回已结束的故障"},
+		"limit":   {Type: graphql.Int, DefaultValue: defaultGraphQLLimit},
+	}
+)
+
+var (
+	eventType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Event",
+		Description: "状态变化事件",
+		Fields: graphql.Fields{
+			"id":      {Type: graphql.Int},
+			"service": {Type: graphql.String},
+			"from":    {Type: graphql.String},
+			"to":      {Type: graphql.String},
+			"kind":    {Type: graphql.String},
+			"time":    {Type: graphql.DateTime},
+			"reason":  {Type: graphql.String},
+		},
+	})
+
+	incidentType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Incident",
+		Description: "由离线与恢复事件组成的一次故障",
+		Fields: graphql.Fields{
+			"service":          {Type: graphql.String},
+			"start":            {Type: graphql.DateTime},
+			"end":              {Type: graphql.DateTime, Description: "恢复时间，进行中的故障为null"},
+			"duration_seconds": {Type: graphql.Float},
+			"reason":           {Type: graphql.String},
+		},
+	})
+
+	uptimeBucketType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "UptimeBucket",
+		Fields: graphql.Fields{
+			"start":          {Type: graphql.DateTime},
+			"checks":         {Type: graphql.Int},
+			"uptime_percent": {Type: graphql.Float, Description: "可用率，没有数据时为null"},
+			"status":         {Type: graphql.String, Description: "时间段内最差的状态"},
+		},
+	})
+
+	uptimeType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Uptime",
+		Fields: graphql.Fields{
+			"uptime_percent": {Type: graphql.Float},
+			"buckets":        {Type: graphql.NewList(uptimeBucketType)},
+		},
+	})
+
+	regionType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "RegionResult",
+		Fields: graphql.Fields{
+			"region":       {Type: graphql.String},
+			"status":       {Type: graphql.String},
+			"latency_ms":   {Type: graphql.Float},
+			"last_checked": {Type: graphql.DateTime},
+			"last_error":   {Type: graphql.String},
+			"stale":        {Type: graphql.Boolean},
+		},
+	})
+
+	ackType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Acknowledgement",
+		Fields: graphql.Fields{
+			"by":            {Type: graphql.String},
+			"note":          {Type: graphql.String},
+			"time":          {Type: graphql.DateTime},
+			"silence_until": {Type: graphql.DateTime},
+		},
+	})
+
+	anomalyType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "LatencyAnomaly",
+		Fields: graphql.Fields{
+			"since":       {Type: graphql.DateTime},
+			"latency_ms":  {Type: graphql.Float},
+			"baseline_ms": {Type: graphql.Float},
+			"stddev_ms":   {Type: graphql.Float},
+		},
+	})
+
+	serviceType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Service",
+		Description: "服务及其当前状态",
+		Fields: graphql.Fields{
+			"name":                  {Type: graphql.String},
+			"description":           {Type: graphql.String},
+			"url":                   {Type: graphql.String},
+			"host":                  {Type: graphql.String, Description: "上报该服务的远程探针，本机服务为空"},
+			"order":                 {Type: graphql.Int},
+			"hidden":                {Type: graphql.Boolean},
+			"enabled":               {Type: graphql.Boolean},
+			"invert":                {Type: graphql.Boolean, Description: "反向检查，目标不可达时服务正常"},
+			"status":                {Type: graphql.String},
+			"last_checked":          {Type: graphql.DateTime},
+			"age_seconds":           {Type: graphql.Float},
+			"checking":              {Type: graphql.Boolean},
+			"next_check":            {Type: graphql.DateTime},
+			"last_error":            {Type: graphql.String},
+			"latency_ms":            {Type: graphql.Float},
+			"consecutive_failures":  {Type: graphql.Int},
+			"consecutive_successes": {Type: graphql.Int},
+			"depends_on":            {Type: graphql.NewList(graphql.String)},
+			"impacted_by":           {Type: graphql.NewList(graphql.String)},
+			"latency_level":         {Type: graphql.String},
+			"anomaly":               {Type: anomalyType},
+			"ack":                   {Type: ackType},
+			"regions": {
+				Type: graphql.NewList(regionType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*Service).RegionResults, nil
+				},
+			},
+			"events": {
+				Type: graphql.NewList(eventType),
+				Args: eventsArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return graphqlEvents(p, p.Source.(*Service).Name), nil
+				},
+			},
+			"incidents": {
+				Type: graphql.NewList(incidentType),
+				Args: incidentsArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return graphqlIncidents(p, p.Source.(*Service).Name), nil
+				},
+			},
+			"uptime": {
+				Type:        uptimeType,
+				Description: "最近days天按天（day）或按小时（hour）汇总的可用率",
+				Args: graphql.FieldConfigArgument{
+					"days": {Type: graphql.Int, DefaultValue: defaultUptimeDays},
+					"step": {Type: graphql.String, DefaultValue: "day"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					days, _ := p.Args["days"].(int)
+					step, _ := p.Args["step"].(string)
+					if days <= 0 || days > maxUptimeDays {
+						return nil, graphqlError(p, "error.invalid_param", "days")
+					}
+					start, size, count, ok := uptimeRange(step, days, time.Now())
+					if !ok {
+						return nil, graphqlError(p, "error.invalid_step")
+					}
+					rollups, err := serviceManager.History().QueryRollups(p.Source.(*Service).Name, start)
+					if err != nil {
+						return nil, err
+					}
+					buckets := aggregateUptime(rollups, start, size, count)
+					return graphqlUptime{UptimePercent: overallUptime(buckets), Buckets: buckets}, nil
+				},
+			},
+		},
+	})
+)
+
+// graphqlSchema 状态数据的GraphQL schema
+var graphqlSchema = func() graphql.Schema {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"services": {
+				Type:        graphql.NewList(serviceType),
+				Description: "请求可见的服务，按显示顺序排列",
+				Args: graphql.FieldConfigArgument{
+					"status": {Type: graphql.NewList(graphql.String), Description: "只返回这些状态的服务"},
+					"names":  {Type: graphql.NewList(graphql.String), Description: "只返回这些名称的服务"},
+					"host":   {Type: graphql.String, Description: "只返回该远程探针上报的服务，本机服务为空字符串"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					statuses := graphqlStrings(p.Args["status"])
+					names := graphqlStrings(p.Args["names"])
+					host, filterHost := p.Args["host"].(string)
+					services := make([]*Service, 0)
+					for _, service := range allServices() {
+						switch {
+						case !visible(service, graphqlAuthorized(p)),
+							statuses != nil && !statuses[service.Status.String()],
+							names != nil && !names[service.Name],
+							filterHost && service.Host != host:
+							continue
+						}
+						services = append(services, service)
+					}
+					sortServices(services)
+					if detailsHidden(graphqlAuthorized(p)) {
+						for i, service := range services {
+							services[i] = redactService(service, graphqlLocale(p))
+						}
+					}
+					return services, nil
+				},
+			},
+			"service": {
+				Type:        serviceType,
+				Description: "按名称获取本机服务，不存在或不可见时为null",
+				Args: graphql.FieldConfigArgument{
+					"name": {Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					service := serviceManager.GetService(p.Args["name"].(string))
+					if service == nil || !visible(service, graphqlAuthorized(p)) {
+						return nil, nil
+					}
+					if detailsHidden(graphqlAuthorized(p)) {
+						return redactService(service, graphqlLocale(p)), nil
+					}
+					return service, nil
+				},
+			},
+			"events": {
+				Type:        graphql.NewList(eventType),
+				Description: "按时间倒序的状态变化事件",
+				Args: func() graphql.FieldConfigArgument {
+					args := graphql.FieldConfigArgument{"service": {Type: graphql.String}}
+					for name, arg := range eventsArgs {
+						args[name] = arg
+					}
+					return args
+				}(),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					service, _ := p.Args["service"].(string)
+					return graphqlEvents(p, service), nil
+				},
+			},
+			"incidents": {
+				Type:        graphql.NewList(incidentType),
+				Description: "按开始时间倒序的故障",
+				Args: func() graphql.FieldConfigArgument {
+					args := graphql.FieldConfigArgument{"service": {Type: graphql.String}}
+					for name, arg := range incidentsArgs {
+						args[name] = arg
+					}
+					return args
+				}(),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					service, _ := p.Args["service"].(string)
+					return graphqlIncidents(p, service), nil
+				},
+			},
+		},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}()
+
+// graphqlStrings 将字符串列表参数转为集合，未提供时返回nil
+func graphqlStrings(arg interface{}) map[string]bool {
+	values, ok := arg.([]interface{})
+	if !ok {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+// graphqlRequest GraphQL请求，GET请求通过同名查询参数传递，variables为JSON字符串
+type graphqlRequest struct {
+	// Query 查询语句
+	Query string `json:"query"`
+	// OperationName 需要执行的操作名称
+	OperationName string `json:"operationName"`
+	// Variables 查询变量
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// graphqlHandler 执行GraphQL查询，携带viewer及以上角色的令牌时包含隐藏的服务
+func graphqlHandler(c *gin.Context) {
+	var req graphqlRequest
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if v := c.Query("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				apiError(c, http.StatusBadRequest, "error.invalid_param", "variables")
+				return
+			}
+		}
+	} else {
+		data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxGraphQLSize+1))
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "error.read_body")
+			return
+		}
+		if len(data) > maxGraphQLSize {
+			apiError(c, http.StatusRequestEntityTooLarge, "error.query_too_large")
+			return
+		}
+		if err := json.Unmarshal(data, &req); err != nil {
+			apiError(c, http.StatusBadRequest, "error.invalid_request", err.Error())
+			return
+		}
+	}
+	if req.Query == "" {
+		apiError(c, http.StatusBadRequest, "error.invalid_param", "query")
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), graphqlAuthKey{}, canViewPrivate(c))
+	ctx = context.WithValue(ctx, graphqlLocaleKey{}, requestLocale(c))
+	result := graphql.Do(graphql.Params{
+		Schema:         graphqlSchema,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        ctx,
+	})
+	c.Header("Vary", "Authorization")
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultHealAfter 默认触发自动修复的连续失败次数
+	defaultHealAfter = 3
+	// defaultHealTimeout 修复命令的默认超时时间
+	defaultHealTimeout = 30 * time.Second
+	// defaultHealCooldown 两次修复之间的默认间隔
+	defaultHealCooldown = 5 * time.Minute
+	// defaultHealMaxAttempts 一次故障中默认的最大修复次数
+	defaultHealMaxAttempts = 3
+	// defaultHealVerifyDelay 修复后重新检查前的默认等待时间
+	defaultHealVerifyDelay = 10 * time.Second
+)
+
+// HealConfig 服务连续检查失败后执行的自动修复动作，command与systemd二选一
+type HealConfig struct {
+	// After 触发修复的连续失败次数，默认3
+	After int `yaml:"after,omitempty"`
+	// Command 修复命令，按空白分割参数后直接执行（不经过shell），{service}会替换为服务名称
+	Command string `yaml:"command,omitempty"`
+	// Systemd 需要重启的systemd单元
+	Systemd string `yaml:"systemd,omitempty"`
+	// User 是否为用户级单元（systemctl --user）
+	User bool `yaml:"user,omitempty"`
+	// Timeout 修复命令的超时时间，默认30s
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Cooldown 两次修复之间的最短间隔，默认5m
+	Cooldown time.Duration `yaml:"cooldown,omitempty"`
+	// MaxAttempts 一次故障中的最大修复次数，服务恢复后重新计数，默认3
+	MaxAttempts int `yaml:"max_attempts,omitempty"`
+	// VerifyDelay 修复后等待多久重新检查服务，默认10s
+	VerifyDelay time.Duration `yaml:"verify_delay,omitempty"`
+}
+
+// validate 校验自动修复配置
+func (hc *HealConfig) validate() error {
+	if (hc.Command == "") == (hc.Systemd == "") {
+		return fmt.Errorf("自动修复需要配置command或systemd之一")
+	}
+	if hc.After < 0 || hc.MaxAttempts < 0 {
+		return fmt.Errorf("自动修复的after与max_attempts不能为负数")
+	}
+	return nil
+}
+
+// args 返回修复命令的参数
+func (hc *HealConfig) args(service string) []string {
+	if hc.Systemd != "" {
+		args := []string{"systemctl", "restart", hc.Systemd}
+		if hc.User {
+			args = []string{"systemctl", "--user", "restart", hc.Systemd}
+		}
+		return args
+	}
+	args := strings.Fields(hc.Command)
+	for i := range args {
+		args[i] = strings.ReplaceAll(args[i], "{service}", service)
+	}
+	return args
+}
+
+// withDefaults 返回补充默认值后的配置
+func (hc HealConfig) withDefaults() HealConfig {
+	if hc.After <= 0 {
+		hc.After = defaultHealAfter
+	}
+	if hc.Timeout <= 0 {
+		hc.Timeout = defaultHealTimeout
+	}
+	if hc.Cooldown <= 0 {
+		hc.Cooldown = defaultHealCooldown
+	}
+	if hc.MaxAttempts <= 0 {
+		hc.MaxAttempts = defaultHealMaxAttempts
+	}
+	if hc.VerifyDelay <= 0 {
+		hc.VerifyDelay = defaultHealVerifyDelay
+	}
+	return hc
+}
+
+// healState 服务当前故障中的修复状态
+type healState struct {
+	// attempts 已执行的修复次数
+	attempts int
+	// last 上次开始修复的时间
+	last time.Time
+	// running 是否正在修复
+	running bool
+}
+
+// Healer 服务连续检查失败时执行自动修复，并记录结果与发送通知
+type Healer struct {
+	lock sync.Mutex
+	// configs 各服务的修复配置
+	configs map[string]HealConfig
+	// states 按服务名称记录的修复状态
+	states map[string]*healState
+}
+
+// healer 全局自动修复器
+var healer = &Healer{
+	configs: make(map[string]HealConfig),
+	states:  make(map[string]*healState),
+}
+
+// Update 根据配置更新修复动作，进行中的修复状态保留
+func (h *Healer) Update(cfg *Config) {
+	configs := make(map[string]HealConfig)
+	for _, sc := range cfg.Services {
+		if sc.Heal != nil {
+			configs[sc.Name] = sc.Heal.withDefaults()
+		}
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.configs = configs
+}
+
+// OnCheck 检查结果监听器：连续失败达到阈值时开始修复，服务恢复后重置修复次数；
+// 因依赖故障而受影响的服务本身可能正常，与维护中一样不执行修复
+func (h *Healer) OnCheck(result CheckResult, failures int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if isUp(result.Status) || result.Status == StatusMaintenance || result.Status == StatusImpacted {
+		if state, ok := h.states[result.Service]; ok && !state.running {
+			delete(h.states, result.Service)
+		}
+		return
+	}
+	cfg, ok := h.configs[result.Service]
+	if !ok || failures < cfg.After {
+		return
+	}
+	state, ok := h.states[result.Service]
+	if !ok {
+		state = &healState{}
+		h.states[result.Service] = state
+	}
+	if state.running || state.attempts >= cfg.MaxAttempts || result.Time.Sub(state.last) < cfg.Cooldown {
+		return
+	}
+	state.attempts++
+	state.last = result.Time
+	state.running = true
+	go h.heal(result, cfg, state.attempts)
+}
+
+// heal 执行修复命令并重新检查服务，结果记录到事件日志并发送通知
+func (h *Healer) heal(before CheckResult, cfg HealConfig, attempt int) {
+	name := before.Service
+	defer func() {
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		if state, ok := h.states[name]; ok {
+			state.running = false
+		}
+	}()
+
+	args := cfg.args(name)
+	slog.Info("开始自动修复", "service", name, "attempt", attempt, "command", strings.Join(args, " "))
+	after := before.Status
+	failure := runHealCommand(args, cfg.Timeout)
+	if failure == "" {
+		time.Sleep(cfg.VerifyDelay)
+		_, result, err := serviceManager.CheckService(context.Background(), name)
+		switch {
+		case err != nil:
+			failure = err.Error()
+		case result == nil:
+			failure = "服务未启用检查"
+		default:
+			after = result.Status
+			if !isUp(after) {
+				failure = fmt.Sprintf("修复后服务仍为%s", statusTitle(localeZH, after))
+			}
+		}
+	}
+
+	kind, title := "heal_succeeded", "自动修复成功"
+	reason := fmt.Sprintf("第 %d/%d 次自动修复", attempt, cfg.MaxAttempts)
+	if failure != "" {
+		kind, title = "heal_failed", "自动修复失败"
+		reason += "：" + failure
+		slog.Warn("自动修复失败", "service", name, "attempt", attempt, "error", failure)
+	} else {
+		slog.Info("自动修复成功", "service", name, "attempt", attempt)
+	}
+	now := time.Now()
+	eventLog.Record(Event{Service: name, From: before.Status, To: after, Kind: kind, Time: now, Reason: reason})
+	notifications.Send(Notification{
+		Kind:    kind,
+		Service: name,
+		From:    before.Status,
+		To:      after,
+		Time:    now,
+		Message: fmt.Sprintf("[JJApps Status] 服务 %s %s\n%s\n时间: %s", name, title, reason, formatTime(localeZH, now, "datetime")),
+	})
+}
+
+// runHealCommand 执行修复命令，失败时返回简短的原因，详细输出记录到日志
+func runHealCommand(args []string, timeout time.Duration) string {
+	if len(args) == 0 {
+		return "修复命令为空"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return fmt.Sprintf("修复命令执行超时（%s）", timeout)
+	}
+	if err != nil {
+		slog.Warn("修复命令执行失败", "command", strings.Join(args, " "), "error", err, "output", lastBytes(out.String(), 2048))
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Sprintf("修复命令退出码 %d", exitErr.ExitCode())
+		}
+		return "修复命令执行失败"
+	}
+	return ""
+}
+
+// lastBytes 返回s的最后n个字节
+func lastBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
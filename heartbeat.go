@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultHeartbeatGrace 心跳默认宽限时间
+const defaultHeartbeatGrace = 10 * time.Minute
+
+// HeartbeatRegistry 心跳记录，按令牌保存最近一次心跳时间，配置重载后仍然保留
+type HeartbeatRegistry struct {
+	lock sync.RWMutex
+	// started 启动时间，尚未收到心跳时以此为基准计算宽限期
+	started time.Time
+	beats   map[string]time.Time
+}
+
+// heartbeats 全局心跳记录
+var heartbeats = &HeartbeatRegistry{
+	started: time.Now(),
+	beats:   make(map[string]time.Time),
+}
+
+// Beat 记录一次心跳
+func (hr *HeartbeatRegistry) Beat(token string) {
+	hr.lock.Lock()
+	defer hr.lock.Unlock()
+	hr.beats[token] = time.Now()
+}
+
+// Last 返回最近一次心跳时间，尚未收到心跳时ok为false
+func (hr *HeartbeatRegistry) Last(token string) (last time.Time, ok bool) {
+	hr.lock.RLock()
+	defer hr.lock.RUnlock()
+	last, ok = hr.beats[token]
+	return last, ok
+}
+
+// HeartbeatChecker 被动心跳检查器，服务需定期请求 POST /api/heartbeat/:token，
+// 超过宽限时间未收到心跳时认为服务离线，适用于备份任务、定时任务等无法主动探测的服务
+type HeartbeatChecker struct {
+	// Token 心跳令牌
+	Token string `yaml:"token"`
+	// Grace 宽限时间，默认10m
+	Grace time.Duration `yaml:"grace"`
+}
+
+// CheckStatus 实现StatusChecker接口，检查最近一次心跳是否在宽限时间内
+func (h *HeartbeatChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	if h.Token == "" {
+		return StatusOffline, fmt.Errorf("心跳令牌不能为空")
+	}
+	grace := h.Grace
+	if grace == 0 {
+		grace = defaultHeartbeatGrace
+	}
+
+	last, ok := heartbeats.Last(h.Token)
+	if !ok {
+		// 启动后的第一个宽限期内不判定离线
+		if time.Since(heartbeats.started) <= grace {
+			return StatusOnline, nil
+		}
+		return StatusOffline, fmt.Errorf("启动后 %s 内未收到心跳", grace)
+	}
+	if elapsed := time.Since(last); elapsed > grace {
+		return StatusOffline, fmt.Errorf("最后一次心跳在 %s 前，超过宽限时间 %s", elapsed.Round(time.Second), grace)
+	}
+	return StatusOnline, nil
+}
+
+// hasHeartbeat 判断是否有服务使用该心跳令牌
+func (sm *ServiceManager) hasHeartbeat(token string) bool {
+	sm.lock.RLock()
+	defer sm.lock.RUnlock()
+	for _, service := range sm.services {
+		if hc, ok := service.Checker.(*HeartbeatChecker); ok && hc.Token == token {
+			return true
+		}
+	}
+	return false
+}
+
+// apiHeartbeatHandler 接收服务心跳
+func apiHeartbeatHandler(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" || !serviceManager.hasHeartbeat(token) {
+		apiError(c, http.StatusNotFound, "error.unknown_heartbeat")
+		return
+	}
+	heartbeats.Beat(token)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultHistogramRange 延迟分布默认统计范围
+	defaultHistogramRange = 24 * time.Hour
+	// maxHistogramBuckets 直方图最多的桶数
+	maxHistogramBuckets = 50
+)
+
+// defaultLatencyBuckets 延迟直方图默认的桶上界（毫秒）
+var defaultLatencyBuckets = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// LatencyHistogram 成功检查的延迟分布，单位为毫秒
+type LatencyHistogram struct {
+	// Bounds 各桶的上界（含），按升序排列，最后还有一个无上界的桶
+	Bounds []float64 `json:"bounds"`
+	// Counts 各桶的样本数（非累计），长度为len(Bounds)+1
+	Counts []int `json:"counts"`
+	// SumMS 延迟总和
+	SumMS float64 `json:"sum_ms"`
+	// Count 样本总数
+	Count int `json:"count"`
+}
+
+// validateLatencyBuckets 校验直方图的桶上界
+func validateLatencyBuckets(bounds []float64) error {
+	if len(bounds) > maxHistogramBuckets {
+		return fmt.Errorf("storage.latency_buckets最多%d个", maxHistogramBuckets)
+	}
+	for i, b := range bounds {
+		if b <= 0 || math.IsInf(b, 0) || math.IsNaN(b) {
+			return fmt.Errorf("storage.latency_buckets的值需为正数")
+		}
+		if i > 0 && b <= bounds[i-1] {
+			return fmt.Errorf("storage.latency_buckets需按升序排列且不能重复")
+		}
+	}
+	return nil
+}
+
+// newLatencyHistogram 创建空的直方图
+func newLatencyHistogram(bounds []float64) *LatencyHistogram {
+	return &LatencyHistogram{Bounds: bounds, Counts: make([]int, len(bounds)+1)}
+}
+
+// observe 记录一个延迟样本
+func (h *LatencyHistogram) observe(ms float64) {
+	idx, _ := slices.BinarySearch(h.Bounds, ms)
+	h.Counts[idx]++
+	h.SumMS += ms
+	h.Count++
+}
+
+// merge 将other累加到h，桶上界不同时（如修改了配置）忽略other并返回false
+func (h *LatencyHistogram) merge(other *LatencyHistogram) bool {
+	if other == nil || !slices.Equal(h.Bounds, other.Bounds) || len(other.Counts) != len(h.Counts) {
+		return false
+	}
+	for i, n := range other.Counts {
+		h.Counts[i] += n
+	}
+	h.SumMS += other.SumMS
+	h.Count += other.Count
+	return true
+}
+
+// clone 返回直方图的副本
+func (h *LatencyHistogram) clone() *LatencyHistogram {
+	if h == nil {
+		return nil
+	}
+	copied := *h
+	copied.Counts = slices.Clone(h.Counts)
+	return &copied
+}
+
+// quantile 按桶内线性插值估算分位数，q取值0~1，与Prometheus的histogram_quantile一致；
+// 落在无上界的桶时返回最大的上界，没有样本时返回nil
+func (h *LatencyHistogram) quantile(q float64) *float64 {
+	if h.Count == 0 {
+		return nil
+	}
+	rank := q * float64(h.Count)
+	cumulative := 0
+	for i, n := range h.Counts {
+		if n == 0 || float64(cumulative+n) < rank {
+			cumulative += n
+			continue
+		}
+		if i == len(h.Bounds) {
+			break
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = h.Bounds[i-1]
+		}
+		v := lower + (h.Bounds[i]-lower)*(rank-float64(cumulative))/float64(n)
+		return &v
+	}
+	if len(h.Bounds) == 0 {
+		return nil
+	}
+	v := h.Bounds[len(h.Bounds)-1]
+	return &v
+}
+
+// apiServiceHistogramHandler 返回服务在统计范围内的延迟分布与估算的分位数，按小时汇总合并
+func apiServiceHistogramHandler(c *gin.Context) {
+	name := c.Param("name")
+	if publicService(c, name) == nil {
+		apiError(c, http.StatusNotFound, "error.service_not_found")
+		return
+	}
+	span := defaultHistogramRange
+	if v := c.Query("range"); v != "" {
+		d, err := parseSpan(v)
+		if err != nil || d <= 0 {
+			apiError(c, http.StatusBadRequest, "error.invalid_param", "range")
+			return
+		}
+		span = d
+	}
+
+	rollups, err := serviceManager.History().QueryRollups(name, time.Now().Add(-span))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// 以当前的桶配置为准，配置修改前的汇总不计入
+	histogram := newLatencyHistogram(serviceManager.History().LatencyBuckets())
+	for i := range rollups {
+		histogram.merge(rollups[i].Histogram)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"service":   name,
+		"range":     span.String(),
+		"histogram": histogram,
+		"p50":       histogram.quantile(0.5),
+		"p95":       histogram.quantile(0.95),
+		"p99":       histogram.quantile(0.99),
+	})
+}
+
+// latencyMetric Prometheus中延迟直方图的指标名称
+const latencyMetric = "jjapps_status_check_latency_seconds"
+
+// metricsHandler 以Prometheus文本格式输出可见服务自启动以来的检查延迟直方图
+func metricsHandler(c *gin.Context) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s 成功检查的耗时分布\n", latencyMetric)
+	fmt.Fprintf(&b, "# TYPE %s histogram\n", latencyMetric)
+	history := serviceManager.History()
+	for _, service := range visibleServices(c) {
+		h := history.Histogram(service.Name)
+		if h == nil {
+			continue
+		}
+		label := promLabel(service.Name)
+		cumulative := 0
+		for i, bound := range h.Bounds {
+			cumulative += h.Counts[i]
+			le := strconv.FormatFloat(bound/1000, 'g', -1, 64)
+			fmt.Fprintf(&b, "%s_bucket{service=\"%s\",le=\"%s\"} %d\n", latencyMetric, label, le, cumulative)
+		}
+		fmt.Fprintf(&b, "%s_bucket{service=\"%s\",le=\"+Inf\"} %d\n", latencyMetric, label, h.Count)
+		fmt.Fprintf(&b, "%s_sum{service=\"%s\"} %s\n", latencyMetric, label, strconv.FormatFloat(h.SumMS/1000, 'g', -1, 64))
+		fmt.Fprintf(&b, "%s_count{service=\"%s\"} %d\n", latencyMetric, label, h.Count)
+	}
+	c.Header("Vary", "Authorization")
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
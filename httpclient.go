@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TransportConfig HTTP检查使用的连接配置，未设置的字段使用默认值
+type TransportConfig struct {
+	// MaxIdleConns 最大空闲连接数，默认100
+	MaxIdleConns int `yaml:"max_idle_conns,omitempty"`
+	// MaxIdleConnsPerHost 每个主机的最大空闲连接数，默认10
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host,omitempty"`
+	// MaxConnsPerHost 每个主机的最大连接数，默认不限制
+	MaxConnsPerHost int `yaml:"max_conns_per_host,omitempty"`
+	// IdleConnTimeout 空闲连接保持时间，默认90s
+	IdleConnTimeout time.Duration `yaml:"idle_conn_timeout,omitempty"`
+	// DialTimeout 建立连接超时时间，默认10s
+	DialTimeout time.Duration `yaml:"dial_timeout,omitempty"`
+	// TLSHandshakeTimeout TLS握手超时时间，默认10s
+	TLSHandshakeTimeout time.Duration `yaml:"tls_handshake_timeout,omitempty"`
+	// DisableKeepAlives 禁用连接复用，每次检查都建立新连接
+	DisableKeepAlives bool `yaml:"disable_keep_alives,omitempty"`
+	// HTTP2 是否尝试HTTP/2，默认启用
+	HTTP2 *bool `yaml:"http2,omitempty"`
+	// Proxy 代理地址，支持http://、https://、socks5://；为空时使用HTTP_PROXY等环境变量，
+	// 为direct时不使用代理
+	Proxy string `yaml:"proxy,omitempty"`
+	// Resolver DNS服务器地址，如1.1.1.1或[2606:4700:4700::1111]:53，为空时使用系统解析
+	Resolver string `yaml:"resolver,omitempty"`
+	// IPFamily 地址族：ipv4或ipv6，为空时两者均可，用于分别检查双栈服务
+	IPFamily string `yaml:"ip_family,omitempty"`
+}
+
+// validate 校验连接配置
+func (tc TransportConfig) validate() error {
+	switch tc.IPFamily {
+	case "", "ipv4", "ipv6":
+	default:
+		return fmt.Errorf("无效的地址族 '%s'，可选ipv4或ipv6", tc.IPFamily)
+	}
+	if tc.Resolver != "" {
+		if _, _, err := net.SplitHostPort(resolverAddr(tc.Resolver)); err != nil {
+			return fmt.Errorf("无效的DNS服务器地址 '%s'", tc.Resolver)
+		}
+	}
+	if tc.Proxy == "" || tc.Proxy == "direct" {
+		return nil
+	}
+	u, err := url.Parse(tc.Proxy)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("无效的代理地址 '%s'", tc.Proxy)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return nil
+	}
+	return fmt.Errorf("不支持的代理协议 '%s'，可选http、https、socks5", u.Scheme)
+}
+
+// proxy 返回传输层使用的代理选择函数
+func (tc TransportConfig) proxy() func(*http.Request) (*url.URL, error) {
+	switch tc.Proxy {
+	case "":
+		return http.ProxyFromEnvironment
+	case "direct":
+		return nil
+	}
+	u, err := url.Parse(tc.Proxy)
+	if err != nil {
+		return http.ProxyFromEnvironment
+	}
+	return http.ProxyURL(u)
+}
+
+// merge 返回以override中已设置的字段覆盖后的配置
+func (tc TransportConfig) merge(override TransportConfig) TransportConfig {
+	if override.MaxIdleConns != 0 {
+		tc.MaxIdleConns = override.MaxIdleConns
+	}
+	if override.MaxIdleConnsPerHost != 0 {
+		tc.MaxIdleConnsPerHost = override.MaxIdleConnsPerHost
+	}
+	if override.MaxConnsPerHost != 0 {
+		tc.MaxConnsPerHost = override.MaxConnsPerHost
+	}
+	if override.IdleConnTimeout != 0 {
+		tc.IdleConnTimeout = override.IdleConnTimeout
+	}
+	if override.DialTimeout != 0 {
+		tc.DialTimeout = override.DialTimeout
+	}
+	if override.TLSHandshakeTimeout != 0 {
+		tc.TLSHandshakeTimeout = override.TLSHandshakeTimeout
+	}
+	if override.DisableKeepAlives {
+		tc.DisableKeepAlives = true
+	}
+	if override.HTTP2 != nil {
+		tc.HTTP2 = override.HTTP2
+	}
+	if override.Proxy != "" {
+		tc.Proxy = override.Proxy
+	}
+	if override.Resolver != "" {
+		tc.Resolver = override.Resolver
+	}
+	if override.IPFamily != "" {
+		tc.IPFamily = override.IPFamily
+	}
+	return tc
+}
+
+// build 根据配置创建传输层
+func (tc TransportConfig) build() *http.Transport {
+	withDefault := func(v, def int) int {
+		if v == 0 {
+			return def
+		}
+		return v
+	}
+	withDefaultDuration := func(v, def time.Duration) time.Duration {
+		if v == 0 {
+			return def
+		}
+		return v
+	}
+	http2 := tc.HTTP2 == nil || *tc.HTTP2
+	transport := &http.Transport{
+		Proxy:                 tc.proxy(),
+		DialContext:           tc.dialContext(withDefaultDuration(tc.DialTimeout, 10*time.Second)),
+		MaxIdleConns:          withDefault(tc.MaxIdleConns, 100),
+		MaxIdleConnsPerHost:   withDefault(tc.MaxIdleConnsPerHost, 10),
+		MaxConnsPerHost:       tc.MaxConnsPerHost,
+		IdleConnTimeout:       withDefaultDuration(tc.IdleConnTimeout, 90*time.Second),
+		TLSHandshakeTimeout:   withDefaultDuration(tc.TLSHandshakeTimeout, 10*time.Second),
+		ExpectContinueTimeout: time.Second,
+		DisableKeepAlives:     tc.DisableKeepAlives,
+		ForceAttemptHTTP2:     http2,
+	}
+	if !http2 {
+		// TLSNextProto非nil且为空时不会协商HTTP/2
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	return transport
+}
+
+// resolverAddr 为未指定端口的DNS服务器地址补充53端口
+func resolverAddr(addr string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	return net.JoinHostPort(strings.Trim(addr, "[]"), "53")
+}
+
+// resolver 返回按配置的DNS服务器解析域名的解析器，未配置时返回nil，使用系统解析
+func (tc TransportConfig) resolver(timeout time.Duration) *net.Resolver {
+	if tc.Resolver == "" {
+		return nil
+	}
+	server := resolverAddr(tc.Resolver)
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{Timeout: timeout}).DialContext(ctx, network, server)
+		},
+	}
+}
+
+// dialContext 返回按配置的DNS服务器与地址族建立连接的拨号函数
+func (tc TransportConfig) dialContext(timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second, Resolver: tc.resolver(timeout)}
+	suffix := map[string]string{"ipv4": "4", "ipv6": "6"}[tc.IPFamily]
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if suffix != "" && (network == "tcp" || network == "udp") {
+			network += suffix
+		}
+		conn, err := dialer.DialContext(ctx, network, addr)
+		// 解析错误中的服务器地址来自系统配置，替换为实际使用的DNS服务器
+		var dnsErr *net.DNSError
+		if err != nil && tc.Resolver != "" && errors.As(err, &dnsErr) {
+			dnsErr.Server = resolverAddr(tc.Resolver)
+		}
+		return conn, err
+	}
+}
+
+var (
+	// transportConfig 全局的HTTP检查连接配置
+	transportConfig atomic.Pointer[TransportConfig]
+	// sharedTransport 所有未单独配置连接的HTTP检查共用的传输层
+	sharedTransport atomic.Pointer[http.Transport]
+)
+
+func init() {
+	setTransportConfig(TransportConfig{})
+}
+
+// setTransportConfig 更新全局连接配置并替换共享传输层，旧传输层的空闲连接会被关闭
+func setTransportConfig(cfg TransportConfig) {
+	transportConfig.Store(&cfg)
+	if old := sharedTransport.Swap(cfg.build()); old != nil {
+		old.CloseIdleConnections()
+	}
+}
+
+// checkTransport 嵌入到HTTP类检查器中，提供复用连接的HTTP客户端，
+// 检查器配置transport时使用在全局配置基础上覆盖后的独立传输层
+type checkTransport struct {
+	// Transport 单独的连接配置
+	Transport *TransportConfig `yaml:"transport,omitempty"`
+
+	once sync.Once
+	own  *http.Transport
+}
+
+// validate 校验检查器单独的连接配置
+func (ct *checkTransport) validate() error {
+	if ct.Transport == nil {
+		return nil
+	}
+	return ct.Transport.validate()
+}
+
+// config 返回全局连接配置以检查器单独的配置覆盖后的结果
+func (ct *checkTransport) config() TransportConfig {
+	tc := *transportConfig.Load()
+	if ct.Transport != nil {
+		tc = tc.merge(*ct.Transport)
+	}
+	return tc
+}
+
+// dialer 返回非HTTP检查建立连接的拨号函数，使用合并后的DNS服务器、地址族与代理
+func (ct *checkTransport) dialer(timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return ct.config().streamDialer(timeout)
+}
+
+// validateUDP 校验使用UDP的检查的连接配置：UDP无法经过代理，单独配置proxy时返回错误
+func (ct *checkTransport) validateUDP(kind string) error {
+	if err := ct.validate(); err != nil {
+		return err
+	}
+	if ct.Transport != nil && ct.Transport.Proxy != "" && ct.Transport.Proxy != "direct" {
+		return fmt.Errorf("%s使用UDP查询，不支持通过代理检查", kind)
+	}
+	return nil
+}
+
+// client 返回指定超时时间的HTTP客户端
+func (ct *checkTransport) client(timeout time.Duration) *http.Client {
+	if ct.Transport == nil {
+		return &http.Client{Transport: sharedTransport.Load(), Timeout: timeout}
+	}
+	ct.once.Do(func() {
+		ct.own = transportConfig.Load().merge(*ct.Transport).build()
+	})
+	return &http.Client{Transport: ct.own, Timeout: timeout}
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 支持的语言
+const (
+	localeZH = "zh"
+	localeEN = "en"
+)
+
+// catalogs 各语言的消息目录，消息中可使用fmt格式化占位符
+var catalogs = map[string]map[string]string{
+	localeZH: {
+		"status.online.label":      "正常",
+		"status.online.title":      "在线",
+		"status.offline.label":     "异常",
+		"status.offline.title":     "离线",
+		"status.degraded.label":    "降级",
+		"status.degraded.title":    "性能降级",
+		"status.maintenance.label": "维护",
+		"status.maintenance.title": "维护中",
+		"status.impacted.label":    "受影响",
+		"status.impacted.title":    "依赖服务异常",
+
+		"error.service_not_found":  "服务不存在",
+		"error.rate_limited":       "请求过于频繁，请稍后再试",
+		"error.unauthorized":       "认证失败",
+		"error.invalid_param":      "无效的%s参数",
+		"error.check_failed":       "检查失败",
+		"error.invalid_since":      "无效的since参数，需为RFC3339格式",
+		"error.invalid_step":       "无效的step参数，可选day或hour",
+		"error.invalid_format":     "无效的format参数，可选csv或json",
+		"error.invalid_request":    "无效的请求: %s",
+		"error.invalid_report":     "无效的上报数据",
+		"error.too_many_buckets":   "数据点过多，请增大step或缩小range",
+		"error.unknown_heartbeat":  "未知的心跳令牌",
+		"error.read_body":          "读取请求失败",
+		"error.config_too_large":   "配置内容过大",
+		"error.slo_not_configured": "服务未配置SLO",
+		"error.no_outage":          "服务 '%s' 当前没有进行中的故障",
+		"error.page_not_found":     "状态页不存在",
+		"error.invalid_email":      "无效的邮箱地址",
+		"error.no_subscriptions":   "未开放订阅",
+		"error.query_too_large":    "查询内容过大",
+		"error.forbidden":          "权限不足，该接口需要%s角色",
+		"error.ip_not_allowed":     "来源地址不在允许访问的网络中",
+		"error.login_disabled":     "未启用登录",
+		"error.login_failed":       "登录失败: %s",
+		"error.login_expired":      "登录请求无效或已过期，请重新登录",
+		"error.login_no_role":      "用户 %s 没有访问权限",
+		"error.pdf_unsupported":    "当前版本不支持生成PDF",
+		"error.snapshot_not_found": "配置快照不存在",
+		"error.no_boot_report":     "未启用启动检查",
+		"error.rotate_failed":      "轮换令牌失败",
+		"error.internal":           "服务器内部错误",
+
+		"page.lang":              "zh-CN",
+		"page.subtitle":          "实时监控服务状态",
+		"page.all_operational":   "所有系统正常运行",
+		"page.partial_outage":    "部分系统异常",
+		"page.major_outage":      "系统严重故障",
+		"page.title_outage":      "(%d 个异常) %s",
+		"page.title_outages":     "(%d 个异常) %s",
+		"page.last_updated":      "最后更新: %s",
+		"page.loading":           "加载中...",
+		"page.fetch_failed":      "获取失败",
+		"page.never_updated":     "尚未检查",
+		"page.system":            "主机资源",
+		"page.load":              "平均负载",
+		"page.memory":            "内存",
+		"page.network":           "网络",
+		"page.available":         "可用 %s",
+		"page.services":          "服务状态",
+		"page.url":               "地址:",
+		"page.last_check":        "检查时间:",
+		"page.age":               "（%s前）",
+		"page.checking":          "（检查中）",
+		"page.impacted_by":       "受影响于:",
+		"page.list_sep":          "、",
+		"page.override":          "状态说明:",
+		"page.override_manual":   "手动设置为%s",
+		"page.override_until":    "（至 %s）",
+		"page.ack":               "已确认:",
+		"page.ack_until":         "（静默至 %s）",
+		"page.regions":           "检查位置:",
+		"page.region_value":      "%s %s %sms",
+		"page.anomaly":           "性能异常:",
+		"page.anomaly_value":     "延迟 %sms，基线 %sms",
+		"page.invert":            "检查方式:",
+		"page.invert_value":      "预期不可达，可以访问时视为异常",
+		"page.process":           "进程资源:",
+		"page.process_value":     "CPU %s%% · 内存 %s · 已运行 %s",
+		"page.download":          "下载测速:",
+		"page.download_value":    "%s · %s",
+		"page.error":             "错误信息:",
+		"page.error_value":       "%s（连续失败 %d 次）",
+		"page.recent_events":     "最近事件",
+		"page.no_events":         "暂无事件",
+		"page.sort":              "排序",
+		"page.sort_order":        "默认顺序",
+		"page.sort_name":         "名称",
+		"page.sort_status":       "状态",
+		"page.sort_uptime":       "可用率",
+		"page.sort_latency":      "延迟",
+		"page.order_asc":         "升序",
+		"page.order_desc":        "降序",
+		"page.status_first":      "异常优先",
+		"page.apply":             "应用",
+		"page.services_range":    "第 %d-%d 个，共 %d 个",
+		"page.prev":              "上一页",
+		"page.next":              "下一页",
+		"page.back":              "返回状态页",
+		"page.checker":           "检查方式:",
+		"page.checker_interval":  "每 %s",
+		"page.checker_config":    "检查配置",
+		"page.slo":               "SLO:",
+		"page.slo_value":         "目标 %s · 本周期 %s · 剩余错误预算 %s",
+		"page.uptime_history":    "最近90天可用率",
+		"page.latency_history":   "最近24小时延迟",
+		"page.latency_max":       "最高 %s",
+		"page.no_data":           "暂无数据",
+		"page.subscribe":         "订阅通知",
+		"page.subscribe_hint":    "服务发生故障与恢复时通过邮件通知您",
+		"page.subscribe_email":   "邮箱地址",
+		"page.subscribe_submit":  "订阅",
+		"page.subscribe_failed":  "订阅失败，请稍后再试",
+		"page.refresh":           "刷新状态",
+		"page.refreshing":        "刷新中...",
+		"page.about":             "关于我们",
+		"page.source":            "源码链接",
+		"page.contact":           "联系我们",
+		"page.login":             "登录",
+		"page.logout":            "退出登录（%s）",
+		"page.copyright":         "保留所有权利.",
+		"page.powered_by_prefix": "由 ",
+		"page.powered_by_suffix": " 强力驱动",
+
+		"maintenance.calendar": "JJApps 计划维护",
+		"maintenance.services": "影响服务: %s",
+
+		"subscribe.pending":            "确认邮件已发送，请打开邮件中的链接完成订阅",
+		"subscribe.confirmed":          "订阅成功，服务发生故障与恢复时您将收到邮件通知",
+		"subscribe.unsubscribe_prompt": "确定不再接收服务状态邮件通知吗？",
+		"subscribe.unsubscribe":        "退订",
+		"subscribe.unsubscribed":       "已退订，您将不再收到服务状态邮件通知",
+		"subscribe.invalid_link":       "链接无效或已过期",
+		"subscribe.confirm_subject":    "[JJApps Status] 请确认订阅服务状态通知",
+		"subscribe.confirm_body":       "您好，\n\n请打开以下链接确认订阅服务状态通知：\n%s\n\n链接在 %s 内有效。如果您没有订阅，请忽略此邮件。",
+		"subscribe.incident_subject":   "[JJApps Status] 服务 %s 发生故障",
+		"subscribe.incident_body":      "服务 %s 于 %s 发生故障，我们正在处理。\n\n查看状态: %s\n退订: %s",
+		"subscribe.resolved_subject":   "[JJApps Status] 服务 %s 已恢复",
+		"subscribe.resolved_body":      "服务 %s 已于 %s 恢复正常。\n\n查看状态: %s\n退订: %s",
+
+		"event.latency_warn":      "延迟偏高",
+		"event.latency_critical":  "延迟严重",
+		"event.latency_recovered": "延迟恢复",
+		"event.latency_anomaly":   "性能异常",
+		"event.anomaly_recovered": "性能异常恢复",
+		"event.heal_succeeded":    "自动修复成功",
+		"event.heal_failed":       "自动修复失败",
+
+		"uptime.days":    "%d天%d小时",
+		"uptime.hours":   "%d小时%d分",
+		"uptime.minutes": "%d分",
+
+		"boot.all_ok":      "[JJApps Status] 状态页已启动，%d 个服务均正常",
+		"boot.outages":     "[JJApps Status] 状态页已启动，%d 个服务中 %d 个异常:",
+		"boot.impacted_by": "（依赖 %s 不可用）",
+		"boot.error":       "（%s）",
+		"boot.time":        "时间: %s",
+
+		"agent.stale": "探针 '%s' 已失联，最后上报于 %s",
+
+		"report.title":          "%s 可用性报告",
+		"report.period":         "统计周期: %s 至 %s",
+		"report.generated":      "生成时间: %s",
+		"report.summary":        "可用性概览",
+		"report.service":        "服务",
+		"report.uptime":         "可用率",
+		"report.slo":            "SLO目标",
+		"report.slo_met":        "达标",
+		"report.slo_missed":     "未达标",
+		"report.checks":         "检查次数",
+		"report.incident_count": "故障次数",
+		"report.mttr":           "平均恢复时间",
+		"report.latency":        "延迟（P50/P95/P99）",
+		"report.incidents":      "故障记录",
+		"report.no_incidents":   "本月没有故障",
+		"report.start":          "开始时间",
+		"report.end":            "恢复时间",
+		"report.duration":       "持续时间",
+		"report.reason":         "原因",
+		"report.ongoing":        "未恢复",
+		"report.subject":        "[JJApps Status] %s 月度可用性报告",
+
+		"time.datetime": "2006-01-02 15:04:05",
+		"time.time":     "15:04:05",
+		"time.short":    "01-02 15:04",
+		"time.date":     "2006-01-02",
+	},
+	localeEN: {
+		"status.online.label":      "Operational",
+		"status.online.title":      "Online",
+		"status.offline.label":     "Down",
+		"status.offline.title":     "Offline",
+		"status.degraded.label":    "Degraded",
+		"status.degraded.title":    "Degraded performance",
+		"status.maintenance.label": "Maintenance",
+		"status.maintenance.title": "Under maintenance",
+		"status.impacted.label":    "Impacted",
+		"status.impacted.title":    "Dependency unavailable",
+
+		"error.service_not_found":  "service not found",
+		"error.rate_limited":       "too many requests, please try again later",
+		"error.unauthorized":       "authentication failed",
+		"error.invalid_param":      "invalid %s parameter",
+		"error.check_failed":       "check failed",
+		"error.invalid_since":      "invalid since parameter, expected RFC3339",
+		"error.invalid_step":       "invalid step parameter, expected day or hour",
+		"error.invalid_format":     "invalid format parameter, expected csv or json",
+		"error.invalid_request":    "invalid request: %s",
+		"error.invalid_report":     "invalid report payload",
+		"error.too_many_buckets":   "too many data points, increase step or narrow range",
+		"error.unknown_heartbeat":  "unknown heartbeat token",
+		"error.read_body":          "failed to read request body",
+		"error.config_too_large":   "config payload too large",
+		"error.slo_not_configured": "no SLO configured for this service",
+		"error.no_outage":          "service '%s' has no ongoing outage",
+		"error.page_not_found":     "page not found",
+		"error.invalid_email":      "invalid email address",
+		"error.no_subscriptions":   "subscriptions are not enabled",
+		"error.query_too_large":    "query payload too large",
+		"error.forbidden":          "insufficient permissions, %s role required",
+		"error.ip_not_allowed":     "source address is not in an allowed network",
+		"error.login_disabled":     "login is not enabled",
+		"error.login_failed":       "login failed: %s",
+		"error.login_expired":      "login request is invalid or has expired, please log in again",
+		"error.login_no_role":      "user %s is not allowed to access",
+		"error.pdf_unsupported":    "PDF reports are not supported by this build",
+		"error.snapshot_not_found": "config snapshot not found",
+		"error.no_boot_report":     "boot report is not enabled",
+		"error.rotate_failed":      "failed to rotate token",
+		"error.internal":           "internal server error",
+
+		"page.lang":              "en",
+		"page.subtitle":          "Real-time service status",
+		"page.all_operational":   "All systems operational",
+		"page.partial_outage":    "Some systems are experiencing issues",
+		"page.major_outage":      "Major outage",
+		"page.title_outage":      "(%d outage) %s",
+		"page.title_outages":     "(%d outages) %s",
+		"page.last_updated":      "Last updated: %s",
+		"page.loading":           "Loading...",
+		"page.fetch_failed":      "failed to fetch",
+		"page.never_updated":     "never",
+		"page.system":            "Host resources",
+		"page.load":              "Load average",
+		"page.memory":            "Memory",
+		"page.network":           "Network",
+		"page.available":         "%s free",
+		"page.services":          "Services",
+		"page.url":               "URL:",
+		"page.last_check":        "Checked at:",
+		"page.age":               " (%s ago)",
+		"page.checking":          " (checking)",
+		"page.impacted_by":       "Impacted by:",
+		"page.list_sep":          ", ",
+		"page.override":          "Status note:",
+		"page.override_manual":   "Manually set to %s",
+		"page.override_until":    " (until %s)",
+		"page.ack":               "Acknowledged:",
+		"page.ack_until":         " (silenced until %s)",
+		"page.regions":           "Regions:",
+		"page.region_value":      "%s %s %sms",
+		"page.anomaly":           "Performance anomaly:",
+		"page.anomaly_value":     "%sms vs. baseline %sms",
+		"page.invert":            "Check mode:",
+		"page.invert_value":      "expected unreachable, reachable means failure",
+		"page.process":           "Process:",
+		"page.process_value":     "CPU %s%% · Memory %s · Up %s",
+		"page.download":          "Download:",
+		"page.download_value":    "%s at %s",
+		"page.error":             "Error:",
+		"page.error_value":       "%s (%d consecutive failures)",
+		"page.recent_events":     "Recent events",
+		"page.no_events":         "No recent events",
+		"page.sort":              "Sort",
+		"page.sort_order":        "Default",
+		"page.sort_name":         "Name",
+		"page.sort_status":       "Status",
+		"page.sort_uptime":       "Uptime",
+		"page.sort_latency":      "Latency",
+		"page.order_asc":         "Ascending",
+		"page.order_desc":        "Descending",
+		"page.status_first":      "Problems first",
+		"page.apply":             "Apply",
+		"page.services_range":    "%d-%d of %d",
+		"page.prev":              "Previous",
+		"page.next":              "Next",
+		"page.back":              "Back to status page",
+		"page.checker":           "Checker:",
+		"page.checker_interval":  "every %s",
+		"page.checker_config":    "Checker configuration",
+		"page.slo":               "SLO:",
+		"page.slo_value":         "target %s · this period %s · error budget left %s",
+		"page.uptime_history":    "Uptime (last 90 days)",
+		"page.latency_history":   "Latency (last 24 hours)",
+		"page.latency_max":       "peak %s",
+		"page.no_data":           "No data",
+		"page.subscribe":         "Subscribe to updates",
+		"page.subscribe_hint":    "Get an email when services go down and recover",
+		"page.subscribe_email":   "Email address",
+		"page.subscribe_submit":  "Subscribe",
+		"page.subscribe_failed":  "Subscription failed, please try again later",
+		"page.refresh":           "Refresh",
+		"page.refreshing":        "Refreshing...",
+		"page.about":             "About",
+		"page.source":            "Source",
+		"page.contact":           "Contact",
+		"page.login":             "Log in",
+		"page.logout":            "Log out (%s)",
+		"page.copyright":         "All rights reserved.",
+		"page.powered_by_prefix": "Powered by ",
+		"page.powered_by_suffix": "",
+
+		"maintenance.calendar": "JJApps scheduled maintenance",
+		"maintenance.services": "Affected services: %s",
+
+		"subscribe.pending":            "A confirmation email has been sent, please open the link in it to complete your subscription",
+		"subscribe.confirmed":          "Subscribed. You will be notified by email when services go down and recover",
+		"subscribe.unsubscribe_prompt": "Stop receiving service status emails?",
+		"subscribe.unsubscribe":        "Unsubscribe",
+		"subscribe.unsubscribed":       "Unsubscribed. You will no longer receive service status emails",
+		"subscribe.invalid_link":       "This link is invalid or has expired",
+		"subscribe.confirm_subject":    "[JJApps Status] Please confirm your subscription",
+		"subscribe.confirm_body":       "Hello,\n\nPlease open the following link to confirm your subscription to service status notifications:\n%s\n\nThe link is valid for %s. If you did not subscribe, please ignore this email.",
+		"subscribe.incident_subject":   "[JJApps Status] %s is down",
+		"subscribe.incident_body":      "%s went down at %s and we are working on it.\n\nStatus page: %s\nUnsubscribe: %s",
+		"subscribe.resolved_subject":   "[JJApps Status] %s has recovered",
+		"subscribe.resolved_body":      "%s recovered at %s.\n\nStatus page: %s\nUnsubscribe: %s",
+
+		"event.latency_warn":      "High latency",
+		"event.latency_critical":  "Critical latency",
+		"event.latency_recovered": "Latency recovered",
+		"event.latency_anomaly":   "Performance anomaly",
+		"event.anomaly_recovered": "Anomaly recovered",
+		"event.heal_succeeded":    "Auto-heal succeeded",
+		"event.heal_failed":       "Auto-heal failed",
+
+		"uptime.days":    "%dd %dh",
+		"uptime.hours":   "%dh %dm",
+		"uptime.minutes": "%dm",
+
+		"boot.all_ok":      "[JJApps Status] Status page started, all %d services are operational",
+		"boot.outages":     "[JJApps Status] Status page started, %[2]d of %[1]d services have issues:",
+		"boot.impacted_by": " (dependency %s unavailable)",
+		"boot.error":       " (%s)",
+		"boot.time":        "Time: %s",
+
+		"agent.stale": "agent '%s' is unreachable, last report at %s",
+
+		"report.title":          "Availability report %s",
+		"report.period":         "Period: %s to %s",
+		"report.generated":      "Generated at %s",
+		"report.summary":        "Summary",
+		"report.service":        "Service",
+		"report.uptime":         "Uptime",
+		"report.slo":            "SLO target",
+		"report.slo_met":        "met",
+		"report.slo_missed":     "missed",
+		"report.checks":         "Checks",
+		"report.incident_count": "Incidents",
+		"report.mttr":           "MTTR",
+		"report.latency":        "Latency (P50/P95/P99)",
+		"report.incidents":      "Incidents",
+		"report.no_incidents":   "No incidents this month",
+		"report.start":          "Started",
+		"report.end":            "Recovered",
+		"report.duration":       "Duration",
+		"report.reason":         "Reason",
+		"report.ongoing":        "Ongoing",
+		"report.subject":        "[JJApps Status] Monthly availability report %s",
+
+		"time.datetime": "Jan 2, 2006 15:04:05",
+		"time.time":     "15:04:05",
+		"time.short":    "Jan 2 15:04",
+		"time.date":     "Jan 2, 2006",
+	},
+}
+
+// defaultLocale 配置的默认语言
+var defaultLocale atomic.Value
+
+func init() {
+	defaultLocale.Store(localeZH)
+}
+
+// validateLocale 校验配置的语言，为空表示使用中文
+func validateLocale(locale string) error {
+	if _, ok := catalogs[locale]; locale != "" && !ok {
+		return fmt.Errorf("不支持的语言 '%s'", locale)
+	}
+	return nil
+}
+
+// prepareDefaultLocale 校验语言，返回设置默认语言的函数，为空时使用中文
+func prepareDefaultLocale(locale string) (func(), error) {
+	if err := validateLocale(locale); err != nil {
+		return nil, err
+	}
+	if locale == "" {
+		locale = localeZH
+	}
+	return func() { defaultLocale.Store(locale) }, nil
+}
+
+// setDefaultLocale 设置默认语言，为空时使用中文
+func setDefaultLocale(locale string) error {
+	apply, err := prepareDefaultLocale(locale)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// translate 返回指定语言的消息，缺失时依次回退到默认语言与消息键
+func translate(locale, key string, args ...interface{}) string {
+	msg, ok := catalogs[locale][key]
+	if !ok {
+		if msg, ok = catalogs[defaultLocale.Load().(string)][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// matchLocale 按权重解析Accept-Language，返回第一个支持的语言
+func matchLocale(header string) (string, bool) {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	tags := make([]tag, 0)
+	for _, part := range strings.Split(header, ",") {
+		lang, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(lang), "-")
+		if primary != "" && q > 0 {
+			tags = append(tags, tag{lang: primary, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	for _, t := range tags {
+		if _, ok := catalogs[t.lang]; ok {
+			return t.lang, true
+		}
+	}
+	return "", false
+}
+
+// requestLocale 确定请求使用的语言：依次为lang参数、Accept-Language与配置的默认语言
+func requestLocale(c *gin.Context) string {
+	if lang := c.Query("lang"); lang != "" {
+		if locale, ok := matchLocale(lang); ok {
+			return locale
+		}
+	}
+	if locale, ok := matchLocale(c.GetHeader("Accept-Language")); ok {
+		return locale
+	}
+	return defaultLocale.Load().(string)
+}
+
+// tr 返回请求语言的消息
+func tr(c *gin.Context, key string, args ...interface{}) string {
+	return translate(requestLocale(c), key, args...)
+}
+
+// apiError 以请求语言返回错误响应
+func apiError(c *gin.Context, code int, key string, args ...interface{}) {
+	c.JSON(code, gin.H{"error": tr(c, key, args...)})
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errUnexpectedlyReachable 反向检查的目标可以访问
+var errUnexpectedlyReachable = errors.New("目标可以访问，预期应不可达")
+
+// validateInvert 校验反向检查配置：延迟与重试针对的是可以访问的目标，与反向检查同时使用没有意义
+func (sc *ServiceConfig) validateInvert() error {
+	if !sc.Invert {
+		return nil
+	}
+	if sc.Retry != nil || sc.Latency != nil || sc.Anomaly != nil {
+		return fmt.Errorf("invert不能与retry、latency或anomaly同时使用")
+	}
+	return nil
+}
+
+// invertResult 反转检查结果：检查失败（目标不可达）时视为在线，检查成功时视为离线
+func invertResult(status ServiceStatus, err error) (ServiceStatus, error) {
+	if err != nil || status == StatusOffline {
+		return StatusOnline, nil
+	}
+	return StatusOffline, errUnexpectedlyReachable
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/itchyny/gojq"
+)
+
+// maxJSONBody JSON检查读取响应体的最大字节数
+const maxJSONBody = 4 << 20
+
+// JSONChecker 请求REST接口并使用jq表达式校验返回的JSON。
+// 配置expect时表达式的第一个结果需与expect相等；未配置时结果需为真值（非false与null），
+// 例如 query: '.status == "ok"'
+type JSONChecker struct {
+	// URL 要请求的URL
+	URL string `yaml:"url"`
+	// Method 请求方法，默认GET
+	Method string `yaml:"method"`
+	// Headers 请求头
+	Headers map[string]string `yaml:"headers"`
+	// Query jq表达式，如.status或.checks[] | select(.name == "db") | .ok
+	Query string `yaml:"query"`
+	// Expect 期望的值
+	Expect interface{} `yaml:"expect"`
+	// Timeout 超时时间
+	Timeout time.Duration `yaml:"timeout"`
+
+	checkTransport `yaml:",inline"`
+
+	once sync.Once
+	code *gojq.Code
+	err  error
+}
+
+// compile 编译jq表达式
+func (j *JSONChecker) compile() (*gojq.Code, error) {
+	j.once.Do(func() {
+		query, err := gojq.Parse(j.Query)
+		if err != nil {
+			j.err = fmt.Errorf("无效的jq表达式 '%s': %v", j.Query, err)
+			return
+		}
+		j.code, j.err = gojq.Compile(query)
+	})
+	return j.code, j.err
+}
+
+// normalizeJSON 将值转换为JSON解码后的通用形式，便于比较YAML与JSON中的值
+func normalizeJSON(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// CheckStatus 实现StatusChecker接口
+func (j *JSONChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	code, err := j.compile()
+	if err != nil {
+		return StatusOffline, err
+	}
+	method := j.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, j.URL, nil)
+	if err != nil {
+		return StatusOffline, fmt.Errorf("创建HTTP请求失败: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range j.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := j.client(j.Timeout).Do(req)
+	if err != nil {
+		return StatusOffline, fmt.Errorf("HTTP请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return StatusOffline, &HTTPStatusError{Code: resp.StatusCode}
+	}
+
+	var body interface{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJSONBody)).Decode(&body); err != nil {
+		return StatusOffline, fmt.Errorf("解析JSON响应失败: %v", err)
+	}
+	iter := code.RunWithContext(ctx, body)
+	v, ok := iter.Next()
+	if !ok {
+		return StatusOffline, fmt.Errorf("表达式 '%s' 没有结果", j.Query)
+	}
+	if err, isErr := v.(error); isErr {
+		return StatusOffline, fmt.Errorf("执行表达式 '%s' 失败: %v", j.Query, err)
+	}
+
+	if j.Expect != nil {
+		if !reflect.DeepEqual(normalizeJSON(v), normalizeJSON(j.Expect)) {
+			return StatusOffline, fmt.Errorf("表达式 '%s' 的结果为 %s，期望 %s", j.Query, jsonString(v), jsonString(j.Expect))
+		}
+		return StatusOnline, nil
+	}
+	if v == nil || v == false {
+		return StatusOffline, fmt.Errorf("表达式 '%s' 的结果为 %s", j.Query, jsonString(v))
+	}
+	return StatusOnline, nil
+}
+
+// jsonString 将值格式化为JSON字符串
+func jsonString(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultLatencyRange 延迟曲线默认时间范围
+	defaultLatencyRange = 24 * time.Hour
+	// defaultLatencyStep 延迟曲线默认时间间隔
+	defaultLatencyStep = 5 * time.Minute
+	// maxLatencyBuckets 延迟曲线最大数据点数
+	maxLatencyBuckets = 2000
+)
+
+// LatencyBucket 一个时间段内的延迟统计，单位为毫秒
+type LatencyBucket struct {
+	// Start 时间段开始时间
+	Start time.Time `json:"start"`
+	// Count 样本数
+	Count int `json:"count"`
+	// Avg 平均延迟，没有样本时为nil
+	Avg *float64 `json:"avg"`
+	// P95 95分位延迟，没有样本时为nil
+	P95 *float64 `json:"p95"`
+	// Max 最大延迟，没有样本时为nil
+	Max *float64 `json:"max"`
+}
+
+// percentile 计算已排序样本的分位数（线性插值），p取值0~100
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo, hi := int(math.Floor(rank)), int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+// maxSpanDays 以天表示的时间长度的最大值，超出后time.Duration溢出
+const maxSpanDays = int(math.MaxInt64 / int64(24*time.Hour))
+
+// parseSpan 解析时间长度，在time.ParseDuration基础上支持天（如"7d"）
+func parseSpan(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		// 与time.ParseDuration一致，超出time.Duration范围时返回错误而不是溢出
+		if n > maxSpanDays || n < -maxSpanDays {
+			return 0, fmt.Errorf("时间长度 %q 超出范围", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// aggregateLatency 将成功检查的延迟按时间段汇总
+func aggregateLatency(results []CheckResult, start time.Time, step time.Duration, count int) []LatencyBucket {
+	samples := make([][]float64, count)
+	for _, result := range results {
+		if !isUp(result.Status) || result.Error != "" {
+			continue
+		}
+		idx := int(result.Time.Sub(start) / step)
+		if idx < 0 || idx >= count {
+			continue
+		}
+		samples[idx] = append(samples[idx], result.LatencyMS)
+	}
+
+	buckets := make([]LatencyBucket, count)
+	for i := range buckets {
+		buckets[i].Start = start.Add(time.Duration(i) * step)
+		values := samples[i]
+		if len(values) == 0 {
+			continue
+		}
+		sort.Float64s(values)
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		avg := sum / float64(len(values))
+		p95 := percentile(values, 95)
+		max := values[len(values)-1]
+		buckets[i].Count = len(values)
+		buckets[i].Avg, buckets[i].P95, buckets[i].Max = &avg, &p95, &max
+	}
+	return buckets
+}
+
+// apiServiceLatencyHandler 返回服务的延迟曲线数据
+func apiServiceLatencyHandler(c *gin.Context) {
+	name := c.Param("name")
+	if publicService(c, name) == nil {
+		apiError(c, http.StatusNotFound, "error.service_not_found")
+		return
+	}
+
+	span, step := defaultLatencyRange, defaultLatencyStep
+	if v := c.Query("range"); v != "" {
+		d, err := parseSpan(v)
+		if err != nil || d <= 0 {
+			apiError(c, http.StatusBadRequest, "error.invalid_param", "range")
+			return
+		}
+		span = d
+	}
+	if v := c.Query("step"); v != "" {
+		d, err := parseSpan(v)
+		if err != nil || d <= 0 {
+			apiError(c, http.StatusBadRequest, "error.invalid_param", "step")
+			return
+		}
+		step = d
+	}
+	// 不使用(span+step-1)/step向上取整，避免span与step都很大时相加溢出
+	count := int(span / step)
+	if span%step != 0 {
+		count++
+	}
+	if count > maxLatencyBuckets {
+		apiError(c, http.StatusBadRequest, "error.too_many_buckets")
+		return
+	}
+
+	end := time.Now().Truncate(step).Add(step)
+	start := end.Add(-time.Duration(count) * step)
+	results, err := serviceManager.History().Query(name, start, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"service": name,
+		"range":   span.String(),
+		"step":    step.String(),
+		"buckets": aggregateLatency(results, start, step, count),
+	})
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxServicesLimit 服务列表单页的最大条数
+	maxServicesLimit = 500
+	// sortUptimeDays 按可用率排序时统计的天数
+	sortUptimeDays = 30
+)
+
+// serviceSorts 服务列表支持的排序字段
+var serviceSorts = map[string]bool{
+	"order":   true,
+	"name":    true,
+	"status":  true,
+	"uptime":  true,
+	"latency": true,
+}
+
+// ServiceListOptions 服务列表的排序与分页参数
+type ServiceListOptions struct {
+	// Sort 排序字段：order（配置顺序，默认）、name、status（按严重程度）、uptime（最近30天可用率）或latency（最近一次延迟）
+	Sort string
+	// Desc 是否降序
+	Desc bool
+	// StatusFirst 是否将异常的服务排在前面，异常服务之间按严重程度从高到低排列
+	StatusFirst bool
+	// Offset 跳过的服务数
+	Offset int
+	// Limit 返回的最大服务数，为0时不分页
+	Limit int
+}
+
+// validateServiceSort 校验排序字段
+func validateServiceSort(sort string) error {
+	if sort != "" && !serviceSorts[sort] {
+		return fmt.Errorf("不支持的排序字段 '%s'，可选值为order、name、status、uptime或latency", sort)
+	}
+	return nil
+}
+
+// parseServiceListOptions 解析sort、order、status_first、limit与offset查询参数，未指定时使用defaults；
+// 参数无效时返回错误响应并返回false
+func parseServiceListOptions(c *gin.Context, defaults ServiceListOptions) (ServiceListOptions, bool) {
+	opts := defaults
+	if v := c.Query("sort"); v != "" {
+		if !serviceSorts[v] {
+			apiError(c, http.StatusBadRequest, "error.invalid_param", "sort")
+			return opts, false
+		}
+		opts.Sort = v
+	}
+	switch c.Query("order") {
+	case "":
+	case "asc":
+		opts.Desc = false
+	case "desc":
+		opts.Desc = true
+	default:
+		apiError(c, http.StatusBadRequest, "error.invalid_param", "order")
+		return opts, false
+	}
+	if v := c.Query("status_first"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "error.invalid_param", "status_first")
+			return opts, false
+		}
+		opts.StatusFirst = b
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int
+		min  int
+	}{{"limit", &opts.Limit, 1}, {"offset", &opts.Offset, 0}} {
+		if v := c.Query(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < p.min {
+				apiError(c, http.StatusBadRequest, "error.invalid_param", p.name)
+				return opts, false
+			}
+			*p.dst = n
+		}
+	}
+	if opts.Limit > maxServicesLimit {
+		opts.Limit = maxServicesLimit
+	}
+	return opts, true
+}
+
+// query 返回与默认值不同的参数，用于生成页面脚本的状态接口地址与翻页链接
+func (o ServiceListOptions) query() url.Values {
+	q := url.Values{}
+	if o.Sort != "" && o.Sort != "order" {
+		q.Set("sort", o.Sort)
+	}
+	if o.Desc {
+		q.Set("order", "desc")
+	}
+	if o.StatusFirst {
+		q.Set("status_first", "true")
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		q.Set("offset", strconv.Itoa(o.Offset))
+	}
+	return q
+}
+
+// ServiceList 排序与分页后的服务列表
+type ServiceList struct {
+	// Services 当前页的服务
+	Services []*Service
+	// Total 分页前的服务总数
+	Total int
+	// Counts 分页前各状态的服务数
+	Counts map[string]int
+	// State 分页前全部服务的整体状态
+	State string
+	// Outages 分页前异常的服务数
+	Outages int
+	// Options 生效的排序与分页参数
+	Options ServiceListOptions
+}
+
+// listServices 按参数排序并分页，services需已按配置顺序排列
+func listServices(services []*Service, opts ServiceListOptions) ServiceList {
+	summary := summarize(services, configReloader.Current().Summary)
+	list := ServiceList{Total: summary.Total, Counts: summary.Counts, State: summary.State, Outages: summary.Outages, Options: opts}
+
+	sorted := make([]*Service, len(services))
+	copy(sorted, services)
+	position := make(map[*Service]int, len(services))
+	for i, service := range services {
+		position[service] = i
+	}
+	var uptime map[*Service]*float64
+	if opts.Sort == "uptime" {
+		uptime = servicesUptime(sorted, time.Now())
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if opts.StatusFirst {
+			if sa, sb := statusSeverity[a.Status], statusSeverity[b.Status]; sa != sb {
+				return sa > sb
+			}
+		}
+		switch opts.Sort {
+		case "name":
+			return lessOrdered(a.Name, b.Name, opts.Desc)
+		case "status":
+			return lessOrdered(statusSeverity[a.Status], statusSeverity[b.Status], opts.Desc)
+		case "uptime":
+			// 没有数据的服务始终排在最后
+			ua, ub := uptime[a], uptime[b]
+			if ua == nil || ub == nil {
+				return ua != nil && ub == nil
+			}
+			return lessOrdered(*ua, *ub, opts.Desc)
+		case "latency":
+			// 尚未检查的服务始终排在最后
+			ca, cb := !a.LastChecked.IsZero(), !b.LastChecked.IsZero()
+			if !ca || !cb {
+				return ca && !cb
+			}
+			return lessOrdered(a.LatencyMS, b.LatencyMS, opts.Desc)
+		default:
+			return lessOrdered(position[a], position[b], opts.Desc)
+		}
+	})
+
+	start := min(opts.Offset, len(sorted))
+	end := len(sorted)
+	if opts.Limit > 0 {
+		end = min(start+opts.Limit, end)
+	}
+	list.Services = sorted[start:end]
+	return list
+}
+
+// lessOrdered 比较两个值，desc为true时按降序比较
+func lessOrdered[T int | float64 | string](a, b T, desc bool) bool {
+	if desc {
+		return a > b
+	}
+	return a < b
+}
+
+// servicesUptime 计算服务最近sortUptimeDays天的可用率，远程探针上报的服务与没有数据的服务为nil
+func servicesUptime(services []*Service, now time.Time) map[*Service]*float64 {
+	uptime := make(map[*Service]*float64, len(services))
+	start, step, count, _ := uptimeRange("day", sortUptimeDays, now)
+	history := serviceManager.History()
+	for _, service := range services {
+		if service.Host != "" {
+			continue
+		}
+		rollups, err := history.QueryRollups(service.Name, start)
+		if err != nil {
+			continue
+		}
+		uptime[service] = overallUptime(aggregateUptime(rollups, start, step, count))
+	}
+	return uptime
+}
+
+// statusResponse 状态接口返回的服务列表与分页信息
+func (l ServiceList) statusResponse() gin.H {
+	return gin.H{
+		"services":     l.Services,
+		"total":        l.Total,
+		"counts":       l.Counts,
+		"state":        l.State,
+		"outages":      l.Outages,
+		"offset":       l.Options.Offset,
+		"limit":        l.Options.Limit,
+		"last_updated": lastUpdatedValue(),
+	}
+}
+
+// Pager 状态页的排序与翻页信息
+type Pager struct {
+	// Options 生效的排序与分页参数
+	Options ServiceListOptions
+	// Total 服务总数
+	Total int
+	// From 当前页第一个服务的序号（从1开始），没有服务时为0
+	From int
+	// To 当前页最后一个服务的序号
+	To int
+	// PrevURL 上一页的地址，没有上一页时为空
+	PrevURL string
+	// NextURL 下一页的地址，没有下一页时为空
+	NextURL string
+	// Keep 排序表单需要保留的其他查询参数
+	Keep url.Values
+}
+
+// listQueried 判断请求是否指定了排序或分页参数
+func listQueried(c *gin.Context) bool {
+	for _, name := range []string{"sort", "order", "status_first", "limit", "offset"} {
+		if c.Query(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// newPager 根据当前请求生成翻页信息，保留请求中的其他查询参数（如token、lang）
+func newPager(c *gin.Context, list ServiceList) *Pager {
+	opts := list.Options
+	p := &Pager{Options: opts, Total: list.Total, Keep: c.Request.URL.Query()}
+	for _, name := range []string{"sort", "order", "status_first", "offset"} {
+		p.Keep.Del(name)
+	}
+	if len(list.Services) > 0 {
+		p.From = opts.Offset + 1
+		p.To = opts.Offset + len(list.Services)
+	}
+	if opts.Limit == 0 {
+		return p
+	}
+	link := func(offset int) string {
+		q := opts
+		q.Offset = offset
+		values := q.query()
+		for name, v := range p.Keep {
+			if name != "limit" {
+				values[name] = v
+			}
+		}
+		return c.Request.URL.Path + "?" + values.Encode()
+	}
+	if opts.Offset > 0 {
+		p.PrevURL = link(max(opts.Offset-opts.Limit, 0))
+	}
+	if opts.Offset+opts.Limit < list.Total {
+		p.NextURL = link(opts.Offset + opts.Limit)
+	}
+	return p
+}
+
+// Sorts 返回页面排序选项
+func (p *Pager) Sorts() []string {
+	return []string{"order", "name", "status", "uptime", "latency"}
+}
+
+// Sort 返回当前的排序字段
+func (p *Pager) Sort() string {
+	if p.Options.Sort == "" {
+		return "order"
+	}
+	return p.Options.Sort
+}
@@ -1,9 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,139 +22,563 @@ import (
 type PageData struct {
 	// Title 页面标题
 	Title string
+	// DocumentTitle 浏览器标签页标题，存在异常服务时带有异常数
+	DocumentTitle string
+	// State 整体状态
+	State string
+	// Favicon 反映整体状态的图标地址
+	Favicon string
 	// Services 服务列表
 	Services []*Service
-	// LastUpdated 最后更新时间
+	// LastUpdated 最近一次检查完成的时间，尚未检查时为提示文字
 	LastUpdated string
+	// System 主机资源使用情况，采集失败时为nil
+	System *SystemMetrics
+	// Locale 页面语言
+	Locale string
+	// Messages 页面脚本使用的消息目录
+	Messages map[string]string
+	// Timezone 页面脚本显示时间使用的时区，为空时使用浏览器时区
+	Timezone string
+	// StatusURL 页面脚本获取状态的接口地址
+	StatusURL string
+	// EventsURL 页面脚本获取最近事件的接口地址，为空时不显示最近事件
+	EventsURL string
+	// Subscribe 是否显示邮件订阅表单
+	Subscribe bool
+	// Login 是否启用OIDC登录
+	Login bool
+	// User 已登录的用户，未登录时为空
+	User string
+	// Path 当前页面路径，登录后返回该页面
+	Path string
+	// Theme 页面主题，已补充默认值
+	Theme ThemeConfig
+	// Pager 排序与翻页信息，未配置page_size且请求未指定排序或分页参数时为nil
+	Pager *Pager
 }
 
+// shutdownTimeout 退出时等待进行中的HTTP请求完成的最长时间
+const shutdownTimeout = 10 * time.Second
+
 var (
 	// serviceManager 全局服务管理器
 	serviceManager *ServiceManager
+	// agentRegistry 远程探针注册表
+	agentRegistry *AgentRegistry
+	// configReloader 配置热重载器
+	configReloader *ConfigReloader
 )
 
-// initServices 初始化服务列表
-func initServices() {
-	serviceManager = NewServiceManager()
-
-	// 添加示例服务
-	serviceManager.AddService(&Service{
-		Name:        "JJApps Center",
-		Description: "微服务管理中心",
-		URL:         "https://service.renj.io",
-		Status:      StatusOnline,
-		Checker: &CmdChecker{
-			ProcessName: "apollo",
-			Timeout:     5 * time.Second,
-		},
-	})
-
-	serviceManager.AddService(&Service{
-		Name:        "Sandwich Proxy",
-		Description: " Sandwich 网关代理服务",
-		URL:         "",
-		Status:      StatusOnline,
-		Checker: &CmdChecker{
-			ProcessName: "sandwich",
-			Timeout:     5 * time.Second,
-		},
-	})
-
-	serviceManager.AddService(&Service{
-		Name:        "Helios",
-		Description: "前端静态代理服务",
-		URL:         "",
-		Status:      StatusOnline,
-		Checker: &CmdChecker{
-			ProcessName: "helios",
-			Timeout:     5 * time.Second,
-		},
-	})
-
-	serviceManager.AddService(&Service{
-		Name:        "Black Hole",
-		Description: "内容分发网络",
-		URL:         "https://pkg.renj.io",
-		Status:      StatusOnline,
-		Checker: &CmdChecker{
-			ProcessName: "black-hole",
-			Timeout:     5 * time.Second,
-		},
-	})
-
-	serviceManager.AddService(&Service{
-		Name:        "Docker",
-		Description: "Docker 容器进程",
-		URL:         "",
-		Status:      StatusOnline,
-		Checker: &CmdChecker{
-			ProcessName: "docker",
-			Timeout:     5 * time.Second,
-		},
-	})
-
-	serviceManager.AddService(&Service{
-		Name:        "Proxy",
-		Description: "Proxy代理",
-		URL:         "",
-		Status:      StatusOnline,
-		Checker: &CmdChecker{
-			ProcessName: "xray",
-			Timeout:     5 * time.Second,
-		},
-	})
-
-	// 初始化时更新一次状态
-	serviceManager.UpdateAllStatus()
+// initServices 根据配置初始化服务列表
+func initServices(cfg *Config) error {
+	history, err := NewFileStore(cfg.Storage)
+	if err != nil {
+		return err
+	}
+	history.StartPruning()
+	serviceManager = NewServiceManager(history)
+	serviceManager.SetRefreshTimeout(cfg.RefreshTimeout)
+	serviceManager.SetRefreshInterval(cfg.RefreshInterval)
+	setTransportConfig(cfg.HTTPTransport)
+
+	services, err := cfg.BuildServices()
+	if err != nil {
+		return err
+	}
+	for _, service := range services {
+		serviceManager.AddService(service)
+	}
+
+	if err := setDefaultLocale(cfg.Locale); err != nil {
+		return err
+	}
+	if err := setDisplayTimezone(cfg.Timezone); err != nil {
+		return err
+	}
+	if err := setAccessTokens(cfg); err != nil {
+		return err
+	}
+	if err := setAdminAllowlist(cfg); err != nil {
+		return err
+	}
+	if err := oidcAuth.Update(cfg); err != nil {
+		return err
+	}
+	if err := notifications.Update(cfg); err != nil {
+		return err
+	}
+	notifications.Start()
+	escalator.Update(cfg)
+	setMaintenance(cfg.Maintenance)
+	setLocalRegion(cfg.Region)
+	if err := eventLog.Open(cfg.Storage); err != nil {
+		return err
+	}
+	serviceManager.OnTransition(eventLog.OnTransition)
+	if err := auditLog.Open(cfg.Storage); err != nil {
+		return err
+	}
+	serviceManager.OnTransition(notifyTransition)
+	serviceManager.OnTransition(escalator.OnTransition)
+	escalator.Start()
+	if err := diagnostics.Open(cfg.Storage); err != nil {
+		return err
+	}
+	diagnostics.Update(cfg)
+	serviceManager.OnTransition(diagnostics.OnTransition)
+	if err := snapshots.Open(cfg.Storage); err != nil {
+		return err
+	}
+	snapshots.Update(cfg)
+	healer.Update(cfg)
+	serviceManager.OnCheck(healer.OnCheck)
+	if err := subscribers.Open(cfg.Storage); err != nil {
+		return err
+	}
+	if err := subscribers.Update(cfg); err != nil {
+		return err
+	}
+	serviceManager.OnTransition(subscribers.OnTransition)
+	subscribers.Start()
+	if err := reports.Open(cfg.Storage); err != nil {
+		return err
+	}
+	if err := reports.Update(cfg); err != nil {
+		return err
+	}
+
+	serviceManager.StartScheduler(cfg.Schedule)
+	if err := StartDiscovery(cfg.Discovery, serviceManager); err != nil {
+		return err
+	}
+
+	agentRegistry = NewAgentRegistry(cfg.Agents, cfg.AgentTimeout)
+	if err := agentRegistry.Open(cfg.Storage); err != nil {
+		return err
+	}
+	if err := pusher.Update(cfg); err != nil {
+		return err
+	}
+
+	// 启用启动检查时按依赖关系检查一次全部服务；否则未启用定时检查时初始化时更新一次状态，
+	// 启用时由调度器分散执行首次检查
+	if cfg.BootReport.Enabled {
+		runBootCheck(cfg.BootReport)
+	} else if !serviceManager.Scheduled() {
+		serviceManager.UpdateAllStatus()
+	}
+	return nil
+}
+
+// allServices 返回本机服务与远程探针上报的服务，作为本机服务检查位置上报的结果不单独列出，
+// 返回的服务均已计算age_seconds
+func allServices() []*Service {
+	services := serviceManager.GetServices()
+	regional := make(map[string]bool)
+	for _, service := range services {
+		for _, region := range service.Regions {
+			regional[region+"/"+service.Name] = true
+		}
+	}
+	for _, service := range agentRegistry.Services() {
+		if !regional[service.Host+"/"+service.Name] {
+			services = append(services, service)
+		}
+	}
+	now := time.Now()
+	for _, service := range services {
+		service.setAge(now)
+	}
+	return services
+}
+
+// setAge 计算服务的age_seconds，尚未检查时为-1
+func (s *Service) setAge(now time.Time) {
+	s.AgeSeconds = -1
+	if !s.LastChecked.IsZero() {
+		s.AgeSeconds = math.Floor(now.Sub(s.LastChecked).Seconds())
+	}
 }
 
 // indexHandler 首页处理器
 func indexHandler(c *gin.Context) {
+	renderStatusPage(c, currentTheme().Title, visibleServices(c), "/api/status", "/api/v1/events", true)
+}
+
+// renderStatusPage 渲染状态页，页面脚本从statusURL获取最新状态，eventsURL非空时显示最近事件
+func renderStatusPage(c *gin.Context, title string, services []*Service, statusURL, eventsURL string, showSystem bool) {
 	// 不再同步更新状态，快速渲染页面
 	// 准备页面数据（使用缓存的服务列表，不更新状态）
+	theme := currentTheme()
+	opts, ok := parseServiceListOptions(c, theme.listDefaults())
+	if !ok {
+		return
+	}
+	list := listServices(services, opts)
+	if query := opts.query().Encode(); query != "" {
+		if strings.Contains(statusURL, "?") {
+			statusURL += "&" + query
+		} else {
+			statusURL += "?" + query
+		}
+	}
+
+	locale := requestLocale(c)
 	data := PageData{
-		Title:       "JJApps Status",
-		Services:    serviceManager.GetServices(),
-		LastUpdated: "加载中...",
+		Title:         title,
+		DocumentTitle: documentTitle(locale, title, list.Outages),
+		State:         list.State,
+		Favicon:       faviconURL(list.State),
+		Services:      list.Services,
+		LastUpdated:   formatLastUpdated(locale),
+		Locale:        locale,
+		Messages:      catalogs[locale],
+		Timezone:      configReloader.Current().Timezone,
+		StatusURL:     statusURL,
+		EventsURL:     eventsURL,
+		// 独立状态页可能包含隐藏的服务，只在首页提供订阅
+		Subscribe: subscribers.Enabled() && c.FullPath() == "/",
+		Login:     oidcAuth.Enabled(),
+		Path:      c.Request.URL.RequestURI(),
+		Theme:     theme,
+	}
+	if theme.PageSize > 0 || listQueried(c) {
+		data.Pager = newPager(c, list)
+	}
+	if s, ok := oidcAuth.Session(c); ok {
+		data.User = s.Name
+	}
+	if showSystem {
+		if system, err := CollectSystemMetrics(); err == nil {
+			data.System = system
+		}
 	}
 
 	// 渲染模板
 	c.HTML(http.StatusOK, "index.html", data)
 }
 
+// formatLastUpdated 返回页面显示的最近一次检查完成时间
+func formatLastUpdated(locale string) string {
+	latest := serviceManager.LastUpdated()
+	if latest.IsZero() {
+		return translate(locale, "page.never_updated")
+	}
+	return formatTime(locale, latest, "datetime")
+}
+
+// lastUpdatedValue 返回状态接口中的last_updated，尚未检查时为null
+func lastUpdatedValue() interface{} {
+	latest := serviceManager.LastUpdated()
+	if latest.IsZero() {
+		return nil
+	}
+	return inDisplayZone(latest).Format(time.RFC3339)
+}
+
+// refreshOnDemand 未启用定时检查时，在访问状态接口时后台刷新全部服务，刷新频率受refresh_interval限制
+func refreshOnDemand() {
+	if !serviceManager.Scheduled() {
+		serviceManager.RefreshAsync()
+	}
+}
+
 // apiStatusHandler API状态接口
 func apiStatusHandler(c *gin.Context) {
+	opts, ok := parseServiceListOptions(c, ServiceListOptions{})
+	if !ok {
+		return
+	}
+
 	// 更新服务状态
-	go serviceManager.UpdateAllStatus()
+	refreshOnDemand()
+
+	// 携带viewer及以上角色的令牌时包含隐藏的服务
+	c.Header("Vary", "Authorization")
 
-	// 返回JSON格式的服务状态
-	c.JSON(http.StatusOK, gin.H{
-		"services":     serviceManager.GetServices(),
-		"last_updated": time.Now().Format("2006-01-02 15:04:05"),
-	})
+	// 状态与检查进度均未变化时返回304
+	services := visibleServices(c)
+	changed, version := statusVersion(services)
+	if notModified(c, changed, version) {
+		return
+	}
+
+	// 返回JSON格式的服务状态，指定limit时分页
+	c.JSON(http.StatusOK, listServices(services, opts).statusResponse())
+}
+
+// statusVersion 返回状态接口的缓存校验值：除状态变化时间外，还包括最近一次检查完成的时间、
+// 各服务的检查时间、进行中的检查与下次检查时间，这些字段变化时不会调用touch。
+// 存在进行中的检查时最后修改时间取当前时间，仅发送If-Modified-Since的客户端不会收到304；
+// age_seconds随时间增长，收到304的客户端可按last_checked自行计算
+func statusVersion(services []*Service) (time.Time, string) {
+	lastChange, lastUpdated := serviceManager.LastChange(), serviceManager.LastUpdated()
+	changed := lastChange
+	if lastUpdated.After(changed) {
+		changed = lastUpdated
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d/%d", lastChange.UnixNano(), lastUpdated.UnixNano())
+	for _, service := range services {
+		fmt.Fprintf(h, "/%s:%d:%t", service.Name, service.LastChecked.UnixNano(), service.Checking)
+		if service.NextCheck != nil {
+			fmt.Fprintf(h, ":%d", service.NextCheck.UnixNano())
+		}
+		if service.Checking {
+			changed = time.Now()
+		}
+	}
+	return changed, fmt.Sprintf("%x", h.Sum64())
+}
+
+// notModified 根据最后变化时间与校验值设置ETag/Last-Modified头，version为空时以变化时间作为ETag，
+// 客户端缓存仍然有效时返回304并返回true
+func notModified(c *gin.Context, changed time.Time, version string) bool {
+	if version == "" {
+		version = fmt.Sprintf("%x", changed.UnixNano())
+	}
+	// 仅检查时间变化不会更新ETag，因此使用弱校验
+	etag := fmt.Sprintf(`W/"%s"`, version)
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", changed.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "no-cache")
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				c.Status(http.StatusNotModified)
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := c.GetHeader("If-Modified-Since"); since != "" {
+		t, err := http.ParseTime(since)
+		// HTTP时间精度为秒，需截断后比较
+		if err == nil && !changed.Truncate(time.Second).After(t) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
 }
 
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// serveOptions 启动Web服务的参数
+type serveOptions struct {
+	// configPath 配置文件路径
+	configPath string
+	// listen 监听地址，为空时不提供HTTP服务
+	listen string
+	// socket unix socket路径，非空时优先于listen
+	socket string
+	// socketMode unix socket权限
+	socketMode string
+	// assetsDir 覆盖内嵌模板与静态资源的目录
+	assetsDir string
+	// mock 是否以模拟模式运行
+	mock bool
+}
+
+// serve 启动Web服务，正常情况下不会返回
+func serve(opts serveOptions) {
 	// 初始化服务
-	initServices()
+	configPath := opts.configPath
+	mockMode.Store(opts.mock)
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		fatal("加载配置失败", "path", configPath, "error", err)
+	}
+	if err := setupLogger(cfg.Log); err != nil {
+		fatal("初始化日志失败", "error", err)
+	}
+	if opts.mock {
+		slog.Warn("以模拟模式运行，所有检查器已替换为模拟检查器")
+	}
+	if err := initServices(cfg); err != nil {
+		fatal("初始化服务失败", "error", err)
+	}
 
-	// 创建Gin引擎
-	r := gin.Default()
+	// 监听配置文件变更，支持热重载
+	configReloader = NewConfigReloader(configPath, cfg, serviceManager)
+	if err := configReloader.Start(); err != nil {
+		slog.Warn("启动配置热重载失败", "error", err)
+	}
+	snapshots.Start()
+
+	// 创建Gin引擎，请求日志统一输出到slog
 	gin.SetMode(gin.ReleaseMode)
-	// 加载HTML模板
-	r.LoadHTMLGlob("templates/*")
+	if err := cfg.Compression.validate(); err != nil {
+		fatal("无效的压缩配置", "error", err)
+	}
+	r := gin.New()
+	// 来源IP统一由requestIP按admin_access.trusted_proxies解析，gin不信任任何代理的请求头
+	if err := r.SetTrustedProxies(nil); err != nil {
+		fatal("设置可信代理失败", "error", err)
+	}
+	r.Use(requestLogger(), gin.Recovery(), CompressionMiddleware(cfg.Compression))
+	// 加载HTML模板与静态资源，ASSETS_DIR可覆盖内嵌资源
+	assets := newAssetsFS(opts.assetsDir)
+	tmpl, err := loadTemplates(assets)
+	if err != nil {
+		fatal("加载模板失败", "error", err)
+	}
+	r.SetHTMLTemplate(tmpl)
+	// 月度报告需要使用模板渲染，加载模板后再开始发送
+	reports.SetTemplates(tmpl)
+	reports.Start()
 
 	// 静态文件服务
-	r.Static("/static", "./static")
+	static, err := staticFS(assets)
+	if err != nil {
+		fatal("加载静态资源失败", "error", err)
+	}
+	r.StaticFS("/static", http.FS(static))
+
+	// 路由设置，公开接口按IP限流
+	limiter := NewRateLimiter(cfg.RateLimit).Middleware()
+	r.GET("/", limiter, indexHandler)
+	r.GET("/favicon.svg", limiter, faviconHandler)
+	r.GET("/favicon.ico", limiter, faviconHandler)
+	r.GET("/metrics", limiter, metricsHandler)
+	r.GET("/p/:page", limiter, pageHandler)
+	r.GET("/service/:name", limiter, servicePageHandler)
+	r.GET("/maintenance.ics", limiter, maintenanceHandler)
+	r.GET("/subscribe/confirm", limiter, subscribeConfirmHandler)
+	r.GET("/subscribe/unsubscribe", limiter, unsubscribePageHandler)
+	r.POST("/subscribe/unsubscribe", limiter, unsubscribeHandler)
+	r.GET("/auth/login", limiter, oidcLoginHandler)
+	r.GET("/auth/callback", limiter, oidcCallbackHandler)
+	r.GET("/auth/logout", oidcLogoutHandler)
+	gql := r.Group("/graphql", CORSMiddleware(cfg.CORS), limiter)
+	gql.GET("", graphqlHandler)
+	gql.POST("", graphqlHandler)
+	gql.OPTIONS("", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	api := r.Group("/api", CORSMiddleware(cfg.CORS), limiter)
+	api.GET("/status", apiStatusHandler)
+	api.GET("/system", apiSystemHandler)
+	api.POST("/heartbeat/:token", apiHeartbeatHandler)
 
-	// 路由设置
-	r.GET("/", indexHandler)
-	r.GET("/api/status", apiStatusHandler)
+	v1 := api.Group("/v1")
+	v1.GET("/summary", apiSummaryHandler)
+	v1.GET("/services/:name", apiServiceHandler)
+	v1.GET("/services/:name/checks", apiServiceChecksHandler)
+	v1.GET("/services/:name/uptime", apiServiceUptimeHandler)
+	v1.GET("/services/:name/latency", apiServiceLatencyHandler)
+	v1.GET("/services/:name/histogram", apiServiceHistogramHandler)
+	v1.GET("/services/:name/slo", apiServiceSLOHandler)
+	v1.GET("/slo", apiSLOHandler)
+	v1.GET("/dependencies", apiDependenciesHandler)
+	v1.GET("/events", apiEventsHandler)
+	v1.GET("/pages/:page/status", apiPageStatusHandler)
+	v1.POST("/agents/:name/report", requireAdminNetwork(), apiAgentReportHandler)
+	v1.POST("/agents/:name/rotate", requireAdminNetwork(), apiAgentRotateHandler)
+	v1.POST("/subscribe", apiSubscribeHandler)
+
+	// 管理接口，按来源IP白名单与令牌的角色授权
+	viewer := v1.Group("", requireAdminNetwork(), requireRole(RoleViewer))
+	viewer.GET("/services/:name/diagnostics", apiServiceDiagnosticsHandler)
+	viewer.GET("/export/history", apiExportHistoryHandler)
+	viewer.GET("/reports/:month", apiReportHandler)
+	viewer.GET("/boot-report", apiBootReportHandler)
+	operator := v1.Group("", requireAdminNetwork(), requireRole(RoleOperator))
+	operator.POST("/services/:name/check", apiServiceCheckHandler)
+	operator.PUT("/services/:name/override", apiSetOverrideHandler)
+	operator.DELETE("/services/:name/override", apiClearOverrideHandler)
+	operator.POST("/services/:name/ack", apiAckHandler)
+	admin := v1.Group("", requireAdminNetwork(), requireRole(RoleAdmin))
+	admin.GET("/export/config", apiExportConfigHandler)
+	admin.POST("/import/config", apiImportConfigHandler)
+	admin.GET("/audit", apiAuditHandler)
+	admin.GET("/config/snapshots", apiSnapshotsHandler)
+	admin.GET("/config/snapshots/:id", apiSnapshotHandler)
+	admin.POST("/config/snapshots/:id/rollback", apiSnapshotRollbackHandler)
+	// 接口文档根据上面注册的路由生成，需放在最后
+	registerOpenAPI(r, api)
+
+	// 探针模式：定期上报本机检查结果到中心节点
+	agentMode := cfg.Agent.Server != ""
+	if agentMode {
+		go NewAgent(cfg.Agent, serviceManager).Run(serviceManager.Context())
+	}
+	api.OPTIONS("/*path", func(c *gin.Context) { c.Status(http.StatusNoContent) })
 
-	port := os.Getenv("PORTS")
-	if port == "" {
+	// 收到退出信号时取消进行中的检查并等待HTTP请求处理完成
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Handler: r}
+	errCh := make(chan error, 1)
+	switch {
+	case opts.socket != "":
+		// 优先使用unix socket监听
+		ln, err := listenUnix(opts.socket, opts.socketMode)
+		if err != nil {
+			fatal("监听unix socket失败", "path", opts.socket, "error", err)
+		}
+		go func() { errCh <- srv.Serve(ln) }()
+	case opts.listen != "":
+		srv.Addr = opts.listen
+		go func() { errCh <- srv.ListenAndServe() }()
+	case !agentMode:
 		return
+	default:
+		// 探针模式下可以不提供HTTP服务
+		srv = nil
+	}
+
+	select {
+	case err := <-errCh:
+		fatal("HTTP服务异常退出", "listen", opts.listen, "socket", opts.socket, "error", err)
+	case <-ctx.Done():
+	}
+	slog.Info("收到退出信号，正在停止服务")
+	serviceManager.Shutdown()
+	if srv != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("HTTP服务未能在时限内停止", "error", err)
+		}
+	}
+	if err := serviceManager.History().Close(); err != nil {
+		slog.Warn("关闭检查记录存储失败", "error", err)
+	}
+}
+
+// listenUnix 在指定路径创建unix socket监听，mode为八进制权限字符串，默认0660
+func listenUnix(path, mode string) (net.Listener, error) {
+	perm := os.FileMode(0660)
+	if mode != "" {
+		m, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("无效的socket权限 '%s': %v", mode, err)
+		}
+		perm = os.FileMode(m)
+	}
+
+	// 清理上次运行残留的socket文件
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("路径 '%s' 已存在且不是socket文件", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("删除旧socket文件失败: %v", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("设置socket权限失败: %v", err)
 	}
-	// 启动服务器
-	r.Run(fmt.Sprintf("127.0.0.1:%s", port))
+	return ln, nil
 }
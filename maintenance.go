@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceWindow 计划维护时段，时段内相关服务显示为维护状态
+type MaintenanceWindow struct {
+	// Title 维护标题
+	Title string `yaml:"title" json:"title"`
+	// Description 维护说明
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Services 受影响的服务，为空时影响全部服务
+	Services []string `yaml:"services,omitempty" json:"services,omitempty"`
+	// Start 开始时间
+	Start time.Time `yaml:"start" json:"start"`
+	// End 结束时间
+	End time.Time `yaml:"end" json:"end"`
+}
+
+// Active 判断维护时段在now时刻是否进行中
+func (w *MaintenanceWindow) Active(now time.Time) bool {
+	return !now.Before(w.Start) && now.Before(w.End)
+}
+
+// Covers 判断维护时段是否影响指定服务
+func (w *MaintenanceWindow) Covers(service string) bool {
+	if len(w.Services) == 0 {
+		return true
+	}
+	for _, name := range w.Services {
+		if name == service {
+			return true
+		}
+	}
+	return false
+}
+
+// maintenanceWindows 当前配置的计划维护时段
+var maintenanceWindows atomic.Pointer[[]MaintenanceWindow]
+
+func init() {
+	maintenanceWindows.Store(&[]MaintenanceWindow{})
+}
+
+// setMaintenance 更新计划维护时段，下一次检查时生效
+func setMaintenance(windows []MaintenanceWindow) {
+	windows = append([]MaintenanceWindow(nil), windows...)
+	maintenanceWindows.Store(&windows)
+}
+
+// validateMaintenance 校验计划维护配置：标题不为空、结束时间晚于开始时间且引用的服务存在
+func (cfg *Config) validateMaintenance() error {
+	services := make(map[string]bool, len(cfg.Services))
+	for _, sc := range cfg.Services {
+		services[sc.Name] = true
+	}
+	for i, w := range cfg.Maintenance {
+		if w.Title == "" {
+			return fmt.Errorf("第 %d 个维护计划缺少标题", i+1)
+		}
+		if w.Start.IsZero() || !w.End.After(w.Start) {
+			return fmt.Errorf("维护计划 '%s' 的结束时间需晚于开始时间", w.Title)
+		}
+		for _, name := range w.Services {
+			if !services[name] {
+				return fmt.Errorf("维护计划 '%s' 引用了不存在的服务 '%s'", w.Title, name)
+			}
+		}
+	}
+	return nil
+}
+
+// applyMaintenance 服务处于进行中的维护时段时返回维护状态
+func applyMaintenance(service *Service, status ServiceStatus, now time.Time) ServiceStatus {
+	for _, w := range *maintenanceWindows.Load() {
+		if w.Active(now) && w.Covers(service.Name) {
+			return StatusMaintenance
+		}
+	}
+	return status
+}
+
+// maintenanceHandler 以iCalendar格式输出全部计划维护，便于在日历中订阅；
+// 未认证的请求仅能看到涉及公开服务的维护，且只列出其中的公开服务
+func maintenanceHandler(c *gin.Context) {
+	authorized := canViewPrivate(c)
+	windows := append([]MaintenanceWindow(nil), *maintenanceWindows.Load()...)
+	sort.SliceStable(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+
+	locale := requestLocale(c)
+	var b strings.Builder
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:-//JJApplication//Status//"+strings.ToUpper(locale))
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	writeICalLine(&b, "METHOD:PUBLISH")
+	writeICalLine(&b, "X-WR-CALNAME:"+icalText(translate(locale, "maintenance.calendar")))
+	stamp := icalTime(time.Now())
+	for _, w := range windows {
+		services := maintenanceServices(w, authorized)
+		if services == nil {
+			continue
+		}
+		description := w.Description
+		if len(services) > 0 {
+			affected := translate(locale, "maintenance.services", strings.Join(services, translate(locale, "page.list_sep")))
+			if description != "" {
+				description += "\n"
+			}
+			description += affected
+		}
+		uid := sha1.Sum([]byte(w.Title + "\x00" + w.Start.UTC().Format(time.RFC3339)))
+		writeICalLine(&b, "BEGIN:VEVENT")
+		writeICalLine(&b, fmt.Sprintf("UID:%x@jjapps-status", uid[:8]))
+		writeICalLine(&b, "DTSTAMP:"+stamp)
+		writeICalLine(&b, "DTSTART:"+icalTime(w.Start))
+		writeICalLine(&b, "DTEND:"+icalTime(w.End))
+		writeICalLine(&b, "SUMMARY:"+icalText(w.Title))
+		if description != "" {
+			writeICalLine(&b, "DESCRIPTION:"+icalText(description))
+		}
+		writeICalLine(&b, "END:VEVENT")
+	}
+	writeICalLine(&b, "END:VCALENDAR")
+
+	c.Header("Vary", "Authorization")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(b.String()))
+}
+
+// maintenanceServices 返回维护涉及的、请求可见的服务名称；维护影响全部服务时返回空列表，
+// 不涉及任何可见服务时返回nil
+func maintenanceServices(w MaintenanceWindow, authorized bool) []string {
+	if len(w.Services) == 0 {
+		return []string{}
+	}
+	names := make([]string, 0, len(w.Services))
+	for _, name := range w.Services {
+		if service := serviceManager.GetService(name); service != nil && visible(service, authorized) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return names
+}
+
+// icalTime 将时间格式化为iCalendar的UTC时间
+func icalTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icalText 转义iCalendar文本值中的特殊字符
+func icalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeICalLine 写入一行内容，超过75字节时按RFC 5545折行，不拆分UTF-8字符
+func writeICalLine(b *strings.Builder, line string) {
+	width := 75
+	for len(line) > width {
+		cut := width
+		for cut > 0 && (line[cut]&0xC0) == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// 续行的首个空格占用一个字节
+		width = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 支持导入的配置来源
+const (
+	// importUptimeKuma Uptime Kuma的备份JSON
+	importUptimeKuma = "uptime-kuma"
+	// importGatus Gatus的YAML配置
+	importGatus = "gatus"
+)
+
+// importResult 导入转换的结果
+type importResult struct {
+	// Services 转换后的服务配置
+	Services []ServiceConfig
+	// Warnings 跳过的监控项与未能转换的设置，需人工检查
+	Warnings []string
+}
+
+// warn 记录一条转换警告
+func (r *importResult) warn(name, format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf("%s: %s", name, fmt.Sprintf(format, args...)))
+}
+
+// newCheckerConfig 按给定顺序的键值对创建检查器配置，值为空的项被省略
+func newCheckerConfig(typ string, pairs ...interface{}) (CheckerConfig, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	pairs = append([]interface{}{"type", typ}, pairs...)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		value := pairs[i+1]
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				continue
+			}
+		case time.Duration:
+			if v == 0 {
+				continue
+			}
+			value = v.String()
+		case []string:
+			if len(v) == 0 {
+				continue
+			}
+		case nil:
+			continue
+		}
+		var valueNode yaml.Node
+		if err := valueNode.Encode(value); err != nil {
+			return CheckerConfig{}, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: pairs[i].(string)}, &valueNode)
+	}
+	return CheckerConfig{Type: typ, node: node}, nil
+}
+
+// kumaBool Uptime Kuma备份中的布尔值，不同版本可能为true/false或1/0
+type kumaBool bool
+
+// UnmarshalJSON 实现json.Unmarshaler接口
+func (b *kumaBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true", "1":
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// kumaMonitor Uptime Kuma备份中的监控项，只包含可以转换的字段
+type kumaMonitor struct {
+	Name                string    `json:"name"`
+	Description         string    `json:"description"`
+	Type                string    `json:"type"`
+	Active              *kumaBool `json:"active"`
+	URL                 string    `json:"url"`
+	Method              string    `json:"method"`
+	Hostname            string    `json:"hostname"`
+	Interval            float64   `json:"interval"`
+	Timeout             float64   `json:"timeout"`
+	Keyword             string    `json:"keyword"`
+	InvertKeyword       kumaBool  `json:"invertKeyword"`
+	UpsideDown          kumaBool  `json:"upsideDown"`
+	IgnoreTLS           kumaBool  `json:"ignoreTls"`
+	AcceptedStatusCodes []string  `json:"accepted_statuscodes"`
+	JSONPath            string    `json:"jsonPath"`
+	ExpectedValue       string    `json:"expectedValue"`
+	DNSResolveServer    string    `json:"dns_resolve_server"`
+	DNSResolveType      string    `json:"dns_resolve_type"`
+	DNSResolvePort      float64   `json:"dns_resolve_server_port"`
+	PushToken           string    `json:"pushToken"`
+}
+
+// kumaSimplePath 可以直接改写为jq表达式的JSONata路径，如status或data.health
+var kumaSimplePath = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// importKuma 转换Uptime Kuma备份JSON中的监控项
+func importKuma(data []byte) (*importResult, error) {
+	var backup struct {
+		MonitorList []kumaMonitor `json:"monitorList"`
+	}
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("解析Uptime Kuma备份失败: %v", err)
+	}
+	result := &importResult{}
+	for _, m := range backup.MonitorList {
+		timeout := time.Duration(m.Timeout * float64(time.Second))
+		var checker CheckerConfig
+		var err error
+		switch m.Type {
+		case "http", "keyword":
+			if m.Method != "" && !strings.EqualFold(m.Method, "GET") {
+				result.warn(m.Name, "http检查只支持GET请求，已忽略method %s", m.Method)
+			}
+			conditions := kumaStatusConditions(m.AcceptedStatusCodes)
+			if m.Type == "keyword" {
+				keyword := fmt.Sprintf("body contains %s", strconv.Quote(m.Keyword))
+				if m.InvertKeyword {
+					keyword = "not (" + keyword + ")"
+				}
+				conditions = append(conditions, keyword)
+			}
+			checker, err = newCheckerConfig("http", "url", m.URL, "timeout", timeout, "expr", strings.Join(conditions, " && "))
+		case "json-query":
+			query := m.JSONPath
+			if !kumaSimplePath.MatchString(query) {
+				result.warn(m.Name, "JSONata表达式 '%s' 需要手动改写为jq表达式", query)
+			} else {
+				query = "." + query
+			}
+			// 期望值在备份中均为字符串，能解析为JSON时按解析后的值比较
+			var expect interface{}
+			if m.ExpectedValue != "" {
+				if err := json.Unmarshal([]byte(m.ExpectedValue), &expect); err != nil {
+					expect = m.ExpectedValue
+				}
+			}
+			checker, err = newCheckerConfig("json", "url", m.URL, "query", query, "expect", expect, "timeout", timeout)
+		case "dns":
+			server := m.DNSResolveServer
+			if m.DNSResolvePort != 0 && m.DNSResolvePort != 53 {
+				server = net.JoinHostPort(strings.Trim(server, "[]"), strconv.Itoa(int(m.DNSResolvePort)))
+			}
+			checker, err = newCheckerConfig("dns", "server", server, "name", m.Hostname, "record_type", m.DNSResolveType, "timeout", timeout)
+		case "ping":
+			checker, err = newCheckerConfig("ping", "host", m.Hostname)
+		case "push":
+			checker, err = newCheckerConfig("heartbeat", "token", m.PushToken, "grace", time.Duration(m.Interval*float64(time.Second))*2)
+			result.warn(m.Name, "推送地址需改为 /api/heartbeat/%s", m.PushToken)
+		case "group":
+			continue
+		default:
+			result.warn(m.Name, "不支持的监控类型 '%s'，已跳过", m.Type)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("转换监控项 '%s' 失败: %v", m.Name, err)
+		}
+		if m.IgnoreTLS {
+			result.warn(m.Name, "不支持忽略证书错误，已忽略ignoreTls")
+		}
+
+		sc := ServiceConfig{
+			Name:        m.Name,
+			Description: m.Description,
+			Checker:     checker,
+			Interval:    time.Duration(m.Interval * float64(time.Second)),
+			Invert:      bool(m.UpsideDown),
+		}
+		if m.Active != nil && !*m.Active {
+			sc.Enabled = new(bool)
+		}
+		result.Services = append(result.Services, sc)
+	}
+	return result, nil
+}
+
+// kumaStatusConditions 将Uptime Kuma的状态码范围（如200-299）转换为检查表达式，默认的2xx不生成条件
+func kumaStatusConditions(codes []string) []string {
+	if len(codes) == 0 || len(codes) == 1 && codes[0] == "200-299" {
+		return nil
+	}
+	parts := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if lo, hi, ok := strings.Cut(code, "-"); ok {
+			parts = append(parts, fmt.Sprintf("(code >= %s && code <= %s)", lo, hi))
+		} else {
+			parts = append(parts, "code == "+code)
+		}
+	}
+	if len(parts) == 1 {
+		return parts
+	}
+	return []string{"(" + strings.Join(parts, " || ") + ")"}
+}
+
+// gatusEndpoint Gatus配置中的端点，只包含可以转换的字段
+type gatusEndpoint struct {
+	Name       string            `yaml:"name"`
+	Group      string            `yaml:"group"`
+	Enabled    *bool             `yaml:"enabled"`
+	URL        string            `yaml:"url"`
+	Method     string            `yaml:"method"`
+	Body       string            `yaml:"body"`
+	Headers    map[string]string `yaml:"headers"`
+	Interval   time.Duration     `yaml:"interval"`
+	Conditions []string          `yaml:"conditions"`
+	DNS        *struct {
+		QueryName string `yaml:"query-name"`
+		QueryType string `yaml:"query-type"`
+	} `yaml:"dns"`
+}
+
+// gatusCondition Gatus的条件，如[STATUS] == 200
+var gatusCondition = regexp.MustCompile(`^\[([A-Z_]+)\](\S*)\s*(==|!=|<=|>=|<|>)\s*(.+)$`)
+
+// gatusAny Gatus的any(...)取值
+var gatusAny = regexp.MustCompile(`^any\((.*)\)$`)
+
+// gatusPattern Gatus的pat(...)取值
+var gatusPattern = regexp.MustCompile(`^pat\((.*)\)$`)
+
+// importGatusConfig 转换Gatus配置中的端点
+func importGatusConfig(data []byte) (*importResult, error) {
+	var cfg struct {
+		Endpoints []gatusEndpoint `yaml:"endpoints"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析Gatus配置失败: %v", err)
+	}
+	result := &importResult{}
+	for _, e := range cfg.Endpoints {
+		var checker CheckerConfig
+		var err error
+		u, _ := url.Parse(e.URL)
+		switch {
+		case e.DNS != nil:
+			var expect []string
+			for _, cond := range e.Conditions {
+				m := gatusCondition.FindStringSubmatch(cond)
+				switch {
+				case m != nil && m[1] == "BODY" && m[2] == "" && m[3] == "==":
+					expect = append(expect, strings.TrimSpace(m[4]))
+				case m != nil && m[1] == "DNS_RCODE" && m[3] == "==" && strings.TrimSpace(m[4]) == "NOERROR":
+				default:
+					result.warn(e.Name, "无法转换条件 '%s'，已忽略", cond)
+				}
+			}
+			checker, err = newCheckerConfig("dns", "server", e.URL, "name", e.DNS.QueryName, "record_type", e.DNS.QueryType, "expect", expect)
+		case u != nil && (u.Scheme == "http" || u.Scheme == "https"):
+			if e.Method != "" && !strings.EqualFold(e.Method, "GET") || e.Body != "" || len(e.Headers) > 0 {
+				result.warn(e.Name, "http检查只支持不带请求体与请求头的GET请求，已忽略method、body与headers")
+			}
+			checker, err = newCheckerConfig("http", "url", e.URL, "expr", gatusExpr(e, result))
+		case u != nil && u.Scheme == "icmp":
+			checker, err = newCheckerConfig("ping", "host", u.Host)
+		default:
+			result.warn(e.Name, "不支持的地址 '%s'，已跳过", e.URL)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("转换端点 '%s' 失败: %v", e.Name, err)
+		}
+		result.Services = append(result.Services, ServiceConfig{
+			Name:        e.Name,
+			Description: e.Group,
+			Enabled:     e.Enabled,
+			Checker:     checker,
+			Interval:    e.Interval,
+		})
+	}
+	return result, nil
+}
+
+// gatusExpr 将Gatus的HTTP条件转换为检查表达式，无法转换的条件记录警告后忽略
+func gatusExpr(e gatusEndpoint, result *importResult) string {
+	var conditions []string
+	for _, cond := range e.Conditions {
+		m := gatusCondition.FindStringSubmatch(cond)
+		if m == nil || m[2] != "" {
+			result.warn(e.Name, "无法转换条件 '%s'，已忽略", cond)
+			continue
+		}
+		field, op, value := m[1], m[3], strings.TrimSpace(m[4])
+		switch field {
+		case "STATUS":
+			if values := gatusAny.FindStringSubmatch(value); values != nil && (op == "==" || op == "!=") {
+				in := "code in [" + values[1] + "]"
+				if op == "!=" {
+					in = "not (" + in + ")"
+				}
+				conditions = append(conditions, in)
+				continue
+			}
+			conditions = append(conditions, fmt.Sprintf("code %s %s", op, value))
+		case "RESPONSE_TIME":
+			conditions = append(conditions, fmt.Sprintf("latency %s %sms", op, value))
+		case "CERTIFICATE_EXPIRATION":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				result.warn(e.Name, "无法转换条件 '%s'，已忽略", cond)
+				continue
+			}
+			conditions = append(conditions, fmt.Sprintf("cert_days %s %s", op, strconv.FormatFloat(d.Hours()/24, 'f', -1, 64)))
+		case "BODY":
+			pat := gatusPattern.FindStringSubmatch(value)
+			if pat == nil || (op != "==" && op != "!=") {
+				result.warn(e.Name, "无法转换条件 '%s'，已忽略", cond)
+				continue
+			}
+			glob := "^" + strings.ReplaceAll(regexp.QuoteMeta(pat[1]), `\*`, ".*") + "$"
+			match := fmt.Sprintf("body matches %s", strconv.Quote("(?s)"+glob))
+			if op == "!=" {
+				match = "not (" + match + ")"
+			}
+			conditions = append(conditions, match)
+		case "CONNECTED":
+		default:
+			result.warn(e.Name, "无法转换条件 '%s'，已忽略", cond)
+		}
+	}
+	return strings.Join(conditions, " && ")
+}
+
+// importCommand 将其他监控工具的配置转换为本程序的服务配置，输出YAML
+func importCommand(args []string) int {
+	fs, _ := newFlagSet("import", "import [--from 来源] [--output 文件] <文件>")
+	from := fs.String("from", "", "配置来源：uptime-kuma（备份JSON）或gatus（YAML配置），默认按文件扩展名判断")
+	output := fs.String("output", "", "写入的文件路径，默认输出到标准输出")
+	if code := parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	path := fs.Arg(0)
+	source := *from
+	if source == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			source = importUptimeKuma
+		case ".yaml", ".yml":
+			source = importGatus
+		}
+	}
+	convert := map[string]func([]byte) (*importResult, error){
+		importUptimeKuma: importKuma,
+		importGatus:      importGatusConfig,
+	}[source]
+	if convert == nil {
+		fmt.Fprintf(os.Stderr, "无效的来源 '%s'，可选uptime-kuma或gatus\n", source)
+		return exitUsage
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取文件失败: %v\n", err)
+		return exitUsage
+	}
+	result, err := convert(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return exitUsage
+	}
+	// 逐个校验转换后的服务，无法通过校验的服务不输出
+	services := make([]ServiceConfig, 0, len(result.Services))
+	for i := range result.Services {
+		if _, err := result.Services[i].Build(nil); err != nil {
+			result.warn(result.Services[i].Name, "转换后的配置无效，已跳过: %v", err)
+			continue
+		}
+		services = append(services, result.Services[i])
+	}
+	for _, w := range result.Warnings {
+		fmt.Fprintln(os.Stderr, "警告:", w)
+	}
+	err = writeOutput(*output, func(w io.Writer) error {
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(map[string]interface{}{"services": services}); err != nil {
+			return err
+		}
+		return enc.Close()
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	fmt.Fprintf(os.Stderr, "已转换 %d 个服务，跳过或需检查的项目 %d 个\n", len(services), len(result.Warnings))
+	return exitOK
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// mockMode 是否以模拟模式运行：所有服务的检查器替换为模拟检查器，由serve --mock开启
+var mockMode atomic.Bool
+
+const (
+	// defaultMockLatency 未配置mock的服务在模拟模式下的基础延迟
+	defaultMockLatency = 20 * time.Millisecond
+	// defaultMockJitter 未配置mock的服务在模拟模式下随机增加的最大延迟
+	defaultMockJitter = 100 * time.Millisecond
+)
+
+// MockChecker 模拟检查器，按脚本依次返回状态并注入延迟与抖动，不访问任何真实服务，
+// 用于开发页面与通知时复现各种状态变化
+type MockChecker struct {
+	// Sequence 依次返回的状态，到末尾后从头循环，默认始终在线
+	Sequence []ServiceStatus `yaml:"sequence,omitempty"`
+	// Latency 每次检查的基础延迟
+	Latency time.Duration `yaml:"latency,omitempty"`
+	// Jitter 在基础延迟上随机增加的最大延迟
+	Jitter time.Duration `yaml:"jitter,omitempty"`
+	// FlapRate 每次检查随机翻转结果（在线与离线互换）的概率，取值0~1，用于模拟抖动
+	FlapRate float64 `yaml:"flap_rate,omitempty"`
+	// Error 非在线状态时返回的错误信息，默认为"模拟故障"
+	Error string `yaml:"error,omitempty"`
+
+	// step 已执行的检查次数
+	step atomic.Uint64
+}
+
+// validate 校验模拟检查器配置
+func (m *MockChecker) validate() error {
+	if m.Latency < 0 || m.Jitter < 0 {
+		return fmt.Errorf("模拟检查器的latency与jitter不能为负数")
+	}
+	if m.FlapRate < 0 || m.FlapRate > 1 {
+		return fmt.Errorf("模拟检查器的flap_rate需在0到1之间")
+	}
+	return nil
+}
+
+// CheckStatus 实现StatusChecker接口，等待模拟延迟后返回脚本中的下一个状态
+func (m *MockChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	n := m.step.Add(1) - 1
+	status := StatusOnline
+	if len(m.Sequence) > 0 {
+		status = m.Sequence[n%uint64(len(m.Sequence))]
+	}
+	if m.FlapRate > 0 && rand.Float64() < m.FlapRate {
+		if status == StatusOnline {
+			status = StatusOffline
+		} else {
+			status = StatusOnline
+		}
+	}
+
+	delay := m.Latency
+	if m.Jitter > 0 {
+		delay += rand.N(m.Jitter)
+	}
+	select {
+	case <-ctx.Done():
+		return StatusOffline, ctx.Err()
+	case <-time.After(delay):
+	}
+
+	if status == StatusOnline {
+		return status, nil
+	}
+	msg := m.Error
+	if msg == "" {
+		msg = "模拟故障"
+	}
+	return status, errors.New(msg)
+}
+
+// mockChecker 返回服务在模拟模式下使用的检查器，未配置mock时始终在线并带有少量随机延迟
+func (sc *ServiceConfig) mockChecker() (*MockChecker, error) {
+	if sc.Mock == nil {
+		return &MockChecker{Latency: defaultMockLatency, Jitter: defaultMockJitter}, nil
+	}
+	if err := sc.Mock.validate(); err != nil {
+		return nil, err
+	}
+	return &MockChecker{
+		Sequence: sc.Mock.Sequence,
+		Latency:  sc.Mock.Latency,
+		Jitter:   sc.Mock.Jitter,
+		FlapRate: sc.Mock.FlapRate,
+		Error:    sc.Mock.Error,
+	}, nil
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// notifyTimeout 单次通知发送的超时时间
+const notifyTimeout = 10 * time.Second
+
+// Notification 通知内容
+type Notification struct {
+	// Kind 通知类型，如status_change
+	Kind string `json:"kind"`
+	// Service 服务名称
+	Service string `json:"service"`
+	// From 变化前的状态
+	From ServiceStatus `json:"from"`
+	// To 变化后的状态
+	To ServiceStatus `json:"to"`
+	// Time 发生时间
+	Time time.Time `json:"time"`
+	// Message 通知正文
+	Message string `json:"message"`
+}
+
+// Notifier 通知渠道接口
+type Notifier interface {
+	// Notify 发送一条通知
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotificationConfig 通知配置
+type NotificationConfig struct {
+	// Channels 通知渠道定义
+	Channels []ChannelConfig `yaml:"channels"`
+	// DefaultRoute 未配置notify的服务使用的通知渠道
+	DefaultRoute []string `yaml:"default_route"`
+	// Escalation 告警升级与重复提醒规则
+	Escalation EscalationConfig `yaml:"escalation"`
+}
+
+// ChannelConfig 通知渠道配置，Type决定渠道种类，其余字段由对应渠道解析
+type ChannelConfig struct {
+	// Name 渠道名称，供服务的notify字段引用
+	Name string `yaml:"name"`
+	// Type 渠道类型
+	Type string `yaml:"type"`
+	// QuietHours 免打扰时段，期间非关键服务的通知合并为汇总在结束后发送
+	QuietHours *QuietHours `yaml:"quiet_hours,omitempty"`
+	// node 原始配置节点
+	node *yaml.Node
+}
+
+// notifierFactories 通知渠道类型注册表
+var notifierFactories = map[string]func() Notifier{
+	"webhook":  func() Notifier { return &WebhookNotifier{} },
+	"telegram": func() Notifier { return &TelegramNotifier{} },
+	"email":    func() Notifier { return &EmailNotifier{} },
+}
+
+// UnmarshalYAML 实现yaml.Unmarshaler接口，保留原始节点供渠道解析
+func (c *ChannelConfig) UnmarshalYAML(node *yaml.Node) error {
+	var head struct {
+		Name       string      `yaml:"name"`
+		Type       string      `yaml:"type"`
+		QuietHours *QuietHours `yaml:"quiet_hours"`
+	}
+	if err := node.Decode(&head); err != nil {
+		return err
+	}
+	c.Name, c.Type, c.QuietHours, c.node = head.Name, head.Type, head.QuietHours, node
+	return nil
+}
+
+// MarshalYAML 实现yaml.Marshaler接口，原样输出渠道配置
+func (c ChannelConfig) MarshalYAML() (interface{}, error) {
+	if c.node != nil {
+		return c.node, nil
+	}
+	return map[string]string{"name": c.Name, "type": c.Type}, nil
+}
+
+// Build 根据配置创建通知渠道
+func (c *ChannelConfig) Build() (Notifier, error) {
+	factory, ok := notifierFactories[c.Type]
+	if !ok {
+		return nil, fmt.Errorf("通知渠道 '%s': 未知的渠道类型 '%s'", c.Name, c.Type)
+	}
+	notifier := factory()
+	if c.node != nil {
+		if err := c.node.Decode(notifier); err != nil {
+			return nil, fmt.Errorf("通知渠道 '%s': 解析配置失败: %v", c.Name, err)
+		}
+	}
+	return notifier, nil
+}
+
+// NotificationRouter 通知路由，根据服务配置将通知分发到对应渠道
+type NotificationRouter struct {
+	lock         sync.RWMutex
+	channels     map[string]Notifier
+	defaultRoute []string
+	// routes 各服务配置的通知渠道
+	routes map[string][]string
+	// quiet 各渠道的免打扰时段
+	quiet map[string]*quietWindow
+	// critical 标记为关键的服务，不受免打扰限制
+	critical map[string]bool
+	// digest 免打扰期间暂存的通知
+	digest digestQueue
+}
+
+// notifications 全局通知路由
+var notifications = &NotificationRouter{
+	channels: make(map[string]Notifier),
+	routes:   make(map[string][]string),
+}
+
+// buildChannels 校验并创建配置中的全部通知渠道
+func (nc *NotificationConfig) buildChannels(services []ServiceConfig) (map[string]Notifier, error) {
+	channels := make(map[string]Notifier, len(nc.Channels))
+	for i := range nc.Channels {
+		cc := &nc.Channels[i]
+		if cc.Name == "" {
+			return nil, fmt.Errorf("第 %d 个通知渠道缺少名称", i+1)
+		}
+		if _, ok := channels[cc.Name]; ok {
+			return nil, fmt.Errorf("通知渠道名称 '%s' 重复", cc.Name)
+		}
+		notifier, err := cc.Build()
+		if err != nil {
+			return nil, err
+		}
+		channels[cc.Name] = notifier
+		if cc.QuietHours != nil {
+			if _, err := cc.QuietHours.parse(); err != nil {
+				return nil, fmt.Errorf("通知渠道 '%s' 的免打扰时段: %v", cc.Name, err)
+			}
+		}
+	}
+
+	check := func(owner string, route []string) error {
+		for _, name := range route {
+			if _, ok := channels[name]; !ok {
+				return fmt.Errorf("%s 引用的通知渠道 '%s' 不存在", owner, name)
+			}
+		}
+		return nil
+	}
+	if err := check("default_route", nc.DefaultRoute); err != nil {
+		return nil, err
+	}
+	for i, step := range nc.Escalation.Steps {
+		if err := check(fmt.Sprintf("第 %d 条升级规则", i+1), step.Channels); err != nil {
+			return nil, err
+		}
+	}
+	for _, sc := range services {
+		owner := fmt.Sprintf("服务 '%s'", sc.Name)
+		if err := check(owner, sc.Notify); err != nil {
+			return nil, err
+		}
+		if sc.Escalation != nil {
+			for _, step := range sc.Escalation.Steps {
+				if err := check(owner+" 的升级规则", step.Channels); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return channels, nil
+}
+
+// Update 根据配置重建通知渠道与路由
+func (nr *NotificationRouter) Update(cfg *Config) error {
+	apply, err := nr.prepare(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// prepare 构建通知渠道与路由，返回替换为新渠道与路由的函数
+func (nr *NotificationRouter) prepare(cfg *Config) (func(), error) {
+	channels, err := cfg.Notifications.buildChannels(cfg.Services)
+	if err != nil {
+		return nil, err
+	}
+	routes := make(map[string][]string, len(cfg.Services))
+	critical := make(map[string]bool)
+	for _, sc := range cfg.Services {
+		if len(sc.Notify) > 0 {
+			routes[sc.Name] = sc.Notify
+		}
+		if sc.Critical {
+			critical[sc.Name] = true
+		}
+	}
+	quiet := make(map[string]*quietWindow)
+	for _, cc := range cfg.Notifications.Channels {
+		if cc.QuietHours != nil {
+			quiet[cc.Name], _ = cc.QuietHours.parse()
+		}
+	}
+
+	return func() {
+		nr.lock.Lock()
+		defer nr.lock.Unlock()
+		nr.channels = channels
+		nr.defaultRoute = cfg.Notifications.DefaultRoute
+		nr.routes = routes
+		nr.quiet = quiet
+		nr.critical = critical
+	}, nil
+}
+
+// route 返回服务对应的通知渠道名称
+func (nr *NotificationRouter) route(service string) []string {
+	if route, ok := nr.routes[service]; ok {
+		return route
+	}
+	return nr.defaultRoute
+}
+
+// Send 将通知异步发送到服务对应的各个渠道
+func (nr *NotificationRouter) Send(n Notification) {
+	nr.lock.RLock()
+	defer nr.lock.RUnlock()
+	for _, name := range nr.route(n.Service) {
+		nr.deliver(name, nr.channels[name], n)
+	}
+}
+
+// SendTo 将通知异步发送到指定渠道
+func (nr *NotificationRouter) SendTo(channels []string, n Notification) {
+	nr.lock.RLock()
+	defer nr.lock.RUnlock()
+	for _, name := range channels {
+		nr.deliver(name, nr.channels[name], n)
+	}
+}
+
+// deliver 在后台发送通知并记录结果
+func (nr *NotificationRouter) deliver(name string, notifier Notifier, n Notification) {
+	if notifier == nil {
+		return
+	}
+	if window := nr.quiet[name]; window != nil && !nr.critical[n.Service] && window.Contains(time.Now()) {
+		nr.digest.push(name, n)
+		slog.Debug("免打扰时段，通知已暂存", "channel", name, "service", n.Service, "kind", n.Kind)
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		if err := notifier.Notify(ctx, n); err != nil {
+			slog.Error("发送通知失败", "channel", name, "service", n.Service, "kind", n.Kind, "error", err)
+			return
+		}
+		slog.Info("通知已发送", "channel", name, "service", n.Service, "kind", n.Kind)
+	}()
+}
+
+// flushDigests 向免打扰已结束的渠道发送汇总通知，已删除渠道的暂存通知被丢弃
+func (nr *NotificationRouter) flushDigests(now time.Time) {
+	nr.lock.RLock()
+	defer nr.lock.RUnlock()
+	ready := func(channel string) bool {
+		window := nr.quiet[channel]
+		return window == nil || !window.Contains(now)
+	}
+	for channel, queued := range nr.digest.take(ready) {
+		nr.deliver(channel, nr.channels[channel], digestNotification(queued, now))
+	}
+}
+
+// Start 在后台定期发送免打扰结束后的汇总通知
+func (nr *NotificationRouter) Start() {
+	go func() {
+		ticker := time.NewTicker(digestTick)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			nr.flushDigests(now)
+		}
+	}()
+}
+
+// statusChangeMessage 生成状态变化通知正文
+func statusChangeMessage(t Transition) string {
+	var msg string
+	switch t.Kind {
+	case "latency_warn":
+		msg = fmt.Sprintf("[JJApps Status] 服务 %s 延迟偏高", t.Service)
+	case "latency_critical":
+		msg = fmt.Sprintf("[JJApps Status] 服务 %s 延迟严重超标", t.Service)
+	case "latency_recovered":
+		msg = fmt.Sprintf("[JJApps Status] 服务 %s 延迟已恢复正常", t.Service)
+	case "latency_anomaly":
+		msg = fmt.Sprintf("[JJApps Status] 服务 %s 出现性能异常", t.Service)
+	case "anomaly_recovered":
+		msg = fmt.Sprintf("[JJApps Status] 服务 %s 性能异常已恢复", t.Service)
+	default:
+		msg = fmt.Sprintf("[JJApps Status] 服务 %s 状态变化: %s → %s", t.Service, statusTitle(localeZH, t.From), statusTitle(localeZH, t.To))
+	}
+	if t.Reason != "" {
+		msg += "\n原因: " + t.Reason
+	}
+	return msg + "\n时间: " + formatTime(localeZH, t.Time, "datetime")
+}
+
+// notifyTransition 状态变化时发送通知，启动后的首次检查不发送
+func notifyTransition(t Transition) {
+	if t.Initial || t.Quiet {
+		return
+	}
+	// 已确认的故障不再发送重复通知，恢复时确认已被清除
+	if serviceManager.Acknowledgement(t.Service) != nil {
+		slog.Debug("故障已确认，通知已忽略", "service", t.Service, "kind", t.Kind)
+		return
+	}
+	notifications.Send(Notification{
+		Kind:    t.Kind,
+		Service: t.Service,
+		From:    t.From,
+		To:      t.To,
+		Time:    t.Time,
+		Message: statusChangeMessage(t),
+	})
+}
+
+// postJSON 以JSON格式POST请求，状态码非2xx时返回错误
+func postJSON(ctx context.Context, url string, body interface{}, headers map[string]string) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP状态码异常: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// WebhookNotifier 以JSON格式POST通知内容到指定URL
+type WebhookNotifier struct {
+	// URL 接收通知的地址
+	URL string `yaml:"url"`
+	// Headers 附加的请求头
+	Headers map[string]string `yaml:"headers"`
+}
+
+// Notify 实现Notifier接口
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, w.URL, n, w.Headers)
+}
+
+// TelegramNotifier 通过Telegram机器人发送通知
+type TelegramNotifier struct {
+	// BotToken 机器人令牌
+	BotToken string `yaml:"bot_token"`
+	// ChatID 接收消息的会话ID
+	ChatID string `yaml:"chat_id"`
+}
+
+// Notify 实现Notifier接口
+func (t *TelegramNotifier) Notify(ctx context.Context, n Notification) error {
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.BotToken)
+	return postJSON(ctx, url, map[string]string{
+		"chat_id": t.ChatID,
+		"text":    n.Message,
+	}, nil)
+}
+
+// EmailNotifier 通过SMTP发送邮件通知
+type EmailNotifier struct {
+	// Host SMTP服务器地址
+	Host string `yaml:"host"`
+	// Port SMTP端口，默认587
+	Port int `yaml:"port"`
+	// Username 登录用户名，为空时不认证
+	Username string `yaml:"username"`
+	// Password 登录密码
+	Password string `yaml:"password"`
+	// From 发件人
+	From string `yaml:"from"`
+	// To 收件人列表
+	To []string `yaml:"to"`
+}
+
+// emailChannel 返回名为name的email类型通知渠道
+func (nc *NotificationConfig) emailChannel(name string) (*EmailNotifier, error) {
+	for i := range nc.Channels {
+		cc := &nc.Channels[i]
+		if cc.Name != name {
+			continue
+		}
+		notifier, err := cc.Build()
+		if err != nil {
+			return nil, err
+		}
+		email, ok := notifier.(*EmailNotifier)
+		if !ok {
+			return nil, fmt.Errorf("通知渠道 '%s' 不是email类型", name)
+		}
+		return email, nil
+	}
+	return nil, fmt.Errorf("通知渠道 '%s' 不存在", name)
+}
+
+// Notify 实现Notifier接口
+func (e *EmailNotifier) Notify(ctx context.Context, n Notification) error {
+	subject := strings.SplitN(n.Message, "\n", 2)[0]
+	return e.send(ctx, e.To, subject, n.Message)
+}
+
+// send 发送一封纯文本邮件
+func (e *EmailNotifier) send(ctx context.Context, to []string, subject, body string) error {
+	return e.sendMessage(ctx, to, subject, "text/plain", body)
+}
+
+// sendMessage 发送一封指定内容类型的邮件，如text/plain或text/html
+func (e *EmailNotifier) sendMessage(ctx context.Context, to []string, subject, contentType, body string) error {
+	port := e.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		auth = smtp.PlainAuth("", e.Username, e.Password, e.Host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: %s; charset=UTF-8\r\n\r\n%s",
+		e.From, strings.Join(to, ", "), mime.BEncoding.Encode("UTF-8", subject), contentType, body)
+
+	// net/smtp不支持context，使用协程配合超时控制
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, e.From, to, []byte(msg))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// defaultNTPMaxOffset 默认允许的最大时钟偏差
+	defaultNTPMaxOffset = 500 * time.Millisecond
+	// ntpEpochOffset NTP纪元（1900年）与Unix纪元之间的秒数
+	ntpEpochOffset = 2208988800
+)
+
+// NTPChecker 时钟偏差检查器：向NTP服务器查询时间并计算本机时钟偏差，
+// 偏差超过max_offset时显示为降级，超过critical_offset时显示为离线
+type NTPChecker struct {
+	// Server NTP服务器地址，未指定端口时使用123，默认pool.ntp.org
+	Server string `yaml:"server"`
+	// MaxOffset 允许的最大偏差，默认500ms
+	MaxOffset time.Duration `yaml:"max_offset"`
+	// CriticalOffset 视为离线的偏差，0表示不检查
+	CriticalOffset time.Duration `yaml:"critical_offset"`
+	// Timeout 超时时间，默认5s
+	Timeout time.Duration `yaml:"timeout"`
+
+	checkTransport `yaml:",inline"`
+}
+
+// validate 校验时钟偏差检查配置
+func (n *NTPChecker) validate() error {
+	if n.MaxOffset < 0 || n.CriticalOffset < 0 {
+		return fmt.Errorf("时钟偏差阈值不能为负数")
+	}
+	if n.CriticalOffset > 0 && n.CriticalOffset < n.maxOffset() {
+		return fmt.Errorf("critical_offset不能小于max_offset")
+	}
+	return n.checkTransport.validateUDP("NTP检查")
+}
+
+// maxOffset 返回允许的最大偏差
+func (n *NTPChecker) maxOffset() time.Duration {
+	if n.MaxOffset > 0 {
+		return n.MaxOffset
+	}
+	return defaultNTPMaxOffset
+}
+
+// CheckStatus 实现StatusChecker接口
+func (n *NTPChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	server := n.Server
+	if server == "" {
+		server = "pool.ntp.org"
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	offset, err := queryNTP(ctx, n.dialer(timeout), server)
+	if err != nil {
+		return StatusOffline, fmt.Errorf("查询NTP服务器 %s 失败: %v", server, err)
+	}
+	abs := offset
+	if abs < 0 {
+		abs = -abs
+	}
+	if n.CriticalOffset > 0 && abs > n.CriticalOffset {
+		return StatusOffline, fmt.Errorf("本机时钟偏差 %s，超过 %s", offset.Round(time.Millisecond), n.CriticalOffset)
+	}
+	if abs > n.maxOffset() {
+		return StatusDegraded, fmt.Errorf("本机时钟偏差 %s，超过 %s", offset.Round(time.Millisecond), n.maxOffset())
+	}
+	return StatusOnline, nil
+}
+
+// queryNTP 发送SNTP请求并返回本机时钟相对服务器的偏差，本机时间落后时为正数
+func queryNTP(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), server string) (time.Duration, error) {
+	conn, err := dial(ctx, "udp", server)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	req := make([]byte, 48)
+	// LI=0，版本4，客户端模式
+	req[0] = 0x23
+	t1 := time.Now()
+	binary.BigEndian.PutUint64(req[40:], toNTPTime(t1))
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+
+	resp := make([]byte, 48)
+	for {
+		size, err := conn.Read(resp)
+		if err != nil {
+			return 0, err
+		}
+		// 忽略不是对本次请求的响应
+		if size >= 48 && resp[0]&0x07 == 4 && binary.BigEndian.Uint64(resp[24:]) == binary.BigEndian.Uint64(req[40:]) {
+			break
+		}
+	}
+	t4 := time.Now()
+
+	if resp[1] == 0 {
+		return 0, fmt.Errorf("服务器拒绝了请求（%s）", string(resp[12:16]))
+	}
+	if resp[0]>>6 == 3 {
+		return 0, fmt.Errorf("服务器时钟未同步")
+	}
+	t2 := fromNTPTime(binary.BigEndian.Uint64(resp[32:]))
+	t3 := fromNTPTime(binary.BigEndian.Uint64(resp[40:]))
+	return (t2.Sub(t1) + t3.Sub(t4)) / 2, nil
+}
+
+// toNTPTime 转换为NTP时间戳
+func toNTPTime(t time.Time) uint64 {
+	nsec := uint64(t.UnixNano()) + ntpEpochOffset*1e9
+	sec := nsec / 1e9
+	frac := (nsec % 1e9) << 32 / 1e9
+	return sec<<32 | frac
+}
+
+// fromNTPTime 转换NTP时间戳
+func fromNTPTime(ts uint64) time.Time {
+	sec := int64(ts>>32) - ntpEpochOffset
+	nsec := int64((ts & 0xffffffff) * 1e9 >> 32)
+	return time.Unix(sec, nsec)
+}
@@ -0,0 +1,562 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// sessionCookie 登录会话的Cookie名称
+	sessionCookie = "status_session"
+	// oidcStateCookie 保存登录请求state的Cookie名称，回调时校验，确保登录由同一浏览器发起
+	oidcStateCookie = "status_oidc_state"
+	// defaultSessionTTL 登录会话的默认有效期
+	defaultSessionTTL = 12 * time.Hour
+	// oidcLoginTimeout 跳转到身份提供方后完成登录的时限
+	oidcLoginTimeout = 10 * time.Minute
+	// oidcRequestTimeout 请求身份提供方的超时时间
+	oidcRequestTimeout = 10 * time.Second
+	// defaultOIDCGroupsClaim 默认的用户组声明名称
+	defaultOIDCGroupsClaim = "groups"
+)
+
+// defaultOIDCScopes 默认申请的授权范围
+var defaultOIDCScopes = []string{"openid", "profile", "email"}
+
+// oidcRoleClaims roles键的前缀与对应的ID令牌声明，不同声明分开匹配，
+// 避免用户将可自行修改的用户名或用户组设为他人的邮箱以获得其角色
+var oidcRoleClaims = []struct {
+	prefix string
+	claim  string
+}{
+	{"email:", "email"},
+	{"user:", "preferred_username"},
+	{"sub:", "sub"},
+}
+
+// oidcGroupPrefix 按用户组授予角色的roles键前缀
+const oidcGroupPrefix = "group:"
+
+// OIDCConfig 通过OpenID Connect身份提供方登录浏览器会话的配置，登录后按用户或用户组授予角色，
+// 会话Cookie与Bearer令牌的权限相同
+type OIDCConfig struct {
+	// Issuer 身份提供方地址，从<issuer>/.well-known/openid-configuration获取接口地址，为空时不启用
+	Issuer string `yaml:"issuer,omitempty"`
+	// ClientID 客户端ID
+	ClientID string `yaml:"client_id,omitempty"`
+	// ClientSecret 客户端密钥，公共客户端可为空
+	ClientSecret string `yaml:"client_secret,omitempty"`
+	// RedirectURL 回调地址，需为本服务对外地址下的/auth/callback
+	RedirectURL string `yaml:"redirect_url,omitempty"`
+	// Scopes 申请的授权范围，默认openid profile email
+	Scopes []string `yaml:"scopes,omitempty"`
+	// GroupsClaim ID令牌中用户组的声明名称，默认groups
+	GroupsClaim string `yaml:"groups_claim,omitempty"`
+	// Roles 按邮箱、用户名、sub或用户组授予的角色，键分别以email:、user:、sub:、group:开头，
+	// 匹配多项时取最高的角色
+	Roles map[string]Role `yaml:"roles,omitempty"`
+	// DefaultRole 未匹配roles的用户的角色，为空时拒绝登录
+	DefaultRole Role `yaml:"default_role,omitempty"`
+	// SessionTTL 会话有效期，默认12h
+	SessionTTL time.Duration `yaml:"session_ttl,omitempty"`
+}
+
+// enabled 是否启用了OIDC登录
+func (oc *OIDCConfig) enabled() bool {
+	return oc.Issuer != ""
+}
+
+// validate 校验OIDC配置，未启用时不校验
+func (oc *OIDCConfig) validate() error {
+	if !oc.enabled() {
+		return nil
+	}
+	if u, err := url.Parse(oc.Issuer); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("OIDC的issuer无效")
+	}
+	if oc.ClientID == "" {
+		return fmt.Errorf("OIDC缺少client_id")
+	}
+	if u, err := url.Parse(oc.RedirectURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("OIDC的redirect_url无效")
+	}
+	if oc.SessionTTL < 0 {
+		return fmt.Errorf("OIDC的session_ttl不能为负数")
+	}
+	for key := range oc.Roles {
+		if !validOIDCRoleKey(key) {
+			return fmt.Errorf("OIDC的roles键 '%s' 无效，需以email:、user:、sub:或group:开头", key)
+		}
+	}
+	return nil
+}
+
+// validOIDCRoleKey 判断roles键是否带有已知的前缀且前缀后不为空
+func validOIDCRoleKey(key string) bool {
+	prefixes := []string{oidcGroupPrefix}
+	for _, rc := range oidcRoleClaims {
+		prefixes = append(prefixes, rc.prefix)
+	}
+	for _, prefix := range prefixes {
+		if v, ok := strings.CutPrefix(key, prefix); ok {
+			return v != ""
+		}
+	}
+	return false
+}
+
+// withDefaults 返回补充默认值后的配置
+func (oc OIDCConfig) withDefaults() OIDCConfig {
+	if len(oc.Scopes) == 0 {
+		oc.Scopes = defaultOIDCScopes
+	}
+	if oc.GroupsClaim == "" {
+		oc.GroupsClaim = defaultOIDCGroupsClaim
+	}
+	if oc.SessionTTL == 0 {
+		oc.SessionTTL = defaultSessionTTL
+	}
+	return oc
+}
+
+// role 根据ID令牌的声明确定用户的角色
+func (oc *OIDCConfig) role(claims map[string]interface{}) Role {
+	keys := make([]string, 0)
+	for _, rc := range oidcRoleClaims {
+		if v, ok := claims[rc.claim].(string); ok && v != "" {
+			keys = append(keys, rc.prefix+v)
+		}
+	}
+	switch groups := claims[oc.GroupsClaim].(type) {
+	case string:
+		keys = append(keys, oidcGroupPrefix+groups)
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				keys = append(keys, oidcGroupPrefix+s)
+			}
+		}
+	}
+	role, matched := RoleNone, false
+	for _, key := range keys {
+		if r, ok := oc.Roles[key]; ok {
+			role, matched = max(role, r), true
+		}
+	}
+	if !matched {
+		return oc.DefaultRole
+	}
+	return role
+}
+
+// oidcProvider 身份提供方的元数据
+type oidcProvider struct {
+	// Issuer 身份提供方标识，ID令牌的iss需与之一致
+	Issuer string `json:"issuer"`
+	// AuthorizationEndpoint 授权地址
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	// TokenEndpoint 令牌地址
+	TokenEndpoint string `json:"token_endpoint"`
+}
+
+// oidcLogin 跳转到身份提供方后等待回调的登录请求
+type oidcLogin struct {
+	// nonce ID令牌中需包含的随机值
+	nonce string
+	// verifier PKCE校验码
+	verifier string
+	// redirect 登录完成后返回的本站路径
+	redirect string
+	// expires 过期时间
+	expires time.Time
+}
+
+// session 已登录的浏览器会话
+type session struct {
+	// name 用户名称，作为审计日志中的操作者
+	name string
+	// role 授予的角色
+	role Role
+	// expires 过期时间
+	expires time.Time
+}
+
+// OIDCAuth OIDC登录与会话管理，会话保存在内存中，重启后需重新登录
+type OIDCAuth struct {
+	lock sync.Mutex
+	// cfg 当前的OIDC配置
+	cfg OIDCConfig
+	// provider 缓存的身份提供方元数据
+	provider *oidcProvider
+	// logins 按state记录的进行中的登录
+	logins map[string]*oidcLogin
+	// sessions 按会话ID记录的已登录会话
+	sessions map[string]*session
+}
+
+// oidcAuth 全局OIDC登录管理器
+var oidcAuth = &OIDCAuth{
+	logins:   make(map[string]*oidcLogin),
+	sessions: make(map[string]*session),
+}
+
+// prepare 校验OIDC配置，返回更新配置的函数
+func (a *OIDCAuth) prepare(cfg *Config) (func(), error) {
+	if err := cfg.OIDC.validate(); err != nil {
+		return nil, err
+	}
+	oc := cfg.OIDC.withDefaults()
+	return func() { a.set(oc) }, nil
+}
+
+// set 更新OIDC配置，配置变化时清除已有的会话，使角色调整立即生效
+func (a *OIDCAuth) set(oc OIDCConfig) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if reflect.DeepEqual(a.cfg, oc) {
+		return
+	}
+	if a.cfg.Issuer != oc.Issuer {
+		a.provider = nil
+	}
+	a.cfg = oc
+	a.logins = make(map[string]*oidcLogin)
+	a.sessions = make(map[string]*session)
+}
+
+// Update 校验并更新OIDC配置
+func (a *OIDCAuth) Update(cfg *Config) error {
+	apply, err := a.prepare(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// Enabled 是否启用了OIDC登录
+func (a *OIDCAuth) Enabled() bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.cfg.enabled()
+}
+
+// Session 返回请求的会话Cookie对应的用户，未登录或会话已过期时返回false
+func (a *OIDCAuth) Session(c *gin.Context) (APIToken, bool) {
+	id, err := c.Cookie(sessionCookie)
+	if err != nil || id == "" {
+		return APIToken{}, false
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	s, ok := a.sessions[id]
+	if !ok {
+		return APIToken{}, false
+	}
+	if time.Now().After(s.expires) {
+		delete(a.sessions, id)
+		return APIToken{}, false
+	}
+	return APIToken{Name: s.name, Role: s.role}, true
+}
+
+// discover 获取身份提供方的元数据，成功后缓存到配置变化为止
+func (a *OIDCAuth) discover(ctx context.Context, issuer string) (*oidcProvider, error) {
+	a.lock.Lock()
+	provider := a.provider
+	a.lock.Unlock()
+	if provider != nil {
+		return provider, nil
+	}
+
+	endpoint := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取OIDC配置失败，HTTP状态码: %d", resp.StatusCode)
+	}
+	provider = new(oidcProvider)
+	if err := json.NewDecoder(resp.Body).Decode(provider); err != nil {
+		return nil, fmt.Errorf("解析OIDC配置失败: %v", err)
+	}
+	if provider.Issuer != issuer || provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" {
+		return nil, fmt.Errorf("OIDC配置中的issuer或接口地址无效")
+	}
+
+	a.lock.Lock()
+	if a.cfg.Issuer == issuer {
+		a.provider = provider
+	}
+	a.lock.Unlock()
+	return provider, nil
+}
+
+// config 返回当前的OIDC配置
+func (a *OIDCAuth) config() OIDCConfig {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.cfg
+}
+
+// pruneLocked 清除过期的登录请求与会话，需持有锁调用
+func (a *OIDCAuth) pruneLocked(now time.Time) {
+	for state, login := range a.logins {
+		if now.After(login.expires) {
+			delete(a.logins, state)
+		}
+	}
+	for id, s := range a.sessions {
+		if now.After(s.expires) {
+			delete(a.sessions, id)
+		}
+	}
+}
+
+// safeRedirect 只允许跳转到本站的路径，其他值返回首页
+func safeRedirect(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/"
+	}
+	return target
+}
+
+// oidcLoginHandler 跳转到身份提供方登录，登录完成后返回redirect参数指定的本站路径
+func oidcLoginHandler(c *gin.Context) {
+	cfg := oidcAuth.config()
+	if !cfg.enabled() {
+		apiError(c, http.StatusNotFound, "error.login_disabled")
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), oidcRequestTimeout)
+	defer cancel()
+	provider, err := oidcAuth.discover(ctx, cfg.Issuer)
+	if err != nil {
+		slog.Warn("OIDC登录失败", "error", err)
+		apiError(c, http.StatusBadGateway, "error.login_failed", err.Error())
+		return
+	}
+
+	state, login := rand.Text(), &oidcLogin{
+		nonce:    rand.Text(),
+		verifier: rand.Text() + rand.Text(),
+		redirect: safeRedirect(c.Query("redirect")),
+		expires:  time.Now().Add(oidcLoginTimeout),
+	}
+	oidcAuth.lock.Lock()
+	oidcAuth.pruneLocked(time.Now())
+	oidcAuth.logins[state] = login
+	oidcAuth.lock.Unlock()
+
+	challenge := sha256.Sum256([]byte(login.verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {cfg.ClientID},
+		"redirect_uri":          {cfg.RedirectURL},
+		"scope":                 {strings.Join(cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {login.nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	// 身份提供方以顶层GET跳转回调，SameSite=Lax的Cookie会随回调请求发送
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(oidcLoginTimeout.Seconds()), "/auth", "", strings.HasPrefix(cfg.RedirectURL, "https://"), true)
+	c.Redirect(http.StatusFound, provider.AuthorizationEndpoint+sep+query.Encode())
+}
+
+// oidcCallbackHandler 处理身份提供方的回调：用授权码换取ID令牌，校验后创建会话
+func oidcCallbackHandler(c *gin.Context) {
+	cfg := oidcAuth.config()
+	if !cfg.enabled() {
+		apiError(c, http.StatusNotFound, "error.login_disabled")
+		return
+	}
+	if e := c.Query("error"); e != "" {
+		apiError(c, http.StatusUnauthorized, "error.login_failed", strings.TrimSpace(e+" "+c.Query("error_description")))
+		return
+	}
+
+	now := time.Now()
+	state := c.Query("state")
+	// state需与发起登录的浏览器中保存的一致，防止攻击者让受害者以攻击者的账号登录
+	cookie, err := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, "/auth", "", false, true)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		apiError(c, http.StatusBadRequest, "error.login_expired")
+		return
+	}
+	oidcAuth.lock.Lock()
+	login, ok := oidcAuth.logins[state]
+	delete(oidcAuth.logins, state)
+	oidcAuth.lock.Unlock()
+	if !ok || now.After(login.expires) {
+		apiError(c, http.StatusBadRequest, "error.login_expired")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), oidcRequestTimeout)
+	defer cancel()
+	claims, err := exchangeCode(ctx, cfg, c.Query("code"), login)
+	if err != nil {
+		slog.Warn("OIDC登录失败", "error", err)
+		apiError(c, http.StatusUnauthorized, "error.login_failed", err.Error())
+		return
+	}
+	name := oidcUserName(claims)
+	role := cfg.role(claims)
+	if role == RoleNone {
+		slog.Warn("OIDC用户没有可用的角色", "user", name)
+		apiError(c, http.StatusForbidden, "error.login_no_role", name)
+		return
+	}
+
+	id := rand.Text()
+	oidcAuth.lock.Lock()
+	oidcAuth.pruneLocked(now)
+	oidcAuth.sessions[id] = &session{name: name, role: role, expires: now.Add(cfg.SessionTTL)}
+	oidcAuth.lock.Unlock()
+	auditLog.Record(AuditEntry{Actor: name, ClientIP: requestIP(c), Action: auditLogin, Diff: "+role: " + role.String() + "\n"})
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookie, id, int(cfg.SessionTTL.Seconds()), "/", "", strings.HasPrefix(cfg.RedirectURL, "https://"), true)
+	c.Redirect(http.StatusFound, login.redirect)
+}
+
+// oidcLogoutHandler 退出登录并返回首页
+func oidcLogoutHandler(c *gin.Context) {
+	if id, err := c.Cookie(sessionCookie); err == nil {
+		oidcAuth.lock.Lock()
+		delete(oidcAuth.sessions, id)
+		oidcAuth.lock.Unlock()
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookie, "", -1, "/", "", false, true)
+	c.Redirect(http.StatusFound, "/")
+}
+
+// tokenResponse 令牌接口的响应
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchangeCode 用授权码换取ID令牌并返回校验后的声明。ID令牌直接通过TLS从令牌接口获取，
+// 按OpenID Connect规范以TLS校验代替签名校验，只校验iss、aud、exp与nonce
+func exchangeCode(ctx context.Context, cfg OIDCConfig, code string, login *oidcLogin) (map[string]interface{}, error) {
+	if code == "" {
+		return nil, fmt.Errorf("回调缺少code参数")
+	}
+	provider, err := oidcAuth.discover(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {cfg.RedirectURL},
+		"client_id":     {cfg.ClientID},
+		"code_verifier": {login.verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("解析令牌响应失败: %v", err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("%s %s", token.Error, token.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || token.IDToken == "" {
+		return nil, fmt.Errorf("令牌接口未返回ID令牌，HTTP状态码: %d", resp.StatusCode)
+	}
+
+	parts := strings.Split(token.IDToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("ID令牌格式无效")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("ID令牌格式无效")
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("ID令牌格式无效")
+	}
+	if iss, _ := claims["iss"].(string); iss != provider.Issuer {
+		return nil, fmt.Errorf("ID令牌的iss不匹配")
+	}
+	if !audienceContains(claims["aud"], cfg.ClientID) {
+		return nil, fmt.Errorf("ID令牌的aud不匹配")
+	}
+	if exp, _ := claims["exp"].(float64); time.Now().After(time.Unix(int64(exp), 0)) {
+		return nil, fmt.Errorf("ID令牌已过期")
+	}
+	if nonce, _ := claims["nonce"].(string); nonce != login.nonce {
+		return nil, fmt.Errorf("ID令牌的nonce不匹配")
+	}
+	return claims, nil
+}
+
+// audienceContains 判断ID令牌的aud声明是否包含客户端ID
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// oidcUserName 返回用户的显示名称，依次使用邮箱、用户名与sub
+func oidcUserName(claims map[string]interface{}) string {
+	for _, claim := range []string{"email", "preferred_username", "sub"} {
+		if v, ok := claims[claim].(string); ok && v != "" {
+			return v
+		}
+	}
+	return "oidc"
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiParam 接口参数说明
+type apiParam struct {
+	// Name 参数名称
+	Name string
+	// In 参数位置：query或header，路径参数由路由自动生成
+	In string
+	// Type 参数类型：string、integer或boolean
+	Type string
+	// Description 参数说明
+	Description string
+}
+
+// apiDoc 接口说明，路径、方法与路径参数由注册的路由生成
+type apiDoc struct {
+	// Summary 接口摘要
+	Summary string
+	// Tag 接口分组
+	Tag string
+	// Role 需要的最低角色，为RoleNone时不需要角色
+	Role Role
+	// Token 是否需要探针令牌等其他Bearer令牌
+	Token bool
+	// Params 查询参数
+	Params []apiParam
+	// Body 请求体说明，为空时没有请求体
+	Body string
+	// BodyType 请求体内容类型，默认为application/json
+	BodyType string
+	// Produces 响应内容类型，默认为application/json
+	Produces []string
+}
+
+// serviceListParams 服务列表接口的排序与分页参数
+var serviceListParams = []apiParam{
+	{Name: "sort", In: "query", Type: "string", Description: "排序字段：order（默认）、name、status、uptime或latency"},
+	{Name: "order", In: "query", Type: "string", Description: "排序方向：asc（默认）或desc"},
+	{Name: "status_first", In: "query", Type: "boolean", Description: "是否将异常的服务排在前面"},
+	{Name: "limit", In: "query", Type: "integer", Description: "返回的最大服务数，默认不分页，最大500"},
+	{Name: "offset", In: "query", Type: "integer", Description: "跳过的服务数"},
+}
+
+// apiDocs 各接口的说明，按"方法 路由"索引；未登记的路由仍会出现在文档中
+var apiDocs = map[string]apiDoc{
+	"GET /api/status": {
+		Summary: "所有可见服务的当前状态，携带viewer及以上角色的令牌时包含隐藏的服务",
+		Tag:     "status",
+		Params: append([]apiParam{
+			{Name: "lang", In: "query", Type: "string", Description: "语言：zh或en"},
+		}, serviceListParams...),
+	},
+	"GET /api/system": {Summary: "主机资源使用情况", Tag: "status"},
+	"POST /api/heartbeat/:token": {
+		Summary: "上报心跳检查的心跳",
+		Tag:     "status",
+	},
+	"GET /api/v1/services/:name": {
+		Summary: "服务详情：当前状态、检查器摘要、最近事件、最近90天可用率与最近24小时延迟，携带viewer及以上角色的令牌时包含检查器配置",
+		Tag:     "status",
+	},
+	"GET /api/v1/services/:name/checks": {
+		Summary: "服务的检查记录",
+		Tag:     "history",
+		Params: []apiParam{
+			{Name: "limit", In: "query", Type: "integer", Description: "返回的最大条数，默认50，最大500"},
+			{Name: "since", In: "query", Type: "string", Description: "起始时间（RFC3339）"},
+		},
+	},
+	"GET /api/v1/services/:name/uptime": {
+		Summary: "服务的可用率",
+		Tag:     "history",
+		Params: []apiParam{
+			{Name: "days", In: "query", Type: "integer", Description: "统计天数"},
+			{Name: "step", In: "query", Type: "string", Description: "统计粒度：day或hour"},
+		},
+	},
+	"GET /api/v1/services/:name/latency": {
+		Summary: "服务的延迟统计",
+		Tag:     "history",
+		Params: []apiParam{
+			{Name: "range", In: "query", Type: "string", Description: "统计范围，如24h、7d"},
+			{Name: "step", In: "query", Type: "string", Description: "统计粒度，如1h"},
+		},
+	},
+	"GET /api/v1/services/:name/histogram": {
+		Summary: "服务的延迟分布与按桶估算的P50/P95/P99，按小时汇总合并",
+		Tag:     "history",
+		Params: []apiParam{
+			{Name: "range", In: "query", Type: "string", Description: "统计范围，如24h、30d，默认24h"},
+		},
+	},
+	"GET /api/v1/services/:name/slo": {Summary: "服务的SLO达成情况与错误预算", Tag: "history"},
+	"GET /api/v1/slo":                {Summary: "所有配置了SLO的服务的达成情况", Tag: "history"},
+	"GET /api/v1/dependencies":       {Summary: "服务依赖关系图", Tag: "status"},
+	"GET /api/v1/summary": {
+		Summary: "按服务权重汇总的整体状态（operational、partial_outage或major_outage）与各状态的服务数",
+		Tag:     "status",
+	},
+	"GET /api/v1/events": {
+		Summary: "状态变化事件",
+		Tag:     "history",
+		Params: []apiParam{
+			{Name: "service", In: "query", Type: "string", Description: "只返回指定服务的事件"},
+			{Name: "since", In: "query", Type: "string", Description: "起始时间（RFC3339）"},
+			{Name: "until", In: "query", Type: "string", Description: "结束时间（RFC3339）"},
+			{Name: "limit", In: "query", Type: "integer", Description: "返回的最大条数，默认50，最大500"},
+			{Name: "offset", In: "query", Type: "integer", Description: "跳过的条数"},
+		},
+	},
+	"GET /api/v1/pages/:page/status": {
+		Summary: "独立状态页的服务状态",
+		Tag:     "status",
+		Params: append([]apiParam{
+			{Name: "token", In: "query", Type: "string", Description: "私有页面的访问令牌"},
+		}, serviceListParams...),
+	},
+	"POST /api/v1/agents/:name/report": {
+		Summary: "远程探针上报检查结果，请求需以探针令牌签名（X-Agent-Timestamp、X-Agent-Nonce、X-Agent-Signature），响应附带X-Server-Signature",
+		Tag:     "agent",
+		Token:   true,
+		Body:    "探针上报的服务状态列表",
+	},
+	"POST /api/v1/agents/:name/rotate": {
+		Summary: "远程探针轮换令牌，以当前令牌签名请求，返回以当前令牌签名的新令牌；探针使用新令牌前旧令牌仍然有效",
+		Tag:     "agent",
+		Token:   true,
+	},
+	"POST /api/v1/subscribe": {
+		Summary: "提交邮件订阅，确认邮件中的链接后生效",
+		Tag:     "subscribe",
+		Body:    `{"email": "邮箱地址", "services": ["服务名称"]}，services为空时订阅全部公开服务`,
+	},
+	"POST /api/v1/services/:name/check": {Summary: "立即重新检查服务", Tag: "admin", Role: RoleOperator},
+	"PUT /api/v1/services/:name/override": {
+		Summary: "设置服务的状态覆盖",
+		Tag:     "admin",
+		Role:    RoleOperator,
+		Body:    `{"status": "maintenance", "reason": "说明", "until": "RFC3339时间", "duration": "2h"}，status为online、offline、degraded或maintenance，until与duration二选一`,
+	},
+	"DELETE /api/v1/services/:name/override": {Summary: "清除服务的状态覆盖", Tag: "admin", Role: RoleOperator},
+	"POST /api/v1/services/:name/ack": {
+		Summary: "确认服务的故障，停止重复通知并在页面显示确认信息",
+		Tag:     "admin",
+		Role:    RoleOperator,
+		Body:    `{"by": "确认人", "note": "备注", "silence_until": "RFC3339时间", "silence": "2h"}，请求体可为空`,
+	},
+	"GET /api/v1/services/:name/diagnostics": {Summary: "服务离线时执行的诊断报告", Tag: "admin", Role: RoleViewer},
+	"GET /api/v1/export/history": {
+		Summary:  "导出检查记录",
+		Tag:      "admin",
+		Role:     RoleViewer,
+		Produces: []string{"application/json", "text/csv"},
+		Params: []apiParam{
+			{Name: "service", In: "query", Type: "string", Description: "只导出指定服务的记录"},
+			{Name: "since", In: "query", Type: "string", Description: "起始时间（RFC3339）"},
+			{Name: "format", In: "query", Type: "string", Description: "导出格式：json或csv"},
+		},
+	},
+	"GET /api/v1/reports/:month": {
+		Summary:  "月度可用性报告，包含各服务的可用率、故障记录、平均恢复时间与延迟分位数",
+		Tag:      "admin",
+		Role:     RoleViewer,
+		Produces: []string{"text/html", "application/pdf", "application/json"},
+		Params: []apiParam{
+			{Name: "format", In: "query", Type: "string", Description: "报告格式：html、pdf或json，PDF需使用 -tags chrome 编译"},
+		},
+	},
+	"GET /api/v1/boot-report": {
+		Summary: "启动检查的结果：按依赖关系逐层检查后启动时已经异常的服务，未启用boot_report时返回404",
+		Tag:     "admin",
+		Role:    RoleViewer,
+	},
+	"GET /api/v1/export/config": {
+		Summary:  "导出当前配置",
+		Tag:      "admin",
+		Role:     RoleAdmin,
+		Produces: []string{"application/yaml"},
+	},
+	"POST /api/v1/import/config": {
+		Summary:  "校验并导入配置，校验失败时不修改任何内容",
+		Tag:      "admin",
+		Role:     RoleAdmin,
+		Body:     "YAML格式的完整配置",
+		BodyType: "application/yaml",
+	},
+	"GET /api/v1/config/snapshots": {
+		Summary: "配置快照列表，按时间倒序，不包含配置内容",
+		Tag:     "admin",
+		Role:    RoleAdmin,
+	},
+	"GET /api/v1/config/snapshots/:id": {
+		Summary:  "快照的配置内容",
+		Tag:      "admin",
+		Role:     RoleAdmin,
+		Produces: []string{"application/yaml"},
+	},
+	"POST /api/v1/config/snapshots/:id/rollback": {
+		Summary: "将配置回滚到指定快照，写入配置文件并立即生效，校验失败时不修改任何内容",
+		Tag:     "admin",
+		Role:    RoleAdmin,
+	},
+	"GET /api/v1/audit": {
+		Summary: "管理操作与配置变更的审计记录，按时间倒序分页",
+		Tag:     "admin",
+		Role:    RoleAdmin,
+		Params: []apiParam{
+			{Name: "action", In: "query", Type: "string", Description: "只返回指定操作类型的记录，如override_set、service_updated"},
+			{Name: "target", In: "query", Type: "string", Description: "只返回指定操作对象（服务名称）的记录"},
+			{Name: "actor", In: "query", Type: "string", Description: "只返回指定操作者（令牌名称或config_file）的记录"},
+			{Name: "since", In: "query", Type: "string", Description: "起始时间（RFC3339）"},
+			{Name: "until", In: "query", Type: "string", Description: "结束时间（RFC3339）"},
+			{Name: "limit", In: "query", Type: "integer", Description: "返回的最大条数，默认50，最大500"},
+			{Name: "offset", In: "query", Type: "integer", Description: "跳过的条数"},
+		},
+	},
+}
+
+// routeParam 匹配gin路由中的路径参数
+var routeParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// buildOpenAPI 根据注册的/api路由生成OpenAPI 3.0文档
+func buildOpenAPI(routes gin.RoutesInfo) map[string]interface{} {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	paths := make(map[string]map[string]interface{})
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/api/") || route.Method == http.MethodOptions || strings.Contains(route.Path, "*") {
+			continue
+		}
+		doc, ok := apiDocs[route.Method+" "+route.Path]
+		if !ok {
+			doc = apiDoc{Summary: route.Path}
+		}
+
+		params := make([]map[string]interface{}, 0)
+		for _, m := range routeParam.FindAllStringSubmatch(route.Path, -1) {
+			params = append(params, map[string]interface{}{
+				"name": m[1], "in": "path", "required": true,
+				"schema": map[string]string{"type": "string"},
+			})
+		}
+		for _, p := range doc.Params {
+			params = append(params, map[string]interface{}{
+				"name": p.Name, "in": p.In, "description": p.Description,
+				"schema": map[string]string{"type": p.Type},
+			})
+		}
+
+		produces := doc.Produces
+		if len(produces) == 0 {
+			produces = []string{"application/json"}
+		}
+		content := make(map[string]interface{}, len(produces))
+		for _, ct := range produces {
+			content[ct] = map[string]interface{}{}
+		}
+		responses := map[string]interface{}{
+			"200": map[string]interface{}{"description": "成功", "content": content},
+			"400": errorResponse("请求参数无效"),
+			"429": errorResponse("请求过于频繁"),
+		}
+		if routeParam.MatchString(route.Path) {
+			responses["404"] = errorResponse("资源不存在")
+		}
+
+		op := map[string]interface{}{
+			"summary":     doc.Summary,
+			"operationId": operationID(route.Method, route.Path),
+			"parameters":  params,
+			"responses":   responses,
+		}
+		if doc.Tag != "" {
+			op["tags"] = []string{doc.Tag}
+		}
+		if doc.Role != RoleNone || doc.Token {
+			op["security"] = []map[string][]string{{"bearer": {}}}
+			responses["401"] = errorResponse("令牌无效")
+			responses["403"] = errorResponse("来源IP不在admin_access白名单中")
+		}
+		if doc.Role != RoleNone {
+			op["description"] = fmt.Sprintf("需要%s及以上角色的令牌", doc.Role)
+			responses["403"] = errorResponse("令牌的角色权限不足或来源IP不在admin_access白名单中")
+		}
+		if doc.Body != "" {
+			bodyType := doc.BodyType
+			if bodyType == "" {
+				bodyType = "application/json"
+			}
+			op["requestBody"] = map[string]interface{}{
+				"required":    true,
+				"description": doc.Body,
+				"content":     map[string]interface{}{bodyType: map[string]interface{}{}},
+			}
+		}
+
+		path := routeParam.ReplaceAllString(route.Path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":       "JJApps Status API",
+			"description": "服务状态、检查记录与管理接口；管理接口需在Authorization头中携带对应角色的Bearer令牌",
+			"version":     "v1",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]string{"type": "http", "scheme": "bearer"},
+			},
+			"schemas": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"error": map[string]string{"type": "string"}},
+				},
+			},
+		},
+	}
+}
+
+// errorResponse 返回错误响应的说明
+func errorResponse(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]string{"$ref": "#/components/schemas/Error"},
+			},
+		},
+	}
+}
+
+// operationID 根据方法与路由生成唯一的操作ID，如get_v1_services_name_checks
+func operationID(method, path string) string {
+	path = strings.TrimPrefix(path, "/api/")
+	path = strings.NewReplacer("/", "_", ":", "", "-", "_").Replace(path)
+	return strings.ToLower(method) + "_" + path
+}
+
+// swaggerUIPage Swagger UI页面，资源从CDN加载
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>JJApps Status API</title>
+<link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>SwaggerUIBundle({url: "openapi.json", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`
+
+// registerOpenAPI 在所有路由注册完成后注册OpenAPI文档与Swagger UI
+func registerOpenAPI(r *gin.Engine, api *gin.RouterGroup) {
+	spec := buildOpenAPI(r.Routes())
+	api.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, spec)
+	})
+	api.GET("/docs", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+	})
+}
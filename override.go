@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusOverride 手动设置的服务状态，生效期间优先于检查结果
+type StatusOverride struct {
+	// Status 强制显示的状态
+	Status ServiceStatus `json:"status" yaml:"status"`
+	// Reason 原因说明
+	Reason string `json:"reason,omitempty" yaml:"reason"`
+	// Until 过期时间，为空表示直到手动清除
+	Until *time.Time `json:"until,omitempty" yaml:"until"`
+}
+
+// Active 判断覆盖在now时刻是否生效
+func (o *StatusOverride) Active(now time.Time) bool {
+	return o != nil && (o.Until == nil || now.Before(*o.Until))
+}
+
+// validate 校验覆盖的状态，受影响状态由依赖关系计算得出，不能手动设置
+func (o *StatusOverride) validate() error {
+	switch o.Status {
+	case StatusOnline, StatusOffline, StatusDegraded, StatusMaintenance:
+		return nil
+	}
+	return fmt.Errorf("状态覆盖不支持状态 '%s'", o.Status)
+}
+
+// SetOverride 设置或清除（override为nil）服务的状态覆盖，立即重新计算服务状态
+func (sm *ServiceManager) SetOverride(name string, override *StatusOverride) (*Service, error) {
+	sm.lock.Lock()
+	service := sm.lookup(name)
+	if service == nil {
+		sm.lock.Unlock()
+		return nil, fmt.Errorf("服务 '%s' 不存在", name)
+	}
+	now := time.Now()
+	before := sm.statusSnapshot()
+	service.Override = override
+	refreshOverride(service, now)
+	sm.scheduleOverrideExpiry(now)
+	sm.applyDependencies()
+	transitions := sm.transitionsSince(before, nil, nil, nil, now)
+	snapshot := service.snapshot()
+	sm.lock.Unlock()
+	sm.emit(transitions)
+	return snapshot, nil
+}
+
+// refreshOverride 按当前的状态覆盖重新计算服务未考虑依赖关系时的状态，覆盖清除或过期时恢复为检查结果；
+// 需持有锁调用，调用方负责重新计算依赖关系并调用scheduleOverrideExpiry
+func refreshOverride(service *Service, now time.Time) {
+	service.checkStatus = applyOverride(service, service.baseStatus, now)
+}
+
+// scheduleOverrideExpiry 重新设置过期定时器，在最早的状态覆盖过期时恢复服务状态，需持有锁调用
+func (sm *ServiceManager) scheduleOverrideExpiry(now time.Time) {
+	var next *time.Time
+	for _, service := range sm.services {
+		if o := service.Override; o.Active(now) && o.Until != nil && (next == nil || o.Until.Before(*next)) {
+			next = o.Until
+		}
+	}
+	if sm.overrideTimer != nil {
+		sm.overrideTimer.Stop()
+		sm.overrideTimer = nil
+	}
+	if next != nil {
+		sm.overrideTimer = time.AfterFunc(next.Sub(now), sm.expireOverrides)
+	}
+}
+
+// expireOverrides 清除已过期的状态覆盖并恢复为检查结果，由覆盖的过期定时器调用
+func (sm *ServiceManager) expireOverrides() {
+	if sm.ctx.Err() != nil {
+		return
+	}
+	sm.lock.Lock()
+	now := time.Now()
+	before := sm.statusSnapshot()
+	expired := false
+	for _, service := range sm.services {
+		if service.Override != nil && !service.Override.Active(now) {
+			refreshOverride(service, now)
+			expired = true
+		}
+	}
+	sm.scheduleOverrideExpiry(now)
+	if !expired {
+		sm.lock.Unlock()
+		return
+	}
+	sm.applyDependencies()
+	transitions := sm.transitionsSince(before, nil, nil, nil, now)
+	sm.lock.Unlock()
+	sm.emit(transitions)
+}
+
+// applyOverride 在检查结果上应用状态覆盖，过期的覆盖会被清除，需持有锁调用
+func applyOverride(service *Service, status ServiceStatus, now time.Time) ServiceStatus {
+	if service.Override == nil {
+		return status
+	}
+	if !service.Override.Active(now) {
+		service.Override = nil
+		return status
+	}
+	return service.Override.Status
+}
+
+// overrideRequest 设置状态覆盖的请求
+type overrideRequest struct {
+	// Status 强制显示的状态，必填
+	Status *ServiceStatus `json:"status"`
+	// Reason 原因说明
+	Reason string `json:"reason"`
+	// Until 过期时间（RFC3339）
+	Until *time.Time `json:"until"`
+	// Duration 持续时间（如"2h"），与Until二选一
+	Duration string `json:"duration"`
+}
+
+// apiSetOverrideHandler 设置服务的状态覆盖
+func apiSetOverrideHandler(c *gin.Context) {
+	var req overrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiError(c, http.StatusBadRequest, "error.invalid_request", err.Error())
+		return
+	}
+
+	if req.Status == nil {
+		apiError(c, http.StatusBadRequest, "error.invalid_param", "status")
+		return
+	}
+	override := &StatusOverride{
+		Status: *req.Status,
+		Reason: req.Reason,
+		Until:  req.Until,
+	}
+	if err := override.validate(); err != nil {
+		apiError(c, http.StatusBadRequest, "error.invalid_param", "status")
+		return
+	}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			apiError(c, http.StatusBadRequest, "error.invalid_param", "duration")
+			return
+		}
+		until := time.Now().Add(d)
+		override.Until = &until
+	}
+
+	name := c.Param("name")
+	previous := currentOverride(name)
+	service, err := serviceManager.SetOverride(name, override)
+	if err != nil {
+		apiError(c, http.StatusNotFound, "error.service_not_found")
+		return
+	}
+	recordAudit(c, auditOverrideSet, name, yamlDiff(previous, override))
+	c.JSON(http.StatusOK, service)
+}
+
+// apiClearOverrideHandler 清除服务的状态覆盖
+func apiClearOverrideHandler(c *gin.Context) {
+	name := c.Param("name")
+	previous := currentOverride(name)
+	service, err := serviceManager.SetOverride(name, nil)
+	if err != nil {
+		apiError(c, http.StatusNotFound, "error.service_not_found")
+		return
+	}
+	recordAudit(c, auditOverrideClear, name, yamlDiff(previous, nil))
+	c.JSON(http.StatusOK, service)
+}
+
+// currentOverride 返回服务当前的状态覆盖，用于记录审计差异
+func currentOverride(name string) *StatusOverride {
+	if service := serviceManager.GetService(name); service != nil {
+		return service.Override
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultPageTitle 默认的页面标题
+const defaultPageTitle = "JJApps Status"
+
+// PageConfig 独立状态页配置，访问路径为/p/<name>
+type PageConfig struct {
+	// Name 页面名称
+	Name string `yaml:"name"`
+	// Title 页面标题，为空时使用主题配置的标题
+	Title string `yaml:"title,omitempty"`
+	// Services 页面展示的服务，为空时展示所有公开服务；列出的隐藏服务同样展示
+	Services []string `yaml:"services,omitempty"`
+	// Token 访问令牌，为空时无需认证；可通过Bearer令牌或token参数提供，viewer及以上角色的令牌同样有效
+	Token string `yaml:"token,omitempty"`
+	// HideSystem 是否隐藏主机资源
+	HideSystem bool `yaml:"hide_system,omitempty"`
+}
+
+// validatePages 校验状态页配置：名称唯一且引用的服务存在
+func (cfg *Config) validatePages() error {
+	services := make(map[string]bool, len(cfg.Services))
+	for _, sc := range cfg.Services {
+		services[sc.Name] = true
+	}
+	seen := make(map[string]bool, len(cfg.Pages))
+	for i, page := range cfg.Pages {
+		if page.Name == "" {
+			return fmt.Errorf("第 %d 个状态页缺少名称", i+1)
+		}
+		if seen[page.Name] {
+			return fmt.Errorf("状态页名称 '%s' 重复", page.Name)
+		}
+		seen[page.Name] = true
+		for _, name := range page.Services {
+			if !services[name] {
+				return fmt.Errorf("状态页 '%s' 引用了不存在的服务 '%s'", page.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// findPage 按名称查找状态页配置
+func findPage(name string) *PageConfig {
+	pages := configReloader.Current().Pages
+	for i := range pages {
+		if pages[i].Name == name {
+			return &pages[i]
+		}
+	}
+	return nil
+}
+
+// title 返回页面标题
+func (p *PageConfig) title() string {
+	if p.Title != "" {
+		return p.Title
+	}
+	return currentTheme().Title
+}
+
+// authorized 判断请求是否可以访问该页面
+func (p *PageConfig) authorized(c *gin.Context) bool {
+	if p.Token == "" || canViewPrivate(c) || validToken(c, p.Token) {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(p.Token)) == 1
+}
+
+// services 返回页面展示的服务，按显示顺序排列
+func (p *PageConfig) services(c *gin.Context) []*Service {
+	if len(p.Services) == 0 {
+		return visibleServices(c)
+	}
+	listed := make(map[string]bool, len(p.Services))
+	for _, name := range p.Services {
+		listed[name] = true
+	}
+	authorized := canViewPrivate(c)
+	services := make([]*Service, 0, len(p.Services))
+	for _, service := range serviceManager.GetServices() {
+		if listed[service.Name] && (service.Enabled || authorized) {
+			services = append(services, service)
+		}
+	}
+	sortServices(services)
+	return redactServices(c, services)
+}
+
+// pageFromRequest 获取请求的状态页并校验访问权限，失败时写入错误响应并返回nil
+func pageFromRequest(c *gin.Context) *PageConfig {
+	page := findPage(c.Param("page"))
+	if page == nil {
+		apiError(c, http.StatusNotFound, "error.page_not_found")
+		return nil
+	}
+	if !page.authorized(c) {
+		apiError(c, http.StatusUnauthorized, "error.unauthorized")
+		return nil
+	}
+	return page
+}
+
+// pageHandler 独立状态页处理器
+func pageHandler(c *gin.Context) {
+	page := pageFromRequest(c)
+	if page == nil {
+		return
+	}
+	statusURL := "/api/v1/pages/" + url.PathEscape(page.Name) + "/status"
+	if token := c.Query("token"); token != "" {
+		statusURL += "?token=" + url.QueryEscape(token)
+	}
+	renderStatusPage(c, page.title(), page.services(c), statusURL, "", !page.HideSystem)
+}
+
+// apiPageStatusHandler 独立状态页的状态接口
+func apiPageStatusHandler(c *gin.Context) {
+	page := pageFromRequest(c)
+	if page == nil {
+		return
+	}
+	opts, ok := parseServiceListOptions(c, ServiceListOptions{})
+	if !ok {
+		return
+	}
+	refreshOnDemand()
+
+	c.Header("Vary", "Authorization")
+	services := page.services(c)
+	changed, version := statusVersion(services)
+	if notModified(c, changed, version) {
+		return
+	}
+	resp := listServices(services, opts).statusResponse()
+	resp["page"] = page.Name
+	resp["title"] = page.title()
+	c.JSON(http.StatusOK, resp)
+}
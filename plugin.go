@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultPluginTimeout 插件检查的默认超时时间
+const defaultPluginTimeout = 10 * time.Second
+
+// PluginConfig 外部检查器插件，注册后可作为检查器类型使用。
+//
+// 插件协议：每次检查执行一次插件，标准输入为JSON格式的请求
+//
+//	{"service": "服务名称", "type": "插件名称", "config": {检查器配置}}
+//
+// 插件在标准输出中返回JSON格式的结果
+//
+//	{"status": "online|offline|degraded|maintenance", "message": "说明", "latency_ms": 12.5}
+//
+// status为online以外的值时message作为错误信息；latency_ms可选，未返回时使用插件的执行耗时。
+// 插件退出码非0且没有输出有效结果时视为离线，错误信息为标准错误的内容
+type PluginConfig struct {
+	// Name 插件名称，作为检查器类型，不能与内置检查器同名
+	Name string `yaml:"name"`
+	// Path 可执行文件路径
+	Path string `yaml:"path"`
+	// Args 执行参数
+	Args []string `yaml:"args,omitempty"`
+	// Env 额外的环境变量
+	Env map[string]string `yaml:"env,omitempty"`
+	// Timeout 超时时间，默认10s
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// validatePlugins 校验插件配置，返回按名称索引的插件
+func (cfg *Config) validatePlugins() (map[string]*PluginConfig, error) {
+	plugins := make(map[string]*PluginConfig, len(cfg.Plugins))
+	for i := range cfg.Plugins {
+		p := &cfg.Plugins[i]
+		if p.Name == "" {
+			return nil, fmt.Errorf("第 %d 个插件缺少名称", i+1)
+		}
+		if _, ok := checkerFactories[p.Name]; ok {
+			return nil, fmt.Errorf("插件名称 '%s' 与内置检查器重复", p.Name)
+		}
+		if _, ok := plugins[p.Name]; ok {
+			return nil, fmt.Errorf("插件名称 '%s' 重复", p.Name)
+		}
+		if p.Path == "" {
+			return nil, fmt.Errorf("插件 '%s' 缺少可执行文件路径", p.Name)
+		}
+		plugins[p.Name] = p
+	}
+	return plugins, nil
+}
+
+// pluginRequest 发送给插件的请求
+type pluginRequest struct {
+	Service string                 `json:"service"`
+	Type    string                 `json:"type"`
+	Config  map[string]interface{} `json:"config"`
+}
+
+// pluginResponse 插件返回的结果
+type pluginResponse struct {
+	Status    ServiceStatus `json:"status"`
+	Message   string        `json:"message"`
+	LatencyMS *float64      `json:"latency_ms"`
+}
+
+// PluginChecker 通过外部插件执行检查
+type PluginChecker struct {
+	plugin  PluginConfig
+	request []byte
+
+	mu      sync.Mutex
+	latency time.Duration
+}
+
+// newPluginChecker 根据检查器配置节点创建插件检查器
+func newPluginChecker(plugin *PluginConfig, service string, node *yaml.Node) (*PluginChecker, error) {
+	config := make(map[string]interface{})
+	if node != nil {
+		if err := node.Decode(&config); err != nil {
+			return nil, fmt.Errorf("解析检查器配置失败: %v", err)
+		}
+	}
+	delete(config, "type")
+	request, err := json.Marshal(pluginRequest{Service: service, Type: plugin.Name, Config: config})
+	if err != nil {
+		return nil, fmt.Errorf("插件 '%s' 的配置无法转换为JSON: %v", plugin.Name, err)
+	}
+	return &PluginChecker{plugin: *plugin, request: request}, nil
+}
+
+// CheckStatus 实现StatusChecker接口
+func (p *PluginChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	timeout := p.plugin.Timeout
+	if timeout <= 0 {
+		timeout = defaultPluginTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.plugin.Path, p.plugin.Args...)
+	cmd.Stdin = bytes.NewReader(p.request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// 插件的子进程可能继承输出管道，超时后不再等待管道关闭
+	cmd.WaitDelay = time.Second
+	cmd.Env = os.Environ()
+	for k, v := range p.plugin.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	start := time.Now()
+	runErr := cmd.Run()
+	elapsed := time.Since(start)
+	defer func() {
+		p.mu.Lock()
+		p.latency = elapsed
+		p.mu.Unlock()
+	}()
+	if ctx.Err() != nil {
+		return StatusOffline, fmt.Errorf("插件 '%s' 执行超时", p.plugin.Name)
+	}
+
+	var resp pluginResponse
+	if err := json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), &resp); err != nil {
+		if runErr != nil {
+			msg := strings.TrimSpace(stderr.String())
+			if msg == "" {
+				msg = runErr.Error()
+			}
+			return StatusOffline, fmt.Errorf("插件 '%s' 执行失败: %s", p.plugin.Name, msg)
+		}
+		return StatusOffline, fmt.Errorf("插件 '%s' 返回的结果无效: %v", p.plugin.Name, err)
+	}
+
+	if resp.LatencyMS != nil {
+		elapsed = time.Duration(*resp.LatencyMS * float64(time.Millisecond))
+	}
+
+	if resp.Status == StatusOnline {
+		return StatusOnline, nil
+	}
+	if resp.Message == "" {
+		resp.Message = fmt.Sprintf("插件返回状态 %s", resp.Status)
+	}
+	return resp.Status, fmt.Errorf("%s", resp.Message)
+}
+
+// Latency 实现LatencyReporter接口，返回插件报告的检查耗时
+func (p *PluginChecker) Latency() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.latency
+}
@@ -0,0 +1,19 @@
+package main
+
+// ProcessMetrics 进程资源使用情况，匹配到多个进程时为合计值
+type ProcessMetrics struct {
+	// PIDs 匹配到的进程ID
+	PIDs []int `json:"pids"`
+	// CPUPercent CPU使用率（单核百分比），为两次检查之间的平均值
+	CPUPercent float64 `json:"cpu_percent"`
+	// RSSBytes 常驻内存（字节）
+	RSSBytes uint64 `json:"rss_bytes"`
+	// UptimeSeconds 运行时间最长的进程的运行时间（秒）
+	UptimeSeconds float64 `json:"uptime_seconds"`
+}
+
+// ProcessMetricsReporter 可提供进程资源信息的检查器
+type ProcessMetricsReporter interface {
+	// ProcessMetrics 返回最近一次成功检查时采集的进程资源信息，无数据时返回nil
+	ProcessMetrics() *ProcessMetrics
+}
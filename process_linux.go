@@ -0,0 +1,130 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks /proc中CPU时间的单位（USER_HZ），Linux上固定为100
+const clockTicks = 100
+
+// procSample 进程CPU时间采样
+type procSample struct {
+	// cpuTicks 累计CPU时间
+	cpuTicks uint64
+	// at 采样时间
+	at time.Time
+}
+
+// collectProcessMetrics 从/proc采集命令行包含name的进程的资源使用情况
+func collectProcessMetrics(name string, prev map[int]procSample) (*ProcessMetrics, map[int]procSample, error) {
+	btime, err := bootTime()
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	self := os.Getpid()
+	pageSize := uint64(os.Getpagesize())
+	metrics := &ProcessMetrics{}
+	samples := make(map[int]procSample)
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		dir := filepath.Join("/proc", entry.Name())
+		cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+		if err != nil || len(cmdline) == 0 {
+			continue
+		}
+		cmd := string(bytes.ReplaceAll(cmdline, []byte{0}, []byte{' '}))
+		// 与ps ax | grep | grep -v grep的匹配规则保持一致
+		if !strings.Contains(cmd, name) || strings.Contains(cmd, "grep") {
+			continue
+		}
+
+		cpuTicks, startTicks, rssPages, err := readProcStat(dir)
+		if err != nil {
+			continue
+		}
+
+		metrics.PIDs = append(metrics.PIDs, pid)
+		metrics.RSSBytes += rssPages * pageSize
+
+		started := btime.Add(time.Duration(startTicks) * time.Second / clockTicks)
+		uptime := now.Sub(started)
+		if uptime.Seconds() > metrics.UptimeSeconds {
+			metrics.UptimeSeconds = uptime.Seconds()
+		}
+
+		// 有上次采样时按区间计算，否则按整个生命周期平均
+		usedTicks, elapsed := cpuTicks, uptime
+		if last, ok := prev[pid]; ok && cpuTicks >= last.cpuTicks {
+			usedTicks, elapsed = cpuTicks-last.cpuTicks, now.Sub(last.at)
+		}
+		if elapsed > 0 {
+			used := time.Duration(usedTicks) * time.Second / clockTicks
+			metrics.CPUPercent += float64(used) / float64(elapsed) * 100
+		}
+		samples[pid] = procSample{cpuTicks: cpuTicks, at: now}
+	}
+
+	if len(metrics.PIDs) == 0 {
+		return nil, samples, fmt.Errorf("未找到进程 '%s'", name)
+	}
+	return metrics, samples, nil
+}
+
+// readProcStat 读取/proc/<pid>/stat，返回累计CPU时间、启动时间与常驻内存页数
+func readProcStat(dir string) (cpuTicks, startTicks, rssPages uint64, err error) {
+	data, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	// 进程名可能包含空格，从最后一个')'之后开始解析
+	idx := bytes.LastIndexByte(data, ')')
+	if idx < 0 {
+		return 0, 0, 0, fmt.Errorf("无法解析 %s/stat", dir)
+	}
+	fields := strings.Fields(string(data[idx+1:]))
+	// fields[0]为第3个字段state，utime/stime/starttime/rss分别为第14/15/22/24个字段
+	if len(fields) < 22 {
+		return 0, 0, 0, fmt.Errorf("无法解析 %s/stat", dir)
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	startTicks, _ = strconv.ParseUint(fields[19], 10, 64)
+	rssPages, _ = strconv.ParseUint(fields[21], 10, 64)
+	return utime + stime, startTicks, rssPages, nil
+}
+
+// bootTime 读取系统启动时间
+func bootTime() (time.Time, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "btime "); ok {
+			sec, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(sec, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("/proc/stat中缺少btime")
+}
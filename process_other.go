@@ -0,0 +1,19 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// procSample 进程CPU时间采样
+type procSample struct {
+	cpuTicks uint64
+	at       time.Time
+}
+
+// collectProcessMetrics 非Linux平台不支持采集进程资源信息
+func collectProcessMetrics(name string, prev map[int]procSample) (*ProcessMetrics, map[int]procSample, error) {
+	return nil, nil, fmt.Errorf("当前平台不支持采集进程资源信息")
+}
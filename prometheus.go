@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PromQLChecker 通过Prometheus查询检查服务状态。
+// 配置compare时每条结果都需满足 <值> <compare> <threshold>；未配置时查询结果非空即为正常，
+// 适用于 up{job="apollo"} == 1 这类过滤型表达式
+type PromQLChecker struct {
+	// URL Prometheus地址
+	URL string `yaml:"url"`
+	// Query PromQL查询语句
+	Query string `yaml:"query"`
+	// Compare 比较运算符：==、!=、>、>=、<、<=
+	Compare string `yaml:"compare"`
+	// Threshold 比较的阈值
+	Threshold float64 `yaml:"threshold"`
+	// Headers 请求头，如Authorization
+	Headers map[string]string `yaml:"headers"`
+	// Timeout 查询超时时间，默认10s
+	Timeout time.Duration `yaml:"timeout"`
+
+	checkTransport `yaml:",inline"`
+}
+
+// promResponse Prometheus即时查询响应
+type promResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// promSample 查询结果中的一条样本
+type promSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  [2]interface{}    `json:"value"`
+}
+
+// compareValue 按运算符比较数值
+func compareValue(v float64, op string, threshold float64) (bool, error) {
+	switch op {
+	case "==":
+		return v == threshold, nil
+	case "!=":
+		return v != threshold, nil
+	case ">":
+		return v > threshold, nil
+	case ">=":
+		return v >= threshold, nil
+	case "<":
+		return v < threshold, nil
+	case "<=":
+		return v <= threshold, nil
+	}
+	return false, fmt.Errorf("未知的比较运算符 '%s'", op)
+}
+
+// sampleValue 解析样本值
+func sampleValue(value [2]interface{}) (float64, error) {
+	s, ok := value[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("无效的样本值")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// query 执行即时查询并返回样本
+func (p *PromQLChecker) query(ctx context.Context) ([]promSample, error) {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	endpoint := strings.TrimRight(p.URL, "/") + "/api/v1/query?query=" + url.QueryEscape(p.Query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建查询请求失败: %v", err)
+	}
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := p.client(0).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求Prometheus失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result promResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析Prometheus响应失败: %v", err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("Prometheus查询失败: %s", result.Error)
+	}
+
+	switch result.Data.ResultType {
+	case "vector":
+		var samples []promSample
+		if err := json.Unmarshal(result.Data.Result, &samples); err != nil {
+			return nil, fmt.Errorf("解析查询结果失败: %v", err)
+		}
+		return samples, nil
+	case "scalar":
+		var value [2]interface{}
+		if err := json.Unmarshal(result.Data.Result, &value); err != nil {
+			return nil, fmt.Errorf("解析查询结果失败: %v", err)
+		}
+		return []promSample{{Value: value}}, nil
+	}
+	return nil, fmt.Errorf("不支持的查询结果类型 '%s'", result.Data.ResultType)
+}
+
+// CheckStatus 实现StatusChecker接口
+func (p *PromQLChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	if p.URL == "" || p.Query == "" {
+		return StatusOffline, fmt.Errorf("Prometheus地址和查询语句不能为空")
+	}
+	samples, err := p.query(ctx)
+	if err != nil {
+		return StatusOffline, err
+	}
+	if len(samples) == 0 {
+		return StatusOffline, fmt.Errorf("查询 '%s' 结果为空", p.Query)
+	}
+	if p.Compare == "" {
+		return StatusOnline, nil
+	}
+	for _, sample := range samples {
+		v, err := sampleValue(sample.Value)
+		if err != nil {
+			return StatusOffline, err
+		}
+		ok, err := compareValue(v, p.Compare, p.Threshold)
+		if err != nil {
+			return StatusOffline, err
+		}
+		if !ok {
+			return StatusOffline, fmt.Errorf("查询结果 %v 不满足 %s %v %s", v, p.Compare, p.Threshold, formatLabels(sample.Metric))
+		}
+	}
+	return StatusOnline, nil
+}
+
+// formatLabels 格式化样本标签
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(labels))
+	for k, v := range labels {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, v))
+	}
+	sort.Strings(parts)
+	return "{" + strings.Join(parts, ", ") + "}"
+}
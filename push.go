@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultPushInterval 默认推送间隔
+	defaultPushInterval = time.Minute
+	// defaultPushTimeout 单次推送的默认超时时间
+	defaultPushTimeout = 10 * time.Second
+	// defaultPushRetries 推送失败后默认的重试次数
+	defaultPushRetries = 3
+	// pushInitialBackoff 第一次重试前的等待时间，之后每次翻倍
+	pushInitialBackoff = time.Second
+)
+
+// 推送格式
+const (
+	// pushFormatSnapshot POST完整的状态快照JSON
+	pushFormatSnapshot = "snapshot"
+	// pushFormatUptimeKuma 请求Uptime Kuma的推送监控地址
+	pushFormatUptimeKuma = "uptime_kuma"
+)
+
+// PushTarget 定期推送状态快照的外部地址，用于接入中心监控面板等聚合系统
+type PushTarget struct {
+	// Name 目标名称，用于日志
+	Name string `yaml:"name"`
+	// URL 推送地址
+	URL string `yaml:"url"`
+	// Format 推送格式：snapshot（默认）POST状态快照JSON，uptime_kuma以GET请求Uptime Kuma推送监控地址
+	Format string `yaml:"format,omitempty"`
+	// Service uptime_kuma格式推送的服务，为空时推送所有公开服务的整体状态
+	Service string `yaml:"service,omitempty"`
+	// Headers 附加的请求头，如Authorization
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Interval 推送间隔，默认1m
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Timeout 单次推送的超时时间，默认10s
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Retries 推送失败后的重试次数，间隔从1s开始翻倍，默认3，为负数时不重试
+	Retries int `yaml:"retries,omitempty"`
+	// IncludeHidden 快照中是否包含隐藏的服务
+	IncludeHidden bool `yaml:"include_hidden,omitempty"`
+}
+
+// validate 校验推送目标配置
+func (pt *PushTarget) validate() error {
+	if pt.Name == "" {
+		return fmt.Errorf("推送目标缺少name")
+	}
+	if u, err := url.Parse(pt.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("推送目标 '%s' 的url无效", pt.Name)
+	}
+	switch pt.Format {
+	case "", pushFormatSnapshot, pushFormatUptimeKuma:
+	default:
+		return fmt.Errorf("推送目标 '%s' 不支持的格式 '%s'", pt.Name, pt.Format)
+	}
+	if pt.Service != "" && pt.Format != pushFormatUptimeKuma {
+		return fmt.Errorf("推送目标 '%s' 的service仅用于uptime_kuma格式", pt.Name)
+	}
+	return nil
+}
+
+// withDefaults 返回补充默认值后的配置
+func (pt PushTarget) withDefaults() PushTarget {
+	if pt.Format == "" {
+		pt.Format = pushFormatSnapshot
+	}
+	if pt.Interval <= 0 {
+		pt.Interval = defaultPushInterval
+	}
+	if pt.Timeout <= 0 {
+		pt.Timeout = defaultPushTimeout
+	}
+	if pt.Retries == 0 {
+		pt.Retries = defaultPushRetries
+	}
+	return pt
+}
+
+// validatePush 校验推送目标，名称不能重复
+func (cfg *Config) validatePush() error {
+	names := make(map[string]bool, len(cfg.Push))
+	for i := range cfg.Push {
+		target := &cfg.Push[i]
+		if err := target.validate(); err != nil {
+			return err
+		}
+		if names[target.Name] {
+			return fmt.Errorf("推送目标 '%s' 重复", target.Name)
+		}
+		names[target.Name] = true
+	}
+	return nil
+}
+
+// StatusSnapshot 推送的状态快照
+type StatusSnapshot struct {
+	// Source 本实例的检查位置名称
+	Source string `json:"source"`
+	// Status 所有服务中最差的状态
+	Status ServiceStatus `json:"status"`
+	// Services 服务状态
+	Services []*Service `json:"services"`
+	// SentAt 生成时间
+	SentAt time.Time `json:"sent_at"`
+}
+
+// worstStatus 返回服务中最严重的状态，没有服务时为在线
+func worstStatus(services []*Service) ServiceStatus {
+	worst := StatusOnline
+	for _, service := range services {
+		if statusSeverity[service.Status] > statusSeverity[worst] {
+			worst = service.Status
+		}
+	}
+	return worst
+}
+
+// Pusher 按配置定期向外部地址推送状态
+type Pusher struct {
+	lock sync.Mutex
+	// cancel 停止当前的推送任务
+	cancel context.CancelFunc
+}
+
+// pusher 全局状态推送器
+var pusher = &Pusher{}
+
+// Update 校验配置并重新启动推送任务，管理器关闭时所有任务随之停止
+func (p *Pusher) Update(cfg *Config) error {
+	apply, err := p.prepare(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// prepare 校验推送配置，返回重新启动推送任务的函数
+func (p *Pusher) prepare(cfg *Config) (func(), error) {
+	if err := cfg.validatePush(); err != nil {
+		return nil, err
+	}
+	return func() {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		if p.cancel != nil {
+			p.cancel()
+		}
+		ctx, cancel := context.WithCancel(serviceManager.Context())
+		p.cancel = cancel
+		for _, target := range cfg.Push {
+			go p.run(ctx, target.withDefaults())
+		}
+	}, nil
+}
+
+// run 按间隔推送，直到ctx取消
+func (p *Pusher) run(ctx context.Context, target PushTarget) {
+	ticker := time.NewTicker(target.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.pushWithRetry(ctx, target)
+		}
+	}
+}
+
+// pushWithRetry 推送一次，失败时按指数退避重试，退避时间不超过推送间隔
+func (p *Pusher) pushWithRetry(ctx context.Context, target PushTarget) {
+	backoff := pushInitialBackoff
+	for attempt := 0; ; attempt++ {
+		err := p.push(ctx, target)
+		if err == nil {
+			slog.Debug("状态推送完成", "target", target.Name, "attempt", attempt+1)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if attempt >= target.Retries {
+			slog.Warn("状态推送失败", "target", target.Name, "attempts", attempt+1, "error", err)
+			return
+		}
+		slog.Debug("状态推送失败，稍后重试", "target", target.Name, "attempt", attempt+1, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, target.Interval)
+	}
+}
+
+// push 按目标格式推送一次当前状态
+func (p *Pusher) push(ctx context.Context, target PushTarget) error {
+	ctx, cancel := context.WithTimeout(ctx, target.Timeout)
+	defer cancel()
+
+	services := make([]*Service, 0)
+	for _, service := range allServices() {
+		if visible(service, target.IncludeHidden) && (target.Service == "" || service.Name == target.Service) {
+			services = append(services, service)
+		}
+	}
+	sortServices(services)
+	if target.Format == pushFormatUptimeKuma {
+		if target.Service != "" && len(services) == 0 {
+			return fmt.Errorf("服务 '%s' 不存在", target.Service)
+		}
+		return pushUptimeKuma(ctx, target, services)
+	}
+	return postJSON(ctx, target.URL, StatusSnapshot{
+		Source:   localRegion.Load().(string),
+		Status:   worstStatus(services),
+		Services: services,
+		SentAt:   time.Now(),
+	}, target.Headers)
+}
+
+// pushUptimeKuma 请求Uptime Kuma推送监控地址：离线的服务计为down，
+// msg为离线服务的错误信息，ping为最大延迟
+func pushUptimeKuma(ctx context.Context, target PushTarget, services []*Service) error {
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return err
+	}
+	status, ping := "up", 0.0
+	down := make([]string, 0)
+	for _, service := range services {
+		ping = max(ping, service.LatencyMS)
+		if service.Status == StatusOffline || service.Status == StatusImpacted {
+			down = append(down, fmt.Sprintf("%s: %s", service.Name, service.LastError))
+		}
+	}
+	msg := "OK"
+	if len(down) > 0 {
+		status, msg = "down", strings.Join(down, "; ")
+	}
+	query := u.Query()
+	query.Set("status", status)
+	query.Set("msg", msg)
+	query.Set("ping", strconv.FormatFloat(ping, 'f', 0, 64))
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range target.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP状态码异常: %d", resp.StatusCode)
+	}
+	return nil
+}
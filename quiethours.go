@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata"
+)
+
+// digestTick 检查免打扰是否结束并发送汇总的间隔
+const digestTick = time.Minute
+
+// QuietHours 免打扰时段配置，时间格式为HH:MM，可跨越午夜
+type QuietHours struct {
+	// Start 开始时间
+	Start string `yaml:"start"`
+	// End 结束时间
+	End string `yaml:"end"`
+	// Timezone 时区，如Asia/Shanghai，为空时使用本地时区
+	Timezone string `yaml:"timezone,omitempty"`
+}
+
+// quietWindow 解析后的免打扰时段
+type quietWindow struct {
+	start, end int
+	loc        *time.Location
+}
+
+// parse 解析免打扰时段配置
+func (q *QuietHours) parse() (*quietWindow, error) {
+	parseClock := func(s string) (int, error) {
+		t, err := time.Parse("15:04", s)
+		if err != nil {
+			return 0, fmt.Errorf("无效的时间 '%s'，格式应为HH:MM", s)
+		}
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(q.End)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.Local
+	if q.Timezone != "" {
+		if loc, err = time.LoadLocation(q.Timezone); err != nil {
+			return nil, fmt.Errorf("无效的时区 '%s': %v", q.Timezone, err)
+		}
+	}
+	return &quietWindow{start: start, end: end, loc: loc}, nil
+}
+
+// Contains 判断时间是否处于免打扰时段
+func (w *quietWindow) Contains(t time.Time) bool {
+	t = t.In(w.loc)
+	m := t.Hour()*60 + t.Minute()
+	if w.start <= w.end {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+// digestQueue 免打扰期间暂存的通知
+type digestQueue struct {
+	lock    sync.Mutex
+	pending map[string][]Notification
+}
+
+// push 暂存一条通知
+func (q *digestQueue) push(channel string, n Notification) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.pending == nil {
+		q.pending = make(map[string][]Notification)
+	}
+	q.pending[channel] = append(q.pending[channel], n)
+}
+
+// take 取出满足条件的渠道的暂存通知
+func (q *digestQueue) take(ready func(channel string) bool) map[string][]Notification {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	taken := make(map[string][]Notification)
+	for channel, queued := range q.pending {
+		if ready(channel) {
+			taken[channel] = queued
+			delete(q.pending, channel)
+		}
+	}
+	return taken
+}
+
+// digestNotification 将暂存的通知合并为一条汇总通知
+func digestNotification(queued []Notification, now time.Time) Notification {
+	parts := make([]string, 0, len(queued))
+	for _, n := range queued {
+		parts = append(parts, n.Message)
+	}
+	return Notification{
+		Kind:    "digest",
+		Time:    now,
+		Message: fmt.Sprintf("[JJApps Status] 免打扰期间共有 %d 条通知:\n\n%s", len(queued), strings.Join(parts, "\n\n")),
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitIdleTTL 空闲令牌桶的回收时间
+const rateLimitIdleTTL = 10 * time.Minute
+
+// RateLimitConfig 限流配置
+type RateLimitConfig struct {
+	// Rate 每秒补充的令牌数，为0时不限流
+	Rate float64 `yaml:"rate"`
+	// Burst 令牌桶容量
+	Burst int `yaml:"burst"`
+}
+
+// tokenBucket 单个客户端的令牌桶
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter 按客户端IP限流的令牌桶限流器
+type RateLimiter struct {
+	lock    sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+	// lastSweep 上次回收空闲令牌桶的时间
+	lastSweep time.Time
+}
+
+// NewRateLimiter 创建限流器
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(cfg.Rate))
+	}
+	return &RateLimiter{
+		rate:      cfg.Rate,
+		burst:     float64(burst),
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow 判断key对应的客户端是否允许本次请求，不允许时返回需要等待的时间
+func (rl *RateLimiter) Allow(key string) (bool, time.Duration) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	now := time.Now()
+	rl.sweep(now)
+
+	bucket, ok := rl.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: rl.burst, last: now}
+		rl.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(rl.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*rl.rate)
+	bucket.last = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - bucket.tokens) / rl.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep 回收长时间未访问的令牌桶，避免内存无限增长
+func (rl *RateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rateLimitIdleTTL {
+		return
+	}
+	rl.lastSweep = now
+	for key, bucket := range rl.buckets {
+		if now.Sub(bucket.last) > rateLimitIdleTTL {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// Middleware 返回gin限流中间件
+func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rl.rate <= 0 {
+			c.Next()
+			return
+		}
+		// 按可信代理解析来源IP，避免伪造X-Forwarded-For绕过限流；来自unix socket且未携带
+		// X-Forwarded-For的请求无法区分客户端，共用一个令牌桶会让单个客户端影响所有人，因此不限流
+		ip := requestIP(c)
+		if ip == "" {
+			c.Next()
+			return
+		}
+		ok, wait := rl.Allow(ip)
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": tr(c, "error.rate_limited"),
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// defaultMaxRedirects 默认最多跟随的重定向次数，与net/http一致
+const defaultMaxRedirects = 10
+
+// RedirectPolicy HTTP检查的重定向策略，用于确认网关的重定向没有失效或降级到HTTP
+type RedirectPolicy struct {
+	// Follow 是否跟随重定向，默认跟随；为false时以3xx响应本身作为检查结果，可在expr中校验code与headers.location
+	Follow *bool `yaml:"follow,omitempty"`
+	// Max 最多跟随的重定向次数，超过时视为离线，默认10
+	Max int `yaml:"max,omitempty"`
+	// FinalURL 跟随重定向后最终地址须与该值一致
+	FinalURL string `yaml:"final_url,omitempty"`
+	// HTTPSOnly 重定向的目标均须为https地址，首个地址可以是http，用于检查http到https的跳转
+	HTTPSOnly bool `yaml:"https_only,omitempty"`
+}
+
+// validate 校验重定向策略
+func (rp *RedirectPolicy) validate() error {
+	if rp.Max < 0 {
+		return fmt.Errorf("重定向的max不能为负数")
+	}
+	if rp.FinalURL != "" {
+		if u, err := url.Parse(rp.FinalURL); err != nil || u.Host == "" {
+			return fmt.Errorf("无效的final_url '%s'", rp.FinalURL)
+		}
+		if !rp.follow() {
+			return fmt.Errorf("不跟随重定向时不能配置final_url")
+		}
+	}
+	return nil
+}
+
+// follow 返回是否跟随重定向
+func (rp *RedirectPolicy) follow() bool {
+	return rp.Follow == nil || *rp.Follow
+}
+
+// checkRedirect 返回http.Client的重定向回调，策略为nil时使用默认行为
+func (rp *RedirectPolicy) checkRedirect() func(req *http.Request, via []*http.Request) error {
+	if rp == nil {
+		return nil
+	}
+	return func(req *http.Request, via []*http.Request) error {
+		if !rp.follow() {
+			return http.ErrUseLastResponse
+		}
+		limit := rp.Max
+		if limit == 0 {
+			limit = defaultMaxRedirects
+		}
+		if len(via) > limit {
+			return fmt.Errorf("重定向次数超过 %d", limit)
+		}
+		if rp.HTTPSOnly && req.URL.Scheme != "https" {
+			return fmt.Errorf("从 %s 重定向到非HTTPS地址 %s", via[len(via)-1].URL, req.URL)
+		}
+		return nil
+	}
+}
+
+// verify 校验跟随重定向后的最终地址
+func (rp *RedirectPolicy) verify(resp *http.Response) error {
+	if rp == nil || rp.FinalURL == "" {
+		return nil
+	}
+	final := resp.Request.URL.String()
+	if final != rp.FinalURL {
+		return fmt.Errorf("最终地址为 %s，期望 %s", final, rp.FinalURL)
+	}
+	return nil
+}
+
+// redirectCount 返回得到该响应前经过的重定向次数
+func redirectCount(resp *http.Response) int {
+	n := 0
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		n++
+	}
+	return n
+}
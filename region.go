@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultRegion 未配置region时本机检查位置的名称
+const defaultRegion = "local"
+
+// localRegion 本机检查位置的名称
+var localRegion atomic.Value
+
+func init() {
+	localRegion.Store(defaultRegion)
+}
+
+// setLocalRegion 设置本机检查位置的名称，为空时使用local
+func setLocalRegion(region string) {
+	if region == "" {
+		region = defaultRegion
+	}
+	localRegion.Store(region)
+}
+
+// RegionResult 单个检查位置对服务的检查结果
+type RegionResult struct {
+	// Region 检查位置，本机为配置的region，远程探针为探针名称
+	Region string `json:"region"`
+	// Status 该位置检查到的状态
+	Status ServiceStatus `json:"status"`
+	// LatencyMS 该位置最近一次检查的耗时（毫秒）
+	LatencyMS float64 `json:"latency_ms"`
+	// LastChecked 该位置最后检查时间
+	LastChecked time.Time `json:"last_checked"`
+	// LastError 该位置最近一次检查的错误信息
+	LastError string `json:"last_error,omitempty"`
+	// Stale 探针已失联或尚未上报该服务，结果不参与汇总
+	Stale bool `json:"stale,omitempty"`
+}
+
+// validateRegions 校验服务引用的检查位置均为已配置的远程探针
+func (cfg *Config) validateRegions() error {
+	agents := make(map[string]bool, len(cfg.Agents))
+	for _, agent := range cfg.Agents {
+		agents[agent.Name] = true
+	}
+	for _, sc := range cfg.Services {
+		for _, region := range sc.Regions {
+			if !agents[region] {
+				return fmt.Errorf("服务 '%s' 的检查位置 '%s' 不是已配置的远程探针", sc.Name, region)
+			}
+		}
+	}
+	return nil
+}
+
+// Result 返回探针上报的指定服务的检查结果，探针未上报该服务时返回false
+func (ar *AgentRegistry) Result(agent, name string, now time.Time) (RegionResult, bool) {
+	ar.lock.RLock()
+	defer ar.lock.RUnlock()
+	state, ok := ar.agents[agent]
+	if !ok {
+		return RegionResult{}, false
+	}
+	for _, reported := range state.report.Services {
+		if reported.Name == name {
+			return RegionResult{
+				Region:      agent,
+				Status:      reported.Status,
+				LatencyMS:   reported.LatencyMS,
+				LastChecked: reported.LastChecked,
+				LastError:   reported.LastError,
+				Stale:       now.Sub(state.received) > ar.timeout,
+			}, true
+		}
+	}
+	return RegionResult{}, false
+}
+
+// applyRegions 汇总本机与远程探针对同一服务的检查结果：所有位置均离线时为离线，
+// 部分位置离线时降级并在错误信息中列出离线的位置；未配置regions时原样返回，需持有锁调用
+func applyRegions(service *Service, status ServiceStatus, err error, now time.Time) (ServiceStatus, error) {
+	if len(service.Regions) == 0 {
+		service.RegionResults = nil
+		return status, err
+	}
+	local := RegionResult{
+		Region:      localRegion.Load().(string),
+		Status:      status,
+		LatencyMS:   service.LatencyMS,
+		LastChecked: now,
+	}
+	if err != nil {
+		local.LastError = err.Error()
+	}
+	results := []RegionResult{local}
+	for _, region := range service.Regions {
+		result, ok := RegionResult{}, false
+		if agentRegistry != nil {
+			result, ok = agentRegistry.Result(region, service.Name, now)
+		}
+		if !ok {
+			result = RegionResult{Region: region, Status: StatusOffline, LastError: "探针尚未上报该服务", Stale: true}
+		}
+		results = append(results, result)
+	}
+	service.RegionResults = results
+
+	total, degraded := 0, false
+	down := make([]string, 0)
+	for _, result := range results {
+		if result.Stale {
+			continue
+		}
+		total++
+		switch result.Status {
+		case StatusOffline:
+			down = append(down, fmt.Sprintf("%s（%s）", result.Region, result.LastError))
+		case StatusDegraded:
+			degraded = true
+		}
+	}
+	switch {
+	case len(down) == total:
+		return StatusOffline, err
+	case len(down) > 0:
+		return StatusDegraded, fmt.Errorf("部分检查位置不可用: %s", strings.Join(down, "; "))
+	case degraded && status == StatusOnline:
+		return StatusDegraded, err
+	}
+	return status, err
+}
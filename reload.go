@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce 文件变更后的合并等待时间，避免编辑器多次写入触发多次重载
+const reloadDebounce = 500 * time.Millisecond
+
+// ConfigReloader 配置热重载器，监听配置文件变更与SIGHUP信号
+type ConfigReloader struct {
+	// path 配置文件路径
+	path string
+	// manager 需要同步的服务管理器
+	manager *ServiceManager
+	// current 当前生效的配置
+	current atomic.Pointer[Config]
+	// writeLock 保证写配置文件与重载依次执行
+	writeLock sync.Mutex
+	// appliedHash 当前生效的配置文件内容的SHA-256，需持有writeLock访问；
+	// 监听到的变更与之相同时不重复加载，避免导入配置写文件后再次触发重载
+	appliedHash string
+}
+
+// NewConfigReloader 创建配置热重载器，cfg为启动时加载的配置
+func NewConfigReloader(path string, cfg *Config, manager *ServiceManager) *ConfigReloader {
+	cr := &ConfigReloader{
+		path:    path,
+		manager: manager,
+	}
+	if data, err := readConfigFile(path); err == nil {
+		cr.appliedHash = sha256Hex(data)
+	}
+	cr.current.Store(cfg)
+	return cr
+}
+
+// Current 返回当前生效的配置
+func (cr *ConfigReloader) Current() *Config {
+	return cr.current.Load()
+}
+
+// Reload 重新加载配置文件并同步服务列表，变更记入审计日志；
+// force为false时文件内容与当前生效的配置相同则跳过
+func (cr *ConfigReloader) Reload(force bool) error {
+	cr.writeLock.Lock()
+	defer cr.writeLock.Unlock()
+
+	data, err := readConfigFile(cr.path)
+	if err != nil {
+		return err
+	}
+	hash := sha256Hex(data)
+	if !force && hash == cr.appliedHash {
+		return nil
+	}
+	cfg, err := parseConfig(data)
+	if err != nil {
+		return err
+	}
+	commit, err := cr.prepare(cfg)
+	if err != nil {
+		return err
+	}
+	old := cr.current.Load()
+	commit()
+	cr.appliedHash = hash
+	recordConfigChange(old, cfg, auditConfigReload, auditActorConfigFile, "")
+	snapshots.Capture(cfg, auditConfigReload, auditActorConfigFile)
+	return nil
+}
+
+// prepare 校验新的配置并预先构建全部组件，不修改任何状态，任一组件构建失败时返回错误；
+// 全部成功后返回的函数一次性替换为新配置，替换过程不会失败，避免只应用了部分配置
+func (cr *ConfigReloader) prepare(cfg *Config) (func(), error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	services, err := cfg.BuildServices()
+	if err != nil {
+		return nil, err
+	}
+	preparers := []func(*Config) (func(), error){
+		func(cfg *Config) (func(), error) { return prepareDefaultLocale(cfg.Locale) },
+		func(cfg *Config) (func(), error) { return prepareDisplayTimezone(cfg.Timezone) },
+		prepareAccessTokens,
+		prepareAdminAllowlist,
+		oidcAuth.prepare,
+		notifications.prepare,
+		subscribers.prepare,
+		pusher.prepare,
+		reports.prepare,
+	}
+	applies := make([]func(), 0, len(preparers))
+	for _, prepare := range preparers {
+		apply, err := prepare(cfg)
+		if err != nil {
+			return nil, err
+		}
+		applies = append(applies, apply)
+	}
+
+	return func() {
+		for _, apply := range applies {
+			apply()
+		}
+		escalator.Update(cfg)
+		setTransportConfig(cfg.HTTPTransport)
+		cr.manager.SetRefreshInterval(cfg.RefreshInterval)
+		setMaintenance(cfg.Maintenance)
+		setLocalRegion(cfg.Region)
+		diagnostics.Update(cfg)
+		healer.Update(cfg)
+		snapshots.Update(cfg)
+		cr.manager.SyncServices(services)
+		cr.current.Store(cfg)
+		slog.Info("配置已重新加载", "path", cr.path, "services", len(services))
+	}, nil
+}
+
+// Import 校验并导入新的配置内容：写入配置文件后立即生效，校验失败时不修改任何内容；
+// 变更以actor与clientIP记入审计日志
+func (cr *ConfigReloader) Import(data []byte, actor, clientIP string) error {
+	return cr.write(data, auditConfigImport, actor, clientIP)
+}
+
+// Rollback 将配置回滚到快照的内容，与导入配置相同，校验失败时不修改任何内容
+func (cr *ConfigReloader) Rollback(snapshot ConfigSnapshot, actor, clientIP string) error {
+	return cr.write([]byte(snapshot.Config), auditConfigRollback, actor, clientIP)
+}
+
+// write 校验配置内容并构建全部组件，成功后才写入配置文件并应用，
+// 变更以action记入审计日志并保存快照
+func (cr *ConfigReloader) write(data []byte, action, actor, clientIP string) error {
+	cfg, err := parseConfig(data)
+	if err != nil {
+		return err
+	}
+
+	cr.writeLock.Lock()
+	defer cr.writeLock.Unlock()
+	commit, err := cr.prepare(cfg)
+	if err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，避免写入一半时被监听器读取
+	tmp := cr.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, cr.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	old := cr.current.Load()
+	commit()
+	cr.appliedHash = sha256Hex(data)
+	recordConfigChange(old, cfg, action, actor, clientIP)
+	snapshots.Capture(cfg, action, actor)
+	return nil
+}
+
+// reload 执行重载并打印错误，重载失败时保留原有配置；force为false时跳过内容未变化的文件
+func (cr *ConfigReloader) reload(force bool) {
+	if err := cr.Reload(force); err != nil {
+		slog.Error("重新加载配置失败", "path", cr.path, "error", err)
+	}
+}
+
+// Start 在后台开始监听配置变更
+func (cr *ConfigReloader) Start() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建文件监听失败: %v", err)
+	}
+	// 监听所在目录而非文件本身，兼容编辑器的重命名保存方式
+	if err := watcher.Add(filepath.Dir(cr.path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("监听配置目录失败: %v", err)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer watcher.Close()
+		target := filepath.Clean(cr.path)
+		var debounce <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != target {
+					continue
+				}
+				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
+					debounce = time.After(reloadDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("配置文件监听出错", "error", err)
+			case <-debounce:
+				debounce = nil
+				cr.reload(false)
+			case <-hup:
+				cr.reload(true)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// reportCheckInterval 检查是否需要发送月度报告的间隔
+	reportCheckInterval = time.Hour
+	// reportSendTimeout 生成并发送一次月度报告的超时时间
+	reportSendTimeout = 2 * time.Minute
+	// reportMonthLayout 报告月份的格式
+	reportMonthLayout = "2006-01"
+)
+
+// ReportConfig 月度可用性报告配置，报告可通过接口随时下载，配置channel后每月初自动发送上月的报告
+type ReportConfig struct {
+	// Channel 发送报告使用的email类型通知渠道，为空时不发送
+	Channel string `yaml:"channel,omitempty"`
+	// To 收件人，默认使用渠道配置的收件人
+	To []string `yaml:"to,omitempty"`
+	// Services 报告包含的服务，默认为所有服务
+	Services []string `yaml:"services,omitempty"`
+	// Locale 邮件中报告的语言，默认使用全局语言
+	Locale string `yaml:"locale,omitempty"`
+	// ChromePath 生成PDF时使用的Chrome可执行文件路径，为空时自动查找
+	ChromePath string `yaml:"chrome_path,omitempty"`
+}
+
+// validate 校验报告配置并返回发送报告的渠道，未配置渠道时返回nil
+func (rc *ReportConfig) validate(nc NotificationConfig) (*EmailNotifier, error) {
+	if err := validateLocale(rc.Locale); err != nil {
+		return nil, fmt.Errorf("报告配置: %v", err)
+	}
+	if rc.Channel == "" {
+		return nil, nil
+	}
+	email, err := nc.emailChannel(rc.Channel)
+	if err != nil {
+		return nil, fmt.Errorf("报告使用的%v", err)
+	}
+	return email, nil
+}
+
+// Report 一个月的可用性报告
+type Report struct {
+	// Month 报告月份，如2026-09
+	Month string `json:"month"`
+	// Start 统计开始时间
+	Start time.Time `json:"start"`
+	// End 统计结束时间，当月报告为生成时间
+	End time.Time `json:"end"`
+	// GeneratedAt 生成时间
+	GeneratedAt time.Time `json:"generated_at"`
+	// Services 各服务的统计
+	Services []ServiceReport `json:"services"`
+}
+
+// ServiceReport 服务在报告月份内的可用性统计
+type ServiceReport struct {
+	// Service 服务名称
+	Service string `json:"service"`
+	// Checks 计入统计的检查次数，维护期间不计入
+	Checks int `json:"checks"`
+	// FailedChecks 不可用的检查次数
+	FailedChecks int `json:"failed_checks"`
+	// UptimePercent 可用率，没有数据时为nil
+	UptimePercent *float64 `json:"uptime_percent"`
+	// SLOTarget 配置的SLO可用率目标，未配置时为nil
+	SLOTarget *float64 `json:"slo_target,omitempty"`
+	// SLOMet 可用率是否达到SLO目标，未配置SLO或没有数据时为nil
+	SLOMet *bool `json:"slo_met,omitempty"`
+	// Incidents 与报告月份有重叠的故障，按开始时间正序
+	Incidents []Incident `json:"incidents"`
+	// MTTRSeconds 已恢复故障的平均恢复时间（秒），没有已恢复的故障时为nil
+	MTTRSeconds *float64 `json:"mttr_seconds"`
+	// Latency 成功检查的延迟统计，基于保留期内的原始检查记录
+	Latency ReportLatency `json:"latency"`
+}
+
+// ReportLatency 报告中的延迟统计，单位为毫秒，没有样本时均为nil
+type ReportLatency struct {
+	// Count 样本数
+	Count int `json:"count"`
+	// Avg 平均延迟
+	Avg *float64 `json:"avg"`
+	// P50 中位数延迟
+	P50 *float64 `json:"p50"`
+	// P95 95分位延迟
+	P95 *float64 `json:"p95"`
+	// P99 99分位延迟
+	P99 *float64 `json:"p99"`
+	// Max 最大延迟
+	Max *float64 `json:"max"`
+}
+
+// reportMonth 返回t所在月份在显示时区中的开始时间
+func reportMonth(t time.Time) time.Time {
+	t = inDisplayZone(t)
+	y, m, _ := t.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
+
+// parseReportMonth 解析YYYY-MM格式的报告月份，不能晚于当月
+func parseReportMonth(s string, now time.Time) (time.Time, error) {
+	current := reportMonth(now)
+	month, err := time.ParseInLocation(reportMonthLayout, s, current.Location())
+	if err != nil {
+		return time.Time{}, err
+	}
+	if month.After(current) {
+		return time.Time{}, fmt.Errorf("报告月份 %s 尚未开始", s)
+	}
+	return month, nil
+}
+
+// buildReport 根据检查记录、小时汇总与事件生成月度报告，当月的报告统计到now
+func buildReport(month time.Time, services []*Service, now time.Time) (*Report, error) {
+	end := month.AddDate(0, 1, 0)
+	if end.After(now) {
+		end = now
+	}
+	report := &Report{
+		Month:       month.Format(reportMonthLayout),
+		Start:       month,
+		End:         end,
+		GeneratedAt: now,
+		Services:    make([]ServiceReport, 0, len(services)),
+	}
+
+	names := make(map[string]bool, len(services))
+	for _, service := range services {
+		names[service.Name] = true
+	}
+	incidents := make(map[string][]Incident)
+	for _, incident := range eventLog.Incidents(func(service string) bool { return names[service] }, now) {
+		if incident.Start.Before(end) && (incident.End == nil || incident.End.After(month)) {
+			incidents[incident.Service] = append(incidents[incident.Service], incident)
+		}
+	}
+
+	history := serviceManager.History()
+	for _, service := range services {
+		sr := ServiceReport{Service: service.Name, Incidents: incidents[service.Name]}
+		if sr.Incidents == nil {
+			sr.Incidents = make([]Incident, 0)
+		}
+		// 故障按开始时间倒序返回，报告中按时间正序
+		slices.Reverse(sr.Incidents)
+		if service.SLO != nil {
+			target := service.SLO.Target
+			sr.SLOTarget = &target
+		}
+
+		rollups, err := history.QueryRollups(service.Name, month)
+		if err != nil {
+			return nil, err
+		}
+		bucket := UptimeBucket{Counts: make(map[ServiceStatus]int)}
+		for _, rollup := range rollups {
+			if rollup.Hour.Before(end) {
+				for status, n := range rollup.Counts {
+					bucket.add(status, n)
+				}
+			}
+		}
+		bucket.finish()
+		sr.UptimePercent = bucket.UptimePercent
+		if sr.SLOTarget != nil && sr.UptimePercent != nil {
+			met := *sr.UptimePercent >= *sr.SLOTarget
+			sr.SLOMet = &met
+		}
+		for status, n := range bucket.Counts {
+			if status == StatusMaintenance {
+				continue
+			}
+			sr.Checks += n
+			if !isUp(status) {
+				sr.FailedChecks += n
+			}
+		}
+
+		resolved, total := 0, 0.0
+		for _, incident := range sr.Incidents {
+			if incident.End != nil {
+				resolved++
+				total += incident.DurationSeconds
+			}
+		}
+		if resolved > 0 {
+			mttr := total / float64(resolved)
+			sr.MTTRSeconds = &mttr
+		}
+
+		results, err := history.Query(service.Name, month, 0)
+		if err != nil {
+			return nil, err
+		}
+		sr.Latency = reportLatency(results, end)
+		report.Services = append(report.Services, sr)
+	}
+	return report, nil
+}
+
+// SLOResult 返回SLO达标情况：met或missed，未配置SLO或没有数据时为空
+func (sr ServiceReport) SLOResult() string {
+	switch {
+	case sr.SLOMet == nil:
+		return ""
+	case *sr.SLOMet:
+		return "met"
+	default:
+		return "missed"
+	}
+}
+
+// reportLatency 统计end之前成功检查的延迟
+func reportLatency(results []CheckResult, end time.Time) ReportLatency {
+	samples := make([]float64, 0, len(results))
+	sum := 0.0
+	for _, r := range results {
+		if r.Time.Before(end) && isUp(r.Status) && r.Error == "" {
+			samples = append(samples, r.LatencyMS)
+			sum += r.LatencyMS
+		}
+	}
+	latency := ReportLatency{Count: len(samples)}
+	if len(samples) == 0 {
+		return latency
+	}
+	sort.Float64s(samples)
+	value := func(v float64) *float64 { return &v }
+	latency.Avg = value(sum / float64(len(samples)))
+	latency.P50 = value(percentile(samples, 50))
+	latency.P95 = value(percentile(samples, 95))
+	latency.P99 = value(percentile(samples, 99))
+	latency.Max = value(samples[len(samples)-1])
+	return latency
+}
+
+// reportPageData 报告页面的模板数据
+type reportPageData struct {
+	// Locale 报告语言
+	Locale string
+	// Report 报告内容
+	Report *Report
+}
+
+// ReportMailer 每月初将上月的可用性报告发送给配置的收件人，已发送的月份保存在检查记录存储目录下
+type ReportMailer struct {
+	lock sync.Mutex
+	cfg  ReportConfig
+	// mailer 发送报告的渠道，为nil时不发送
+	mailer *EmailNotifier
+	// tmpl 渲染报告的模板
+	tmpl *template.Template
+	// path 保存已发送月份的文件，为空时仅保存在内存中
+	path string
+	// lastSent 最近一次发送的报告月份
+	lastSent string
+}
+
+// reportState 报告状态文件的内容
+type reportState struct {
+	// LastSent 最近一次发送的报告月份
+	LastSent string `json:"last_sent"`
+}
+
+// reports 全局月度报告
+var reports = &ReportMailer{}
+
+// Open 加载已发送的报告月份，没有记录时视为上月已发送，避免首次启用时立即补发
+func (rm *ReportMailer) Open(storage StorageConfig) error {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	rm.lastSent = reportMonth(time.Now()).AddDate(0, -1, 0).Format(reportMonthLayout)
+	if storage.Path == "" {
+		return nil
+	}
+	rm.path = strings.TrimSuffix(storage.Path, filepath.Ext(storage.Path)) + ".reports.json"
+	data, err := os.ReadFile(rm.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var state reportState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("解析报告状态文件失败: %v", err)
+	}
+	if state.LastSent != "" {
+		rm.lastSent = state.LastSent
+	}
+	return nil
+}
+
+// Update 根据配置更新报告设置
+func (rm *ReportMailer) Update(cfg *Config) error {
+	apply, err := rm.prepare(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// prepare 校验报告设置，返回更新设置的函数
+func (rm *ReportMailer) prepare(cfg *Config) (func(), error) {
+	mailer, err := cfg.Reports.validate(cfg.Notifications)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		rm.lock.Lock()
+		defer rm.lock.Unlock()
+		rm.cfg = cfg.Reports
+		rm.mailer = mailer
+	}, nil
+}
+
+// SetTemplates 设置渲染报告使用的模板
+func (rm *ReportMailer) SetTemplates(tmpl *template.Template) {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	rm.tmpl = tmpl
+}
+
+// config 返回当前的报告配置
+func (rm *ReportMailer) config() ReportConfig {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	return rm.cfg
+}
+
+// Render 将报告渲染为HTML
+func (rm *ReportMailer) Render(locale string, report *Report) ([]byte, error) {
+	rm.lock.Lock()
+	tmpl := rm.tmpl
+	rm.lock.Unlock()
+	if tmpl == nil {
+		return nil, fmt.Errorf("报告模板未加载")
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "report.html", reportPageData{Locale: locale, Report: report}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Start 在后台定期检查，上月的报告尚未发送时生成并发送
+func (rm *ReportMailer) Start() {
+	go func() {
+		ticker := time.NewTicker(reportCheckInterval)
+		defer ticker.Stop()
+		for {
+			rm.sendDue(time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+// services 返回报告包含的服务
+func (rc ReportConfig) services() []*Service {
+	all := serviceManager.GetServices()
+	if len(rc.Services) == 0 {
+		return all
+	}
+	out := make([]*Service, 0, len(rc.Services))
+	for _, service := range all {
+		if slices.Contains(rc.Services, service.Name) {
+			out = append(out, service)
+		}
+	}
+	return out
+}
+
+// sendDue 上月的报告尚未发送时生成并发送，发送失败时在下次检查时重试
+func (rm *ReportMailer) sendDue(now time.Time) {
+	rm.lock.Lock()
+	mailer, cfg, lastSent := rm.mailer, rm.cfg, rm.lastSent
+	rm.lock.Unlock()
+	month := reportMonth(now).AddDate(0, -1, 0)
+	if mailer == nil || month.Format(reportMonthLayout) <= lastSent {
+		return
+	}
+
+	locale := cfg.Locale
+	if locale == "" {
+		locale = defaultLocale.Load().(string)
+	}
+	report, err := buildReport(month, cfg.services(), now)
+	if err != nil {
+		slog.Error("生成月度报告失败", "month", month.Format(reportMonthLayout), "error", err)
+		return
+	}
+	body, err := rm.Render(locale, report)
+	if err != nil {
+		slog.Error("渲染月度报告失败", "month", report.Month, "error", err)
+		return
+	}
+	to := cfg.To
+	if len(to) == 0 {
+		to = mailer.To
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), reportSendTimeout)
+	defer cancel()
+	if err := mailer.sendMessage(ctx, to, translate(locale, "report.subject", report.Month), "text/html", string(body)); err != nil {
+		slog.Error("发送月度报告失败", "month", report.Month, "error", err)
+		return
+	}
+	slog.Info("月度报告已发送", "month", report.Month, "to", to)
+
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	rm.lastSent = report.Month
+	if rm.path == "" {
+		return
+	}
+	data, _ := json.Marshal(reportState{LastSent: rm.lastSent})
+	if err := os.WriteFile(rm.path, data, 0644); err != nil {
+		slog.Error("保存报告状态失败", "path", rm.path, "error", err)
+	}
+}
+
+// apiReportHandler 下载月度可用性报告，format可选html、pdf或json，PDF需使用 -tags chrome 编译
+func apiReportHandler(c *gin.Context) {
+	now := time.Now()
+	month, err := parseReportMonth(c.Param("month"), now)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "error.invalid_param", "month")
+		return
+	}
+	report, err := buildReport(month, reports.config().services(), now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := "report-" + report.Month
+	format := c.DefaultQuery("format", "html")
+	switch format {
+	case "json":
+		c.JSON(http.StatusOK, report)
+		return
+	case "html", "pdf":
+	default:
+		apiError(c, http.StatusBadRequest, "error.invalid_param", "format")
+		return
+	}
+	page, err := reports.Render(requestLocale(c), report)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if format == "html" {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+		return
+	}
+	if !browserSupported {
+		apiError(c, http.StatusNotImplemented, "error.pdf_unsupported")
+		return
+	}
+	pdf, err := renderPDF(c.Request.Context(), reports.config().ChromePath, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, filename))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// defaultRetryAttempts 默认的最大检查次数（含首次检查）
+	defaultRetryAttempts = 3
+	// defaultRetryBackoff 第一次重试前默认的等待时间
+	defaultRetryBackoff = time.Second
+	// defaultRetryMaxBackoff 两次重试之间默认的最长等待时间
+	defaultRetryMaxBackoff = 10 * time.Second
+)
+
+// 检查失败的错误分类
+const (
+	// errorClassTimeout 超时
+	errorClassTimeout = "timeout"
+	// errorClassRefused 连接被拒绝
+	errorClassRefused = "connection_refused"
+	// errorClassReset 连接被重置或意外断开
+	errorClassReset = "connection_reset"
+	// errorClassDNS 域名解析失败
+	errorClassDNS = "dns"
+	// errorClass5xx HTTP 5xx状态码
+	errorClass5xx = "5xx"
+	// errorClass4xx HTTP 4xx状态码
+	errorClass4xx = "4xx"
+	// errorClassOther 其他错误
+	errorClassOther = "other"
+	// errorClassAny 重试配置中表示全部错误
+	errorClassAny = "any"
+)
+
+// defaultRetryOn 默认重试的错误分类，均为服务启动或短暂抖动时常见的错误
+var defaultRetryOn = []string{errorClassTimeout, errorClassRefused, errorClassReset, errorClass5xx}
+
+// retryClasses 重试配置支持的错误分类
+var retryClasses = map[string]bool{
+	errorClassTimeout: true,
+	errorClassRefused: true,
+	errorClassReset:   true,
+	errorClassDNS:     true,
+	errorClass5xx:     true,
+	errorClass4xx:     true,
+	errorClassOther:   true,
+	errorClassAny:     true,
+}
+
+// RetryPolicy 检查失败后的重试策略，适用于任意检查器；重试在服务的timeout内进行，
+// 只有最后一次的结果计入服务状态
+type RetryPolicy struct {
+	// MaxAttempts 最大检查次数（含首次检查），默认3
+	MaxAttempts int `yaml:"max_attempts,omitempty"`
+	// Backoff 第一次重试前的等待时间，之后每次翻倍，默认1s
+	Backoff time.Duration `yaml:"backoff,omitempty"`
+	// MaxBackoff 两次重试之间的最长等待时间，默认10s
+	MaxBackoff time.Duration `yaml:"max_backoff,omitempty"`
+	// RetryOn 需要重试的错误分类：timeout、connection_refused、connection_reset、dns、5xx、4xx、other或any，
+	// 默认为timeout、connection_refused、connection_reset与5xx
+	RetryOn []string `yaml:"retry_on,omitempty"`
+}
+
+// CheckAttempt 一次检查尝试的结果
+type CheckAttempt struct {
+	// Time 开始时间
+	Time time.Time `json:"time"`
+	// Status 检查结果状态
+	Status ServiceStatus `json:"status"`
+	// LatencyMS 耗时（毫秒）
+	LatencyMS float64 `json:"latency_ms"`
+	// Error 错误信息
+	Error string `json:"error,omitempty"`
+	// Class 错误分类
+	Class string `json:"class,omitempty"`
+}
+
+// validate 校验重试策略
+func (rp *RetryPolicy) validate() error {
+	if rp.MaxAttempts < 0 {
+		return fmt.Errorf("重试的max_attempts不能为负数")
+	}
+	if rp.Backoff < 0 || rp.MaxBackoff < 0 {
+		return fmt.Errorf("重试的backoff与max_backoff不能为负数")
+	}
+	for _, class := range rp.RetryOn {
+		if !retryClasses[class] {
+			return fmt.Errorf("未知的重试错误分类 '%s'，可选值为timeout、connection_refused、connection_reset、dns、5xx、4xx、other或any", class)
+		}
+	}
+	return nil
+}
+
+// withDefaults 返回补充默认值后的重试策略
+func (rp RetryPolicy) withDefaults() RetryPolicy {
+	if rp.MaxAttempts == 0 {
+		rp.MaxAttempts = defaultRetryAttempts
+	}
+	if rp.Backoff == 0 {
+		rp.Backoff = defaultRetryBackoff
+	}
+	if rp.MaxBackoff == 0 {
+		rp.MaxBackoff = defaultRetryMaxBackoff
+	}
+	if len(rp.RetryOn) == 0 {
+		rp.RetryOn = defaultRetryOn
+	}
+	return rp
+}
+
+// retryable 判断该分类的错误是否需要重试
+func (rp *RetryPolicy) retryable(class string) bool {
+	for _, c := range rp.RetryOn {
+		if c == errorClassAny || c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPStatusError 状态码异常的HTTP响应，用于按5xx、4xx区分重试
+type HTTPStatusError struct {
+	// Code HTTP状态码
+	Code int
+}
+
+// Error 实现error接口
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("HTTP状态码异常: %d", e.Code)
+}
+
+// classifyError 返回检查错误的分类；多数检查器以%v包装底层错误，无法识别类型时按错误信息判断
+func classifyError(err error) string {
+	var statusErr *HTTPStatusError
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &statusErr) && statusErr.Code >= 500:
+		return errorClass5xx
+	case errors.As(err, &statusErr) && statusErr.Code >= 400:
+		return errorClass4xx
+	case errors.As(err, &dnsErr):
+		return errorClassDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return errorClassTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return errorClassRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return errorClassReset
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "no such host"), strings.Contains(msg, "server misbehaving"):
+		return errorClassDNS
+	case strings.Contains(msg, "timeout"), strings.Contains(msg, "deadline exceeded"), strings.Contains(msg, "超时"):
+		return errorClassTimeout
+	case strings.Contains(msg, "connection refused"):
+		return errorClassRefused
+	case strings.Contains(msg, "connection reset"), strings.Contains(msg, "broken pipe"), strings.Contains(msg, "eof"):
+		return errorClassReset
+	}
+	if i := strings.Index(msg, "http状态码异常: "); i >= 0 {
+		switch msg[i+len("http状态码异常: ")] {
+		case '5':
+			return errorClass5xx
+		case '4':
+			return errorClass4xx
+		}
+	}
+	return errorClassOther
+}
+
+// runChecker 执行一次检查，返回结果与耗时
+func runChecker(ctx context.Context, checker StatusChecker) (ServiceStatus, time.Duration, error) {
+	start := time.Now()
+	status, err := checker.CheckStatus(ctx)
+	duration := time.Since(start)
+	if reporter, ok := checker.(LatencyReporter); ok {
+		duration = reporter.Latency()
+	}
+	return status, duration, err
+}
+
+// checkWithRetry 按重试策略执行检查，policy为nil时只检查一次；
+// 返回最后一次检查的结果与耗时，发生重试时同时返回每次尝试的结果
+func checkWithRetry(ctx context.Context, checker StatusChecker, policy *RetryPolicy) (ServiceStatus, time.Duration, []CheckAttempt, error) {
+	if policy == nil {
+		status, duration, err := runChecker(ctx, checker)
+		return status, duration, nil, err
+	}
+	rp := policy.withDefaults()
+	backoff := rp.Backoff
+	var attempts []CheckAttempt
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		status, duration, err := runChecker(ctx, checker)
+		record := CheckAttempt{Time: start, Status: status, LatencyMS: float64(duration) / float64(time.Millisecond)}
+		if err != nil {
+			record.Error = err.Error()
+			record.Class = classifyError(err)
+		}
+		attempts = append(attempts, record)
+
+		if err == nil || attempt >= rp.MaxAttempts || !rp.retryable(record.Class) || ctx.Err() != nil {
+			if len(attempts) == 1 {
+				attempts = nil
+			}
+			return status, duration, attempts, err
+		}
+		select {
+		case <-ctx.Done():
+			return status, duration, attempts, err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, rp.MaxBackoff)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash 空请求体的SHA256
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Checker S3兼容对象存储检查器（AWS S3、MinIO等）：配置key时HEAD该对象，
+// 否则列出存储桶中的一个对象，用于确认凭证与存储桶可用
+type S3Checker struct {
+	// Endpoint 服务地址，如https://s3.amazonaws.com或http://127.0.0.1:9000
+	Endpoint string `yaml:"endpoint"`
+	// Region 区域，默认us-east-1
+	Region string `yaml:"region"`
+	// Bucket 存储桶名称
+	Bucket string `yaml:"bucket"`
+	// Key 需要存在的对象，为空时只列出存储桶
+	Key string `yaml:"key"`
+	// AccessKey 访问密钥，为空时发送匿名请求
+	AccessKey string `yaml:"access_key"`
+	// SecretKey 访问密钥的密码
+	SecretKey string `yaml:"secret_key"`
+	// VirtualHost 使用bucket.endpoint形式的地址，默认使用路径形式
+	VirtualHost bool `yaml:"virtual_host"`
+	// Timeout 超时时间，默认10s
+	Timeout time.Duration `yaml:"timeout"`
+
+	checkTransport `yaml:",inline"`
+}
+
+// validate 校验对象存储检查配置
+func (s *S3Checker) validate() error {
+	if s.Endpoint == "" || s.Bucket == "" {
+		return fmt.Errorf("S3检查缺少endpoint或bucket")
+	}
+	if u, err := url.Parse(s.Endpoint); err != nil || u.Host == "" {
+		return fmt.Errorf("无效的S3地址 '%s'", s.Endpoint)
+	}
+	if (s.AccessKey == "") != (s.SecretKey == "") {
+		return fmt.Errorf("S3检查需要同时配置access_key与secret_key")
+	}
+	return s.checkTransport.validate()
+}
+
+// requestURL 返回检查使用的请求地址
+func (s *S3Checker) requestURL() *url.URL {
+	u, _ := url.Parse(strings.TrimRight(s.Endpoint, "/"))
+	path := "/"
+	if s.VirtualHost {
+		u.Host = s.Bucket + "." + u.Host
+	} else {
+		path += s.Bucket + "/"
+	}
+	if s.Key != "" {
+		path += strings.TrimPrefix(s.Key, "/")
+	} else {
+		u.RawQuery = "list-type=2&max-keys=1"
+	}
+	u.Path = u.Path + path
+	// 发送的路径与签名使用相同的编码
+	u.RawPath = s3Escape(u.Path, false)
+	return u
+}
+
+// CheckStatus 实现StatusChecker接口
+func (s *S3Checker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	method := http.MethodGet
+	if s.Key != "" {
+		method = http.MethodHead
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.requestURL().String(), nil)
+	if err != nil {
+		return StatusOffline, fmt.Errorf("创建HTTP请求失败: %v", err)
+	}
+	if s.AccessKey != "" {
+		s.sign(req, time.Now())
+	}
+	resp, err := s.client(timeout).Do(req)
+	if err != nil {
+		return StatusOffline, fmt.Errorf("HTTP请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return StatusOnline, nil
+	}
+	// 错误响应体为XML，HEAD请求没有响应体
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&s3Err) == nil && s3Err.Code != "" {
+		return StatusOffline, fmt.Errorf("S3请求失败（%d %s）: %s", resp.StatusCode, s3Err.Code, s3Err.Message)
+	}
+	if s.Key != "" && resp.StatusCode == http.StatusNotFound {
+		return StatusOffline, fmt.Errorf("对象 '%s' 不存在", s.Key)
+	}
+	return StatusOffline, &HTTPStatusError{Code: resp.StatusCode}
+}
+
+// sign 使用AWS Signature Version 4为请求签名
+func (s *S3Checker) sign(req *http.Request, now time.Time) {
+	region := s.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	amzDate := now.UTC().Format("20060102T150405Z")
+	scope := amzDate[:8] + "/" + region + "/s3/aws4_request"
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+
+	// 查询参数按名称排序
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			pairs = append(pairs, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		s3Escape(req.URL.Path, false),
+		strings.Join(pairs, "&"),
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:" + emptyPayloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + s.SecretKey)
+	for _, part := range []string{amzDate[:8], region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape 按SigV4规则编码，encodeSlash为false时保留路径中的斜杠
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// scheduleTick 调度器检查到期服务的间隔
+	scheduleTick = 500 * time.Millisecond
+	// defaultScheduleJitter 默认的抖动比例
+	defaultScheduleJitter = 0.1
+)
+
+// ScheduleConfig 后台定时检查配置，修改后需重启生效
+type ScheduleConfig struct {
+	// Interval 默认检查间隔，为0时不启用定时检查，仅在访问状态接口时检查
+	Interval time.Duration `yaml:"interval"`
+	// Jitter 每次检查间隔的随机抖动比例（0~1），默认0.1，即间隔±10%
+	Jitter *float64 `yaml:"jitter,omitempty"`
+	// NoSpread 关闭首次检查的分散，所有服务在启动时立即检查
+	NoSpread bool `yaml:"no_spread,omitempty"`
+}
+
+// scheduler 按服务的检查间隔在后台定时检查
+type scheduler struct {
+	manager  *ServiceManager
+	interval time.Duration
+	jitter   float64
+	spread   bool
+	// next 各服务下次检查的时间
+	next map[*Service]time.Time
+	// running 正在检查的服务
+	running map[*Service]*atomic.Bool
+}
+
+// StartScheduler 启动后台定时检查，未配置检查间隔时不启动
+func (sm *ServiceManager) StartScheduler(cfg ScheduleConfig) {
+	if cfg.Interval <= 0 {
+		return
+	}
+	jitter := defaultScheduleJitter
+	if cfg.Jitter != nil {
+		jitter = min(max(*cfg.Jitter, 0), 1)
+	}
+	s := &scheduler{
+		manager:  sm,
+		interval: cfg.Interval,
+		jitter:   jitter,
+		spread:   !cfg.NoSpread,
+		next:     make(map[*Service]time.Time),
+		running:  make(map[*Service]*atomic.Bool),
+	}
+	sm.scheduled.Store(true)
+	go s.run()
+}
+
+// Scheduled 是否启用了后台定时检查
+func (sm *ServiceManager) Scheduled() bool {
+	return sm.scheduled.Load()
+}
+
+// intervalOf 返回服务的检查间隔
+func (s *scheduler) intervalOf(service *Service) time.Duration {
+	if service.Interval > 0 {
+		return service.Interval
+	}
+	return s.interval
+}
+
+// initialOffset 按服务名称的哈希将首次检查分散到检查间隔内
+func (s *scheduler) initialOffset(service *Service, interval time.Duration) time.Duration {
+	if !s.spread {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(service.Name))
+	return time.Duration(h.Sum64() % uint64(interval))
+}
+
+// nextDelay 返回带随机抖动的检查间隔
+func (s *scheduler) nextDelay(interval time.Duration) time.Duration {
+	if s.jitter == 0 {
+		return interval
+	}
+	delta := (rand.Float64()*2 - 1) * s.jitter * float64(interval)
+	return interval + time.Duration(delta)
+}
+
+// run 定期检查到期的服务，同一时刻到期的服务作为一批检查
+func (s *scheduler) run() {
+	ticker := time.NewTicker(scheduleTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.manager.ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.tick(now)
+		}
+	}
+}
+
+// tick 检查到期的服务，并清理已删除服务的调度状态
+func (s *scheduler) tick(now time.Time) {
+	s.manager.lock.RLock()
+	services := append([]*Service(nil), s.manager.services...)
+	intervals := make(map[*Service]time.Duration, len(services))
+	for _, service := range services {
+		intervals[service] = s.intervalOf(service)
+	}
+	s.manager.lock.RUnlock()
+
+	current := make(map[*Service]bool, len(services))
+	due := make([]*Service, 0)
+	// scheduled 本次重新计算了下次检查时间的服务
+	scheduled := make(map[*Service]time.Time)
+	for _, service := range services {
+		current[service] = true
+		interval := intervals[service]
+		next, ok := s.next[service]
+		if !ok {
+			s.next[service] = now.Add(s.initialOffset(service, interval))
+			s.running[service] = new(atomic.Bool)
+			scheduled[service] = s.next[service]
+			continue
+		}
+		if now.Before(next) || !s.running[service].CompareAndSwap(false, true) {
+			continue
+		}
+		s.next[service] = now.Add(s.nextDelay(interval))
+		scheduled[service] = s.next[service]
+		due = append(due, service)
+	}
+	if len(scheduled) > 0 {
+		s.manager.lock.Lock()
+		for service, next := range scheduled {
+			service.NextCheck = &next
+		}
+		s.manager.lock.Unlock()
+	}
+	for service := range s.next {
+		if !current[service] {
+			delete(s.next, service)
+			delete(s.running, service)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	flags := make([]*atomic.Bool, len(due))
+	for i, service := range due {
+		flags[i] = s.running[service]
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(s.manager.ctx, s.manager.refreshTimeout)
+		defer cancel()
+		s.manager.checkBatch(ctx, due)
+		for _, flag := range flags {
+			flag.Store(false)
+		}
+	}()
+}
@@ -1,11 +1,21 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"os/exec"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/expr-lang/expr/vm"
+	"golang.org/x/sync/singleflight"
 )
 
 // ServiceStatus 表示服务状态
@@ -16,6 +26,12 @@ const (
 	StatusOnline ServiceStatus = iota
 	// StatusOffline 离线状态
 	StatusOffline
+	// StatusDegraded 降级状态，服务可用但存在异常
+	StatusDegraded
+	// StatusMaintenance 维护状态
+	StatusMaintenance
+	// StatusImpacted 受影响状态，服务检查失败且其依赖的服务不可用
+	StatusImpacted
 )
 
 // String 返回状态的字符串表示
@@ -25,23 +41,80 @@ func (s ServiceStatus) String() string {
 		return "online"
 	case StatusOffline:
 		return "offline"
+	case StatusDegraded:
+		return "degraded"
+	case StatusMaintenance:
+		return "maintenance"
+	case StatusImpacted:
+		return "impacted"
 	default:
 		return "unknown"
 	}
 }
 
-// MarshalJSON 实现JSON序列化
+// ParseServiceStatus 将字符串解析为状态
+func ParseServiceStatus(str string) (ServiceStatus, error) {
+	for s := StatusOnline; s <= StatusImpacted; s++ {
+		if strings.EqualFold(s.String(), str) {
+			return s, nil
+		}
+	}
+	return StatusOffline, fmt.Errorf("未知的服务状态 '%s'", str)
+}
+
+// MarshalJSON 实现JSON序列化，输出状态的字符串表示
 func (s ServiceStatus) MarshalJSON() ([]byte, error) {
-	return []byte(fmt.Sprintf("%d", int(s))), nil
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON 实现JSON反序列化，兼容旧版本的数字格式
+func (s *ServiceStatus) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		*s = ServiceStatus(n)
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	status, err := ParseServiceStatus(str)
+	if err != nil {
+		return err
+	}
+	*s = status
+	return nil
+}
+
+// MarshalText 实现encoding.TextMarshaler，用于YAML等文本格式
+func (s ServiceStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText 实现encoding.TextUnmarshaler，用于YAML等文本格式
+func (s *ServiceStatus) UnmarshalText(text []byte) error {
+	status, err := ParseServiceStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*s = status
+	return nil
 }
 
 // StatusChecker 定义状态检查接口
 type StatusChecker interface {
-	// CheckStatus 检查服务状态，返回状态和错误信息
-	CheckStatus() (ServiceStatus, error)
+	// CheckStatus 检查服务状态，返回状态和错误信息，ctx取消时应尽快返回
+	CheckStatus(ctx context.Context) (ServiceStatus, error)
+}
+
+// LatencyReporter 可自行提供检查耗时的检查器，如由外部插件测量的延迟
+type LatencyReporter interface {
+	// Latency 返回最近一次检查的耗时
+	Latency() time.Duration
 }
 
-// Service 表示一个服务
+// Service 表示一个服务；管理器中的服务只在持有管理器锁时读写，
+// 管理器之外通过GetService与GetServices获取快照
 type Service struct {
 	// Name 服务名称
 	Name string `json:"name"`
@@ -49,49 +122,183 @@ type Service struct {
 	Description string `json:"description"`
 	// URL 服务URL
 	URL string `json:"url"`
+	// Host 服务所在的远程探针名称，本机服务为空
+	Host string `json:"host,omitempty"`
+	// Order 显示顺序，数值小的在前
+	Order int `json:"order"`
+	// Hidden 是否在公开页面中隐藏，隐藏的服务仍会检查
+	Hidden bool `json:"hidden,omitempty"`
+	// Enabled 是否启用，停用的服务保留在配置中但不检查
+	Enabled bool `json:"enabled"`
+	// Invert 反向检查，目标不可达时服务正常
+	Invert bool `json:"invert,omitempty"`
+	// Weight 计入整体状态的权重，为0时按1计算
+	Weight float64 `json:"-"`
 	// Status 当前状态
 	Status ServiceStatus `json:"status"`
 	// LastChecked 最后检查时间
 	LastChecked time.Time `json:"last_checked"`
+	// AgeSeconds 返回时距最后检查经过的秒数，尚未检查时为-1
+	AgeSeconds float64 `json:"age_seconds"`
+	// Checking 是否有进行中的检查
+	Checking bool `json:"checking"`
+	// NextCheck 下次定时检查的时间，未启用定时检查时为空
+	NextCheck *time.Time `json:"next_check,omitempty"`
+	// LastError 最近一次检查的错误信息，检查成功时为空
+	LastError string `json:"last_error"`
+	// LatencyMS 最近一次检查的耗时（毫秒）
+	LatencyMS float64 `json:"latency_ms"`
+	// ConsecutiveFailures 连续检查失败次数
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// ConsecutiveSuccesses 连续检查成功次数
+	ConsecutiveSuccesses int `json:"consecutive_successes"`
+	// Process 进程资源使用情况，仅进程检查成功时存在
+	Process *ProcessMetrics `json:"process,omitempty"`
+	// Download 最近一次下载测速的结果，仅下载测速模式的HTTP检查存在
+	Download *DownloadMetrics `json:"download,omitempty"`
+	// Override 手动设置的状态覆盖
+	Override *StatusOverride `json:"override,omitempty"`
+	// Ack 进行中故障的确认信息
+	Ack *Acknowledgement `json:"ack,omitempty"`
+	// DependsOn 依赖的服务名称
+	DependsOn []string `json:"depends_on,omitempty"`
+	// ImpactedBy 导致该服务受影响的不可用依赖
+	ImpactedBy []string `json:"impacted_by,omitempty"`
+	// LatencyLevel 延迟告警级别（warn或critical），未超过阈值时为空
+	LatencyLevel string `json:"latency_level,omitempty"`
+	// Anomaly 进行中的性能异常，延迟未偏离基线时为空
+	Anomaly *LatencyAnomaly `json:"anomaly,omitempty"`
+	// RegionResults 各检查位置的结果，仅配置了regions的服务存在，本机结果在最前
+	RegionResults []RegionResult `json:"regions,omitempty"`
+	// Regions 同时检查该服务的远程探针
+	Regions []string `json:"-"`
+	// Interval 定时检查间隔，为0时使用全局间隔
+	Interval time.Duration `json:"-"`
+	// Timeout 单次检查的总时限，为0时只受一轮检查的总超时限制
+	Timeout time.Duration `json:"-"`
+	// Retry 检查失败后的重试策略，为nil时不重试
+	Retry *RetryPolicy `json:"-"`
+	// Latency 延迟阈值配置
+	Latency *LatencyThreshold `json:"-"`
+	// AnomalyDetection 延迟异常检测配置
+	AnomalyDetection *AnomalyConfig `json:"-"`
+	// SLO 服务等级目标
+	SLO *SLOConfig `json:"-"`
+	// latencies 最近成功检查的延迟
+	latencies []time.Duration
+	// latencyAvg 最近一次计算的滑动窗口平均延迟
+	latencyAvg time.Duration
+	// baseline 延迟异常检测的基线
+	baseline latencyBaseline
+	// checkStatus 未考虑依赖关系时的状态（已应用状态覆盖）
+	checkStatus ServiceStatus
+	// baseStatus 未应用状态覆盖时的状态，覆盖清除或过期时据此恢复
+	baseStatus ServiceStatus
+	// configOverride 配置文件中的状态覆盖，用于区分通过接口设置的覆盖
+	configOverride *StatusOverride
+	// source 服务来源，配置文件中的服务为空，自动发现的服务为发现来源名称
+	source string
 	// Checker 状态检查器
 	Checker StatusChecker `json:"-"`
+	// CheckerType 检查器类型，如http、ping或插件名称
+	CheckerType string `json:"-"`
 }
 
 // HTTPChecker HTTP状态检查器
 type HTTPChecker struct {
 	// URL 要检查的URL
-	URL string
+	URL string `yaml:"url"`
 	// Timeout 超时时间
-	Timeout time.Duration
+	Timeout time.Duration `yaml:"timeout"`
+	// Expr 检查表达式，如 code == 200 && latency < 800ms && body contains "ok"，
+	// 可使用code、latency、body、headers、cert_days、final_url、redirects；为空时状态码为2xx即为在线
+	Expr string `yaml:"expr,omitempty"`
+	// Redirects 重定向策略：是否跟随、最多次数、最终地址与是否只允许重定向到https
+	Redirects *RedirectPolicy `yaml:"redirects,omitempty"`
+	// Download 下载测速模式，设置后完整下载响应内容并校验长度、校验和与下载速度
+	Download *DownloadCheck `yaml:"download,omitempty"`
+
+	checkTransport `yaml:",inline"`
+
+	// program 编译后的检查表达式
+	program *vm.Program
+	// mu 保护download，手动检查与定时检查可能同时执行
+	mu sync.Mutex
+	// download 最近一次下载测速的结果
+	download *DownloadMetrics
 }
 
-// CheckStatus 实现StatusChecker接口，检查HTTP服务状态
-func (h *HTTPChecker) CheckStatus() (ServiceStatus, error) {
-	client := &http.Client{
-		Timeout: h.Timeout,
+// validate 校验连接配置并编译检查表达式
+func (h *HTTPChecker) validate() error {
+	if err := h.checkTransport.validate(); err != nil {
+		return err
+	}
+	if h.Download != nil {
+		if err := h.Download.validate(); err != nil {
+			return err
+		}
 	}
+	if h.Redirects != nil {
+		if err := h.Redirects.validate(); err != nil {
+			return err
+		}
+	}
+	if h.Expr == "" {
+		return nil
+	}
+	program, err := compileCheckExpression(h.Expr)
+	if err != nil {
+		return err
+	}
+	h.program = program
+	return nil
+}
 
-	resp, err := client.Get(h.URL)
+// CheckStatus 实现StatusChecker接口，检查HTTP服务状态
+func (h *HTTPChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	client := h.client(h.Timeout)
+	client.CheckRedirect = h.Redirects.checkRedirect()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.URL, nil)
+	if err != nil {
+		return StatusOffline, fmt.Errorf("创建HTTP请求失败: %v", err)
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
 	if err != nil {
 		return StatusOffline, fmt.Errorf("HTTP请求失败: %v", err)
 	}
 	defer resp.Body.Close()
+	if err := h.Redirects.verify(resp); err != nil {
+		return StatusOffline, err
+	}
+
+	if h.Download != nil {
+		return h.checkDownload(resp, start)
+	}
+	if h.program != nil {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxExprBody))
+		if err != nil {
+			return StatusOffline, fmt.Errorf("读取响应失败: %v", err)
+		}
+		return evalCheckExpression(h.program, h.Expr, newProbeResult(resp, body, time.Since(start)))
+	}
 
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		return StatusOnline, nil
 	}
 
-	return StatusOffline, fmt.Errorf("HTTP状态码异常: %d", resp.StatusCode)
+	return StatusOffline, &HTTPStatusError{Code: resp.StatusCode}
 }
 
 // PingChecker 简单的ping检查器（模拟）
 type PingChecker struct {
 	// Host 主机地址
-	Host string
+	Host string `yaml:"host"`
 }
 
 // CheckStatus 实现StatusChecker接口，模拟ping检查
-func (p *PingChecker) CheckStatus() (ServiceStatus, error) {
+func (p *PingChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
 	// 这里简化实现，实际项目中可以使用真正的ping
 	// 为了演示，我们随机返回状态
 	if len(p.Host) > 0 {
@@ -103,13 +310,22 @@ func (p *PingChecker) CheckStatus() (ServiceStatus, error) {
 // CmdChecker 基于命令行的进程状态检查器
 type CmdChecker struct {
 	// ProcessName 要检查的进程名称
-	ProcessName string
+	ProcessName string `yaml:"process"`
 	// Timeout 命令执行超时时间
-	Timeout time.Duration
+	Timeout time.Duration `yaml:"timeout"`
+	// DisableMetrics 不采集进程资源信息
+	DisableMetrics bool `yaml:"disable_metrics"`
+
+	// mu 保护metrics与samples，手动检查与定时检查可能同时执行
+	mu sync.Mutex
+	// metrics 最近一次采集的进程资源信息
+	metrics *ProcessMetrics
+	// samples 上次采集时各进程的CPU时间，用于计算区间CPU使用率
+	samples map[int]procSample
 }
 
 // CheckStatus 实现StatusChecker接口，通过ps命令检查进程状态
-func (c *CmdChecker) CheckStatus() (ServiceStatus, error) {
+func (c *CmdChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
 	if c.ProcessName == "" {
 		return StatusOffline, fmt.Errorf("进程名称不能为空")
 	}
@@ -120,81 +336,645 @@ func (c *CmdChecker) CheckStatus() (ServiceStatus, error) {
 		timeout = 5 * time.Second
 	}
 
+	// 设置超时，超时或ctx取消时命令会被终止
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	// 构建命令：ps ax | grep 进程名 | grep -v grep
 	cmdStr := fmt.Sprintf("ps ax | grep '%s' | grep -v grep", c.ProcessName)
-	cmd := exec.Command("bash", "-c", cmdStr)
+	cmd := exec.CommandContext(ctx, "bash", "-c", cmdStr)
 
-	// 设置超时
-	done := make(chan error, 1)
-	go func() {
-		_, err := cmd.Output()
-		done <- err
-	}()
-
-	select {
-	case err := <-done:
-		if err != nil {
-			// 如果命令执行失败或没有找到进程，认为服务离线
-			return StatusOffline, fmt.Errorf("进程 '%s' 未运行: %v", c.ProcessName, err)
+	if _, err := cmd.Output(); err != nil {
+		if ctx.Err() != nil {
+			// 超时
+			return StatusOffline, fmt.Errorf("检查进程 '%s' 超时", c.ProcessName)
 		}
-		// 命令执行成功且有输出，说明进程存在
-		return StatusOnline, nil
-	case <-time.After(timeout):
-		// 超时
-		cmd.Process.Kill()
-		return StatusOffline, fmt.Errorf("检查进程 '%s' 超时", c.ProcessName)
+		// 如果命令执行失败或没有找到进程，认为服务离线
+		c.mu.Lock()
+		c.metrics = nil
+		c.mu.Unlock()
+		return StatusOffline, fmt.Errorf("进程 '%s' 未运行: %v", c.ProcessName, err)
 	}
+	// 命令执行成功且有输出，说明进程存在
+	c.collectMetrics()
+	return StatusOnline, nil
 }
 
+// collectMetrics 采集进程资源信息，采集失败不影响检查结果
+func (c *CmdChecker) collectMetrics() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = nil
+	if c.DisableMetrics {
+		return
+	}
+	metrics, samples, err := collectProcessMetrics(c.ProcessName, c.samples)
+	c.samples = samples
+	if err != nil {
+		slog.Debug("采集进程资源信息失败", "process", c.ProcessName, "error", err)
+		return
+	}
+	c.metrics = metrics
+}
+
+// ProcessMetrics 实现ProcessMetricsReporter接口
+func (c *CmdChecker) ProcessMetrics() *ProcessMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.metrics
+}
+
+// defaultRefreshTimeout 一轮全量检查的默认最长时间
+const defaultRefreshTimeout = 30 * time.Second
+
+// defaultRefreshInterval 按需刷新的默认最小间隔
+const defaultRefreshInterval = 5 * time.Second
+
 // ServiceManager 服务管理器
 type ServiceManager struct {
-	lock        *sync.RWMutex
-	refreshFlag bool
+	// lock 保护服务列表及服务状态字段，检查执行期间不持有
+	lock *sync.RWMutex
+	// ctx 所有检查的父context，Shutdown时取消
+	ctx context.Context
+	// cancel 取消ctx
+	cancel context.CancelFunc
+	// refresh 合并并发的刷新请求
+	refresh singleflight.Group
+	// refreshTimeout 一轮全量检查的总超时时间
+	refreshTimeout time.Duration
+	// refreshInterval 按需刷新的最小间隔（纳秒）
+	refreshInterval atomic.Int64
+	// lastRefresh 最近一轮全量检查开始的时间（UnixNano）
+	lastRefresh atomic.Int64
+	// refreshing 是否正在进行全量检查
+	refreshing atomic.Bool
+	// lastChange 最后一次状态变化的时间（UnixNano），独立于lock以免读取时被检查阻塞
+	lastChange atomic.Int64
+	// lastUpdated 最近一次检查完成的时间（UnixNano），启动时从检查记录中恢复
+	lastUpdated atomic.Int64
 	// services 服务列表
 	services []*Service
+	// history 检查记录存储
+	history HistoryStore
+	// listeners 状态变化监听器
+	listeners []func(Transition)
+	// checkListeners 检查结果监听器
+	checkListeners []func(CheckResult, int)
+	// scheduled 是否启用了后台定时检查
+	scheduled atomic.Bool
+	// overrideTimer 最早过期的状态覆盖的过期定时器，需持有锁访问
+	overrideTimer *time.Timer
+}
+
+// Transition 服务状态变化
+type Transition struct {
+	// Service 服务名称
+	Service string `json:"service"`
+	// From 变化前的状态
+	From ServiceStatus `json:"from"`
+	// To 变化后的状态
+	To ServiceStatus `json:"to"`
+	// Time 发生时间
+	Time time.Time `json:"time"`
+	// Kind 变化类型：status_change，延迟告警latency_warn、latency_critical、latency_recovered，
+	// 或性能异常latency_anomaly、anomaly_recovered
+	Kind string `json:"kind"`
+	// Reason 变化原因，通常为检查错误信息
+	Reason string `json:"reason,omitempty"`
+	// CheckError Reason是否为检查器返回的错误信息
+	CheckError bool `json:"check_error,omitempty"`
+	// Initial 是否为启动后的首次检查
+	Initial bool `json:"-"`
+	// Quiet 只记录事件，不发送通知
+	Quiet bool `json:"-"`
 }
 
 // NewServiceManager 创建新的服务管理器
-func NewServiceManager() *ServiceManager {
-	return &ServiceManager{
-		lock:        new(sync.RWMutex),
-		refreshFlag: false,
-		services:    make([]*Service, 0),
+func NewServiceManager(history HistoryStore) *ServiceManager {
+	sm := &ServiceManager{
+		lock:           new(sync.RWMutex),
+		refreshTimeout: defaultRefreshTimeout,
+		services:       make([]*Service, 0),
+		history:        history,
+	}
+	sm.ctx, sm.cancel = context.WithCancel(context.Background())
+	sm.refreshInterval.Store(int64(defaultRefreshInterval))
+	if latest := history.LastUpdated(); !latest.IsZero() {
+		sm.lastUpdated.Store(latest.UnixNano())
+	}
+	sm.touch()
+	return sm
+}
+
+// Context 返回管理器的context，后台任务应在其取消后退出
+func (sm *ServiceManager) Context() context.Context {
+	return sm.ctx
+}
+
+// Shutdown 取消正在进行的检查并停止后台定时检查，此后的检查结果不再写入
+func (sm *ServiceManager) Shutdown() {
+	sm.cancel()
+}
+
+// touch 记录一次状态变化
+func (sm *ServiceManager) touch() {
+	sm.lastChange.Store(time.Now().UnixNano())
+}
+
+// LastChange 返回最后一次状态变化的时间
+func (sm *ServiceManager) LastChange() time.Time {
+	return time.Unix(0, sm.lastChange.Load())
+}
+
+// LastUpdated 返回最近一次检查完成的时间，从未检查过时返回零值
+func (sm *ServiceManager) LastUpdated() time.Time {
+	if n := sm.lastUpdated.Load(); n != 0 {
+		return time.Unix(0, n)
+	}
+	return time.Time{}
+}
+
+// SetRefreshTimeout 设置一轮全量检查的总超时时间，d<=0时使用默认值
+func (sm *ServiceManager) SetRefreshTimeout(d time.Duration) {
+	if d <= 0 {
+		d = defaultRefreshTimeout
+	}
+	sm.refreshTimeout = d
+}
+
+// SetRefreshInterval 设置按需刷新的最小间隔，d<=0时使用默认值
+func (sm *ServiceManager) SetRefreshInterval(d time.Duration) {
+	if d <= 0 {
+		d = defaultRefreshInterval
+	}
+	sm.refreshInterval.Store(int64(d))
+}
+
+// OnTransition 注册状态变化监听器，监听器在锁外同步调用，不应阻塞
+func (sm *ServiceManager) OnTransition(fn func(Transition)) {
+	sm.listeners = append(sm.listeners, fn)
+}
+
+// OnCheck 注册检查结果监听器，参数为检查记录与连续失败次数，监听器在锁外同步调用，不应阻塞
+func (sm *ServiceManager) OnCheck(fn func(result CheckResult, failures int)) {
+	sm.checkListeners = append(sm.checkListeners, fn)
+}
+
+// statusSnapshot 记录所有服务的当前状态，需持有锁调用
+func (sm *ServiceManager) statusSnapshot() map[*Service]ServiceStatus {
+	snapshot := make(map[*Service]ServiceStatus, len(sm.services))
+	for _, service := range sm.services {
+		snapshot[service] = service.Status
+	}
+	return snapshot
+}
+
+// transitionsSince 与快照对比得到状态变化，levels为检查前的延迟告警级别，
+// initial中的服务标记为首次检查，需持有锁调用
+func (sm *ServiceManager) transitionsSince(before map[*Service]ServiceStatus, levels map[*Service]string, anomalies map[*Service]bool, initial map[*Service]bool, now time.Time) []Transition {
+	transitions := make([]Transition, 0)
+	for _, service := range sm.services {
+		from, ok := before[service]
+		if !ok {
+			continue
+		}
+		if anomalies[service] {
+			kind := "anomaly_recovered"
+			if service.Anomaly != nil {
+				kind = "latency_anomaly"
+			}
+			transitions = append(transitions, Transition{
+				Service: service.Name,
+				From:    from,
+				To:      service.Status,
+				Time:    now,
+				Kind:    kind,
+				Reason:  anomalyReason(service),
+				Quiet:   !service.AnomalyDetection.notify(),
+			})
+		}
+		kind := "status_change"
+		if level, checked := levels[service]; checked && level != service.LatencyLevel && !service.Override.Active(now) {
+			switch {
+			case service.LatencyLevel != "":
+				kind = "latency_" + service.LatencyLevel
+			case from == StatusDegraded && service.Status == StatusOnline:
+				kind = "latency_recovered"
+			}
+		}
+		if kind == "status_change" && from == service.Status {
+			continue
+		}
+		reason, checkError := service.LastError, service.LastError != ""
+		if service.Override.Active(now) && service.Override.Reason != "" {
+			reason, checkError = service.Override.Reason, false
+		} else if len(service.ImpactedBy) > 0 {
+			reason, checkError = fmt.Sprintf("依赖的服务不可用: %s", strings.Join(service.ImpactedBy, ", ")), false
+		} else if kind != "status_change" {
+			reason, checkError = latencyReason(service), false
+		}
+		transitions = append(transitions, Transition{
+			Service:    service.Name,
+			From:       from,
+			To:         service.Status,
+			Time:       now,
+			Kind:       kind,
+			Reason:     reason,
+			CheckError: checkError,
+			Initial:    initial[service],
+		})
+	}
+	return transitions
+}
+
+// emit 通知监听器，需在锁外调用
+func (sm *ServiceManager) emit(transitions []Transition) {
+	for _, t := range transitions {
+		for _, fn := range sm.listeners {
+			fn(t)
+		}
 	}
 }
 
 // AddService 添加服务
 func (sm *ServiceManager) AddService(service *Service) {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
 	sm.services = append(sm.services, service)
+	now := time.Now()
+	refreshOverride(service, now)
+	sm.scheduleOverrideExpiry(now)
+	sm.applyDependencies()
+	sm.touch()
 }
 
-// GetServices 获取所有服务
+// SyncServices 按名称同步配置文件中的服务：新增、删除或更新服务定义，保留已有服务的状态
+func (sm *ServiceManager) SyncServices(services []*Service) {
+	sm.SyncSource("", services)
+}
+
+// SyncSource 按名称同步指定来源的服务，其他来源的服务不受影响，返回同步后的服务；
+// 来源为空表示配置文件中的服务；重名时配置文件中的服务优先，自动发现的同名服务被忽略。
+// 服务按来源分组排列：配置文件中的服务在前，其余来源按名称排序
+func (sm *ServiceManager) SyncSource(source string, services []*Service) []*Service {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+
+	existing := make(map[string]*Service, len(sm.services))
+	groups := make(map[string][]*Service)
+	for _, service := range sm.services {
+		existing[service.Name] = service
+		if service.source != source {
+			groups[service.source] = append(groups[service.source], service)
+		}
+	}
+
+	synced := make([]*Service, 0, len(services))
+	for _, service := range services {
+		if old, ok := existing[service.Name]; ok && old.source != source {
+			if source != "" {
+				slog.Warn("自动发现的服务与已有服务重名，已忽略", "service", service.Name, "source", source)
+				continue
+			}
+			groups[old.source] = removeService(groups[old.source], old)
+			delete(existing, service.Name)
+		}
+		if old, ok := existing[service.Name]; ok {
+			old.Description = service.Description
+			old.URL = service.URL
+			old.Checker = service.Checker
+			old.CheckerType = service.CheckerType
+			old.DependsOn = service.DependsOn
+			old.Order = service.Order
+			old.Hidden = service.Hidden
+			old.Enabled = service.Enabled
+			old.Interval = service.Interval
+			old.Timeout = service.Timeout
+			old.Retry = service.Retry
+			old.Invert = service.Invert
+			old.Weight = service.Weight
+			old.Latency = service.Latency
+			old.AnomalyDetection = service.AnomalyDetection
+			old.SLO = service.SLO
+			old.Regions = service.Regions
+			// 配置中的状态覆盖优先，否则保留通过接口设置的覆盖；配置中删除的覆盖随之清除
+			switch {
+			case service.Override != nil:
+				old.Override = service.Override
+			case old.Override != nil && old.Override == old.configOverride:
+				old.Override = nil
+			}
+			old.configOverride = service.configOverride
+			synced = append(synced, old)
+			continue
+		}
+		service.source = source
+		synced = append(synced, service)
+	}
+	groups[source] = synced
+	now := time.Now()
+	for _, service := range synced {
+		refreshOverride(service, now)
+	}
+
+	sources := make([]string, 0, len(groups))
+	for name := range groups {
+		if name != "" {
+			sources = append(sources, name)
+		}
+	}
+	sort.Strings(sources)
+	all := append([]*Service(nil), groups[""]...)
+	for _, name := range sources {
+		all = append(all, groups[name]...)
+	}
+	sm.services = all
+	sm.scheduleOverrideExpiry(now)
+	sm.applyDependencies()
+	sm.touch()
+	return synced
+}
+
+// removeService 从列表中移除指定服务
+func removeService(services []*Service, target *Service) []*Service {
+	kept := services[:0]
+	for _, service := range services {
+		if service != target {
+			kept = append(kept, service)
+		}
+	}
+	return kept
+}
+
+// lookup 按名称查找管理器中的服务，不存在时返回nil，需持有锁调用
+func (sm *ServiceManager) lookup(name string) *Service {
+	for _, service := range sm.services {
+		if service.Name == name {
+			return service
+		}
+	}
+	return nil
+}
+
+// snapshot 返回服务的副本，需持有锁调用；副本中的切片与指针字段在管理器中只会整体替换，
+// 因此可与管理器共享
+func (s *Service) snapshot() *Service {
+	c := *s
+	c.latencies = nil
+	return &c
+}
+
+// GetService 按名称获取服务的快照，不存在时返回nil
+func (sm *ServiceManager) GetService(name string) *Service {
+	sm.lock.RLock()
+	defer sm.lock.RUnlock()
+	if service := sm.lookup(name); service != nil {
+		return service.snapshot()
+	}
+	return nil
+}
+
+// History 返回检查记录存储
+func (sm *ServiceManager) History() HistoryStore {
+	return sm.history
+}
+
+// GetServices 获取所有服务的快照，修改快照不会影响管理器中的服务
 func (sm *ServiceManager) GetServices() []*Service {
-	return sm.services
+	sm.lock.RLock()
+	defer sm.lock.RUnlock()
+	services := make([]*Service, len(sm.services))
+	for i, service := range sm.services {
+		services[i] = service.snapshot()
+	}
+	return services
 }
 
-// UpdateStatus 更新服务状态
-func (sm *ServiceManager) UpdateStatus(service *Service) {
-	if service.Checker != nil {
-		status, err := service.Checker.CheckStatus()
+// checkOutcome 一次检查的原始结果
+type checkOutcome struct {
+	service  *Service
+	checker  StatusChecker
+	status   ServiceStatus
+	err      error
+	duration time.Duration
+	// attempts 按重试策略重试时每次尝试的结果
+	attempts []CheckAttempt
+}
+
+// runCheck 执行服务的检查器，不持有锁；服务配置了timeout时检查在该时限内取消，
+// 反向检查的服务在此反转结果；服务没有检查器或已停用时返回nil
+func (sm *ServiceManager) runCheck(ctx context.Context, service *Service) *checkOutcome {
+	sm.lock.Lock()
+	checker, enabled, timeout, retry, invert := service.Checker, service.Enabled, service.Timeout, service.Retry, service.Invert
+	if checker != nil && enabled {
+		service.Checking = true
+	}
+	sm.lock.Unlock()
+	if checker == nil || !enabled {
+		return nil
+	}
+
+	checkCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		checkCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	status, duration, attempts, err := checkWithRetry(checkCtx, checker, retry)
+	if err != nil && ctx.Err() == nil && checkCtx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("检查超过时限 %s: %v", timeout, err)
+	}
+	// 整轮检查被取消时结果不可信，不视为目标不可达
+	if invert && ctx.Err() == nil {
+		status, err = invertResult(status, err)
+	}
+	return &checkOutcome{
+		service:  service,
+		checker:  checker,
+		status:   status,
+		err:      err,
+		duration: duration,
+		attempts: attempts,
+	}
+}
+
+// applyOutcomes 将一批检查结果写入服务状态，统一计算依赖关系后保存检查记录；
+// 管理器已关闭时丢弃结果，避免被取消的检查显示为离线
+func (sm *ServiceManager) applyOutcomes(outcomes []*checkOutcome) []CheckResult {
+	if sm.ctx.Err() != nil {
+		return nil
+	}
+	sm.lock.Lock()
+	now := time.Now()
+	before := sm.statusSnapshot()
+	initial := make(map[*Service]bool)
+	levels := make(map[*Service]string)
+	anomalies := make(map[*Service]bool)
+	for _, o := range outcomes {
+		service := o.service
+		initial[service] = service.LastChecked.IsZero()
+		levels[service] = service.LatencyLevel
+		service.LatencyMS = float64(o.duration) / float64(time.Millisecond)
+		status := applyLatency(service, o.status, o.duration)
+		anomalies[service] = applyAnomaly(service, o.status, o.err, o.duration, now)
+		status, o.err = applyRegions(service, status, o.err, now)
+		status = applyMaintenance(service, status, now)
+		service.baseStatus = status
+		status = applyOverride(service, status, now)
+		service.checkStatus = status
+		if status != service.Status {
+			sm.touch()
+		}
 		service.Status = status
-		service.LastChecked = time.Now()
-		if err != nil {
-			fmt.Printf("检查服务 %s 状态时出错: %v\n", service.Name, err)
+		service.LastChecked = now
+		service.Checking = false
+		service.Process = nil
+		if reporter, ok := o.checker.(ProcessMetricsReporter); ok && status == StatusOnline {
+			service.Process = reporter.ProcessMetrics()
+		}
+		service.Download = nil
+		if reporter, ok := o.checker.(DownloadMetricsReporter); ok {
+			service.Download = reporter.DownloadMetrics()
+		}
+		if o.err != nil {
+			service.LastError = o.err.Error()
+			service.ConsecutiveFailures++
+			service.ConsecutiveSuccesses = 0
+		} else {
+			service.LastError = ""
+			service.ConsecutiveSuccesses++
+			service.ConsecutiveFailures = 0
+		}
+	}
+	sm.applyDependencies()
+	for _, o := range outcomes {
+		if expireAck(o.service, now) {
+			sm.touch()
+		}
+	}
+
+	results := make([]CheckResult, 0, len(outcomes))
+	failures := make([]int, 0, len(outcomes))
+	for _, o := range outcomes {
+		failures = append(failures, o.service.ConsecutiveFailures)
+		results = append(results, CheckResult{
+			Service:   o.service.Name,
+			Time:      now,
+			Status:    o.service.Status,
+			LatencyMS: float64(o.duration) / float64(time.Millisecond),
+			Error:     o.service.LastError,
+			Attempts:  o.attempts,
+		})
+	}
+	transitions := sm.transitionsSince(before, levels, anomalies, initial, now)
+	if len(outcomes) > 0 {
+		sm.lastUpdated.Store(now.UnixNano())
+	}
+	sm.lock.Unlock()
+	sm.emit(transitions)
+	for i := range results {
+		for _, fn := range sm.checkListeners {
+			fn(results[i], failures[i])
+		}
+	}
+
+	for i, o := range outcomes {
+		if err := sm.history.Append(results[i]); err != nil {
+			slog.Error("保存检查记录失败", "service", o.service.Name, "error", err)
 		}
+		if o.err != nil {
+			slog.Warn("服务检查失败", "service", o.service.Name, "status", results[i].Status, "duration", o.duration, "error", o.err)
+		} else {
+			slog.Debug("服务检查完成", "service", o.service.Name, "status", results[i].Status, "duration", o.duration)
+		}
+	}
+	return results
+}
+
+// updateStatus 更新管理器中服务的状态并返回本次检查记录，检查期间不持有锁，仅在写入结果时加锁；
+// 服务没有检查器时返回nil
+func (sm *ServiceManager) updateStatus(ctx context.Context, service *Service) *CheckResult {
+	outcome := sm.runCheck(ctx, service)
+	if outcome == nil {
+		return nil
+	}
+	results := sm.applyOutcomes([]*checkOutcome{outcome})
+	if len(results) == 0 {
+		return nil
+	}
+	return &results[0]
+}
+
+// CheckService 立即检查单个服务，返回检查后的服务快照，服务不存在时返回错误
+func (sm *ServiceManager) CheckService(ctx context.Context, name string) (*Service, *CheckResult, error) {
+	sm.lock.RLock()
+	service := sm.lookup(name)
+	sm.lock.RUnlock()
+	if service == nil {
+		return nil, nil, fmt.Errorf("服务 '%s' 不存在", name)
 	}
+	// 调用方的ctx与管理器关闭均会取消检查
+	ctx, cancel := context.WithTimeout(ctx, sm.refreshTimeout)
+	defer cancel()
+	stop := context.AfterFunc(sm.ctx, cancel)
+	defer stop()
+	result := sm.updateStatus(ctx, service)
+
+	sm.lock.RLock()
+	defer sm.lock.RUnlock()
+	return service.snapshot(), result, nil
 }
 
-// UpdateAllStatus 更新所有服务状态
+// UpdateAllStatus 并发更新所有服务状态，并发调用会合并为同一轮检查
 func (sm *ServiceManager) UpdateAllStatus() {
-	sm.lock.Lock()
-	defer sm.lock.Unlock()
-	if sm.refreshFlag {
+	sm.refresh.Do("all", func() (interface{}, error) {
+		sm.refreshing.Store(true)
+		defer sm.refreshing.Store(false)
+		sm.lastRefresh.Store(time.Now().UnixNano())
+		ctx, cancel := context.WithTimeout(sm.ctx, sm.refreshTimeout)
+		defer cancel()
+
+		sm.lock.RLock()
+		services := append([]*Service(nil), sm.services...)
+		sm.lock.RUnlock()
+
+		sm.checkBatch(ctx, services)
+		return nil, nil
+	})
+}
+
+// RefreshAsync 在后台触发一轮全量检查；已有检查进行中，或距上一轮开始不足最小间隔时直接返回，
+// 每个间隔内无论请求多少最多触发一次
+func (sm *ServiceManager) RefreshAsync() {
+	if sm.refreshing.Load() {
 		return
 	}
-	sm.refreshFlag = true
-	for _, service := range sm.services {
-		sm.UpdateStatus(service)
+	now := time.Now().UnixNano()
+	last := sm.lastRefresh.Load()
+	if now-last < sm.refreshInterval.Load() || !sm.lastRefresh.CompareAndSwap(last, now) {
+		return
+	}
+	go sm.UpdateAllStatus()
+}
+
+// checkBatch 并发检查一批服务，统一写入结果，保证依赖关系按本轮全部结果计算
+func (sm *ServiceManager) checkBatch(ctx context.Context, services []*Service) []CheckResult {
+	outcomes := make([]*checkOutcome, len(services))
+	var wg sync.WaitGroup
+	for i, service := range services {
+		wg.Add(1)
+		go func(i int, service *Service) {
+			defer wg.Done()
+			outcomes[i] = sm.runCheck(ctx, service)
+		}(i, service)
+	}
+	wg.Wait()
+
+	checked := make([]*checkOutcome, 0, len(outcomes))
+	for _, o := range outcomes {
+		if o != nil {
+			checked = append(checked, o)
+		}
 	}
-	sm.refreshFlag = false
+	return sm.applyOutcomes(checked)
 }
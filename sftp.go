@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTPChecker SFTP服务检查器：登录后查看path是否存在，用于检查备份目标等存储
+type SFTPChecker struct {
+	// Host 服务器地址，未指定端口时使用22
+	Host string `yaml:"host"`
+	// User 登录用户名
+	User string `yaml:"user"`
+	// Password 登录密码
+	Password string `yaml:"password"`
+	// PrivateKey 私钥文件路径，与密码可同时配置
+	PrivateKey string `yaml:"private_key"`
+	// Passphrase 私钥密码
+	Passphrase string `yaml:"passphrase"`
+	// HostKey 服务器公钥指纹，如SHA256:...，与known_hosts二选一
+	HostKey string `yaml:"host_key"`
+	// KnownHosts known_hosts文件路径
+	KnownHosts string `yaml:"known_hosts"`
+	// Path 需要存在的路径，默认为登录目录
+	Path string `yaml:"path"`
+	// Timeout 超时时间，默认10s
+	Timeout time.Duration `yaml:"timeout"`
+
+	checkTransport `yaml:",inline"`
+}
+
+// validate 校验SFTP检查配置
+func (s *SFTPChecker) validate() error {
+	if s.Host == "" || s.User == "" {
+		return fmt.Errorf("SFTP检查缺少host或user")
+	}
+	if s.Password == "" && s.PrivateKey == "" {
+		return fmt.Errorf("SFTP检查需要配置password或private_key")
+	}
+	if s.HostKey == "" && s.KnownHosts == "" {
+		return fmt.Errorf("SFTP检查需要配置host_key或known_hosts以校验服务器身份")
+	}
+	return s.checkTransport.validate()
+}
+
+// clientConfig 创建SSH客户端配置
+func (s *SFTPChecker) clientConfig(timeout time.Duration) (*ssh.ClientConfig, error) {
+	var auth []ssh.AuthMethod
+	if s.PrivateKey != "" {
+		data, err := os.ReadFile(s.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("读取私钥失败: %v", err)
+		}
+		var signer ssh.Signer
+		if s.Passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(s.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥失败: %v", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if s.Password != "" {
+		auth = append(auth, ssh.Password(s.Password))
+	}
+
+	var hostKey ssh.HostKeyCallback
+	if s.HostKey != "" {
+		hostKey = func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			if fp := ssh.FingerprintSHA256(key); fp != s.HostKey {
+				return fmt.Errorf("服务器公钥指纹 %s 与配置不一致", fp)
+			}
+			return nil
+		}
+	} else {
+		callback, err := knownhosts.New(s.KnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("读取known_hosts失败: %v", err)
+		}
+		hostKey = callback
+	}
+	return &ssh.ClientConfig{User: s.User, Auth: auth, HostKeyCallback: hostKey, Timeout: timeout}, nil
+}
+
+// CheckStatus 实现StatusChecker接口
+func (s *SFTPChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	config, err := s.clientConfig(timeout)
+	if err != nil {
+		return StatusOffline, err
+	}
+	addr := s.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+	conn, err := s.dialer(timeout)(ctx, "tcp", addr)
+	if err != nil {
+		return StatusOffline, fmt.Errorf("连接SFTP服务器失败: %v", err)
+	}
+	// 握手与后续操作均受截止时间限制
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return StatusOffline, fmt.Errorf("SSH登录失败: %v", err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	sc, err := sftp.NewClient(client)
+	if err != nil {
+		return StatusOffline, fmt.Errorf("打开SFTP会话失败: %v", err)
+	}
+	defer sc.Close()
+
+	path := s.Path
+	if path == "" {
+		path = "."
+	}
+	if _, err := sc.Stat(path); err != nil {
+		return StatusOffline, fmt.Errorf("访问路径 '%s' 失败: %v", path, err)
+	}
+	return StatusOnline, nil
+}
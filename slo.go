@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultSLOPeriod SLO默认统计周期
+const defaultSLOPeriod = "month"
+
+// SLOConfig 服务等级目标配置
+type SLOConfig struct {
+	// Target 可用率目标，单位为百分比，如99.9
+	Target float64 `yaml:"target"`
+	// Period 统计周期：month、week、day为自然周期，也可为滚动时长如30d、12h
+	Period string `yaml:"period,omitempty"`
+}
+
+// SLOReport 服务在当前周期内的SLO与错误预算
+type SLOReport struct {
+	// Service 服务名称
+	Service string `json:"service"`
+	// Target 可用率目标
+	Target float64 `json:"target"`
+	// Period 统计周期
+	Period string `json:"period"`
+	// PeriodStart 周期开始时间
+	PeriodStart time.Time `json:"period_start"`
+	// PeriodEnd 周期结束时间，滚动周期为当前时间
+	PeriodEnd time.Time `json:"period_end"`
+	// Checks 计入统计的检查次数，维护期间不计入
+	Checks int `json:"checks"`
+	// FailedChecks 不可用的检查次数
+	FailedChecks int `json:"failed_checks"`
+	// UptimePercent 周期内的可用率，没有数据时为nil
+	UptimePercent *float64 `json:"uptime_percent"`
+	// BudgetChecks 周期内允许失败的检查次数
+	BudgetChecks float64 `json:"budget_checks"`
+	// BudgetRemainingPercent 剩余错误预算的百分比，超支时为负数
+	BudgetRemainingPercent float64 `json:"budget_remaining_percent"`
+	// Exhausted 错误预算是否已耗尽
+	Exhausted bool `json:"exhausted"`
+}
+
+// period 返回统计周期名称
+func (slo *SLOConfig) period() string {
+	if slo.Period != "" {
+		return slo.Period
+	}
+	return defaultSLOPeriod
+}
+
+// validate 校验SLO配置
+func (slo *SLOConfig) validate() error {
+	if slo.Target <= 0 || slo.Target >= 100 {
+		return fmt.Errorf("SLO目标必须在0到100之间")
+	}
+	if _, _, err := slo.window(time.Now()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// window 计算当前统计周期的起止时间
+func (slo *SLOConfig) window(now time.Time) (time.Time, time.Time, error) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	switch slo.period() {
+	case "month":
+		start := time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0), nil
+	case "week":
+		// 以周一为一周的开始
+		start := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		return start, start.AddDate(0, 0, 7), nil
+	case "day":
+		return today, today.AddDate(0, 0, 1), nil
+	}
+	span, err := parseSpan(slo.Period)
+	if err != nil || span < time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("无效的SLO周期 '%s'", slo.Period)
+	}
+	return now.Add(-span), now, nil
+}
+
+// buildSLOReport 根据小时汇总计算服务的SLO报告
+func buildSLOReport(service string, slo *SLOConfig, now time.Time) (*SLOReport, error) {
+	start, end, err := slo.window(now)
+	if err != nil {
+		return nil, err
+	}
+	rollups, err := serviceManager.History().QueryRollups(service, start.Truncate(time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SLOReport{
+		Service:     service,
+		Target:      slo.Target,
+		Period:      slo.period(),
+		PeriodStart: start,
+		PeriodEnd:   end,
+	}
+	for _, rollup := range rollups {
+		for status, n := range rollup.Counts {
+			if status == StatusMaintenance {
+				continue
+			}
+			report.Checks += n
+			if !isUp(status) {
+				report.FailedChecks += n
+			}
+		}
+	}
+
+	report.BudgetChecks = float64(report.Checks) * (100 - slo.Target) / 100
+	report.BudgetRemainingPercent = 100
+	if report.Checks > 0 {
+		uptime := float64(report.Checks-report.FailedChecks) / float64(report.Checks) * 100
+		report.UptimePercent = &uptime
+		report.BudgetRemainingPercent = (1 - float64(report.FailedChecks)/report.BudgetChecks) * 100
+	}
+	report.Exhausted = report.BudgetRemainingPercent <= 0
+	return report, nil
+}
+
+// apiSLOHandler 返回所有配置了SLO的服务的错误预算
+func apiSLOHandler(c *gin.Context) {
+	now := time.Now()
+	reports := make([]*SLOReport, 0)
+	authorized := canViewPrivate(c)
+	for _, service := range serviceManager.GetServices() {
+		if service.SLO == nil || !visible(service, authorized) {
+			continue
+		}
+		report, err := buildSLOReport(service.Name, service.SLO, now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		reports = append(reports, report)
+	}
+	c.JSON(http.StatusOK, gin.H{"services": reports})
+}
+
+// apiServiceSLOHandler 返回单个服务的SLO与错误预算
+func apiServiceSLOHandler(c *gin.Context) {
+	service := publicService(c, c.Param("name"))
+	if service == nil {
+		apiError(c, http.StatusNotFound, "error.service_not_found")
+		return
+	}
+	if service.SLO == nil {
+		apiError(c, http.StatusNotFound, "error.slo_not_configured")
+		return
+	}
+	report, err := buildSLOReport(service.Name, service.SLO, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultSnapshotInterval 定时快照的默认间隔
+	defaultSnapshotInterval = 24 * time.Hour
+	// defaultSnapshotKeep 默认保留的快照数
+	defaultSnapshotKeep = 30
+	// snapshotCheckInterval 检查是否需要定时快照的间隔
+	snapshotCheckInterval = time.Minute
+)
+
+// 快照原因，配置变更时为对应的审计操作类型
+const (
+	snapshotStartup   = "startup"
+	snapshotScheduled = "scheduled"
+)
+
+// SnapshotConfig 配置快照设置，快照保存在检查记录存储目录下，用于错误修改后回滚
+type SnapshotConfig struct {
+	// Interval 定时快照的间隔，默认24h；配置与最近一次快照相同时不重复保存
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Keep 保留的快照数，超出时删除最旧的快照，默认30
+	Keep int `yaml:"keep,omitempty"`
+}
+
+// validate 校验快照配置
+func (sc *SnapshotConfig) validate() error {
+	if sc.Interval < 0 {
+		return fmt.Errorf("snapshots.interval不能为负数")
+	}
+	if sc.Keep < 0 {
+		return fmt.Errorf("snapshots.keep不能为负数")
+	}
+	return nil
+}
+
+// ConfigSnapshot 一份生效配置的快照
+type ConfigSnapshot struct {
+	// ID 快照序号，单调递增
+	ID int64 `json:"id"`
+	// Time 保存时间
+	Time time.Time `json:"time"`
+	// Reason 保存原因：startup、scheduled，或config_import等配置变更的审计操作类型
+	Reason string `json:"reason"`
+	// Actor 触发配置变更的操作者，定时快照为空
+	Actor string `json:"actor,omitempty"`
+	// Hash 配置内容的SHA-256
+	Hash string `json:"hash"`
+	// Services 配置中的服务数
+	Services int `json:"services"`
+	// Config YAML格式的配置内容，列表接口中不返回
+	Config string `json:"config,omitempty"`
+}
+
+// SnapshotStore 配置快照存储，保存在检查记录存储目录下
+type SnapshotStore struct {
+	lock sync.Mutex
+	// cfg 快照配置
+	cfg SnapshotConfig
+	// snapshots 保留的快照，按时间正序
+	snapshots []ConfigSnapshot
+	// nextID 下一个快照的序号
+	nextID int64
+	// path 快照文件路径，为空时仅保存在内存中
+	path string
+	// file 追加写入的快照文件
+	file *os.File
+}
+
+// snapshots 全局配置快照存储
+var snapshots = &SnapshotStore{nextID: 1}
+
+// Open 加载检查记录存储目录下已保存的快照，并持续追加写入；存储路径为空时仅保存在内存中
+func (ss *SnapshotStore) Open(storage StorageConfig) error {
+	if storage.Path == "" {
+		return nil
+	}
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	ss.path = strings.TrimSuffix(storage.Path, filepath.Ext(storage.Path)) + ".snapshots.jsonl"
+
+	err := readLines(ss.path, func(line []byte) error {
+		var snapshot ConfigSnapshot
+		if err := json.Unmarshal(line, &snapshot); err != nil {
+			return err
+		}
+		ss.snapshots = append(ss.snapshots, snapshot)
+		if snapshot.ID >= ss.nextID {
+			ss.nextID = snapshot.ID + 1
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	ss.file, err = openAppend(ss.path)
+	return err
+}
+
+// Update 根据配置更新快照设置，保留数减少时立即删除多余的快照
+func (ss *SnapshotStore) Update(cfg *Config) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	ss.cfg = cfg.Snapshots
+	if err := ss.trim(); err != nil {
+		slog.Error("清理配置快照失败", "error", err)
+	}
+}
+
+// keep 返回保留的快照数，需持有锁调用
+func (ss *SnapshotStore) keep() int {
+	if ss.cfg.Keep > 0 {
+		return ss.cfg.Keep
+	}
+	return defaultSnapshotKeep
+}
+
+// trim 删除超出保留数的旧快照并重写文件，需持有锁调用
+func (ss *SnapshotStore) trim() error {
+	n := len(ss.snapshots) - ss.keep()
+	if n <= 0 {
+		return nil
+	}
+	ss.snapshots = append(ss.snapshots[:0:0], ss.snapshots[n:]...)
+	if ss.path == "" {
+		return nil
+	}
+	records := make([]interface{}, len(ss.snapshots))
+	for i := range ss.snapshots {
+		records[i] = ss.snapshots[i]
+	}
+	var err error
+	ss.file, err = rewriteFile(ss.file, ss.path, records)
+	return err
+}
+
+// Capture 保存配置的快照，内容与最近一次快照相同时跳过
+func (ss *SnapshotStore) Capture(cfg *Config, reason, actor string) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		slog.Error("序列化配置快照失败", "error", err)
+		return
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	if n := len(ss.snapshots); n > 0 && ss.snapshots[n-1].Hash == hash {
+		return
+	}
+	snapshot := ConfigSnapshot{
+		ID:       ss.nextID,
+		Time:     time.Now(),
+		Reason:   reason,
+		Actor:    actor,
+		Hash:     hash,
+		Services: len(cfg.Services),
+		Config:   string(data),
+	}
+	ss.nextID++
+	ss.snapshots = append(ss.snapshots, snapshot)
+	if len(ss.snapshots) > ss.keep() {
+		err = ss.trim()
+	} else {
+		err = writeLine(ss.file, snapshot)
+	}
+	if err != nil {
+		slog.Error("保存配置快照失败", "id", snapshot.ID, "error", err)
+		return
+	}
+	slog.Info("已保存配置快照", "id", snapshot.ID, "reason", reason)
+}
+
+// List 按时间倒序返回快照，不包含配置内容
+func (ss *SnapshotStore) List() []ConfigSnapshot {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	out := make([]ConfigSnapshot, 0, len(ss.snapshots))
+	for i := len(ss.snapshots) - 1; i >= 0; i-- {
+		snapshot := ss.snapshots[i]
+		snapshot.Config = ""
+		out = append(out, snapshot)
+	}
+	return out
+}
+
+// Get 返回指定序号的快照
+func (ss *SnapshotStore) Get(id int64) (ConfigSnapshot, bool) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	for _, snapshot := range ss.snapshots {
+		if snapshot.ID == id {
+			return snapshot, true
+		}
+	}
+	return ConfigSnapshot{}, false
+}
+
+// captureDue 距最近一次快照超过间隔时保存当前配置
+func (ss *SnapshotStore) captureDue(now time.Time) {
+	ss.lock.Lock()
+	interval := ss.cfg.Interval
+	if interval <= 0 {
+		interval = defaultSnapshotInterval
+	}
+	due := len(ss.snapshots) == 0 || now.Sub(ss.snapshots[len(ss.snapshots)-1].Time) >= interval
+	ss.lock.Unlock()
+	if due {
+		ss.Capture(configReloader.Current(), snapshotScheduled, "")
+	}
+}
+
+// Start 保存启动时的配置，并在后台定期保存快照
+func (ss *SnapshotStore) Start() {
+	ss.Capture(configReloader.Current(), snapshotStartup, "")
+	go func() {
+		ticker := time.NewTicker(snapshotCheckInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			ss.captureDue(now)
+		}
+	}()
+}
+
+// snapshotParam 解析路径中的快照序号，快照不存在时返回404
+func snapshotParam(c *gin.Context) (ConfigSnapshot, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "error.invalid_param", "id")
+		return ConfigSnapshot{}, false
+	}
+	snapshot, ok := snapshots.Get(id)
+	if !ok {
+		apiError(c, http.StatusNotFound, "error.snapshot_not_found")
+		return ConfigSnapshot{}, false
+	}
+	return snapshot, true
+}
+
+// apiSnapshotsHandler 按时间倒序返回配置快照
+func apiSnapshotsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"snapshots": snapshots.List()})
+}
+
+// apiSnapshotHandler 返回快照的YAML配置内容
+func apiSnapshotHandler(c *gin.Context) {
+	snapshot, ok := snapshotParam(c)
+	if !ok {
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="services-%d.yaml"`, snapshot.ID))
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", []byte(snapshot.Config))
+}
+
+// apiSnapshotRollbackHandler 将配置回滚到指定快照：校验通过后写入配置文件并立即生效
+func apiSnapshotRollbackHandler(c *gin.Context) {
+	snapshot, ok := snapshotParam(c)
+	if !ok {
+		return
+	}
+	if err := configReloader.Rollback(snapshot, requestActor(c), requestIP(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"snapshot": snapshot.ID, "services": len(configReloader.Current().Services)})
+}
@@ -0,0 +1,598 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMaxResults 每个服务在内存中保留的检查记录数
+	defaultMaxResults = 1000
+	// defaultRawRetention 原始检查记录默认保留时间
+	defaultRawRetention = 30 * 24 * time.Hour
+	// defaultRollupRetention 小时汇总默认保留时间
+	defaultRollupRetention = 365 * 24 * time.Hour
+	// defaultPruneInterval 默认清理间隔
+	defaultPruneInterval = time.Hour
+)
+
+// StorageConfig 历史记录存储配置
+type StorageConfig struct {
+	// Path 检查记录文件路径（JSON Lines），为空时仅保存在内存中；
+	// 小时汇总保存在同目录下的 <文件名>.rollups.jsonl 中
+	Path string `yaml:"path"`
+	// MaxResults 每个服务在内存中缓存的最大原始检查记录数，不影响文件中按raw_retention保留的记录
+	MaxResults int `yaml:"max_results"`
+	// RawRetention 原始检查记录保留时间，默认30天
+	RawRetention time.Duration `yaml:"raw_retention"`
+	// RollupRetention 小时汇总保留时间，默认1年
+	RollupRetention time.Duration `yaml:"rollup_retention"`
+	// PruneInterval 清理过期数据的间隔，默认1小时
+	PruneInterval time.Duration `yaml:"prune_interval"`
+	// LatencyBuckets 延迟直方图各桶的上界（毫秒），按升序排列，默认5ms到10s共11个桶
+	LatencyBuckets []float64 `yaml:"latency_buckets,omitempty"`
+}
+
+// CheckResult 单次检查结果
+type CheckResult struct {
+	// Service 服务名称
+	Service string `json:"service"`
+	// Time 检查时间
+	Time time.Time `json:"time"`
+	// Status 检查结果状态
+	Status ServiceStatus `json:"status"`
+	// LatencyMS 检查耗时（毫秒）
+	LatencyMS float64 `json:"latency_ms"`
+	// Error 错误信息
+	Error string `json:"error,omitempty"`
+	// Attempts 按重试策略重试时每次尝试的结果，便于排查，未重试时为空
+	Attempts []CheckAttempt `json:"attempts,omitempty"`
+}
+
+// HourlyRollup 单个服务一小时内检查结果的汇总
+type HourlyRollup struct {
+	// Service 服务名称
+	Service string `json:"service"`
+	// Hour 小时开始时间
+	Hour time.Time `json:"hour"`
+	// Counts 各状态的检查次数
+	Counts map[ServiceStatus]int `json:"counts"`
+	// LatencySumMS 成功检查的延迟总和（毫秒）
+	LatencySumMS float64 `json:"latency_sum_ms"`
+	// LatencyCount 成功检查次数
+	LatencyCount int `json:"latency_count"`
+	// LatencyMaxMS 成功检查的最大延迟（毫秒）
+	LatencyMaxMS float64 `json:"latency_max_ms"`
+	// Histogram 成功检查的延迟分布，用于计算较长时间范围内的分位数
+	Histogram *LatencyHistogram `json:"histogram,omitempty"`
+}
+
+// add 将一条检查记录计入汇总
+func (r *HourlyRollup) add(result CheckResult) {
+	r.Counts[result.Status]++
+	if isUp(result.Status) && result.Error == "" {
+		r.LatencySumMS += result.LatencyMS
+		r.LatencyCount++
+		if result.LatencyMS > r.LatencyMaxMS {
+			r.LatencyMaxMS = result.LatencyMS
+		}
+		if r.Histogram != nil {
+			r.Histogram.observe(result.LatencyMS)
+		}
+	}
+}
+
+// HistoryStore 检查记录存储接口
+type HistoryStore interface {
+	// Append 追加一条检查记录
+	Append(result CheckResult) error
+	// Query 查询服务在since之后的检查记录，按时间倒序返回，limit<=0表示不限制
+	Query(service string, since time.Time, limit int) ([]CheckResult, error)
+	// QueryRollups 查询服务在since之后的小时汇总，按时间正序返回
+	QueryRollups(service string, since time.Time) ([]HourlyRollup, error)
+	// LastUpdated 返回最新一条检查记录的时间，没有记录时返回零值
+	LastUpdated() time.Time
+	// Histogram 返回服务自启动以来的延迟直方图，没有成功的检查时返回nil
+	Histogram(service string) *LatencyHistogram
+	// LatencyBuckets 返回延迟直方图的桶上界
+	LatencyBuckets() []float64
+	// Prune 清理过期数据
+	Prune(now time.Time) error
+	// Close 关闭存储
+	Close() error
+}
+
+// FileStore 内存+文件的检查记录存储，文件按行追加写入，清理时重写压缩
+type FileStore struct {
+	lock sync.RWMutex
+	cfg  StorageConfig
+	// results 按服务名称分组的检查记录，按时间正序
+	results map[string][]CheckResult
+	// rollups 按服务名称分组的小时汇总，按时间正序，最后一个可能是尚未结束的小时
+	rollups map[string][]*HourlyRollup
+	// histograms 按服务名称分组的延迟直方图，仅统计启动后的检查，供Prometheus抓取
+	histograms map[string]*LatencyHistogram
+	// file 追加写入的记录文件，为nil时仅保存在内存中
+	file *os.File
+	// rollupFile 追加写入的小时汇总文件，每个小时结束时写入一次
+	rollupFile *os.File
+}
+
+// NewFileStore 创建检查记录存储，path非空时加载已有记录并持续追加写入
+func NewFileStore(cfg StorageConfig) (*FileStore, error) {
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = defaultMaxResults
+	}
+	if cfg.RawRetention <= 0 {
+		cfg.RawRetention = defaultRawRetention
+	}
+	if cfg.RollupRetention <= 0 {
+		cfg.RollupRetention = defaultRollupRetention
+	}
+	if cfg.PruneInterval <= 0 {
+		cfg.PruneInterval = defaultPruneInterval
+	}
+	if len(cfg.LatencyBuckets) == 0 {
+		cfg.LatencyBuckets = defaultLatencyBuckets
+	}
+	fs := &FileStore{
+		cfg:        cfg,
+		results:    make(map[string][]CheckResult),
+		rollups:    make(map[string][]*HourlyRollup),
+		histograms: make(map[string]*LatencyHistogram),
+	}
+	if cfg.Path == "" {
+		return fs, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("创建存储目录失败: %v", err)
+	}
+	if err := fs.loadRollups(); err != nil {
+		return nil, err
+	}
+	if err := fs.loadResults(); err != nil {
+		return nil, err
+	}
+	var err error
+	if fs.file, err = openAppend(cfg.Path); err != nil {
+		return nil, err
+	}
+	if fs.rollupFile, err = openAppend(fs.rollupPath()); err != nil {
+		fs.file.Close()
+		return nil, err
+	}
+	return fs, nil
+}
+
+// rollupPath 返回小时汇总文件路径
+func (fs *FileStore) rollupPath() string {
+	return strings.TrimSuffix(fs.cfg.Path, filepath.Ext(fs.cfg.Path)) + ".rollups.jsonl"
+}
+
+// openAppend 以追加方式打开文件
+func openAppend(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开存储文件失败: %v", err)
+	}
+	return file, nil
+}
+
+// readLines 逐行读取JSON Lines文件，文件不存在时不报错，损坏的行会被跳过
+func readLines(path string, fn func(line []byte) error) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取存储文件失败: %v", err)
+	}
+	defer file.Close()
+	return scanLines(file, fn)
+}
+
+// scanLines 逐行读取r，fn返回错误的行被跳过
+func scanLines(r io.Reader, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := fn(scanner.Bytes()); err != nil {
+			continue
+		}
+	}
+	return scanner.Err()
+}
+
+// loadRollups 加载已结束小时的汇总，同一小时出现多次时以最后一次为准
+func (fs *FileStore) loadRollups() error {
+	byKey := make(map[string]*HourlyRollup)
+	err := readLines(fs.rollupPath(), func(line []byte) error {
+		rollup := new(HourlyRollup)
+		if err := json.Unmarshal(line, rollup); err != nil {
+			return err
+		}
+		if rollup.Counts == nil {
+			rollup.Counts = make(map[ServiceStatus]int)
+		}
+		byKey[rollup.Service+"\x00"+rollup.Hour.Format(time.RFC3339)] = rollup
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, rollup := range byKey {
+		fs.rollups[rollup.Service] = append(fs.rollups[rollup.Service], rollup)
+	}
+	for _, list := range fs.rollups {
+		sort.Slice(list, func(i, j int) bool { return list[i].Hour.Before(list[j].Hour) })
+	}
+	return nil
+}
+
+// loadResults 加载原始检查记录，并补齐尚未写入汇总文件的小时
+func (fs *FileStore) loadResults() error {
+	// 已持久化的最后一个小时，之后的记录需要重新汇总
+	persisted := make(map[string]time.Time)
+	for service, list := range fs.rollups {
+		persisted[service] = list[len(list)-1].Hour
+	}
+	return readLines(fs.cfg.Path, func(line []byte) error {
+		var result CheckResult
+		if err := json.Unmarshal(line, &result); err != nil {
+			return err
+		}
+		fs.addResult(result)
+		if result.Time.Truncate(time.Hour).After(persisted[result.Service]) {
+			fs.addRollup(result)
+		}
+		return nil
+	})
+}
+
+// addResult 将记录加入内存，超出容量时丢弃最旧的记录
+func (fs *FileStore) addResult(result CheckResult) {
+	list := append(fs.results[result.Service], result)
+	if len(list) > fs.cfg.MaxResults {
+		list = append(list[:0:0], list[len(list)-fs.cfg.MaxResults:]...)
+	}
+	fs.results[result.Service] = list
+}
+
+// addRollup 将记录计入对应小时的汇总，返回因进入新的小时而结束的汇总
+func (fs *FileStore) addRollup(result CheckResult) *HourlyRollup {
+	hour := result.Time.Truncate(time.Hour)
+	list := fs.rollups[result.Service]
+	var closed *HourlyRollup
+	if n := len(list); n > 0 {
+		last := list[n-1]
+		if last.Hour.Equal(hour) {
+			last.add(result)
+			return nil
+		}
+		if last.Hour.After(hour) {
+			// 时钟回拨时计入最后一个小时
+			last.add(result)
+			return nil
+		}
+		closed = last
+	}
+	rollup := &HourlyRollup{
+		Service:   result.Service,
+		Hour:      hour,
+		Counts:    make(map[ServiceStatus]int),
+		Histogram: newLatencyHistogram(fs.cfg.LatencyBuckets),
+	}
+	rollup.add(result)
+	fs.rollups[result.Service] = append(list, rollup)
+	return closed
+}
+
+// writeLine 向文件追加一行JSON
+func writeLine(file *os.File, v interface{}) error {
+	if file == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// Append 实现HistoryStore接口
+func (fs *FileStore) Append(result CheckResult) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	fs.addResult(result)
+	if isUp(result.Status) && result.Error == "" {
+		h := fs.histograms[result.Service]
+		if h == nil {
+			h = newLatencyHistogram(fs.cfg.LatencyBuckets)
+			fs.histograms[result.Service] = h
+		}
+		h.observe(result.LatencyMS)
+	}
+	if closed := fs.addRollup(result); closed != nil {
+		if err := writeLine(fs.rollupFile, closed); err != nil {
+			return err
+		}
+	}
+	return writeLine(fs.file, result)
+}
+
+// Query 实现HistoryStore接口，优先从内存中查询；内存缓存已满且不足以覆盖查询范围时从文件中读取
+func (fs *FileStore) Query(service string, since time.Time, limit int) ([]CheckResult, error) {
+	fs.lock.RLock()
+	list := fs.results[service]
+	out := make([]CheckResult, 0)
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].Time.Before(since) {
+			fs.lock.RUnlock()
+			return out, nil
+		}
+		out = append(out, list[i])
+		if limit > 0 && len(out) >= limit {
+			fs.lock.RUnlock()
+			return out, nil
+		}
+	}
+	if fs.file == nil || len(list) < fs.cfg.MaxResults {
+		fs.lock.RUnlock()
+		return out, nil
+	}
+	// 在锁内打开文件并记下当前长度，之后不持有锁读取，避免扫描文件时阻塞写入；
+	// 压缩时文件被替换不影响已打开的句柄
+	file, err := os.Open(fs.cfg.Path)
+	var size int64
+	if err == nil {
+		var info os.FileInfo
+		if info, err = file.Stat(); err == nil {
+			size = info.Size()
+		} else {
+			file.Close()
+		}
+	}
+	fs.lock.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("读取存储文件失败: %v", err)
+	}
+	defer file.Close()
+	return queryFile(io.NewSectionReader(file, 0, size), service, since, limit)
+}
+
+// queryFile 从记录文件中查询服务在since之后的检查记录，按时间倒序返回；
+// limit大于0时只保留最近的limit条，内存占用不随文件大小增长
+func queryFile(r io.Reader, service string, since time.Time, limit int) ([]CheckResult, error) {
+	var matched []CheckResult
+	newestFirst := func() {
+		sort.SliceStable(matched, func(i, j int) bool { return matched[i].Time.After(matched[j].Time) })
+		if limit > 0 && len(matched) > limit {
+			matched = matched[:limit]
+		}
+	}
+	err := scanLines(r, func(line []byte) error {
+		var result CheckResult
+		if err := json.Unmarshal(line, &result); err != nil {
+			return err
+		}
+		if result.Service == service && !result.Time.Before(since) {
+			matched = append(matched, result)
+			if limit > 0 && len(matched) >= 2*limit {
+				newestFirst()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	newestFirst()
+	return matched, nil
+}
+
+// QueryRollups 实现HistoryStore接口
+func (fs *FileStore) QueryRollups(service string, since time.Time) ([]HourlyRollup, error) {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	list := fs.rollups[service]
+	idx := sort.Search(len(list), func(i int) bool {
+		return !list[i].Hour.Before(since.Truncate(time.Hour))
+	})
+	out := make([]HourlyRollup, 0, len(list)-idx)
+	for _, rollup := range list[idx:] {
+		copied := *rollup
+		copied.Counts = make(map[ServiceStatus]int, len(rollup.Counts))
+		for status, n := range rollup.Counts {
+			copied.Counts[status] = n
+		}
+		copied.Histogram = rollup.Histogram.clone()
+		out = append(out, copied)
+	}
+	return out, nil
+}
+
+// LastUpdated 实现HistoryStore接口
+func (fs *FileStore) LastUpdated() time.Time {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	var latest time.Time
+	for _, list := range fs.results {
+		if len(list) > 0 && list[len(list)-1].Time.After(latest) {
+			latest = list[len(list)-1].Time
+		}
+	}
+	return latest
+}
+
+// Histogram 实现HistoryStore接口
+func (fs *FileStore) Histogram(service string) *LatencyHistogram {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	return fs.histograms[service].clone()
+}
+
+// LatencyBuckets 实现HistoryStore接口
+func (fs *FileStore) LatencyBuckets() []float64 {
+	return fs.cfg.LatencyBuckets
+}
+
+// Prune 实现HistoryStore接口，清理过期的原始记录与小时汇总并压缩文件
+func (fs *FileStore) Prune(now time.Time) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	rawCutoff := now.Add(-fs.cfg.RawRetention)
+	for service, list := range fs.results {
+		idx := sort.Search(len(list), func(i int) bool { return !list[i].Time.Before(rawCutoff) })
+		if idx == len(list) {
+			delete(fs.results, service)
+			continue
+		}
+		fs.results[service] = append(list[:0:0], list[idx:]...)
+	}
+
+	rollupCutoff := now.Add(-fs.cfg.RollupRetention)
+	for service, list := range fs.rollups {
+		idx := sort.Search(len(list), func(i int) bool { return !list[i].Hour.Before(rollupCutoff) })
+		if idx == len(list) {
+			delete(fs.rollups, service)
+			continue
+		}
+		fs.rollups[service] = append(list[:0:0], list[idx:]...)
+	}
+
+	if fs.file == nil {
+		return nil
+	}
+	return fs.compact(rawCutoff)
+}
+
+// compact 按保留时间逐行过滤记录文件，并用内存中的小时汇总重写汇总文件，需持有锁调用；
+// 内存中的原始记录受max_results限制，不能用于重写记录文件
+func (fs *FileStore) compact(rawCutoff time.Time) error {
+	var err error
+	fs.file, err = filterFile(fs.file, fs.cfg.Path, func(line []byte) bool {
+		var result CheckResult
+		return json.Unmarshal(line, &result) == nil && !result.Time.Before(rawCutoff)
+	})
+	if err != nil {
+		return err
+	}
+	// 尚未结束的小时不写入汇总文件，重启时会由原始记录重新汇总
+	rollups := make([]interface{}, 0)
+	for _, list := range fs.rollups {
+		for _, rollup := range list[:len(list)-1] {
+			rollups = append(rollups, rollup)
+		}
+	}
+
+	fs.rollupFile, err = rewriteFile(fs.rollupFile, fs.rollupPath(), rollups)
+	return err
+}
+
+// filterFile 逐行读取path，将keep返回true的行写入临时文件后替换path，返回重新打开的追加句柄
+func filterFile(old *os.File, path string, keep func(line []byte) bool) (*os.File, error) {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return old, fmt.Errorf("压缩存储文件失败: %v", err)
+	}
+	w := bufio.NewWriter(file)
+	err = readLines(path, func(line []byte) error {
+		if !keep(line) {
+			return nil
+		}
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+		return w.WriteByte('\n')
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	file.Close()
+	if err != nil {
+		os.Remove(tmp)
+		return old, fmt.Errorf("压缩存储文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return old, fmt.Errorf("压缩存储文件失败: %v", err)
+	}
+	old.Close()
+	return openAppend(path)
+}
+
+// rewriteFile 将records写入临时文件后替换path，返回重新打开的追加句柄
+func rewriteFile(old *os.File, path string, records []interface{}) (*os.File, error) {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return old, fmt.Errorf("压缩存储文件失败: %v", err)
+	}
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			file.Close()
+			os.Remove(tmp)
+			return old, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return old, err
+	}
+	file.Close()
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return old, fmt.Errorf("压缩存储文件失败: %v", err)
+	}
+	old.Close()
+	return openAppend(path)
+}
+
+// StartPruning 按配置的间隔在后台定期清理过期数据
+func (fs *FileStore) StartPruning() {
+	go func() {
+		ticker := time.NewTicker(fs.cfg.PruneInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if err := fs.Prune(now); err != nil {
+				slog.Error("清理历史记录失败", "error", err)
+			}
+		}
+	}()
+}
+
+// Close 实现HistoryStore接口，尚未结束的小时汇总会在下次启动时由原始记录重新计算
+func (fs *FileStore) Close() error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	if fs.file == nil {
+		return nil
+	}
+	err := fs.file.Close()
+	if cerr := fs.rollupFile.Close(); err == nil {
+		err = cerr
+	}
+	fs.file, fs.rollupFile = nil, nil
+	return err
+}
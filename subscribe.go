@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultConfirmTTL 未确认订阅的默认有效期
+	defaultConfirmTTL = 24 * time.Hour
+	// maxPendingSubscribers 同时等待确认的最大订阅数，防止被用于批量发送邮件
+	maxPendingSubscribers = 1000
+	// subscriberMailQueue 待发送订阅邮件的队列长度，队列满时丢弃新邮件
+	subscriberMailQueue = 1000
+	// subscriberSweepInterval 清理过期未确认订阅的间隔
+	subscriberSweepInterval = time.Hour
+)
+
+// SubscriptionConfig 访客邮件订阅配置：访客提交邮箱后需通过邮件中的链接确认，
+// 确认后在服务发生故障与恢复时收到邮件，每封邮件附带退订链接
+type SubscriptionConfig struct {
+	// Channel 发送订阅邮件使用的email类型通知渠道，为空时不开放订阅
+	Channel string `yaml:"channel,omitempty"`
+	// BaseURL 邮件中链接使用的状态页地址，如https://status.example.com
+	BaseURL string `yaml:"base_url,omitempty"`
+	// ConfirmTTL 未确认订阅的有效期，默认24h
+	ConfirmTTL time.Duration `yaml:"confirm_ttl,omitempty"`
+}
+
+// mailer 校验订阅配置并返回发送邮件的渠道，未开放订阅时返回nil
+func (sc *SubscriptionConfig) mailer(nc NotificationConfig) (*EmailNotifier, error) {
+	if sc.Channel == "" {
+		return nil, nil
+	}
+	if u, err := url.Parse(sc.BaseURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("订阅配置的base_url无效: '%s'", sc.BaseURL)
+	}
+	email, err := nc.emailChannel(sc.Channel)
+	if err != nil {
+		return nil, fmt.Errorf("订阅使用的%v", err)
+	}
+	return email, nil
+}
+
+// Subscriber 一个邮件订阅
+type Subscriber struct {
+	// Email 邮箱地址
+	Email string `json:"email"`
+	// Services 订阅的服务，为空时订阅全部公开服务
+	Services []string `json:"services,omitempty"`
+	// Locale 邮件使用的语言，取自订阅时的请求语言
+	Locale string `json:"locale"`
+	// Token 确认与退订链接中的令牌
+	Token string `json:"token"`
+	// Confirmed 是否已确认
+	Confirmed bool `json:"confirmed"`
+	// Created 提交订阅的时间
+	Created time.Time `json:"created"`
+}
+
+// wants 判断订阅是否包含指定服务
+func (s *Subscriber) wants(service string) bool {
+	if len(s.Services) == 0 {
+		return true
+	}
+	for _, name := range s.Services {
+		if name == service {
+			return true
+		}
+	}
+	return false
+}
+
+// subscriberMail 一封待发送的订阅邮件
+type subscriberMail struct {
+	to      string
+	subject string
+	body    string
+}
+
+// SubscriberList 访客邮件订阅：保存订阅列表并在后台发送确认与故障邮件
+type SubscriberList struct {
+	lock sync.Mutex
+	// cfg 订阅配置
+	cfg SubscriptionConfig
+	// mailer 发送邮件的渠道，为nil时未开放订阅
+	mailer *EmailNotifier
+	// subscribers 按令牌索引的订阅
+	subscribers map[string]*Subscriber
+	// path 订阅文件路径，为空时仅保存在内存中
+	path string
+	// file 订阅文件，每次变更时整体重写
+	file *os.File
+	// queue 待发送的邮件
+	queue chan subscriberMail
+}
+
+// subscribers 全局订阅列表
+var subscribers = &SubscriberList{
+	subscribers: make(map[string]*Subscriber),
+	queue:       make(chan subscriberMail, subscriberMailQueue),
+}
+
+// Open 加载检查记录存储目录下保存的订阅，存储路径为空时仅保存在内存中
+func (sl *SubscriberList) Open(storage StorageConfig) error {
+	if storage.Path == "" {
+		return nil
+	}
+	sl.lock.Lock()
+	defer sl.lock.Unlock()
+	sl.path = strings.TrimSuffix(storage.Path, filepath.Ext(storage.Path)) + ".subscribers.jsonl"
+	err := readLines(sl.path, func(line []byte) error {
+		var s Subscriber
+		if err := json.Unmarshal(line, &s); err != nil {
+			return err
+		}
+		sl.subscribers[s.Token] = &s
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sl.file, err = openAppend(sl.path)
+	return err
+}
+
+// Update 根据配置更新订阅设置，已有的订阅保留
+func (sl *SubscriberList) Update(cfg *Config) error {
+	apply, err := sl.prepare(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// prepare 校验订阅设置，返回更新设置的函数
+func (sl *SubscriberList) prepare(cfg *Config) (func(), error) {
+	mailer, err := cfg.Subscriptions.mailer(cfg.Notifications)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		sl.lock.Lock()
+		defer sl.lock.Unlock()
+		sl.cfg = cfg.Subscriptions
+		if sl.cfg.ConfirmTTL <= 0 {
+			sl.cfg.ConfirmTTL = defaultConfirmTTL
+		}
+		sl.mailer = mailer
+	}, nil
+}
+
+// Enabled 判断是否开放订阅
+func (sl *SubscriberList) Enabled() bool {
+	sl.lock.Lock()
+	defer sl.lock.Unlock()
+	return sl.mailer != nil
+}
+
+// save 重写订阅文件，需持有锁调用
+func (sl *SubscriberList) save() {
+	if sl.path == "" {
+		return
+	}
+	records := make([]interface{}, 0, len(sl.subscribers))
+	for _, s := range sl.subscribers {
+		records = append(records, s)
+	}
+	file, err := rewriteFile(sl.file, sl.path, records)
+	if err != nil {
+		slog.Error("保存订阅失败", "path", sl.path, "error", err)
+	}
+	sl.file = file
+}
+
+// link 返回状态页上的链接，需持有锁调用
+func (sl *SubscriberList) link(path, token string) string {
+	link := strings.TrimRight(sl.cfg.BaseURL, "/") + path
+	if token != "" {
+		link += "?token=" + url.QueryEscape(token)
+	}
+	return link
+}
+
+// enqueue 将邮件加入发送队列，队列满时丢弃
+func (sl *SubscriberList) enqueue(m subscriberMail) {
+	select {
+	case sl.queue <- m:
+	default:
+		slog.Warn("订阅邮件队列已满，邮件已丢弃", "to", m.to, "subject", m.subject)
+	}
+}
+
+// Subscribe 提交订阅并发送确认邮件；邮箱已确认订阅时不做任何改动，
+// 尚未确认时更新订阅的服务并重新发送确认邮件
+func (sl *SubscriberList) Subscribe(email string, services []string, locale string, now time.Time) error {
+	sl.lock.Lock()
+	defer sl.lock.Unlock()
+	if sl.mailer == nil {
+		return fmt.Errorf("未开放订阅")
+	}
+
+	var found *Subscriber
+	pending := 0
+	for _, s := range sl.subscribers {
+		if strings.EqualFold(s.Email, email) {
+			found = s
+		}
+		if !s.Confirmed {
+			pending++
+		}
+	}
+	switch {
+	case found != nil && found.Confirmed:
+		return nil
+	case found != nil:
+		found.Services, found.Locale, found.Created = services, locale, now
+	default:
+		if pending >= maxPendingSubscribers {
+			return fmt.Errorf("等待确认的订阅过多")
+		}
+		token, err := newSubscriberToken()
+		if err != nil {
+			return err
+		}
+		found = &Subscriber{Email: email, Services: services, Locale: locale, Token: token, Created: now}
+		sl.subscribers[token] = found
+	}
+	sl.save()
+
+	sl.enqueue(subscriberMail{
+		to:      found.Email,
+		subject: translate(locale, "subscribe.confirm_subject"),
+		body: translate(locale, "subscribe.confirm_body",
+			sl.link("/subscribe/confirm", found.Token), formatUptime(locale, sl.cfg.ConfirmTTL.Seconds())),
+	})
+	slog.Info("收到新的订阅", "email", found.Email, "services", services)
+	return nil
+}
+
+// Confirm 确认订阅，令牌不存在或已过期时返回false
+func (sl *SubscriberList) Confirm(token string, now time.Time) bool {
+	sl.lock.Lock()
+	defer sl.lock.Unlock()
+	s, ok := sl.subscribers[token]
+	if !ok || (!s.Confirmed && now.Sub(s.Created) > sl.cfg.ConfirmTTL) {
+		return false
+	}
+	if !s.Confirmed {
+		s.Confirmed = true
+		sl.save()
+		slog.Info("订阅已确认", "email", s.Email)
+	}
+	return true
+}
+
+// Unsubscribe 删除订阅，令牌不存在时返回false
+func (sl *SubscriberList) Unsubscribe(token string) bool {
+	sl.lock.Lock()
+	defer sl.lock.Unlock()
+	s, ok := sl.subscribers[token]
+	if !ok {
+		return false
+	}
+	delete(sl.subscribers, token)
+	sl.save()
+	slog.Info("订阅已退订", "email", s.Email)
+	return true
+}
+
+// sweep 删除过期的未确认订阅
+func (sl *SubscriberList) sweep(now time.Time) {
+	sl.lock.Lock()
+	defer sl.lock.Unlock()
+	removed := 0
+	for token, s := range sl.subscribers {
+		if !s.Confirmed && now.Sub(s.Created) > sl.cfg.ConfirmTTL {
+			delete(sl.subscribers, token)
+			removed++
+		}
+	}
+	if removed > 0 {
+		sl.save()
+	}
+}
+
+// OnTransition 状态变化监听器：公开服务进入离线或从离线恢复时向订阅者发送邮件
+func (sl *SubscriberList) OnTransition(t Transition) {
+	if t.Initial || t.Kind != "status_change" {
+		return
+	}
+	var kind string
+	switch {
+	case t.To == StatusOffline && t.From != StatusOffline:
+		kind = "incident"
+	case t.From == StatusOffline && isUp(t.To):
+		kind = "resolved"
+	default:
+		return
+	}
+	// 隐藏与停用的服务不通知访客
+	if service := serviceManager.GetService(t.Service); service == nil || !visible(service, false) {
+		return
+	}
+
+	sl.lock.Lock()
+	defer sl.lock.Unlock()
+	if sl.mailer == nil {
+		return
+	}
+	for _, s := range sl.subscribers {
+		if !s.Confirmed || !s.wants(t.Service) {
+			continue
+		}
+		sl.enqueue(subscriberMail{
+			to:      s.Email,
+			subject: translate(s.Locale, "subscribe."+kind+"_subject", t.Service),
+			body: translate(s.Locale, "subscribe."+kind+"_body", t.Service,
+				formatTime(s.Locale, t.Time, "datetime"), sl.link("/", ""), sl.link("/subscribe/unsubscribe", s.Token)),
+		})
+	}
+}
+
+// Start 在后台逐封发送订阅邮件，并定期清理过期的未确认订阅
+func (sl *SubscriberList) Start() {
+	go func() {
+		ticker := time.NewTicker(subscriberSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case m := <-sl.queue:
+				sl.send(m)
+			case now := <-ticker.C:
+				sl.sweep(now)
+			}
+		}
+	}()
+}
+
+// send 发送一封订阅邮件
+func (sl *SubscriberList) send(m subscriberMail) {
+	sl.lock.Lock()
+	mailer := sl.mailer
+	sl.lock.Unlock()
+	if mailer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := mailer.send(ctx, []string{m.to}, m.subject, m.body); err != nil {
+		slog.Error("发送订阅邮件失败", "to", m.to, "subject", m.subject, "error", err)
+		return
+	}
+	slog.Debug("订阅邮件已发送", "to", m.to, "subject", m.subject)
+}
+
+// newSubscriberToken 生成随机的订阅令牌
+func newSubscriberToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// subscribeRequest 提交订阅的请求
+type subscribeRequest struct {
+	// Email 邮箱地址
+	Email string `json:"email"`
+	// Services 订阅的服务，为空时订阅全部公开服务
+	Services []string `json:"services"`
+}
+
+// apiSubscribeHandler 提交邮件订阅，无论邮箱是否已订阅都返回相同的结果
+func apiSubscribeHandler(c *gin.Context) {
+	if !subscribers.Enabled() {
+		apiError(c, http.StatusNotFound, "error.no_subscriptions")
+		return
+	}
+	var req subscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiError(c, http.StatusBadRequest, "error.invalid_request", err.Error())
+		return
+	}
+	addr, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+	if err != nil || addr.Name != "" {
+		apiError(c, http.StatusBadRequest, "error.invalid_email")
+		return
+	}
+	for _, name := range req.Services {
+		if publicService(c, name) == nil {
+			apiError(c, http.StatusBadRequest, "error.service_not_found")
+			return
+		}
+	}
+	if err := subscribers.Subscribe(addr.Address, req.Services, requestLocale(c), time.Now()); err != nil {
+		slog.Warn("提交订阅失败", "email", addr.Address, "error", err)
+		apiError(c, http.StatusServiceUnavailable, "error.rate_limited")
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": tr(c, "subscribe.pending")})
+}
+
+// subscribePageData 订阅确认与退订页面的数据
+type subscribePageData struct {
+	// Title 页面标题
+	Title string
+	// Theme 页面主题，已补充默认值
+	Theme ThemeConfig
+	// Locale 页面语言
+	Locale string
+	// Message 显示的消息
+	Message string
+	// Action 非空时显示提交到该地址的确认按钮
+	Action string
+}
+
+// renderSubscribePage 渲染订阅确认与退订页面
+func renderSubscribePage(c *gin.Context, code int, messageKey, action string) {
+	theme := currentTheme()
+	c.HTML(code, "subscribe.html", subscribePageData{
+		Title:   theme.Title,
+		Theme:   theme,
+		Locale:  requestLocale(c),
+		Message: tr(c, messageKey),
+		Action:  action,
+	})
+}
+
+// subscribeConfirmHandler 邮件中的确认订阅链接
+func subscribeConfirmHandler(c *gin.Context) {
+	if !subscribers.Confirm(c.Query("token"), time.Now()) {
+		renderSubscribePage(c, http.StatusNotFound, "subscribe.invalid_link", "")
+		return
+	}
+	renderSubscribePage(c, http.StatusOK, "subscribe.confirmed", "")
+}
+
+// unsubscribePageHandler 邮件中的退订链接，需在页面上再次确认，避免被邮件客户端的链接预取误触发
+func unsubscribePageHandler(c *gin.Context) {
+	renderSubscribePage(c, http.StatusOK, "subscribe.unsubscribe_prompt", c.Request.URL.RequestURI())
+}
+
+// unsubscribeHandler 退订页面提交的退订请求
+func unsubscribeHandler(c *gin.Context) {
+	if !subscribers.Unsubscribe(c.Query("token")) {
+		renderSubscribePage(c, http.StatusNotFound, "subscribe.invalid_link", "")
+		return
+	}
+	renderSubscribePage(c, http.StatusOK, "subscribe.unsubscribed", "")
+}
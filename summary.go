@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMajorOutageThreshold 默认的严重故障阈值
+const defaultMajorOutageThreshold = 0.5
+
+// 整体状态
+const (
+	// overallOperational 所有服务正常运行（维护中的服务不计为异常）
+	overallOperational = "operational"
+	// overallPartialOutage 部分服务异常
+	overallPartialOutage = "partial_outage"
+	// overallMajorOutage 严重故障，加权异常比例达到阈值
+	overallMajorOutage = "major_outage"
+)
+
+// statusImpact 各状态计入整体状态的异常程度，离线为1，降级与受影响为0.5
+var statusImpact = map[ServiceStatus]float64{
+	StatusOnline:      0,
+	StatusMaintenance: 0,
+	StatusDegraded:    0.5,
+	StatusImpacted:    0.5,
+	StatusOffline:     1,
+}
+
+// SummaryConfig 整体状态的计算配置
+type SummaryConfig struct {
+	// MajorThreshold 加权异常比例达到该值时整体状态为严重故障，取值(0, 1]，默认0.5
+	MajorThreshold float64 `yaml:"major_threshold,omitempty"`
+}
+
+// validate 校验整体状态配置
+func (sc *SummaryConfig) validate() error {
+	if sc.MajorThreshold < 0 || sc.MajorThreshold > 1 {
+		return fmt.Errorf("summary.major_threshold需在0到1之间")
+	}
+	return nil
+}
+
+// majorThreshold 返回严重故障阈值
+func (sc *SummaryConfig) majorThreshold() float64 {
+	if sc.MajorThreshold > 0 {
+		return sc.MajorThreshold
+	}
+	return defaultMajorOutageThreshold
+}
+
+// StatusSummary 所有服务汇总后的整体状态
+type StatusSummary struct {
+	// State 整体状态：operational、partial_outage或major_outage
+	State string `json:"state"`
+	// Impact 按服务权重计算的异常比例，0表示全部正常，1表示全部离线
+	Impact float64 `json:"impact"`
+	// Total 服务总数
+	Total int `json:"total"`
+	// Outages 异常（离线、降级或受影响）的服务数
+	Outages int `json:"outages"`
+	// Counts 各状态的服务数
+	Counts map[string]int `json:"counts"`
+}
+
+// summarize 按服务权重汇总整体状态：任一服务异常时为部分异常，加权异常比例达到阈值时为严重故障
+func summarize(services []*Service, cfg SummaryConfig) StatusSummary {
+	summary := StatusSummary{State: overallOperational, Total: len(services), Counts: make(map[string]int)}
+	var weighted, total float64
+	for _, service := range services {
+		summary.Counts[service.Status.String()]++
+		weight := service.weight()
+		weighted += weight * statusImpact[service.Status]
+		total += weight
+		if statusImpact[service.Status] > 0 {
+			summary.State = overallPartialOutage
+			summary.Outages++
+		}
+	}
+	if total > 0 {
+		summary.Impact = weighted / total
+	}
+	if summary.State != overallOperational && summary.Impact >= cfg.majorThreshold() {
+		summary.State = overallMajorOutage
+	}
+	return summary
+}
+
+// weight 返回服务计入整体状态的权重，未配置时为1
+func (s *Service) weight() float64 {
+	if s.Weight > 0 {
+		return s.Weight
+	}
+	return 1
+}
+
+// apiSummaryHandler 返回可见服务汇总后的整体状态与各状态的服务数，供外部看板轻量轮询
+func apiSummaryHandler(c *gin.Context) {
+	refreshOnDemand()
+	c.Header("Vary", "Authorization")
+	services := visibleServices(c)
+	changed, version := statusVersion(services)
+	if notModified(c, changed, version) {
+		return
+	}
+	summary := summarize(services, configReloader.Current().Summary)
+	c.JSON(http.StatusOK, gin.H{
+		"state":        summary.State,
+		"impact":       summary.Impact,
+		"total":        summary.Total,
+		"outages":      summary.Outages,
+		"counts":       summary.Counts,
+		"last_updated": lastUpdatedValue(),
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SystemMetrics 主机资源使用情况
+type SystemMetrics struct {
+	// Hostname 主机名
+	Hostname string `json:"hostname"`
+	// Load 1、5、15分钟平均负载
+	Load [3]float64 `json:"load"`
+	// Memory 内存使用情况
+	Memory MemoryMetrics `json:"memory"`
+	// Disks 各挂载点的磁盘使用情况
+	Disks []DiskMetrics `json:"disks"`
+	// Network 网络接口状态
+	Network []NetworkMetrics `json:"network"`
+	// UptimeSeconds 系统运行时间（秒）
+	UptimeSeconds float64 `json:"uptime_seconds"`
+	// CollectedAt 采集时间
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+// MemoryMetrics 内存使用情况
+type MemoryMetrics struct {
+	// TotalBytes 总内存
+	TotalBytes uint64 `json:"total_bytes"`
+	// AvailableBytes 可用内存
+	AvailableBytes uint64 `json:"available_bytes"`
+	// UsedPercent 内存使用率
+	UsedPercent float64 `json:"used_percent"`
+	// SwapTotalBytes 交换分区总量
+	SwapTotalBytes uint64 `json:"swap_total_bytes"`
+	// SwapFreeBytes 交换分区可用量
+	SwapFreeBytes uint64 `json:"swap_free_bytes"`
+}
+
+// DiskMetrics 单个挂载点的磁盘使用情况
+type DiskMetrics struct {
+	// Mount 挂载点
+	Mount string `json:"mount"`
+	// FSType 文件系统类型
+	FSType string `json:"fs_type"`
+	// TotalBytes 总容量
+	TotalBytes uint64 `json:"total_bytes"`
+	// FreeBytes 可用容量
+	FreeBytes uint64 `json:"free_bytes"`
+	// UsedPercent 使用率
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// NetworkMetrics 网络接口状态
+type NetworkMetrics struct {
+	// Name 接口名称
+	Name string `json:"name"`
+	// Up 接口是否启用
+	Up bool `json:"up"`
+	// Addrs 接口地址
+	Addrs []string `json:"addrs"`
+}
+
+// collectNetworkMetrics 采集非回环网络接口的状态
+func collectNetworkMetrics() []NetworkMetrics {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	out := make([]NetworkMetrics, 0, len(ifaces))
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		nm := NetworkMetrics{
+			Name:  iface.Name,
+			Up:    iface.Flags&net.FlagUp != 0,
+			Addrs: make([]string, 0),
+		}
+		if addrs, err := iface.Addrs(); err == nil {
+			for _, addr := range addrs {
+				nm.Addrs = append(nm.Addrs, addr.String())
+			}
+		}
+		out = append(out, nm)
+	}
+	return out
+}
+
+// apiSystemHandler 返回主机资源使用情况
+func apiSystemHandler(c *gin.Context) {
+	metrics, err := CollectSystemMetrics()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, metrics)
+}
@@ -0,0 +1,145 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// virtualFSTypes 不统计磁盘使用情况的虚拟文件系统
+var virtualFSTypes = map[string]bool{
+	"proc": true, "sysfs": true, "devtmpfs": true, "devpts": true, "tmpfs": true,
+	"cgroup": true, "cgroup2": true, "securityfs": true, "pstore": true, "debugfs": true,
+	"tracefs": true, "configfs": true, "fusectl": true, "mqueue": true, "hugetlbfs": true,
+	"bpf": true, "autofs": true, "binfmt_misc": true, "rpc_pipefs": true, "nsfs": true,
+	"overlay": true, "squashfs": true, "ramfs": true, "efivarfs": true,
+}
+
+// CollectSystemMetrics 从/proc采集主机资源使用情况
+func CollectSystemMetrics() (*SystemMetrics, error) {
+	metrics := &SystemMetrics{
+		Network:     collectNetworkMetrics(),
+		CollectedAt: time.Now(),
+	}
+	metrics.Hostname, _ = os.Hostname()
+
+	if err := readLoadAvg(&metrics.Load); err != nil {
+		return nil, err
+	}
+	if err := readMemInfo(&metrics.Memory); err != nil {
+		return nil, err
+	}
+	disks, err := readDisks()
+	if err != nil {
+		return nil, err
+	}
+	metrics.Disks = disks
+
+	if data, err := os.ReadFile("/proc/uptime"); err == nil {
+		if fields := strings.Fields(string(data)); len(fields) > 0 {
+			metrics.UptimeSeconds, _ = strconv.ParseFloat(fields[0], 64)
+		}
+	}
+	return metrics, nil
+}
+
+// readLoadAvg 读取平均负载
+func readLoadAvg(load *[3]float64) error {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return fmt.Errorf("读取平均负载失败: %v", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return fmt.Errorf("无法解析/proc/loadavg")
+	}
+	for i := 0; i < 3; i++ {
+		load[i], _ = strconv.ParseFloat(fields[i], 64)
+	}
+	return nil
+}
+
+// readMemInfo 读取内存使用情况
+func readMemInfo(mem *MemoryMetrics) error {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return fmt.Errorf("读取内存信息失败: %v", err)
+	}
+	defer file.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		// /proc/meminfo中的单位为kB
+		v, _ := strconv.ParseUint(fields[0], 10, 64)
+		values[key] = v * 1024
+	}
+
+	mem.TotalBytes = values["MemTotal"]
+	mem.AvailableBytes = values["MemAvailable"]
+	mem.SwapTotalBytes = values["SwapTotal"]
+	mem.SwapFreeBytes = values["SwapFree"]
+	if mem.TotalBytes > 0 {
+		mem.UsedPercent = float64(mem.TotalBytes-mem.AvailableBytes) / float64(mem.TotalBytes) * 100
+	}
+	return scanner.Err()
+}
+
+// readDisks 读取各物理文件系统挂载点的使用情况
+func readDisks() ([]DiskMetrics, error) {
+	file, err := os.Open("/proc/mounts")
+	if err != nil {
+		return nil, fmt.Errorf("读取挂载信息失败: %v", err)
+	}
+	defer file.Close()
+
+	disks := make([]DiskMetrics, 0)
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		device, mount, fsType := fields[0], fields[1], fields[2]
+		// 同一设备多次挂载（如bind mount）只统计一次
+		if virtualFSTypes[fsType] || seen[device] {
+			continue
+		}
+
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(mount, &st); err != nil || st.Blocks == 0 {
+			continue
+		}
+		seen[device] = true
+		total := st.Blocks * uint64(st.Bsize)
+		free := st.Bavail * uint64(st.Bsize)
+		used := total - st.Bfree*uint64(st.Bsize)
+		disk := DiskMetrics{
+			Mount:      mount,
+			FSType:     fsType,
+			TotalBytes: total,
+			FreeBytes:  free,
+		}
+		// 与df一致，使用率按 已用/(已用+普通用户可用) 计算
+		if used+free > 0 {
+			disk.UsedPercent = float64(used) / float64(used+free) * 100
+		}
+		disks = append(disks, disk)
+	}
+	return disks, scanner.Err()
+}
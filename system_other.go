@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "fmt"
+
+// CollectSystemMetrics 非Linux平台不支持采集主机资源信息
+func CollectSystemMetrics() (*SystemMetrics, error) {
+	return nil, fmt.Errorf("当前平台不支持采集主机资源信息")
+}
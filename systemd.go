@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+)
+
+// defaultSystemdTimeout systemctl命令默认的超时时间
+const defaultSystemdTimeout = 5 * time.Second
+
+// SystemdChecker systemd单元状态检查器
+type SystemdChecker struct {
+	// Unit 单元名称，如nginx.service
+	Unit string `yaml:"unit"`
+	// User 是否为用户级单元（systemctl --user）
+	User bool `yaml:"user"`
+	// Timeout 命令执行超时时间，默认5s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// systemctl 执行systemctl命令并返回输出
+func systemctl(ctx context.Context, user bool, timeout time.Duration, args ...string) ([]byte, error) {
+	if timeout <= 0 {
+		timeout = defaultSystemdTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if user {
+		args = append([]string{"--user"}, args...)
+	}
+	out, err := exec.CommandContext(ctx, "systemctl", args...).Output()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("执行systemctl超时")
+	}
+	return out, err
+}
+
+// unitStatus 根据单元的ActiveState与SubState计算服务状态
+func unitStatus(unit, active, sub string) (ServiceStatus, error) {
+	switch active {
+	case "active":
+		return StatusOnline, nil
+	case "activating", "reloading", "deactivating":
+		return StatusDegraded, fmt.Errorf("单元 '%s' 状态为 %s (%s)", unit, active, sub)
+	}
+	return StatusOffline, fmt.Errorf("单元 '%s' 未运行: %s (%s)", unit, active, sub)
+}
+
+// CheckStatus 实现StatusChecker接口，检查systemd单元状态
+func (s *SystemdChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	if s.Unit == "" {
+		return StatusOffline, fmt.Errorf("单元名称不能为空")
+	}
+	out, err := systemctl(ctx, s.User, s.Timeout, "show", "--property=LoadState,ActiveState,SubState", s.Unit)
+	if err != nil {
+		return StatusOffline, fmt.Errorf("查询单元 '%s' 失败: %v", s.Unit, err)
+	}
+	props := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			props[key] = value
+		}
+	}
+	if props["LoadState"] == "not-found" {
+		return StatusOffline, fmt.Errorf("单元 '%s' 不存在", s.Unit)
+	}
+	return unitStatus(s.Unit, props["ActiveState"], props["SubState"])
+}
+
+// SystemdSource 枚举匹配通配符的systemd单元并自动创建服务，服务使用SystemdChecker检查
+type SystemdSource struct {
+	// Pattern 单元名称通配符，如jjapp-*.service
+	Pattern string `yaml:"pattern"`
+	// User 是否枚举用户级单元
+	User bool `yaml:"user"`
+	// Timeout 命令执行超时时间，默认5s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Discover 实现DiscoverySource接口，服务名称为去掉.service后缀的单元名称
+func (ss *SystemdSource) Discover(ctx context.Context) ([]DiscoveredService, error) {
+	if ss.Pattern == "" {
+		return nil, fmt.Errorf("单元名称通配符不能为空")
+	}
+	if _, err := path.Match(ss.Pattern, ""); err != nil {
+		return nil, fmt.Errorf("无效的单元名称通配符 '%s'", ss.Pattern)
+	}
+	out, err := systemctl(ctx, ss.User, ss.Timeout, "list-units", "--all", "--plain", "--no-legend", "--no-pager", ss.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("枚举systemd单元失败: %v", err)
+	}
+
+	found := make([]DiscoveredService, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		// 输出格式：UNIT LOAD ACTIVE SUB DESCRIPTION
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[1] == "not-found" {
+			continue
+		}
+		unit := fields[0]
+		if ok, _ := path.Match(ss.Pattern, unit); !ok {
+			continue
+		}
+		status, checkErr := unitStatus(unit, fields[2], fields[3])
+		ds := DiscoveredService{
+			Name:        strings.TrimSuffix(unit, ".service"),
+			Description: strings.Join(fields[4:], " "),
+			Status:      status,
+			Checker:     &SystemdChecker{Unit: unit, User: ss.User, Timeout: ss.Timeout},
+		}
+		if checkErr != nil {
+			ds.Error = checkErr.Error()
+		}
+		found = append(found, ds)
+	}
+	return found, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+const (
+	// defaultAutoRefresh 页面自动刷新状态的默认间隔
+	defaultAutoRefresh = 30 * time.Second
+	// minAutoRefresh 页面自动刷新状态的最小间隔
+	minAutoRefresh = 5 * time.Second
+	// defaultCopyright 页脚默认的版权信息
+	defaultCopyright = "2025 JJApps."
+)
+
+// themeColor 匹配主题配置中的十六进制颜色，如#2923ff
+var themeColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// ThemeConfig 状态页的品牌与外观配置，未配置的项使用内置的默认值
+type ThemeConfig struct {
+	// Title 页面标题，默认JJApps Status，独立状态页未配置标题时同样使用
+	Title string `yaml:"title,omitempty"`
+	// Subtitle 标题下方的说明文字，默认为当前语言的"实时监控服务状态"
+	Subtitle string `yaml:"subtitle,omitempty"`
+	// LogoURL 显示在标题前的Logo地址
+	LogoURL string `yaml:"logo_url,omitempty"`
+	// Colors 配色，为空的颜色使用默认配色
+	Colors ThemeColors `yaml:"colors,omitempty"`
+	// FooterLinks 页脚链接，配置后替换默认的关于、源码与联系链接
+	FooterLinks []FooterLink `yaml:"footer_links,omitempty"`
+	// Copyright 页脚的版权信息，默认2025 JJApps.
+	Copyright string `yaml:"copyright,omitempty"`
+	// AutoRefresh 页面自动刷新状态的间隔，默认30s，最小5s
+	AutoRefresh time.Duration `yaml:"auto_refresh,omitempty"`
+	// PageSize 状态页每页显示的服务数，为0时不分页；配置后页面显示排序与翻页控件
+	PageSize int `yaml:"page_size,omitempty"`
+	// Sort 状态页默认的排序字段：order、name、status、uptime或latency
+	Sort string `yaml:"sort,omitempty"`
+	// StatusFirst 状态页是否默认将异常的服务排在前面
+	StatusFirst bool `yaml:"status_first,omitempty"`
+}
+
+// ThemeColors 状态页配色，均为十六进制颜色
+type ThemeColors struct {
+	// Primary 主色，用于顶部渐变的起始色与按钮
+	Primary string `yaml:"primary,omitempty"`
+	// Accent 强调色，用于顶部渐变的结束色、链接与正常状态
+	Accent string `yaml:"accent,omitempty"`
+	// Background 页面背景色
+	Background string `yaml:"background,omitempty"`
+}
+
+// FooterLink 页脚链接
+type FooterLink struct {
+	// Label 链接文字
+	Label string `yaml:"label"`
+	// URL 链接地址
+	URL string `yaml:"url"`
+}
+
+// validate 校验主题配置
+func (t *ThemeConfig) validate() error {
+	if t.LogoURL != "" {
+		if _, err := url.Parse(t.LogoURL); err != nil {
+			return fmt.Errorf("主题的logo_url无效: %v", err)
+		}
+	}
+	for name, color := range map[string]string{"primary": t.Colors.Primary, "accent": t.Colors.Accent, "background": t.Colors.Background} {
+		if color != "" && !themeColor.MatchString(color) {
+			return fmt.Errorf("主题颜色 %s 的值 '%s' 无效，需为十六进制颜色如#2923ff", name, color)
+		}
+	}
+	for i, link := range t.FooterLinks {
+		if link.Label == "" || link.URL == "" {
+			return fmt.Errorf("第 %d 个页脚链接需要配置label与url", i+1)
+		}
+		if _, err := url.Parse(link.URL); err != nil {
+			return fmt.Errorf("页脚链接 '%s' 的url无效: %v", link.Label, err)
+		}
+	}
+	if t.AutoRefresh != 0 && t.AutoRefresh < minAutoRefresh {
+		return fmt.Errorf("主题的auto_refresh不能小于%s", minAutoRefresh)
+	}
+	if t.PageSize < 0 || t.PageSize > maxServicesLimit {
+		return fmt.Errorf("主题的page_size需在0到%d之间", maxServicesLimit)
+	}
+	return validateServiceSort(t.Sort)
+}
+
+// withDefaults 返回补充默认值后的主题配置
+func (t ThemeConfig) withDefaults() ThemeConfig {
+	if t.Title == "" {
+		t.Title = defaultPageTitle
+	}
+	if t.Copyright == "" {
+		t.Copyright = defaultCopyright
+	}
+	if t.AutoRefresh == 0 {
+		t.AutoRefresh = defaultAutoRefresh
+	}
+	return t
+}
+
+// AutoRefreshMS 返回页面脚本使用的自动刷新间隔（毫秒）
+func (t ThemeConfig) AutoRefreshMS() int64 {
+	return t.AutoRefresh.Milliseconds()
+}
+
+// listDefaults 返回状态页默认的排序与分页参数
+func (t ThemeConfig) listDefaults() ServiceListOptions {
+	return ServiceListOptions{Sort: t.Sort, StatusFirst: t.StatusFirst, Limit: t.PageSize}
+}
+
+// currentTheme 返回当前生效的主题配置
+func currentTheme() ThemeConfig {
+	return configReloader.Current().Theme.withDefaults()
+}
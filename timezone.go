@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// displayLocation 页面与通知中显示时间使用的时区
+var displayLocation atomic.Pointer[time.Location]
+
+func init() {
+	displayLocation.Store(time.Local)
+}
+
+// validateTimezone 校验配置的时区，为空表示使用本地时区
+func validateTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("无效的时区 '%s': %v", name, err)
+	}
+	return nil
+}
+
+// prepareDisplayTimezone 加载时区，返回设置显示时区的函数，为空时使用本地时区
+func prepareDisplayTimezone(name string) (func(), error) {
+	loc := time.Local
+	if name != "" {
+		var err error
+		if loc, err = time.LoadLocation(name); err != nil {
+			return nil, fmt.Errorf("无效的时区 '%s': %v", name, err)
+		}
+	}
+	return func() { displayLocation.Store(loc) }, nil
+}
+
+// setDisplayTimezone 设置显示时区，为空时使用本地时区
+func setDisplayTimezone(name string) error {
+	apply, err := prepareDisplayTimezone(name)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// inDisplayZone 将时间转换到显示时区
+func inDisplayZone(t time.Time) time.Time {
+	return t.In(displayLocation.Load())
+}
+
+// formatTime 按语言的时间格式在显示时区下格式化时间，kind可为datetime、time或short
+func formatTime(locale string, t time.Time, kind string) string {
+	return inDisplayZone(t).Format(translate(locale, "time."+kind))
+}
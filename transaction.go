@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/expr-lang/expr/vm"
+	"github.com/itchyny/gojq"
+)
+
+// transactionVar 匹配步骤中引用提取值的占位符，如{{token}}
+var transactionVar = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// TransactionChecker 按顺序执行多个HTTP步骤，模拟登录、打开页面、校验内容等完整的用户流程，
+// 任一步骤失败时为离线。同一次检查的步骤共享Cookie，captures提取的值可在后续步骤的
+// url、headers、form与body中以{{name}}引用
+type TransactionChecker struct {
+	// Steps 依次执行的步骤
+	Steps []TransactionStep `yaml:"steps"`
+	// Timeout 单个步骤的超时时间
+	Timeout time.Duration `yaml:"timeout"`
+
+	checkTransport `yaml:",inline"`
+}
+
+// TransactionStep 事务检查中的一个HTTP请求
+type TransactionStep struct {
+	// Name 步骤名称，用于错误信息，默认为请求方法与URL
+	Name string `yaml:"name,omitempty"`
+	// Method 请求方法，默认GET，配置form或body时默认POST
+	Method string `yaml:"method,omitempty"`
+	// URL 请求地址
+	URL string `yaml:"url"`
+	// Headers 请求头
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Form 以application/x-www-form-urlencoded发送的表单，与body二选一
+	Form map[string]string `yaml:"form,omitempty"`
+	// Body 原样发送的请求体，需要时通过headers设置Content-Type
+	Body string `yaml:"body,omitempty"`
+	// Expr 检查表达式，同http检查，为空时状态码为2xx即通过
+	Expr string `yaml:"expr,omitempty"`
+	// Captures 从响应中提取供后续步骤使用的值
+	Captures []TransactionCapture `yaml:"captures,omitempty"`
+
+	// program 编译后的检查表达式
+	program *vm.Program
+}
+
+// TransactionCapture 从响应中提取值，json、header、cookie与regex四选一
+type TransactionCapture struct {
+	// Name 变量名称
+	Name string `yaml:"name"`
+	// JSON jq表达式，取第一个结果，如.data.token
+	JSON string `yaml:"json,omitempty"`
+	// Header 响应头名称
+	Header string `yaml:"header,omitempty"`
+	// Cookie Cookie名称
+	Cookie string `yaml:"cookie,omitempty"`
+	// Regex 在响应体中匹配的正则表达式，有分组时取第一个分组
+	Regex string `yaml:"regex,omitempty"`
+
+	// code 编译后的jq表达式
+	code *gojq.Code
+	// pattern 编译后的正则表达式
+	pattern *regexp.Regexp
+}
+
+// validate 校验步骤配置，编译检查表达式与提取规则
+func (t *TransactionChecker) validate() error {
+	if err := t.checkTransport.validate(); err != nil {
+		return err
+	}
+	if len(t.Steps) == 0 {
+		return fmt.Errorf("事务检查至少需要一个步骤")
+	}
+	defined := make(map[string]bool)
+	for i := range t.Steps {
+		step := &t.Steps[i]
+		if step.URL == "" {
+			return fmt.Errorf("事务检查的%s缺少url", step.label(i))
+		}
+		if len(step.Form) > 0 && step.Body != "" {
+			return fmt.Errorf("事务检查的%s不能同时配置form与body", step.label(i))
+		}
+		// 只能引用前面步骤提取的值
+		for _, text := range step.templates() {
+			for _, m := range transactionVar.FindAllStringSubmatch(text, -1) {
+				if !defined[m[1]] {
+					return fmt.Errorf("事务检查的%s引用了未定义的变量 '%s'", step.label(i), m[1])
+				}
+			}
+		}
+		if step.Expr != "" {
+			program, err := compileCheckExpression(step.Expr)
+			if err != nil {
+				return fmt.Errorf("事务检查的%s: %v", step.label(i), err)
+			}
+			step.program = program
+		}
+		for j := range step.Captures {
+			capture := &step.Captures[j]
+			if err := capture.compile(); err != nil {
+				return fmt.Errorf("事务检查的%s: %v", step.label(i), err)
+			}
+			defined[capture.Name] = true
+		}
+	}
+	return nil
+}
+
+// label 返回步骤在错误信息中的名称
+func (s *TransactionStep) label(i int) string {
+	if s.Name != "" {
+		return fmt.Sprintf("第%d步（%s）", i+1, s.Name)
+	}
+	return fmt.Sprintf("第%d步（%s %s）", i+1, s.method(), s.URL)
+}
+
+// method 返回步骤的请求方法
+func (s *TransactionStep) method() string {
+	switch {
+	case s.Method != "":
+		return strings.ToUpper(s.Method)
+	case len(s.Form) > 0 || s.Body != "":
+		return http.MethodPost
+	default:
+		return http.MethodGet
+	}
+}
+
+// templates 返回步骤中可以引用变量的全部文本
+func (s *TransactionStep) templates() []string {
+	texts := []string{s.URL, s.Body}
+	for _, v := range s.Headers {
+		texts = append(texts, v)
+	}
+	for _, v := range s.Form {
+		texts = append(texts, v)
+	}
+	return texts
+}
+
+// compile 校验并编译提取规则
+func (c *TransactionCapture) compile() error {
+	if !transactionVar.MatchString("{{" + c.Name + "}}") {
+		return fmt.Errorf("无效的变量名称 '%s'", c.Name)
+	}
+	sources := 0
+	for _, s := range []string{c.JSON, c.Header, c.Cookie, c.Regex} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("变量 '%s' 需要且只能配置json、header、cookie与regex中的一项", c.Name)
+	}
+	switch {
+	case c.JSON != "":
+		query, err := gojq.Parse(c.JSON)
+		if err != nil {
+			return fmt.Errorf("变量 '%s' 的jq表达式无效: %v", c.Name, err)
+		}
+		if c.code, err = gojq.Compile(query); err != nil {
+			return fmt.Errorf("变量 '%s' 的jq表达式无效: %v", c.Name, err)
+		}
+	case c.Regex != "":
+		pattern, err := regexp.Compile(c.Regex)
+		if err != nil {
+			return fmt.Errorf("变量 '%s' 的正则表达式无效: %v", c.Name, err)
+		}
+		c.pattern = pattern
+	}
+	return nil
+}
+
+// extract 从响应中提取变量的值
+func (c *TransactionCapture) extract(ctx context.Context, resp *http.Response, body []byte, jar http.CookieJar) (string, error) {
+	switch {
+	case c.JSON != "":
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return "", fmt.Errorf("解析JSON响应失败: %v", err)
+		}
+		v, ok := c.code.RunWithContext(ctx, doc).Next()
+		if !ok || v == nil {
+			return "", fmt.Errorf("表达式 '%s' 没有结果", c.JSON)
+		}
+		if err, isErr := v.(error); isErr {
+			return "", fmt.Errorf("执行表达式 '%s' 失败: %v", c.JSON, err)
+		}
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		return jsonString(v), nil
+	case c.Header != "":
+		if v := resp.Header.Get(c.Header); v != "" {
+			return v, nil
+		}
+		return "", fmt.Errorf("响应中没有 %s 头", c.Header)
+	case c.Cookie != "":
+		for _, cookie := range jar.Cookies(resp.Request.URL) {
+			if cookie.Name == c.Cookie {
+				return cookie.Value, nil
+			}
+		}
+		return "", fmt.Errorf("响应中没有名为 %s 的Cookie", c.Cookie)
+	default:
+		m := c.pattern.FindSubmatch(body)
+		if m == nil {
+			return "", fmt.Errorf("响应中没有匹配 %s 的内容", c.Regex)
+		}
+		return string(m[len(m)-1]), nil
+	}
+}
+
+// expandVars 将文本中的{{name}}替换为已提取的值
+func expandVars(text string, vars map[string]string) string {
+	return transactionVar.ReplaceAllStringFunc(text, func(m string) string {
+		return vars[transactionVar.FindStringSubmatch(m)[1]]
+	})
+}
+
+// CheckStatus 实现StatusChecker接口，依次执行全部步骤
+func (t *TransactionChecker) CheckStatus(ctx context.Context) (ServiceStatus, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return StatusOffline, err
+	}
+	client := t.client(t.Timeout)
+	client.Jar = jar
+	vars := make(map[string]string)
+	for i := range t.Steps {
+		step := &t.Steps[i]
+		if err := step.run(ctx, client, vars); err != nil {
+			return StatusOffline, fmt.Errorf("%s失败: %v", step.label(i), err)
+		}
+	}
+	return StatusOnline, nil
+}
+
+// run 执行一个步骤，校验响应并提取变量
+func (s *TransactionStep) run(ctx context.Context, client *http.Client, vars map[string]string) error {
+	var body io.Reader
+	contentType := ""
+	switch {
+	case len(s.Form) > 0:
+		form := url.Values{}
+		for k, v := range s.Form {
+			form.Set(k, expandVars(v, vars))
+		}
+		body = strings.NewReader(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	case s.Body != "":
+		body = strings.NewReader(expandVars(s.Body, vars))
+	}
+	req, err := http.NewRequestWithContext(ctx, s.method(), expandVars(s.URL, vars), body)
+	if err != nil {
+		return fmt.Errorf("创建HTTP请求失败: %v", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range s.Headers {
+		req.Header.Set(k, expandVars(v, vars))
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxExprBody))
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	if s.program != nil {
+		if _, err := evalCheckExpression(s.program, s.Expr, newProbeResult(resp, data, time.Since(start))); err != nil {
+			return err
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &HTTPStatusError{Code: resp.StatusCode}
+	}
+
+	for i := range s.Captures {
+		capture := &s.Captures[i]
+		v, err := capture.extract(ctx, resp, data, client.Jar)
+		if err != nil {
+			return fmt.Errorf("提取变量 '%s' 失败: %v", capture.Name, err)
+		}
+		vars[capture.Name] = v
+	}
+	return nil
+}
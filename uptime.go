@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultUptimeDays 可用率历史默认天数
+	defaultUptimeDays = 90
+	// maxUptimeDays 可用率历史最大天数
+	maxUptimeDays = 366
+)
+
+// UptimeBucket 一个时间段内的检查结果统计
+type UptimeBucket struct {
+	// Start 时间段开始时间
+	Start time.Time `json:"start"`
+	// Checks 检查次数
+	Checks int `json:"checks"`
+	// Counts 各状态的检查次数
+	Counts map[ServiceStatus]int `json:"counts"`
+	// UptimePercent 可用率，维护期间不计入，没有数据时为nil
+	UptimePercent *float64 `json:"uptime_percent"`
+	// Status 时间段内最差的状态，没有数据时为空
+	Status *ServiceStatus `json:"status"`
+}
+
+// statusSeverity 状态的严重程度，用于计算时间段内最差的状态
+var statusSeverity = map[ServiceStatus]int{
+	StatusOnline:      0,
+	StatusMaintenance: 1,
+	StatusDegraded:    2,
+	StatusImpacted:    3,
+	StatusOffline:     4,
+}
+
+// isUp 判断状态是否计为可用
+func isUp(status ServiceStatus) bool {
+	return status == StatusOnline || status == StatusDegraded
+}
+
+// add 将一条检查记录计入统计
+func (b *UptimeBucket) add(status ServiceStatus, n int) {
+	b.Checks += n
+	b.Counts[status] += n
+	if b.Status == nil || statusSeverity[status] > statusSeverity[*b.Status] {
+		s := status
+		b.Status = &s
+	}
+}
+
+// finish 计算可用率
+func (b *UptimeBucket) finish() {
+	up, total := 0, 0
+	for status, n := range b.Counts {
+		if status == StatusMaintenance {
+			continue
+		}
+		total += n
+		if isUp(status) {
+			up += n
+		}
+	}
+	if total > 0 {
+		percent := float64(up) / float64(total) * 100
+		b.UptimePercent = &percent
+	}
+}
+
+// uptimeBucketStart 返回第i个时间段的开始时间，step为一天时按start所在时区的自然日递增，
+// 夏令时切换当天的时间段不足或超过24小时
+func uptimeBucketStart(start time.Time, step time.Duration, i int) time.Time {
+	if step == 24*time.Hour {
+		return start.AddDate(0, 0, i)
+	}
+	return start.Add(time.Duration(i) * step)
+}
+
+// aggregateUptime 将小时汇总按时间段合并，start需按step对齐且step为整小时
+func aggregateUptime(rollups []HourlyRollup, start time.Time, step time.Duration, count int) []UptimeBucket {
+	buckets := make([]UptimeBucket, count)
+	for i := range buckets {
+		buckets[i] = UptimeBucket{
+			Start:  uptimeBucketStart(start, step, i),
+			Counts: make(map[ServiceStatus]int),
+		}
+	}
+	end := uptimeBucketStart(start, step, count)
+	for _, rollup := range rollups {
+		if rollup.Hour.Before(start) || !rollup.Hour.Before(end) {
+			continue
+		}
+		idx := sort.Search(count, func(i int) bool { return buckets[i].Start.After(rollup.Hour) }) - 1
+		for status, n := range rollup.Counts {
+			buckets[idx].add(status, n)
+		}
+	}
+	for i := range buckets {
+		buckets[i].finish()
+	}
+	return buckets
+}
+
+// overallUptime 计算所有时间段合计的可用率
+func overallUptime(buckets []UptimeBucket) *float64 {
+	total := UptimeBucket{Counts: make(map[ServiceStatus]int)}
+	for _, bucket := range buckets {
+		for status, n := range bucket.Counts {
+			total.add(status, n)
+		}
+	}
+	total.finish()
+	return total.UptimePercent
+}
+
+// uptimeRange 返回最近days天按天（day）或按小时（hour）统计时的起始时间、时间段长度与数量，
+// step无效时返回false
+func uptimeRange(step string, days int, now time.Time) (time.Time, time.Duration, int, bool) {
+	switch step {
+	case "day":
+		// 按显示时区的零点对齐
+		now = inDisplayZone(now)
+		y, m, d := now.Date()
+		today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+		return today.AddDate(0, 0, 1-days), 24 * time.Hour, days, true
+	case "hour":
+		return now.Truncate(time.Hour).Add(time.Duration(1-days*24) * time.Hour), time.Hour, days * 24, true
+	}
+	return time.Time{}, 0, 0, false
+}
+
+// apiServiceUptimeHandler 返回服务按天或按小时汇总的可用率历史
+func apiServiceUptimeHandler(c *gin.Context) {
+	name := c.Param("name")
+	if publicService(c, name) == nil {
+		apiError(c, http.StatusNotFound, "error.service_not_found")
+		return
+	}
+
+	days := defaultUptimeDays
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxUptimeDays {
+			apiError(c, http.StatusBadRequest, "error.invalid_param", "days")
+			return
+		}
+		days = n
+	}
+
+	start, step, count, ok := uptimeRange(c.DefaultQuery("step", "day"), days, time.Now())
+	if !ok {
+		apiError(c, http.StatusBadRequest, "error.invalid_step")
+		return
+	}
+
+	rollups, err := serviceManager.History().QueryRollups(name, start)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	buckets := aggregateUptime(rollups, start, step, count)
+	c.JSON(http.StatusOK, gin.H{
+		"service":        name,
+		"step":           c.DefaultQuery("step", "day"),
+		"uptime_percent": overallUptime(buckets),
+		"buckets":        buckets,
+	})
+}
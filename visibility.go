@@ -0,0 +1,104 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// visible 判断服务是否对请求可见：隐藏与停用的服务仅对已认证的调用方可见
+func visible(service *Service, authorized bool) bool {
+	return authorized || (service.Enabled && !service.Hidden)
+}
+
+// sortServices 按Order升序排列服务，Order相同时保持原有顺序
+func sortServices(services []*Service) {
+	sort.SliceStable(services, func(i, j int) bool {
+		return services[i].Order < services[j].Order
+	})
+}
+
+// sortServicesByName 按名称排列服务
+func sortServicesByName(services []*Service) {
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+}
+
+// visibleServices 返回请求可见的全部服务（含探针上报的服务），按显示顺序排列
+func visibleServices(c *gin.Context) []*Service {
+	authorized := canViewPrivate(c)
+	services := make([]*Service, 0)
+	for _, service := range allServices() {
+		if visible(service, authorized) {
+			services = append(services, service)
+		}
+	}
+	sortServices(services)
+	return redactServices(c, services)
+}
+
+// publicService 按名称获取请求可见的本机服务，不存在或不可见时返回nil
+func publicService(c *gin.Context, name string) *Service {
+	authorized := canViewPrivate(c)
+	service := serviceManager.GetService(name)
+	if service == nil || !visible(service, authorized) {
+		return nil
+	}
+	if detailsHidden(authorized) {
+		return redactService(service, requestLocale(c))
+	}
+	return service
+}
+
+// detailsHidden 判断是否需要对调用方隐藏错误详情与服务地址
+func detailsHidden(authorized bool) bool {
+	return !authorized && configReloader.Current().HideErrorDetails
+}
+
+// redactServices 启用hide_error_details且调用方未认证时，返回隐藏了错误详情与服务地址的服务列表
+func redactServices(c *gin.Context, services []*Service) []*Service {
+	if !detailsHidden(canViewPrivate(c)) {
+		return services
+	}
+	locale := requestLocale(c)
+	redacted := make([]*Service, len(services))
+	for i, service := range services {
+		redacted[i] = redactService(service, locale)
+	}
+	return redacted
+}
+
+// redactService 返回隐藏了错误详情与服务地址的服务副本，错误信息替换为检查失败
+func redactService(service *Service, locale string) *Service {
+	c := *service
+	c.URL = ""
+	if c.LastError != "" {
+		c.LastError = translate(locale, "error.check_failed")
+	}
+	if len(c.RegionResults) > 0 {
+		c.RegionResults = make([]RegionResult, len(service.RegionResults))
+		for i, result := range service.RegionResults {
+			if result.LastError != "" {
+				result.LastError = translate(locale, "error.check_failed")
+			}
+			c.RegionResults[i] = result
+		}
+	}
+	return &c
+}
+
+// redactEvents 启用hide_error_details时隐藏事件中的检查错误信息，authorized为true时原样返回
+func redactEvents(events []Event, authorized bool, locale string) []Event {
+	if !detailsHidden(authorized) {
+		return events
+	}
+	redacted := make([]Event, len(events))
+	for i, event := range events {
+		if event.CheckError {
+			event.Reason = translate(locale, "error.check_failed")
+		}
+		redacted[i] = event
+	}
+	return redacted
+}